@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/glueops/autoglue/internal/app"
 	"github.com/glueops/autoglue/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -44,8 +45,35 @@ var dbPsqlCmd = &cobra.Command{
 	},
 }
 
+var dbCheckNodeMetadataCmd = &cobra.Command{
+	Use:   "check-node-metadata",
+	Short: "List labels, annotations and taints Kubernetes would refuse",
+	Long: "Lists labels, annotations and taints saved before they were validated that Kubernetes\n" +
+		"would refuse, and node pools and servers with two values for one label or taint.\n" +
+		"Nothing is changed. Exits non-zero when anything needs fixing.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rt := app.NewRuntime()
+		defer rt.Close()
+
+		problems, err := app.InvalidNodeMetadata(rt.DB)
+		if err != nil {
+			return fmt.Errorf("checking node metadata: %w", err)
+		}
+		if len(problems) == 0 {
+			fmt.Println("No invalid node metadata found.")
+			return nil
+		}
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		return fmt.Errorf("%d node labels, annotations or taints need fixing before they can be applied to a cluster", len(problems))
+	},
+}
+
 func init() {
 	dbCmd.AddCommand(dbPsqlCmd)
+	dbCmd.AddCommand(dbCheckNodeMetadataCmd)
 
 	rootCmd.AddCommand(dbCmd)
 }
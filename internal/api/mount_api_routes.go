@@ -26,15 +26,17 @@ func mountAPIRoutes(r chi.Router, db *gorm.DB, cfg config.Config, jobs *bg.Clien
 			mountOrgRoutes(v1, db, authUser, authOrg)
 
 			mountCredentialRoutes(v1, db, authOrg)
-			mountSSHRoutes(v1, db, authOrg)
-			mountServerRoutes(v1, db, authOrg)
-			mountTaintRoutes(v1, db, authOrg)
-			mountLabelRoutes(v1, db, authOrg)
-			mountAnnotationRoutes(v1, db, authOrg)
-			mountNodePoolRoutes(v1, db, authOrg)
+			mountSSHRoutes(v1, db, jobs, authOrg)
+			mountServerRoutes(v1, db, jobs, authUser, authOrg)
+			mountTaintRoutes(v1, db, jobs, authOrg)
+			mountLabelRoutes(v1, db, jobs, authOrg)
+			mountAnnotationRoutes(v1, db, jobs, authOrg)
+			mountNodePoolRoutes(v1, db, jobs, authOrg)
 			mountDNSRoutes(v1, db, authOrg)
 			mountLoadBalancerRoutes(v1, db, authOrg)
+			mountSubnetRoutes(v1, db, authOrg)
 			mountClusterRoutes(v1, db, cfg, jobs, authOrg)
+			mountExecRoutes(v1, db, authOrg)
 		})
 	})
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
 	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/config"
 	"github.com/glueops/autoglue/internal/handlers"
@@ -39,6 +40,7 @@ func mountClusterRoutes(r chi.Router, db *gorm.DB, cfg config.Config, jobs *bg.C
 
 		c.Post("/{clusterID}/node-pools", handlers.AttachNodePool(db, cfg))
 		c.Delete("/{clusterID}/node-pools/{nodePoolID}", handlers.DetachNodePool(db, cfg))
+		c.Post("/{clusterID}/node-pools/{nodePoolID}/rollouts", handlers.StartNodePoolRollout(db, jobs))
 
 		c.Get("/{clusterID}/metadata", handlers.ListClusterMetadata(db))
 		c.Post("/{clusterID}/metadata", handlers.CreateClusterMetadata(db))
@@ -46,9 +48,16 @@ func mountClusterRoutes(r chi.Router, db *gorm.DB, cfg config.Config, jobs *bg.C
 		c.Patch("/{clusterID}/metadata/{metadataID}", handlers.UpdateClusterMetadata(db))
 		c.Delete("/{clusterID}/metadata/{metadataID}", handlers.DeleteClusterMetadata(db))
 
+		c.Get("/{clusterID}/node-metadata", handlers.ListClusterNodeMetadata(db))
+		c.Post("/{clusterID}/node-metadata/sync", handlers.SyncClusterNodeMetadata(db, jobs))
+
 		c.Get("/{clusterID}/runs", handlers.ListClusterRuns(db))
 		c.Get("/{clusterID}/runs/{runID}", handlers.GetClusterRun(db))
 		c.Get("/{clusterID}/runs/{runID}/logs", handlers.GetClusterRunLogs(db))
+		c.Get("/{clusterID}/runs/{runID}/servers", handlers.ListClusterRunServers(db))
+		c.Get("/{clusterID}/runs/{runID}/servers/{serverID}/logs", handlers.GetClusterRunServerLogs(db))
 		c.Post("/{clusterID}/actions/{actionID}/runs", handlers.RunClusterAction(db, jobs))
+
+		c.With(httpmiddleware.RequireRole("admin")).Post("/{clusterID}/exec", handlers.ExecOnCluster(db, jobs))
 	})
 }
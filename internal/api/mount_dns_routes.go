@@ -17,11 +17,16 @@ func mountDNSRoutes(r chi.Router, db *gorm.DB, authOrg func(http.Handler) http.H
 		d.Get("/domains/{id}", handlers.GetDomain(db))
 		d.Patch("/domains/{id}", handlers.UpdateDomain(db))
 		d.Delete("/domains/{id}", handlers.DeleteDomain(db))
+		d.Get("/domains/{id}/drift", handlers.GetDomainDrift(db))
+		d.Post("/domains/{id}/import", handlers.ImportDomainRecords(db))
+		d.Get("/domains/{id}/zonefile", handlers.ExportZoneFile(db))
+		d.Post("/domains/{id}/zonefile", handlers.ImportZoneFile(db))
 
 		d.Get("/domains/{domain_id}/records", handlers.ListRecordSets(db))
 		d.Post("/domains/{domain_id}/records", handlers.CreateRecordSet(db))
 		d.Get("/records/{id}", handlers.GetRecordSet(db))
 		d.Patch("/records/{id}", handlers.UpdateRecordSet(db))
 		d.Delete("/records/{id}", handlers.DeleteRecordSet(db))
+		d.Post("/records/{id}/adopt", handlers.AdoptRecordSet(db))
 	})
 }
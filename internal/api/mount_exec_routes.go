@@ -0,0 +1,18 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/glueops/autoglue/internal/handlers"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+func mountExecRoutes(r chi.Router, db *gorm.DB, authOrg func(http.Handler) http.Handler) {
+	r.Route("/exec-runs", func(e chi.Router) {
+		e.Use(authOrg)
+		e.Get("/", handlers.ListExecRuns(db))
+		e.Get("/{id}", handlers.GetExecRun(db))
+		e.Get("/{id}/hosts/{hostID}/logs", handlers.GetExecRunHostLogs(db))
+	})
+}
@@ -3,18 +3,19 @@ package api
 import (
 	"net/http"
 
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/handlers"
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
 )
 
-func mountLabelRoutes(r chi.Router, db *gorm.DB, authOrg func(http.Handler) http.Handler) {
+func mountLabelRoutes(r chi.Router, db *gorm.DB, jobs *bg.Client, authOrg func(http.Handler) http.Handler) {
 	r.Route("/labels", func(l chi.Router) {
 		l.Use(authOrg)
 		l.Get("/", handlers.ListLabels(db))
 		l.Post("/", handlers.CreateLabel(db))
 		l.Get("/{id}", handlers.GetLabel(db))
-		l.Patch("/{id}", handlers.UpdateLabel(db))
-		l.Delete("/{id}", handlers.DeleteLabel(db))
+		l.Patch("/{id}", handlers.UpdateLabel(db, jobs))
+		l.Delete("/{id}", handlers.DeleteLabel(db, jobs))
 	})
 }
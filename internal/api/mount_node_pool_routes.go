@@ -3,12 +3,14 @@ package api
 import (
 	"net/http"
 
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/handlers"
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
 )
 
-func mountNodePoolRoutes(r chi.Router, db *gorm.DB, authOrg func(http.Handler) http.Handler) {
+func mountNodePoolRoutes(r chi.Router, db *gorm.DB, jobs *bg.Client, authOrg func(http.Handler) http.Handler) {
 	r.Route("/node-pools", func(n chi.Router) {
 		n.Use(authOrg)
 		n.Get("/", handlers.ListNodePools(db))
@@ -16,25 +18,29 @@ func mountNodePoolRoutes(r chi.Router, db *gorm.DB, authOrg func(http.Handler) h
 		n.Get("/{id}", handlers.GetNodePool(db))
 		n.Patch("/{id}", handlers.UpdateNodePool(db))
 		n.Delete("/{id}", handlers.DeleteNodePool(db))
+		n.Get("/{id}/plan", handlers.GetNodePoolPlan(db))
 
 		// Servers
 		n.Get("/{id}/servers", handlers.ListNodePoolServers(db))
-		n.Post("/{id}/servers", handlers.AttachNodePoolServers(db))
-		n.Delete("/{id}/servers/{serverId}", handlers.DetachNodePoolServer(db))
+		n.Post("/{id}/servers", handlers.AttachNodePoolServers(db, jobs))
+		n.Delete("/{id}/servers/{serverId}", handlers.DetachNodePoolServer(db, jobs))
 
 		// Taints
 		n.Get("/{id}/taints", handlers.ListNodePoolTaints(db))
-		n.Post("/{id}/taints", handlers.AttachNodePoolTaints(db))
-		n.Delete("/{id}/taints/{taintId}", handlers.DetachNodePoolTaint(db))
+		n.Post("/{id}/taints", handlers.AttachNodePoolTaints(db, jobs))
+		n.Delete("/{id}/taints/{taintId}", handlers.DetachNodePoolTaint(db, jobs))
 
 		// Labels
 		n.Get("/{id}/labels", handlers.ListNodePoolLabels(db))
-		n.Post("/{id}/labels", handlers.AttachNodePoolLabels(db))
-		n.Delete("/{id}/labels/{labelId}", handlers.DetachNodePoolLabel(db))
+		n.Post("/{id}/labels", handlers.AttachNodePoolLabels(db, jobs))
+		n.Delete("/{id}/labels/{labelId}", handlers.DetachNodePoolLabel(db, jobs))
 
 		// Annotations
 		n.Get("/{id}/annotations", handlers.ListNodePoolAnnotations(db))
-		n.Post("/{id}/annotations", handlers.AttachNodePoolAnnotations(db))
-		n.Delete("/{id}/annotations/{annotationId}", handlers.DetachNodePoolAnnotation(db))
+		n.Post("/{id}/annotations", handlers.AttachNodePoolAnnotations(db, jobs))
+		n.Delete("/{id}/annotations/{annotationId}", handlers.DetachNodePoolAnnotation(db, jobs))
+
+		// Ad-hoc commands
+		n.With(httpmiddleware.RequireRole("admin")).Post("/{id}/exec", handlers.ExecOnNodePool(db, jobs))
 	})
 }
@@ -3,20 +3,34 @@ package api
 import (
 	"net/http"
 
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/handlers"
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
 )
 
-func mountServerRoutes(r chi.Router, db *gorm.DB, authOrg func(http.Handler) http.Handler) {
+func mountServerRoutes(r chi.Router, db *gorm.DB, jobs *bg.Client, authUser, authOrg func(http.Handler) http.Handler) {
 	r.Route("/servers", func(s chi.Router) {
-		s.Use(authOrg)
-		s.Get("/", handlers.ListServers(db))
-		s.Post("/", handlers.CreateServer(db))
-		s.Get("/{id}", handlers.GetServer(db))
-		s.Get("/{id}/logs", handlers.GetServerLogs(db))
-		s.Patch("/{id}", handlers.UpdateServer(db))
-		s.Delete("/{id}", handlers.DeleteServer(db))
-		s.Post("/{id}/reset-hostkey", handlers.ResetServerHostKey(db))
+		s.Group(func(s chi.Router) {
+			s.Use(authOrg)
+			s.Get("/", handlers.ListServers(db))
+			s.Post("/", handlers.CreateServer(db))
+			s.Post("/import", handlers.ImportServers(db))
+			s.Post("/provision", handlers.ProvisionServer(db, jobs))
+			s.Get("/{id}", handlers.GetServer(db))
+			s.Get("/{id}/logs", handlers.GetServerLogs(db))
+			s.Patch("/{id}", handlers.UpdateServer(db))
+			s.Delete("/{id}", handlers.DeleteServer(db, jobs))
+			s.Post("/{id}/reset-hostkey", handlers.ResetServerHostKey(db))
+			s.Post("/{id}/facts", handlers.RefreshServerFacts(db, jobs))
+
+			s.With(httpmiddleware.RequireRole("admin")).Get("/{id}/terminal-sessions", handlers.ListServerTerminalSessions(db))
+			s.With(httpmiddleware.RequireRole("admin")).Get("/{id}/terminal-sessions/{sessionID}/logs", handlers.GetTerminalSessionLogs(db))
+		})
+
+		// A browser WebSocket cannot send X-Org-ID, so the terminal resolves
+		// the org from the server and checks the caller's role there itself.
+		s.With(authUser).Get("/{id}/terminal", handlers.ServerTerminal(db))
 	})
 }
@@ -3,18 +3,26 @@ package api
 import (
 	"net/http"
 
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/handlers"
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
 )
 
-func mountSSHRoutes(r chi.Router, db *gorm.DB, authOrg func(http.Handler) http.Handler) {
+func mountSSHRoutes(r chi.Router, db *gorm.DB, jobs *bg.Client, authOrg func(http.Handler) http.Handler) {
 	r.Route("/ssh", func(s chi.Router) {
 		s.Use(authOrg)
+		s.Get("/ca", handlers.GetSSHCA(db))
+		s.With(httpmiddleware.RequireRole("admin")).Post("/ca/install", handlers.InstallSSHCA(db, jobs))
+		s.Get("/certificates", handlers.ListSSHCertificates(db))
+		s.Post("/certificates", handlers.IssueSSHCertificate(db))
+
 		s.Get("/", handlers.ListPublicSshKeys(db))
 		s.Post("/", handlers.CreateSSHKey(db))
 		s.Get("/{id}", handlers.GetSSHKey(db))
 		s.Delete("/{id}", handlers.DeleteSSHKey(db))
 		s.Get("/{id}/download", handlers.DownloadSSHKey(db))
+		s.Post("/{id}/rotate", handlers.RotateSSHKey(db, jobs))
 	})
 }
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/glueops/autoglue/internal/handlers"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+func mountSubnetRoutes(r chi.Router, db *gorm.DB, authOrg func(http.Handler) http.Handler) {
+	r.Route("/subnets", func(s chi.Router) {
+		s.Use(authOrg)
+		s.Get("/", handlers.ListSubnets(db))
+		s.Post("/", handlers.CreateSubnet(db))
+		s.Get("/{id}", handlers.GetSubnet(db))
+		s.Patch("/{id}", handlers.UpdateSubnet(db))
+		s.Delete("/{id}", handlers.DeleteSubnet(db))
+		s.Post("/{id}/allocate", handlers.AllocateSubnetAddress(db))
+	})
+}
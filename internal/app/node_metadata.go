@@ -0,0 +1,77 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvalidNodeMetadata lists the labels, annotations and taints saved before
+// they were validated that Kubernetes would refuse, and the node pools and
+// servers that end up with two values for one label or taint. Nothing is
+// changed: a fix means choosing a new key or value, which only the org can
+// do. It reads every org's metadata, so it runs on demand through
+// `autoglue db check-node-metadata` rather than at boot.
+func InvalidNodeMetadata(d *gorm.DB) ([]string, error) {
+	var problems []string
+
+	var labels []models.Label
+	var annotations []models.Annotation
+	var taints []models.Taint
+	var pools []models.NodePool
+	err := d.Find(&labels).Error
+	if err == nil {
+		err = d.Find(&annotations).Error
+	}
+	if err == nil {
+		err = d.Find(&taints).Error
+	}
+	if err == nil {
+		err = d.Preload("Labels").Preload("Taints").Preload("Servers").Find(&pools).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range labels {
+		if err := l.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("org %s label %s: %v", l.OrganizationID, l.ID, err))
+		}
+	}
+	for _, a := range annotations {
+		if err := a.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("org %s annotation %s: %v", a.OrganizationID, a.ID, err))
+		}
+	}
+	for _, t := range taints {
+		if err := t.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("org %s taint %s: %v", t.OrganizationID, t.ID, err))
+		}
+	}
+
+	byServer := map[uuid.UUID][]models.NodePool{}
+	hostnames := map[uuid.UUID]string{}
+	for _, p := range pools {
+		for _, c := range models.NodeMetadataConflicts([]models.NodePool{p}) {
+			problems = append(problems, fmt.Sprintf("org %s node pool %s: %s", p.OrganizationID, p.ID, c))
+		}
+		for _, s := range p.Servers {
+			byServer[s.ID] = append(byServer[s.ID], p)
+			hostnames[s.ID] = s.Hostname
+		}
+	}
+	for id, ps := range byServer {
+		if len(ps) < 2 {
+			continue
+		}
+		for _, c := range models.NodeMetadataConflicts(ps) {
+			problems = append(problems, fmt.Sprintf("org %s server %s (%s): %s", ps[0].OrganizationID, id, hostnames[id], c))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems, nil
+}
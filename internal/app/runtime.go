@@ -38,7 +38,12 @@ func NewRuntime() *Runtime {
 		&models.RefreshToken{},
 		&models.OrganizationKey{},
 		&models.SshKey{},
+		&models.SshCertificateAuthority{},
+		&models.SshCertificate{},
 		&models.Server{},
+		&models.ServerFacts{},
+		&models.ServerInstance{},
+		&models.NodeMetadataStatus{},
 		&models.Taint{},
 		&models.Label{},
 		&models.Annotation{},
@@ -47,11 +52,17 @@ func NewRuntime() *Runtime {
 		&models.Domain{},
 		&models.RecordSet{},
 		&models.LoadBalancer{},
+		&models.Subnet{},
+		&models.SubnetReservation{},
 		&models.Cluster{},
 		&models.Action{},
 		&models.ClusterRun{},
+		&models.ClusterRunServer{},
+		&models.ExecRun{},
+		&models.ExecRunHost{},
 		&models.ClusterMetadata{},
 		&models.JobLog{},
+		&models.TerminalSession{},
 	)
 
 	if err != nil {
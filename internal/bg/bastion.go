@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/sshca"
 	"github.com/glueops/autoglue/internal/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -243,13 +244,26 @@ func (w *BastionBootstrapWorker) Work(ctx context.Context, j *river.Job[BastionB
 		return fail("ssh_install", fmt.Errorf("%v | tail=%q", err, tail))
 	}
 
-	// 4) Mark ready
+	// 4) Trust the org SSH CA, so short-lived certificates from
+	// POST /ssh/certificates work on this bastion from the moment it is ready.
+	// A separate connection on purpose: the install reloads sshd, and doing
+	// it inside the bootstrap session would race the script's own reload.
+	// Best-effort: the bastion works without the CA through its own key, so a
+	// host that cannot take the drop-in (no sshd_config.d, say) still comes
+	// up, and ssh_ca_install retries once it is ready.
+	caTrusted := trustCA(ctx, db, &s, sink)
+
+	// 5) Mark ready
 	if err := setServerStatus(db, s.ID, "ready"); err != nil {
 		return fail("set_ready", err)
 	}
 
 	sink.System("bastion ready")
 
+	if !caTrusted {
+		retryCAInstall(ctx, &s, sink)
+	}
+
 	log.Info().Str("server_id", s.ID.String()).
 		Int64("elapsed_ms", time.Since(start).Milliseconds()).
 		Msg("[bastion] bootstrap ok")
@@ -266,6 +280,38 @@ func (w *BastionBootstrapWorker) Work(ctx context.Context, j *river.Job[BastionB
 
 // ----- Helpers -----
 
+// trustCA installs the org SSH CA on a bastion being bootstrapped. Failures
+// are logged to the sink rather than failing the bootstrap.
+func trustCA(ctx context.Context, db *gorm.DB, s *models.Server, sink *LogSink) bool {
+	ca, err := sshca.EnsureOrgCA(db, s.OrganizationID)
+	if err == nil {
+		sink.System("installing ssh user ca " + ca.Fingerprint)
+		err = installUserCA(ctx, db, s, ca)
+	}
+	if err != nil {
+		sink.System("warning: ssh user ca not installed: " + err.Error())
+		log.Warn().Err(err).Str("server_id", s.ID.String()).Msg("[bastion] ssh ca install failed; will retry")
+		return false
+	}
+	return true
+}
+
+// retryCAInstall queues ssh_ca_install for a bastion whose CA install failed
+// during bootstrap. That worker only touches ready servers, so this runs
+// after the bastion is marked ready.
+func retryCAInstall(ctx context.Context, s *models.Server, sink *LogSink) {
+	client, err := river.ClientFromContextSafely[pgx.Tx](ctx)
+	if err == nil {
+		_, err = client.Insert(ctx, SSHCAInstallArgs{OrgID: s.OrganizationID, ServerIDs: []uuid.UUID{s.ID}}, nil)
+	}
+	if err != nil {
+		sink.System("warning: could not queue ssh ca install retry: " + err.Error())
+		log.Warn().Err(err).Str("server_id", s.ID.String()).Msg("[bastion] could not queue ssh ca install")
+		return
+	}
+	sink.System("queued ssh_ca_install to retry the ssh user ca")
+}
+
 func setServerStatus(db *gorm.DB, id uuid.UUID, status string) error {
 	return db.Model(&models.Server{}).
 		Where("id = ?", id).
@@ -0,0 +1,334 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/compute"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/google/uuid"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// computePoll is how long a create waits between looks at a machine that is
+// still booting. Snoozing rather than sleeping keeps the worker slot free.
+const computePoll = 10 * time.Second
+
+var computeUnique = river.UniqueOpts{
+	ByArgs: true,
+	ByState: []rivertype.JobState{
+		rivertype.JobStateAvailable, rivertype.JobStateScheduled,
+		rivertype.JobStateRunning, rivertype.JobStateRetryable,
+		rivertype.JobStatePending,
+	},
+}
+
+// ComputeCreateArgs creates the cloud machine behind a server in status
+// creating, waits for it to boot, and fills in its addresses.
+type ComputeCreateArgs struct {
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+func (ComputeCreateArgs) Kind() string { return "compute_create" }
+
+func (ComputeCreateArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueClusters, MaxAttempts: 5, UniqueOpts: computeUnique}
+}
+
+// ComputeDeleteArgs deletes the cloud machine behind a server and then the
+// server itself.
+type ComputeDeleteArgs struct {
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+func (ComputeDeleteArgs) Kind() string { return "compute_delete" }
+
+func (ComputeDeleteArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueClusters, MaxAttempts: 10, UniqueOpts: computeUnique}
+}
+
+type ComputeResult struct {
+	Status     string    `json:"status"`
+	ServerID   uuid.UUID `json:"server_id"`
+	InstanceID string    `json:"instance_id,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type ComputeCreateWorker struct {
+	river.WorkerDefaults[ComputeCreateArgs]
+	db *gorm.DB
+}
+
+func (w *ComputeCreateWorker) Timeout(*river.Job[ComputeCreateArgs]) time.Duration {
+	return 2 * time.Minute
+}
+
+func (w *ComputeCreateWorker) Work(ctx context.Context, j *river.Job[ComputeCreateArgs]) error {
+	db := w.db
+
+	var s models.Server
+	if err := db.Preload("SshKey").Preload("Instance").Where("id = ?", j.Args.ServerID).First(&s).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	inst := s.Instance
+	if inst == nil || inst.Status != models.ServerInstanceCreating || s.Status != "creating" {
+		return nil
+	}
+
+	fail := func(err error) error {
+		// Out of attempts, or not worth retrying: park it as failed. The
+		// instance row stays so a delete still cleans up a half-made machine.
+		if j.Attempt >= j.MaxAttempts || errors.Is(err, compute.ErrUnsupported) {
+			log.Error().Err(err).Str("server_id", s.ID.String()).Msg("[compute] create failed")
+			markComputeFailed(db, &s, err)
+			recordComputeOutput(ctx, ComputeResult{Status: "failed", ServerID: s.ID, InstanceID: inst.InstanceID, Error: err.Error()})
+			return nil
+		}
+		return err
+	}
+
+	p, err := computeProviderFor(db, inst)
+	if err != nil {
+		return fail(err)
+	}
+
+	if inst.InstanceID == "" {
+		created, err := p.Create(ctx, compute.Spec{
+			Name:         computeName(&s),
+			Size:         inst.Size,
+			Image:        inst.Image,
+			Location:     inst.Location,
+			Network:      inst.Network,
+			SSHPublicKey: s.SshKey.PublicKey,
+			Labels: map[string]string{
+				"autoglue-org":    s.OrganizationID.String(),
+				"autoglue-server": s.ID.String(),
+			},
+		})
+		if err != nil {
+			return fail(err)
+		}
+
+		// Conditional on the row still being there: if the server was deleted
+		// while the provider was creating, nobody else knows this machine
+		// exists, so it has to go now.
+		res := db.Model(&models.ServerInstance{}).
+			Where("server_id = ? AND instance_id = ''", s.ID).
+			Update("instance_id", created.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if err := p.Delete(ctx, created.ID); err != nil && !errors.Is(err, compute.ErrNotFound) {
+				log.Error().Err(err).Str("instance_id", created.ID).Msg("[compute] could not delete orphaned machine")
+			}
+			return nil
+		}
+		inst.InstanceID = created.ID
+		log.Info().Str("server_id", s.ID.String()).Str("instance_id", created.ID).Msg("[compute] machine created")
+	}
+
+	got, err := p.Get(ctx, inst.InstanceID)
+	if err != nil {
+		return fail(err)
+	}
+	if !got.Ready || (inst.Network != "" && got.PrivateIP == "") {
+		if time.Since(inst.CreatedAt) > interval("compute.ready_timeout_seconds", 15*time.Minute) {
+			return fail(fmt.Errorf("machine %s not ready after waiting (status %q)", inst.InstanceID, got.Status))
+		}
+		return river.JobSnooze(computePoll)
+	}
+
+	if err := markComputeReady(db, &s, got); err != nil {
+		var ic *models.IPConflictError
+		if errors.As(err, &ic) {
+			// Retrying will not free the address; the instance row stays so
+			// deleting the server also deletes the machine.
+			log.Error().Err(err).Str("server_id", s.ID.String()).Msg("[compute] address conflict")
+			markComputeFailed(db, &s, err)
+			recordComputeOutput(ctx, ComputeResult{Status: "failed", ServerID: s.ID, InstanceID: got.ID, Error: err.Error()})
+			return nil
+		}
+		return err
+	}
+	recordComputeOutput(ctx, ComputeResult{Status: "ok", ServerID: s.ID, InstanceID: got.ID})
+	return nil
+}
+
+type ComputeDeleteWorker struct {
+	river.WorkerDefaults[ComputeDeleteArgs]
+	db *gorm.DB
+}
+
+func (w *ComputeDeleteWorker) Timeout(*river.Job[ComputeDeleteArgs]) time.Duration {
+	return 2 * time.Minute
+}
+
+func (w *ComputeDeleteWorker) Work(ctx context.Context, j *river.Job[ComputeDeleteArgs]) error {
+	db := w.db
+
+	var inst models.ServerInstance
+	if err := db.Where("server_id = ?", j.Args.ServerID).First(&inst).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if inst.InstanceID != "" {
+		p, err := computeProviderFor(db, &inst)
+		if err == nil {
+			err = p.Delete(ctx, inst.InstanceID)
+		}
+		if err != nil && !errors.Is(err, compute.ErrNotFound) {
+			_ = db.Model(&models.ServerInstance{}).Where("server_id = ?", inst.ServerID).
+				Update("last_error", truncateErr(err.Error())).Error
+			if j.Attempt >= j.MaxAttempts {
+				log.Error().Err(err).Str("server_id", inst.ServerID.String()).Msg("[compute] delete failed; server kept")
+				_ = db.Model(&models.ServerInstance{}).Where("server_id = ?", inst.ServerID).
+					Update("status", models.ServerInstanceFailed).Error
+				recordComputeOutput(ctx, ComputeResult{Status: "failed", ServerID: inst.ServerID, InstanceID: inst.InstanceID, Error: err.Error()})
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := db.Where("id = ?", inst.ServerID).Delete(&models.Server{}).Error; err != nil {
+		return err
+	}
+	recordComputeOutput(ctx, ComputeResult{Status: "ok", ServerID: inst.ServerID, InstanceID: inst.InstanceID})
+	return nil
+}
+
+// QueueServerDeletion hands a cloud server to the compute_delete job, which
+// removes the machine and then the row. The server is marked deleting first
+// so nothing else picks it up in the meantime.
+func QueueServerDeletion(ctx context.Context, db *gorm.DB, client *Client, serverID uuid.UUID) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Server{}).Where("id = ?", serverID).Update("status", "deleting").Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ServerInstance{}).Where("server_id = ?", serverID).
+			Update("status", models.ServerInstanceDeleting).Error
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.Insert(ctx, ComputeDeleteArgs{ServerID: serverID}, nil)
+	return err
+}
+
+// computeProviderFor builds the provider client from the instance's
+// credential, which must belong to the same org.
+func computeProviderFor(db *gorm.DB, inst *models.ServerInstance) (compute.Provider, error) {
+	var cred models.Credential
+	if err := db.Where("id = ? AND organization_id = ?", inst.CredentialID, inst.OrganizationID).First(&cred).Error; err != nil {
+		return nil, fmt.Errorf("credential not found: %w", err)
+	}
+	secret, err := utils.DecryptForOrg(inst.OrganizationID, cred.EncryptedData, cred.IV, cred.Tag, db)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	var tok dto.APIToken
+	if err := jsonUnmarshalStrict([]byte(secret), &tok); err != nil {
+		return nil, fmt.Errorf("secret decode: %w", err)
+	}
+	return compute.New(cred.Provider, tok.Token)
+}
+
+// computeName is the machine name at the provider. Hostnames are what people
+// recognise in the provider console, but are optional here, so fall back to
+// something derived from the id.
+func computeName(s *models.Server) string {
+	if h := strings.TrimSpace(s.Hostname); h != "" {
+		return h
+	}
+	return "autoglue-" + s.ID.String()[:8]
+}
+
+// markComputeReady fills the addresses in and hands the server to the normal
+// lifecycle as pending. A machine without a private network has only its
+// public address, which then doubles as the private one so everything that
+// expects a private IP keeps working. The addresses are checked against the
+// rest of the org under its lock first; one that is already taken or
+// reserved fails with an IPConflictError and nothing is written.
+func markComputeReady(db *gorm.DB, s *models.Server, in *compute.Instance) error {
+	public, private := canonicalIP(in.PublicIP), canonicalIP(in.PrivateIP)
+	if private == "" {
+		private = public
+	}
+	var addrs []string
+	for _, ip := range []string{public, private} {
+		if ip != "" && !slices.Contains(addrs, ip) {
+			addrs = append(addrs, ip)
+		}
+	}
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := models.CheckIPConflicts(tx, s.OrganizationID, s.ID, addrs...); err != nil {
+			return err
+		}
+		if err := models.CheckIPReservations(tx, s.OrganizationID, addrs...); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Server{}).Where("id = ? AND status = ?", s.ID, "creating").
+			Updates(map[string]any{
+				"public_ip_address":  public,
+				"private_ip_address": private,
+				"ssh_host_key":       "",
+				"ssh_host_key_algo":  "",
+				"status":             "pending",
+				"updated_at":         now,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ServerInstance{}).Where("server_id = ?", s.ID).
+			Updates(map[string]any{
+				"status":     models.ServerInstanceRunning,
+				"last_error": "",
+				"ready_at":   now,
+			}).Error
+	})
+}
+
+// canonicalIP is the canonical form of an address reported by a provider.
+// One that does not parse is kept as it is rather than dropped.
+func canonicalIP(raw string) string {
+	if ip, err := models.NormalizeIP(raw); err == nil {
+		return ip
+	}
+	return raw
+}
+
+func markComputeFailed(db *gorm.DB, s *models.Server, cause error) {
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ServerInstance{}).Where("server_id = ?", s.ID).
+			Updates(map[string]any{
+				"status":     models.ServerInstanceFailed,
+				"last_error": truncateErr(cause.Error()),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Server{}).Where("id = ? AND status = ?", s.ID, "creating").
+			Updates(map[string]any{"status": "failed", "updated_at": time.Now()}).Error
+	})
+}
+
+func recordComputeOutput(ctx context.Context, res ComputeResult) {
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[compute] could not record output")
+	}
+}
@@ -0,0 +1,82 @@
+package bg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glueops/autoglue/internal/compute"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/testutil/pgtest"
+	"github.com/google/uuid"
+)
+
+func TestMarkComputeReadyRefusesTakenAddresses(t *testing.T) {
+	db := pgtest.DB(t)
+
+	org := models.Organization{Name: "compute-ip-org"}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	key := models.SshKey{Name: "key-" + uuid.NewString()}
+	key.OrganizationID = org.ID
+	if err := db.Create(&key).Error; err != nil {
+		t.Fatalf("create ssh key: %v", err)
+	}
+	manual := models.Server{OrganizationID: org.ID, Hostname: "manual", PrivateIPAddress: "10.0.0.5", SSHUser: "root", SshKeyID: key.ID, Role: "worker"}
+	if err := db.Create(&manual).Error; err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+	s := models.Server{OrganizationID: org.ID, Hostname: "cloud", SSHUser: "root", SshKeyID: key.ID, Role: "worker", Status: "creating"}
+	if err := db.Omit("Instance", "Facts").Create(&s).Error; err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+	if err := db.Create(&models.ServerInstance{
+		ServerID:       s.ID,
+		OrganizationID: org.ID,
+		Provider:       "hetzner",
+		CredentialID:   uuid.New(),
+		Size:           "cx22",
+		Image:          "ubuntu-24.04",
+		Status:         models.ServerInstanceCreating,
+	}).Error; err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	subnet := models.Subnet{OrganizationID: org.ID, Name: "lan", CIDR: "10.0.0.0/24"}
+	if err := db.Create(&subnet).Error; err != nil {
+		t.Fatalf("create subnet: %v", err)
+	}
+	if err := db.Create(&models.SubnetReservation{
+		OrganizationID: org.ID,
+		SubnetID:       subnet.ID,
+		Address:        "10.0.0.6",
+		ExpiresAt:      time.Now().Add(time.Minute),
+	}).Error; err != nil {
+		t.Fatalf("create reservation: %v", err)
+	}
+
+	for _, ip := range []string{"10.0.0.5", "10.0.0.6"} {
+		err := markComputeReady(db, &s, &compute.Instance{Ready: true, PublicIP: "198.51.100.7", PrivateIP: ip})
+		var ic *models.IPConflictError
+		if !errors.As(err, &ic) {
+			t.Fatalf("%s: err = %v, want an IPConflictError", ip, err)
+		}
+	}
+	assertServerStatus(t, s.ID, "creating")
+
+	if err := markComputeReady(db, &s, &compute.Instance{Ready: true, PublicIP: "198.51.100.7", PrivateIP: "10.0.0.7"}); err != nil {
+		t.Fatalf("free address: %v", err)
+	}
+	assertServerStatus(t, s.ID, "pending")
+}
+
+func assertServerStatus(t *testing.T, id uuid.UUID, want string) {
+	t.Helper()
+	var got models.Server
+	if err := pgtest.DB(t).Select("status").Where("id = ?", id).First(&got).Error; err != nil {
+		t.Fatalf("load server: %v", err)
+	}
+	if got.Status != want {
+		t.Fatalf("server status = %q, want %q", got.Status, want)
+	}
+}
@@ -5,23 +5,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
+	"math/rand/v2"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/glueops/autoglue/internal/dns"
 	"github.com/glueops/autoglue/internal/handlers/dto"
 	"github.com/glueops/autoglue/internal/models"
 	"github.com/glueops/autoglue/internal/utils"
 	"github.com/riverqueue/river"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
-	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
 	"github.com/aws/smithy-go"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
@@ -39,21 +37,7 @@ func (DNSReconcileArgs) InsertOpts() river.InsertOpts {
 	return river.InsertOpts{Queue: QueueMaintenance, MaxAttempts: 2}
 }
 
-// TXT marker content (compact)
-type ownershipMarker struct {
-	Ver string `json:"v"`   // "ag1"
-	Org string `json:"org"` // org UUID
-	Rec string `json:"rec"` // record UUID
-	Fp  string `json:"fp"`  // short fp (first 16 of sha256)
-}
-
-// ExternalDNS poison owner id – MUST NOT match any real external-dns --txt-owner-id
-const externalDNSPoisonOwner = "autoglue-lock"
-
-// ExternalDNS poison content – fake owner so real external-dns skips it.
-const externalDNSPoisonValue = "heritage=external-dns,external-dns/owner=" + externalDNSPoisonOwner + ",external-dns/resource=manual/autoglue"
-
-// Default TTL for non-alias records (alias not supported in this reconciler)
+// Default TTL for non-alias records; also used for markers and poison records
 const defaultRecordTTLSeconds int64 = 300
 
 /************* entrypoint worker *************/
@@ -119,17 +103,44 @@ func reconcileDNSOnce(ctx context.Context, db *gorm.DB, args DNSReconcileArgs) (
 		}
 	}
 
-	// 2) apply pending record sets for ready domains
-	var readyDomains []models.Domain
-	if err := db.Where("status = ?", "ready").Find(&readyDomains).Error; err != nil {
+	// 2) delete record sets marked deleting, then apply pending ones. A
+	// domain being deleted only gets its deletions.
+	var activeDomains []models.Domain
+	if err := db.Where("status IN ?", []string{"ready", "deleting"}).Find(&activeDomains).Error; err != nil {
 		return domainsProcessed, 0, err
 	}
 
 	recordsProcessed := 0
-	for i := range readyDomains {
-		n, err := processPendingRecordsForDomain(ctx, db, &readyDomains[i], args.MaxRecords)
+	for i := range activeDomains {
+		d := &activeDomains[i]
+		n, err := processDeletingRecordsForDomain(ctx, db, d, args.MaxRecords)
+		recordsProcessed += n
+		if err != nil {
+			log.Error().Err(err).Str("domain", d.DomainName).Msg("[dns] record deletion failed")
+			continue
+		}
+		if d.Status == "deleting" {
+			if err := finishDomainDeletion(db, d); err != nil {
+				log.Error().Err(err).Str("domain", d.DomainName).Msg("[dns] domain deletion failed")
+			}
+			continue
+		}
+
+		if delegationDue(d, time.Now()) {
+			if err := updateDelegation(ctx, db, d); err != nil {
+				log.Error().Err(err).Str("domain", d.DomainName).Msg("[dns] delegation check failed")
+			}
+		}
+
+		n, err = processProvisioningRecordsForDomain(ctx, db, d)
+		recordsProcessed += n
+		if err != nil {
+			log.Error().Err(err).Str("domain", d.DomainName).Msg("[dns] change status check failed")
+		}
+
+		n, err = processPendingRecordsForDomain(ctx, db, d, args.MaxRecords)
 		if err != nil {
-			log.Error().Err(err).Str("domain", readyDomains[i].DomainName).Msg("[dns] record processing failed")
+			log.Error().Err(err).Str("domain", d.DomainName).Msg("[dns] record processing failed")
 			continue
 		}
 		recordsProcessed += n
@@ -141,34 +152,19 @@ func reconcileDNSOnce(ctx context.Context, db *gorm.DB, args DNSReconcileArgs) (
 /************* domain processing *************/
 
 func processDomain(ctx context.Context, db *gorm.DB, d *models.Domain) error {
-	orgID := d.OrganizationID
-
-	// 1) Load credential (org-guarded)
-	var cred models.Credential
-	if err := db.Where("id = ? AND organization_id = ?", d.CredentialID, orgID).First(&cred).Error; err != nil {
-		return setDomainFailed(db, d, fmt.Errorf("credential not found: %w", err))
-	}
-
-	// 2) Decrypt → dto.AWSCredential
-	secret, err := utils.DecryptForOrg(orgID, cred.EncryptedData, cred.IV, cred.Tag, db)
-	if err != nil {
-		return setDomainFailed(db, d, fmt.Errorf("decrypt: %w", err))
-	}
-	var awsCred dto.AWSCredential
-	if err := jsonUnmarshalStrict([]byte(secret), &awsCred); err != nil {
-		return setDomainFailed(db, d, fmt.Errorf("secret decode: %w", err))
-	}
-
-	// 3) Client
-	r53c, _, err := newRoute53Client(ctx, awsCred)
+	// 1) Client from the domain's credential
+	p, err := DNSProviderFor(ctx, db, d)
 	if err != nil {
 		return setDomainFailed(db, d, err)
 	}
 
-	// 4) Backfill zone id if missing
+	// 2) Backfill zone id if missing, creating the zone when asked to
 	zoneID := strings.TrimSpace(d.ZoneID)
 	if zoneID == "" {
-		zid, err := findHostedZoneID(ctx, r53c, d.DomainName)
+		zid, err := p.FindZone(ctx, d.DomainName)
+		if errors.Is(err, dns.ErrZoneNotFound) && d.CreateZone {
+			zid, err = createZone(ctx, p, d)
+		}
 		if err != nil {
 			return setDomainFailed(db, d, fmt.Errorf("discover zone id: %w", err))
 		}
@@ -176,12 +172,18 @@ func processDomain(ctx context.Context, db *gorm.DB, d *models.Domain) error {
 		d.ZoneID = zoneID
 	}
 
-	// 5) Sanity: can fetch zone
-	if _, err := r53c.GetHostedZone(ctx, &r53.GetHostedZoneInput{Id: aws.String(zoneID)}); err != nil {
-		return setDomainFailed(db, d, fmt.Errorf("get hosted zone: %w", err))
+	// 3) Sanity: can fetch zone
+	if err := p.GetZone(ctx, zoneID); err != nil {
+		return setDomainFailed(db, d, fmt.Errorf("get zone: %w", err))
+	}
+
+	// 4) Name servers and delegation; neither holds the domain back
+	if len(d.NameServers) == 0 {
+		refreshNameServers(ctx, p, d)
 	}
+	checkDelegation(ctx, db, d)
 
-	// 6) Mark ready
+	// 5) Mark ready
 	d.Status = "ready"
 	d.LastError = ""
 	if err := db.Save(d).Error; err != nil {
@@ -199,25 +201,28 @@ func setDomainFailed(db *gorm.DB, d *models.Domain, cause error) error {
 
 /************* record processing *************/
 
-func processPendingRecordsForDomain(ctx context.Context, db *gorm.DB, d *models.Domain, max int) (int, error) {
-	orgID := d.OrganizationID
+// recordsPerBatch bounds how many record sets go in one change batch. Each
+// brings its marker and poison records along, so this stays well inside
+// Route 53's 1000 changes per batch.
+const recordsPerBatch = 100
 
-	// reload credential
-	var cred models.Credential
-	if err := db.Where("id = ? AND organization_id = ?", d.CredentialID, orgID).First(&cred).Error; err != nil {
-		return 0, err
-	}
+// throttleBackoff is the first wait after a provider throttles a change
+// batch; it doubles for each of up to maxThrottleRetries retries.
+var throttleBackoff = time.Second
 
-	secret, err := utils.DecryptForOrg(orgID, cred.EncryptedData, cred.IV, cred.Tag, db)
-	if err != nil {
-		return 0, err
-	}
+const maxThrottleRetries = 4
 
-	var awsCred dto.AWSCredential
-	if err := jsonUnmarshalStrict([]byte(secret), &awsCred); err != nil {
-		return 0, err
-	}
-	r53c, _, err := newRoute53Client(ctx, awsCred)
+// recordWrite is one record set's share of a change batch.
+type recordWrite struct {
+	r       *models.RecordSet
+	changes []dns.Change
+}
+
+// processPendingRecordsForDomain applies the domain's pending record sets in
+// as few change batches as it can. Ownership is still checked per record;
+// one that fails its checks is marked failed and left out of the batch.
+func processPendingRecordsForDomain(ctx context.Context, db *gorm.DB, d *models.Domain, max int) (int, error) {
+	p, err := DNSProviderFor(ctx, db, d)
 	if err != nil {
 		return 0, err
 	}
@@ -230,42 +235,124 @@ func processPendingRecordsForDomain(ctx context.Context, db *gorm.DB, d *models.
 		Find(&records).Error; err != nil {
 		return 0, err
 	}
+	return applyRecords(ctx, db, p, d, records)
+}
 
-	applied := 0
+func applyRecords(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, records []models.RecordSet) (int, error) {
+	writes := make([]recordWrite, 0, len(records))
 	for i := range records {
-		if err := applyRecord(ctx, db, r53c, d, &records[i]); err != nil {
-			log.Error().
-				Err(err).
-				Str("zone_id", d.ZoneID).
-				Str("domain", d.DomainName).
-				Str("record_id", records[i].ID.String()).
-				Str("name", records[i].Name).
-				Str("type", strings.ToUpper(records[i].Type)).
-				Msg("[dns] apply record failed")
+		changes, err := prepareRecord(ctx, db, p, d, &records[i])
+		if err != nil {
+			logApplyFailure(d, &records[i], err)
 			_ = setRecordFailed(db, &records[i], err)
 			continue
 		}
-		applied++
+		writes = append(writes, recordWrite{r: &records[i], changes: changes})
+	}
+	writes = dropClashingWrites(db, d, writes)
+
+	applied := 0
+	for start := 0; start < len(writes); start += recordsPerBatch {
+		n, err := applyBatch(ctx, db, p, d, writes[start:min(start+recordsPerBatch, len(writes))])
+		applied += n
+		if err != nil {
+			return applied, err
+		}
 	}
 	return applied, nil
 }
 
-// core write + ownership + external-dns hardening
+// applyBatch writes the record sets in one change batch. When the provider
+// rejects the batch, each record set is tried on its own so one bad record
+// does not fail the rest. A batch still throttled after the retries is left
+// pending for the next tick.
+func applyBatch(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, writes []recordWrite) (int, error) {
+	zoneID := strings.TrimSpace(d.ZoneID)
+	var changes []dns.Change
+	for _, w := range writes {
+		changes = append(changes, w.changes...)
+	}
+	changes = dedupeChanges(changes)
+
+	logCtx := log.With().
+		Str("dns_provider", p.Name()).
+		Str("zone_id", zoneID).
+		Str("domain", d.DomainName).
+		Int("record_count", len(writes)).
+		Logger()
+	logCtx.Debug().
+		Interface("change_batch", toLogChangeBatch(zoneID, changes)).
+		Msg("[dns] provider request preview")
+
+	start := time.Now()
+	changeID, err := applyChanges(ctx, p, zoneID, changes)
+	if err == nil {
+		logCtx.Info().
+			Dur("elapsed", time.Since(start)).
+			Int("change_count", len(changes)).
+			Str("change_id", changeID).
+			Msg("[dns] apply ok")
+		rs := make([]*models.RecordSet, len(writes))
+		for i, w := range writes {
+			rs[i] = w.r
+		}
+		return len(writes), markApplied(db, rs, changeID)
+	}
 
-func applyRecord(ctx context.Context, db *gorm.DB, r53c *r53.Client, d *models.Domain, r *models.RecordSet) error {
+	logProviderError(logCtx, err)
+	if errors.Is(err, dns.ErrThrottled) || ctx.Err() != nil {
+		return 0, err
+	}
+	if len(writes) == 1 {
+		logApplyFailure(d, writes[0].r, err)
+		_ = setRecordFailed(db, writes[0].r, err)
+		return 0, nil
+	}
+
+	logCtx.Warn().Msg("[dns] batch rejected; applying its records one at a time")
+	applied := 0
+	for _, w := range writes {
+		n, err := applyBatch(ctx, db, p, d, []recordWrite{w})
+		applied += n
+		if err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// applyRecord checks and writes a single record set.
+func applyRecord(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, r *models.RecordSet) error {
+	changes, err := prepareRecord(ctx, db, p, d, r)
+	if err != nil {
+		return err
+	}
+	changeID, err := applyChanges(ctx, p, strings.TrimSpace(d.ZoneID), changes)
+	if err != nil {
+		return err
+	}
+	return markApplied(db, []*models.RecordSet{r}, changeID)
+}
+
+// prepareRecord runs the ownership preflight for a record set and returns
+// the changes that write it: the record, its marker and the external-dns
+// poison, which the caller applies together, atomically where the provider
+// supports it.
+func prepareRecord(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, r *models.RecordSet) ([]dns.Change, error) {
 	zoneID := strings.TrimSpace(d.ZoneID)
 	if zoneID == "" {
-		return errors.New("domain has no zone_id")
+		return nil, errors.New("domain has no zone_id")
 	}
 
 	rt := strings.ToUpper(r.Type)
 
 	// FQDN & marker
 	fq := recordFQDN(r.Name, d.DomainName) // ends with "."
-	mname := markerName(fq)
-	expected := buildMarkerValue(d.OrganizationID.String(), r.ID.String(), r.Fingerprint)
+	mname := dns.MarkerName(fq)
+	expected := dns.MarkerValue(d.OrganizationID.String(), r.ID.String(), r.Fingerprint)
 
 	logCtx := log.With().
+		Str("dns_provider", p.Name()).
 		Str("zone_id", zoneID).
 		Str("domain", d.DomainName).
 		Str("fqdn", fq).
@@ -274,35 +361,27 @@ func applyRecord(ctx context.Context, db *gorm.DB, r53c *r53.Client, d *models.D
 		Str("org_id", d.OrganizationID.String()).
 		Logger()
 
-	start := time.Now()
-
 	// ---- ExternalDNS preflight ----
-	extOwned, err := hasExternalDNSOwnership(ctx, r53c, zoneID, fq, rt)
+	extOwned, err := dns.ExternalDNSOwned(ctx, p, zoneID, fq, rt)
 	if err != nil {
-		return fmt.Errorf("external_dns_lookup: %w", err)
+		return nil, fmt.Errorf("external_dns_lookup: %w", err)
 	}
 	if extOwned {
 		logCtx.Warn().Msg("[dns] ownership conflict: external-dns claims this record")
-		r.Owner = "external"
-		_ = db.Save(r).Error
-		return fmt.Errorf("ownership_conflict: external-dns claims %s; refusing to modify", strings.TrimSuffix(fq, "."))
+		return nil, fmt.Errorf("ownership_conflict: external-dns claims %s; refusing to modify", strings.TrimSuffix(fq, "."))
 	}
 
 	// ---- Autoglue ownership preflight via _autoglue.<fqdn> TXT ----
-	markerVals, err := getMarkerTXTValues(ctx, r53c, zoneID, mname)
+	markers, err := dns.GetMarkers(ctx, p, zoneID, fq)
 	if err != nil {
-		return fmt.Errorf("marker lookup: %w", err)
+		return nil, fmt.Errorf("marker lookup: %w", err)
 	}
 
 	hasForeignOwner := false
 	hasOurExact := false
-	for _, v := range markerVals {
-		mk, ok := parseMarkerValue(v)
-		if !ok {
-			continue
-		}
+	for _, mk := range markers {
 		switch {
-		case mk.Org == d.OrganizationID.String() && mk.Rec == r.ID.String() && mk.Fp == shortFP(r.Fingerprint):
+		case mk.Org == d.OrganizationID.String() && mk.Rec == r.ID.String() && mk.Fp == dns.ShortFP(r.Fingerprint):
 			hasOurExact = true
 		case mk.Org != d.OrganizationID.String() || mk.Rec != r.ID.String():
 			hasForeignOwner = true
@@ -311,337 +390,484 @@ func applyRecord(ctx context.Context, db *gorm.DB, r53c *r53.Client, d *models.D
 
 	logCtx.Debug().
 		Bool("externaldns_owned", extOwned).
-		Int("marker_txt_count", len(markerVals)).
+		Int("marker_txt_count", len(markers)).
 		Bool("marker_has_our_exact", hasOurExact).
 		Bool("marker_has_foreign", hasForeignOwner).
 		Msg("[dns] ownership preflight")
 
 	if hasForeignOwner {
 		logCtx.Warn().Msg("[dns] ownership conflict: foreign _autoglue marker")
-		r.Owner = "external"
-		_ = db.Save(r).Error
-		return fmt.Errorf("ownership_conflict: marker for %s is owned by another controller; refusing to modify", strings.TrimSuffix(fq, "."))
+		return nil, fmt.Errorf("ownership_conflict: marker for %s is owned by another controller; refusing to modify", strings.TrimSuffix(fq, "."))
 	}
 
-	// Decode user values
-	var userVals []string
-	rawVals := strings.TrimSpace(string(r.Values))
-	if rawVals != "" && rawVals != "null" {
-		if err := jsonUnmarshalStrict([]byte(rawVals), &userVals); err != nil {
-			return fmt.Errorf("values decode: %w", err)
-		}
+	rec, err := desiredRecord(r, fq)
+	if err != nil {
+		return nil, err
 	}
-
-	// Quote TXT values as required by Route53
-	recs := make([]r53types.ResourceRecord, 0, len(userVals))
-	for _, v := range userVals {
-		v = strings.TrimSpace(v)
-		if v == "" {
+	if rec.Alias == nil && len(rec.Values) == 0 {
+		logCtx.Warn().
+			Str("raw_values", truncateForLog(string(r.Values), 240)).
+			Msg("[dns] invalid record: no values and no alias")
+		return nil, fmt.Errorf("invalid_record: %s %s requires at least one value or an alias", strings.TrimSuffix(fq, "."), rt)
+	}
+
+	changes := []dns.Change{
+		{Action: dns.ActionUpsert, Record: rec},
+		{Action: dns.ActionUpsert, Record: dns.Record{Name: mname, Type: "TXT", TTL: defaultRecordTTLSeconds, Values: []string{expected}}},
+	}
+	for _, pr := range dns.PoisonRecords(fq, rt, defaultRecordTTLSeconds) {
+		changes = append(changes, dns.Change{Action: dns.ActionUpsert, Record: pr})
+	}
+	return changes, nil
+}
+
+// dropClashingWrites fails record sets whose changes would overwrite a
+// different change from an earlier record set in the same tick, such as a
+// second record set at one name writing its own marker over the first's.
+// Each record set's preflight ran before any of them were written, so it
+// could not see the other's marker; this fails the later one as the
+// preflight would have.
+func dropClashingWrites(db *gorm.DB, d *models.Domain, writes []recordWrite) []recordWrite {
+	claimed := map[string]recordWrite{}
+	out := writes[:0:0]
+	for _, w := range writes {
+		var clash *recordWrite
+		for _, ch := range w.changes {
+			if prev, ok := claimed[changeKey(ch)]; ok && !sameChange(prev.changeFor(ch), ch) {
+				clash = &prev
+				break
+			}
+		}
+		if clash != nil {
+			err := fmt.Errorf("ownership_conflict: record set %s also writes %s; refusing to modify",
+				clash.r.ID, strings.TrimSuffix(recordFQDN(w.r.Name, d.DomainName), "."))
+			logApplyFailure(d, w.r, err)
+			_ = setRecordFailed(db, w.r, err)
 			continue
 		}
-		if rt == "TXT" && !(strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`)) {
-			v = strconv.Quote(v)
+		for _, ch := range w.changes {
+			if _, ok := claimed[changeKey(ch)]; !ok {
+				claimed[changeKey(ch)] = w
+			}
 		}
-		recs = append(recs, r53types.ResourceRecord{Value: aws.String(v)})
+		out = append(out, w)
 	}
+	return out
+}
 
-	// Alias is NOT supported - enforce at least one value for all record types we manage
-	if len(recs) == 0 {
-		logCtx.Warn().
-			Str("raw_values", truncateForLog(string(r.Values), 240)).
-			Int("decoded_value_count", len(userVals)).
-			Msg("[dns] invalid record: no values (alias not supported)")
-		return fmt.Errorf("invalid_record: %s %s requires at least one value (alias not supported)", strings.TrimSuffix(fq, "."), rt)
+// changeFor returns w's change to the same record set as ch.
+func (w recordWrite) changeFor(ch dns.Change) dns.Change {
+	for _, c := range w.changes {
+		if changeKey(c) == changeKey(ch) {
+			return c
+		}
 	}
+	return dns.Change{}
+}
 
-	ttl := defaultRecordTTLSeconds
-	if r.TTL != nil && *r.TTL > 0 {
-		ttl = int64(*r.TTL)
-	}
+func changeKey(ch dns.Change) string {
+	return string(ch.Action) + " " + dns.Fqdn(ch.Record.Name) + " " + strings.ToUpper(ch.Record.Type)
+}
 
-	// Build RR change (UPSERT)
-	rrChange := r53types.Change{
-		Action: r53types.ChangeActionUpsert,
-		ResourceRecordSet: &r53types.ResourceRecordSet{
-			Name:            aws.String(fq),
-			Type:            r53types.RRType(rt),
-			TTL:             aws.Int64(ttl),
-			ResourceRecords: recs,
-		},
-	}
+// sameChange reports whether two changes to one record set write the same
+// thing.
+func sameChange(a, b dns.Change) bool {
+	rt := strings.ToUpper(a.Record.Type)
+	return a.Record.TTL == b.Record.TTL &&
+		dns.SameAlias(a.Record.Alias, b.Record.Alias) &&
+		slices.Equal(dns.CanonicalValues(rt, a.Record.Values), dns.CanonicalValues(rt, b.Record.Values))
+}
 
-	// Build marker TXT change (UPSERT)
-	markerChange := r53types.Change{
-		Action: r53types.ChangeActionUpsert,
-		ResourceRecordSet: &r53types.ResourceRecordSet{
-			Name: aws.String(mname),
-			Type: r53types.RRTypeTxt,
-			TTL:  aws.Int64(defaultRecordTTLSeconds),
-			ResourceRecords: []r53types.ResourceRecord{
-				{Value: aws.String(strconv.Quote(expected))},
-			},
-		},
+// dedupeChanges drops repeated identical changes to the same record set,
+// which Route 53 rejects within a batch. Record sets of different types at
+// one name share the external-dns poison record for the name. Changes that
+// differ are all kept; dropClashingWrites has already failed the record
+// sets behind them.
+func dedupeChanges(changes []dns.Change) []dns.Change {
+	seen := make(map[string][]dns.Change, len(changes))
+	out := changes[:0:0]
+	for _, ch := range changes {
+		k := changeKey(ch)
+		if slices.ContainsFunc(seen[k], func(c dns.Change) bool { return sameChange(c, ch) }) {
+			continue
+		}
+		seen[k] = append(seen[k], ch)
+		out = append(out, ch)
 	}
+	return out
+}
 
-	// Build external-dns poison TXT changes
-	poisonChanges := buildExternalDNSPoisonTXTChanges(fq, rt)
+// applyChanges applies a change batch, backing off and retrying while the
+// provider throttles it. It returns the provider's change id when the
+// provider tracks changes.
+func applyChanges(ctx context.Context, p dns.Provider, zoneID string, changes []dns.Change) (string, error) {
+	wait := throttleBackoff
+	for attempt := 0; ; attempt++ {
+		var changeID string
+		var err error
+		if t, ok := p.(dns.ChangeTracker); ok {
+			changeID, err = t.ApplyTracked(ctx, zoneID, changes)
+		} else {
+			err = p.Apply(ctx, zoneID, changes)
+		}
+		if err == nil || !errors.Is(err, dns.ErrThrottled) || attempt == maxThrottleRetries {
+			return changeID, err
+		}
 
-	// Apply all in one batch (atomic-ish)
-	changes := []r53types.Change{rrChange, markerChange}
-	changes = append(changes, poisonChanges...)
+		log.Warn().Err(err).Str("zone_id", zoneID).Dur("wait", wait).Msg("[dns] throttled; backing off")
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(wait + rand.N(wait/2+1)):
+		}
+		wait *= 2
+	}
+}
+
+// markApplied records a successful write. Record sets wait in provisioning
+// while the provider's change propagates, and are ready straight away when
+// the provider does not track changes.
+func markApplied(db *gorm.DB, rs []*models.RecordSet, changeID string) error {
+	status := "ready"
+	if changeID != "" {
+		status = "provisioning"
+	}
+	for _, r := range rs {
+		ok, err := updateRecordFrom(db, r, map[string]any{
+			"status":          status,
+			"change_id":       changeID,
+			"last_error":      "",
+			"owner":           "autoglue",
+			"observed_ttl":    nil,
+			"observed_values": nil,
+			"observed_alias":  nil,
+			"drifted_at":      nil,
+		})
+		if err != nil {
+			return err
+		}
+		if ok {
+			r.Status = status
+			r.ChangeID = changeID
+			r.LastError = ""
+			r.Owner = "autoglue"
+			r.ObservedTTL, r.ObservedValues, r.ObservedAlias, r.DriftedAt = nil, nil, nil, nil
+		}
+	}
+	return nil
+}
 
-	// Log what we are about to send
-	logCtx.Debug().
-		Interface("route53_change_batch", toLogChangeBatch(zoneID, changes)).
-		Msg("[dns] route53 request preview")
+// updateRecordFrom writes fields to r's row only while it still has the
+// status r was loaded with. A row that moved on in the meantime, such as one
+// a user marked deleting while its write was in flight, is left alone and
+// false is returned.
+func updateRecordFrom(db *gorm.DB, r *models.RecordSet, fields map[string]any) (bool, error) {
+	res := db.Model(&models.RecordSet{}).
+		Where("id = ? AND status = ?", r.ID, r.Status).
+		Updates(fields)
+	return res.RowsAffected > 0, res.Error
+}
+
+// processProvisioningRecordsForDomain moves record sets whose change has
+// reached all of the provider's name servers from provisioning to ready.
+func processProvisioningRecordsForDomain(ctx context.Context, db *gorm.DB, d *models.Domain) (int, error) {
+	var changeIDs []string
+	if err := db.Model(&models.RecordSet{}).
+		Where("domain_id = ? AND status = ?", d.ID, "provisioning").
+		Distinct().
+		Pluck("change_id", &changeIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(changeIDs) == 0 {
+		return 0, nil
+	}
 
-	_, err = r53c.ChangeResourceRecordSets(ctx, &r53.ChangeResourceRecordSetsInput{
-		HostedZoneId: aws.String(zoneID),
-		ChangeBatch:  &r53types.ChangeBatch{Changes: changes},
-	})
+	p, err := DNSProviderFor(ctx, db, d)
 	if err != nil {
-		logAWSError(logCtx, err)
-		logCtx.Info().Dur("elapsed", time.Since(start)).Msg("[dns] apply failed")
-		return err
+		return 0, err
 	}
+	return syncChanges(ctx, db, p, d, changeIDs)
+}
 
-	logCtx.Info().
-		Dur("elapsed", time.Since(start)).
-		Int("change_count", len(changes)).
-		Msg("[dns] apply ok")
+func syncChanges(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, changeIDs []string) (int, error) {
+	tracker, _ := p.(dns.ChangeTracker)
 
-	// Success → mark ready & ownership
-	r.Status = "ready"
-	r.LastError = ""
-	r.Owner = "autoglue"
-	if err := db.Save(r).Error; err != nil {
-		return err
+	synced := 0
+	for _, id := range changeIDs {
+		if tracker != nil && id != "" {
+			ok, err := tracker.ChangeSynced(ctx, id)
+			if err != nil {
+				return synced, fmt.Errorf("change %s: %w", id, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		res := db.Model(&models.RecordSet{}).
+			Where("domain_id = ? AND status = ? AND change_id = ?", d.ID, "provisioning", id).
+			Updates(map[string]any{"status": "ready", "change_id": ""})
+		if res.Error != nil {
+			return synced, res.Error
+		}
+		synced += int(res.RowsAffected)
 	}
+	return synced, nil
+}
 
-	_ = hasOurExact // could be used to skip marker write in future
-	return nil
+func logApplyFailure(d *models.Domain, r *models.RecordSet, err error) {
+	log.Error().
+		Err(err).
+		Str("zone_id", d.ZoneID).
+		Str("domain", d.DomainName).
+		Str("record_id", r.ID.String()).
+		Str("name", r.Name).
+		Str("type", strings.ToUpper(r.Type)).
+		Msg("[dns] apply record failed")
 }
 
 func setRecordFailed(db *gorm.DB, r *models.RecordSet, cause error) error {
 	msg := truncateErr(cause.Error())
-	r.Status = "failed"
-	r.LastError = msg
+	owner := r.Owner
 	// classify ownership on conflict
 	if strings.HasPrefix(msg, "ownership_conflict") {
-		r.Owner = "external"
-	} else if r.Owner == "" || r.Owner == "unknown" {
-		r.Owner = "unknown"
+		owner = "external"
+	} else if owner == "" || owner == "unknown" {
+		owner = "unknown"
+	}
+	if ok, _ := updateRecordFrom(db, r, map[string]any{"status": "failed", "last_error": msg, "owner": owner}); ok {
+		r.Status, r.LastError, r.Owner = "failed", msg, owner
 	}
-	_ = db.Save(r).Error
 	return cause
 }
 
-/************* AWS helpers *************/
-
-func newRoute53Client(ctx context.Context, cred dto.AWSCredential) (*r53.Client, *aws.Config, error) {
-	// Route53 is global, but config still wants a region
-	region := strings.TrimSpace(cred.Region)
-	if region == "" {
-		region = "us-east-1"
+// recordValues are the row's values as they go to the provider: trimmed,
+// empties dropped, TXT unquoted (the provider quotes them its own way).
+func recordValues(r *models.RecordSet) ([]string, error) {
+	var userVals []string
+	rawVals := strings.TrimSpace(string(r.Values))
+	if rawVals != "" && rawVals != "null" {
+		if err := jsonUnmarshalStrict([]byte(rawVals), &userVals); err != nil {
+			return nil, fmt.Errorf("values decode: %w", err)
+		}
 	}
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			cred.AccessKeyID, cred.SecretAccessKey, "",
-		)),
-	)
-	if err != nil {
-		return nil, nil, err
+	recs := make([]string, 0, len(userVals))
+	for _, v := range userVals {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if strings.EqualFold(r.Type, "TXT") {
+			v = dns.UnquoteTXT(v)
+		}
+		recs = append(recs, v)
 	}
-	return r53.NewFromConfig(cfg), &cfg, nil
+	return recs, nil
 }
 
-func findHostedZoneID(ctx context.Context, c *r53.Client, domain string) (string, error) {
-	d := normalizeDomain(domain)
-	out, err := c.ListHostedZonesByName(ctx, &r53.ListHostedZonesByNameInput{
-		DNSName: aws.String(d),
-	})
+// desiredRecord is the record set as it should be at the provider: an alias
+// when the row has one, its values and TTL otherwise.
+func desiredRecord(r *models.RecordSet, fq string) (dns.Record, error) {
+	rt := strings.ToUpper(r.Type)
+	alias, err := dto.AliasFromJSON(r.Alias)
 	if err != nil {
-		return "", err
+		return dns.Record{}, fmt.Errorf("alias decode: %w", err)
 	}
-	for _, hz := range out.HostedZones {
-		if strings.TrimSuffix(aws.ToString(hz.Name), ".") == d {
-			return trimZoneID(aws.ToString(hz.Id)), nil
-		}
+	if alias != nil {
+		return dns.Record{Name: fq, Type: rt, Alias: alias.DNS()}, nil
 	}
-	return "", fmt.Errorf("hosted zone not found for %q", d)
+	vals, err := recordValues(r)
+	if err != nil {
+		return dns.Record{}, err
+	}
+	return dns.Record{Name: fq, Type: rt, TTL: recordTTL(r), Values: vals}, nil
 }
 
-func trimZoneID(id string) string {
-	return strings.TrimPrefix(id, "/hostedzone/")
+// aliasColumn is a live alias target as a row stores it.
+func aliasColumn(a *dns.AliasTarget) datatypes.JSON {
+	if a == nil {
+		return nil
+	}
+	b, _ := json.Marshal(dto.AliasTarget{
+		HostedZoneID:         strings.TrimPrefix(a.HostedZoneID, "/hostedzone/"),
+		DNSName:              strings.TrimSuffix(strings.ToLower(a.DNSName), "."),
+		EvaluateTargetHealth: a.EvaluateTargetHealth,
+	})
+	return datatypes.JSON(b)
 }
 
-func normalizeDomain(s string) string {
-	s = strings.TrimSpace(strings.ToLower(s))
-	return strings.TrimSuffix(s, ".")
+func recordTTL(r *models.RecordSet) int64 {
+	if r.TTL != nil && *r.TTL > 0 {
+		return int64(*r.TTL)
+	}
+	return defaultRecordTTLSeconds
 }
 
-func recordFQDN(name, domain string) string {
-	name = strings.TrimSpace(name)
-	if name == "" || name == "@" {
-		return normalizeDomain(domain) + "."
+/************* record deletion *************/
+
+func processDeletingRecordsForDomain(ctx context.Context, db *gorm.DB, d *models.Domain, max int) (int, error) {
+	var records []models.RecordSet
+	if err := db.
+		Where("domain_id = ? AND status = ?", d.ID, "deleting").
+		Order("updated_at ASC").
+		Limit(max).
+		Find(&records).Error; err != nil {
+		return 0, err
 	}
-	if strings.HasSuffix(name, ".") {
-		return name
+	if len(records) == 0 {
+		return 0, nil
 	}
-	return fmt.Sprintf("%s.%s.", name, normalizeDomain(domain))
-}
-
-/************* TXT marker / external-dns helpers *************/
 
-func markerName(fqdn string) string {
-	trimmed := strings.TrimSuffix(fqdn, ".")
-	return "_autoglue." + trimmed + "."
+	p, err := DNSProviderFor(ctx, db, d)
+	if err != nil {
+		d.LastError = truncateErr(err.Error())
+		_ = db.Model(d).Update("last_error", d.LastError).Error
+		return 0, err
+	}
+	return deleteRecords(ctx, db, p, d, records)
 }
 
-func shortFP(full string) string {
-	if len(full) > 16 {
-		return full[:16]
+func deleteRecords(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, records []models.RecordSet) (int, error) {
+	deleted := 0
+	var lastErr error
+	for i := range records {
+		if err := deleteRecord(ctx, db, p, d, &records[i]); err != nil {
+			log.Error().
+				Err(err).
+				Str("zone_id", d.ZoneID).
+				Str("domain", d.DomainName).
+				Str("record_id", records[i].ID.String()).
+				Msg("[dns] delete record failed")
+			// Stay deleting so the next tick retries; only the error changes.
+			_ = db.Model(&records[i]).Update("last_error", truncateErr(err.Error())).Error
+			lastErr = err
+			continue
+		}
+		deleted++
 	}
-	return full
+	return deleted, lastErr
 }
 
-func buildMarkerValue(orgID, recID, fp string) string {
-	return "v=ag1 org=" + orgID + " rec=" + recID + " fp=" + shortFP(fp)
-}
+// deleteRecord removes a record set, its _autoglue marker and the poison
+// records from the provider, then the row. The provider is only touched when
+// the marker says this record wrote what is there: with no marker there is
+// nothing of ours to remove, and with another owner's marker the name has
+// been taken over since, so in both cases only the row goes.
+func deleteRecord(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, r *models.RecordSet) error {
+	zoneID := strings.TrimSpace(d.ZoneID)
+	rt := strings.ToUpper(r.Type)
+	fq := recordFQDN(r.Name, d.DomainName)
 
-func parseMarkerValue(s string) (ownershipMarker, bool) {
-	out := ownershipMarker{}
-	fields := strings.Fields(s)
-	if len(fields) < 4 {
-		return out, false
-	}
-	kv := map[string]string{}
-	for _, f := range fields {
-		parts := strings.SplitN(f, "=", 2)
-		if len(parts) == 2 {
-			kv[parts[0]] = parts[1]
+	logCtx := log.With().
+		Str("dns_provider", p.Name()).
+		Str("zone_id", zoneID).
+		Str("fqdn", fq).
+		Str("rr_type", rt).
+		Str("record_id", r.ID.String()).
+		Logger()
+
+	owned := false
+	if zoneID != "" {
+		markers, err := dns.GetMarkers(ctx, p, zoneID, fq)
+		if err != nil {
+			return fmt.Errorf("marker lookup: %w", err)
+		}
+		for _, mk := range markers {
+			if mk.Org == d.OrganizationID.String() && mk.Rec == r.ID.String() {
+				owned = true
+			}
 		}
 	}
-	if kv["v"] == "" || kv["org"] == "" || kv["rec"] == "" || kv["fp"] == "" {
-		return out, false
+
+	if owned {
+		changes := []dns.Change{
+			{Action: dns.ActionDelete, Record: dns.Record{Name: fq, Type: rt}},
+			{Action: dns.ActionDelete, Record: dns.Record{Name: dns.MarkerName(fq), Type: "TXT"}},
+		}
+		for _, pr := range dns.PoisonRecords(fq, rt, defaultRecordTTLSeconds) {
+			changes = append(changes, dns.Change{Action: dns.ActionDelete, Record: dns.Record{Name: pr.Name, Type: pr.Type}})
+		}
+
+		logCtx.Debug().
+			Interface("change_batch", toLogChangeBatch(zoneID, changes)).
+			Msg("[dns] provider delete preview")
+
+		if _, err := applyChanges(ctx, p, zoneID, changes); err != nil {
+			logProviderError(logCtx, err)
+			return err
+		}
+		logCtx.Info().Msg("[dns] delete ok")
+	} else {
+		logCtx.Info().Msg("[dns] no marker of ours; removing the row only")
 	}
-	out.Ver, out.Org, out.Rec, out.Fp = kv["v"], kv["org"], kv["rec"], kv["fp"]
-	return out, true
-}
 
-func getMarkerTXTValues(ctx context.Context, c *r53.Client, zoneID, marker string) ([]string, error) {
-	return getTXTValues(ctx, c, zoneID, marker)
+	return db.Delete(&models.RecordSet{}, "id = ?", r.ID).Error
 }
 
-// generic TXT fetcher
-func getTXTValues(ctx context.Context, c *r53.Client, zoneID, name string) ([]string, error) {
-	out, err := c.ListResourceRecordSets(ctx, &r53.ListResourceRecordSetsInput{
-		HostedZoneId:    aws.String(zoneID),
-		StartRecordName: aws.String(name),
-		StartRecordType: r53types.RRTypeTxt,
-		MaxItems:        aws.Int32(1),
-	})
-	if err != nil {
-		return nil, err
-	}
-	if len(out.ResourceRecordSets) == 0 {
-		return nil, nil
-	}
-	rrset := out.ResourceRecordSets[0]
-	if aws.ToString(rrset.Name) != name || rrset.Type != r53types.RRTypeTxt {
-		return nil, nil
+// finishDomainDeletion removes a deleting domain once all of its record sets
+// are gone from the provider.
+func finishDomainDeletion(db *gorm.DB, d *models.Domain) error {
+	var left int64
+	if err := db.Model(&models.RecordSet{}).Where("domain_id = ?", d.ID).Count(&left).Error; err != nil {
+		return err
 	}
-	vals := make([]string, 0, len(rrset.ResourceRecords))
-	for _, rr := range rrset.ResourceRecords {
-		vals = append(vals, aws.ToString(rr.Value))
+	if left > 0 {
+		return nil
 	}
-	return vals, nil
+	return db.Delete(&models.Domain{}, "id = ?", d.ID).Error
 }
 
-// detect external-dns-style ownership for this fqdn/type
-func hasExternalDNSOwnership(ctx context.Context, c *r53.Client, zoneID, fqdn, rrType string) (bool, error) {
-	base := strings.TrimSuffix(fqdn, ".")
-	candidates := []string{
-		// with txtPrefix=extdns-, external-dns writes both:
-		// extdns-<fqdn> and extdns-<rrtype-lc>-<fqdn>
-		"extdns-" + base + ".",
-		"extdns-" + strings.ToLower(rrType) + "-" + base + ".",
+/************* provider helpers *************/
+
+// DNSProviderFor builds the provider client from the domain's credential,
+// which must belong to the same org.
+func DNSProviderFor(ctx context.Context, db *gorm.DB, d *models.Domain) (dns.Provider, error) {
+	var cred models.Credential
+	if err := db.Where("id = ? AND organization_id = ?", d.CredentialID, d.OrganizationID).First(&cred).Error; err != nil {
+		return nil, fmt.Errorf("credential not found: %w", err)
 	}
-	for _, name := range candidates {
-		vals, err := getTXTValues(ctx, c, zoneID, name)
-		if err != nil {
-			return false, err
-		}
-		for _, raw := range vals {
-			v := strings.TrimSpace(raw)
-			// strip surrounding quotes if present
-			if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
-				if unq, err := strconv.Unquote(v); err == nil {
-					v = unq
-				}
-			}
-			meta := parseExternalDNSMeta(v)
-			if meta == nil {
-				continue
-			}
-			if meta["heritage"] == "external-dns" &&
-				meta["external-dns/owner"] != "" &&
-				meta["external-dns/owner"] != externalDNSPoisonOwner {
-				return true, nil
-			}
-		}
+	secret, err := utils.DecryptForOrg(d.OrganizationID, cred.EncryptedData, cred.IV, cred.Tag, db)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
 	}
-	return false, nil
-}
 
-// parseExternalDNSMeta parses the comma-separated external-dns TXT format into a small map.
-func parseExternalDNSMeta(v string) map[string]string {
-	parts := strings.Split(v, ",")
-	if len(parts) == 0 {
-		return nil
-	}
-	meta := make(map[string]string, len(parts))
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
+	switch cred.Provider {
+	case "aws":
+		var awsCred dto.AWSCredential
+		if err := jsonUnmarshalStrict([]byte(secret), &awsCred); err != nil {
+			return nil, fmt.Errorf("secret decode: %w", err)
 		}
-		kv := strings.SplitN(p, "=", 2)
-		if len(kv) != 2 {
-			continue
+		return dns.NewRoute53(ctx, awsCred.AccessKeyID, awsCred.SecretAccessKey, awsCred.Region)
+	case "cloudflare":
+		var tok dto.APIToken
+		if err := jsonUnmarshalStrict([]byte(secret), &tok); err != nil {
+			return nil, fmt.Errorf("secret decode: %w", err)
+		}
+		return dns.NewCloudflare(tok.Token), nil
+	case "rfc2136":
+		var key dto.TSIGCredential
+		if err := jsonUnmarshalStrict([]byte(secret), &key); err != nil {
+			return nil, fmt.Errorf("secret decode: %w", err)
 		}
-		meta[kv[0]] = kv[1]
+		return dns.NewRFC2136(key.Server, key.KeyName, key.Algorithm, key.Secret)
+	default:
+		return nil, fmt.Errorf("%w: %q", dns.ErrUnsupported, cred.Provider)
 	}
-	if len(meta) == 0 {
-		return nil
+}
+
+func normalizeDomain(s string) string {
+	s = strings.TrimSpace(strings.ToLower(s))
+	return strings.TrimSuffix(s, ".")
+}
+
+func recordFQDN(name, domain string) string {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "@" {
+		return normalizeDomain(domain) + "."
 	}
-	return meta
-}
-
-// build poison TXT records so external-dns thinks some *other* owner manages this
-func buildExternalDNSPoisonTXTChanges(fqdn, rrType string) []r53types.Change {
-	base := strings.TrimSuffix(fqdn, ".")
-	names := []string{
-		"extdns-" + base + ".",
-		"extdns-" + strings.ToLower(rrType) + "-" + base + ".",
-	}
-	val := strconv.Quote(externalDNSPoisonValue)
-	changes := make([]r53types.Change, 0, len(names))
-	for _, n := range names {
-		changes = append(changes, r53types.Change{
-			Action: r53types.ChangeActionUpsert,
-			ResourceRecordSet: &r53types.ResourceRecordSet{
-				Name: aws.String(n),
-				Type: r53types.RRTypeTxt,
-				TTL:  aws.Int64(defaultRecordTTLSeconds),
-				ResourceRecords: []r53types.ResourceRecord{
-					{Value: aws.String(val)},
-				},
-			},
-		})
+	if strings.HasSuffix(name, ".") {
+		return name
 	}
-	return changes
+	return fmt.Sprintf("%s.%s.", name, normalizeDomain(domain))
 }
 
 /************* misc utils *************/
@@ -669,14 +895,12 @@ type logRR struct {
 }
 
 type logRRSet struct {
-	Action         string  `json:"action"`
-	Name           string  `json:"name"`
-	Type           string  `json:"type"`
-	TTL            *int64  `json:"ttl,omitempty"`
-	Records        []logRR `json:"records,omitempty"`
-	RecordCount    int     `json:"record_count"`
-	HasAliasTarget bool    `json:"has_alias_target"`
-	SetIdentifier  *string `json:"set_identifier,omitempty"`
+	Action      string  `json:"action"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	TTL         *int64  `json:"ttl,omitempty"`
+	Records     []logRR `json:"records,omitempty"`
+	RecordCount int     `json:"record_count"`
 }
 
 type logChangeBatch struct {
@@ -693,7 +917,7 @@ func truncateForLog(s string, max int) string {
 	return s[:max] + "…"
 }
 
-func toLogChangeBatch(zoneID string, changes []r53types.Change) logChangeBatch {
+func toLogChangeBatch(zoneID string, changes []dns.Change) logChangeBatch {
 	out := logChangeBatch{
 		HostedZoneID: zoneID,
 		ChangeCount:  len(changes),
@@ -701,27 +925,23 @@ func toLogChangeBatch(zoneID string, changes []r53types.Change) logChangeBatch {
 	}
 
 	for _, ch := range changes {
-		if ch.ResourceRecordSet == nil {
-			continue
-		}
-		rrs := ch.ResourceRecordSet
+		rrs := ch.Record
+		ttl := rrs.TTL
 		lc := logRRSet{
-			Action:         string(ch.Action),
-			Name:           aws.ToString(rrs.Name),
-			Type:           string(rrs.Type),
-			TTL:            rrs.TTL,
-			HasAliasTarget: rrs.AliasTarget != nil,
-			SetIdentifier:  rrs.SetIdentifier,
-			RecordCount:    len(rrs.ResourceRecords),
-			Records:        make([]logRR, 0, min(len(rrs.ResourceRecords), 5)),
+			Action:      string(ch.Action),
+			Name:        rrs.Name,
+			Type:        rrs.Type,
+			TTL:         &ttl,
+			RecordCount: len(rrs.Values),
+			Records:     make([]logRR, 0, min(len(rrs.Values), 5)),
 		}
 
 		// Log up to first 5 values (truncate each) to avoid log bloat / secrets
-		for i, rr := range rrs.ResourceRecords {
+		for i, v := range rrs.Values {
 			if i >= 5 {
 				break
 			}
-			lc.Records = append(lc.Records, logRR{Value: truncateForLog(aws.ToString(rr.Value), 160)})
+			lc.Records = append(lc.Records, logRR{Value: truncateForLog(v, 160)})
 		}
 
 		out.Changes = append(out.Changes, lc)
@@ -736,9 +956,9 @@ func min(a, b int) int {
 	return b
 }
 
-// logAWSError extracts useful smithy/HTTP metadata (status code + request id + api code) into logs.
-// logAWSError extracts useful smithy/HTTP metadata (status code + request id + api code) into logs.
-func logAWSError(l zerolog.Logger, err error) {
+// logProviderError logs a failed provider call. For Route 53 it pulls the
+// smithy/HTTP metadata (status code + request id + api code) into the log.
+func logProviderError(l zerolog.Logger, err error) {
 	// Add operation context if present
 	var opErr *smithy.OperationError
 	if errors.As(err, &opErr) {
@@ -781,5 +1001,5 @@ func logAWSError(l zerolog.Logger, err error) {
 		return
 	}
 
-	l.Error().Err(err).Msg("[dns] aws route53 error")
+	l.Error().Err(err).Msg("[dns] provider error")
 }
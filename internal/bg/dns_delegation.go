@@ -0,0 +1,171 @@
+package bg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/dns"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	// undelegatedRecheck is how often a domain that is not delegated yet
+	// is looked at again, so fixing it at the registrar shows up soon.
+	undelegatedRecheck = 5 * time.Minute
+	// delegatedRecheck is how often a delegated domain is.
+	delegatedRecheck = 6 * time.Hour
+)
+
+// lookupNS resolves a name's NS records; tests replace it.
+var lookupNS = func(ctx context.Context, name string) ([]string, error) {
+	return dns.LookupNS(ctx, net.DefaultResolver, name)
+}
+
+// createZone creates the domain's zone at a provider that can. The domain
+// id is the request reference, so a retry finds the zone it made.
+func createZone(ctx context.Context, p dns.Provider, d *models.Domain) (string, error) {
+	zc, ok := p.(dns.ZoneCreator)
+	if !ok {
+		return "", fmt.Errorf("%w: %s cannot create zones", dns.ErrUnsupported, p.Name())
+	}
+	zoneID, ns, err := zc.CreateZone(ctx, d.DomainName, d.ID.String())
+	if err != nil {
+		return "", fmt.Errorf("create zone: %w", err)
+	}
+	log.Info().Str("domain", d.DomainName).Str("zone_id", zoneID).Strs("name_servers", ns).Msg("[dns] zone created")
+	d.NameServers = nameServersJSON(ns)
+	return zoneID, nil
+}
+
+// refreshNameServers reads the name servers the zone was assigned: from the
+// provider when it has its own, else from the zone's apex NS record set.
+func refreshNameServers(ctx context.Context, p dns.Provider, d *models.Domain) {
+	var ns []string
+	var err error
+	if l, ok := p.(dns.NameServerLister); ok {
+		ns, err = l.NameServers(ctx, d.ZoneID)
+	} else {
+		var rec *dns.Record
+		rec, err = p.GetRecord(ctx, d.ZoneID, recordFQDN("@", d.DomainName), "NS")
+		if rec != nil {
+			ns = rec.Values
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("domain", d.DomainName).Msg("[dns] could not read the zone's name servers")
+		return
+	}
+	d.NameServers = nameServersJSON(ns)
+}
+
+func delegationDue(d *models.Domain, now time.Time) bool {
+	if d.Status != "ready" || len(d.NameServers) == 0 {
+		return false
+	}
+	if d.DelegationCheckedAt == nil {
+		return true
+	}
+	every := undelegatedRecheck
+	if d.DelegationStatus == dns.DelegationDelegated {
+		every = delegatedRecheck
+	}
+	return now.Sub(*d.DelegationCheckedAt) >= every
+}
+
+// updateDelegation checks the domain's delegation and saves the outcome. A
+// domain whose zone or name changed while it was checked keeps its row as it
+// is; the next tick checks it again.
+func updateDelegation(ctx context.Context, db *gorm.DB, d *models.Domain) error {
+	zoneID, name := d.ZoneID, d.DomainName
+	checkDelegation(ctx, db, d)
+	return db.Model(&models.Domain{}).
+		Where("id = ? AND zone_id = ? AND domain_name = ?", d.ID, zoneID, name).
+		Updates(map[string]any{
+			"delegation_status":      d.DelegationStatus,
+			"delegation_source":      d.DelegationSource,
+			"delegated_name_servers": d.DelegatedNameServers,
+			"delegation_checked_at":  d.DelegationCheckedAt,
+		}).Error
+}
+
+// checkDelegation works out whether the parent zone delegates the domain to
+// the name servers its zone was assigned. When the parent is itself a domain
+// in autoglue its NS record set for the name is what counts, once applied;
+// otherwise the name's NS records are looked up in DNS.
+func checkDelegation(ctx context.Context, db *gorm.DB, d *models.Domain) {
+	var assigned []string
+	if err := json.Unmarshal(d.NameServers, &assigned); err != nil || len(assigned) == 0 {
+		return
+	}
+
+	delegated, found, err := parentDelegation(db, d)
+	source := "autoglue"
+	if err == nil && !found {
+		delegated, err = lookupNS(ctx, d.DomainName)
+		source = "dns"
+	}
+
+	now := time.Now()
+	d.DelegationCheckedAt = &now
+	d.DelegationSource = source
+	if err != nil {
+		log.Warn().Err(err).Str("domain", d.DomainName).Str("source", source).Msg("[dns] delegation lookup failed")
+		d.DelegationStatus = dns.DelegationUnknown
+		return
+	}
+	d.DelegationStatus = dns.DelegationStatus(assigned, delegated)
+	d.DelegatedNameServers = nameServersJSON(delegated)
+}
+
+// parentDelegation reads the NS record set for the domain from the closest
+// parent domain the organization manages in autoglue. found is false when
+// there is no such parent.
+func parentDelegation(db *gorm.DB, d *models.Domain) ([]string, bool, error) {
+	var domains []models.Domain
+	if err := db.
+		Select("id", "domain_name").
+		Where("organization_id = ? AND id <> ? AND status = ?", d.OrganizationID, d.ID, "ready").
+		Find(&domains).Error; err != nil {
+		return nil, false, err
+	}
+	var parent *models.Domain
+	for i := range domains {
+		if strings.HasSuffix(d.DomainName, "."+domains[i].DomainName) &&
+			(parent == nil || len(domains[i].DomainName) > len(parent.DomainName)) {
+			parent = &domains[i]
+		}
+	}
+	if parent == nil {
+		return nil, false, nil
+	}
+	label := strings.TrimSuffix(d.DomainName, "."+parent.DomainName)
+
+	var rows []models.RecordSet
+	if err := db.
+		Where("domain_id = ? AND LOWER(name) = ? AND type = ? AND status = ?", parent.ID, strings.ToLower(label), "NS", "ready").
+		Limit(1).
+		Find(&rows).Error; err != nil {
+		return nil, true, err
+	}
+	if len(rows) == 0 {
+		return nil, true, nil
+	}
+	vals, err := recordValues(&rows[0])
+	return vals, true, err
+}
+
+func nameServersJSON(ns []string) datatypes.JSON {
+	out := make([]string, 0, len(ns))
+	for _, n := range ns {
+		out = append(out, strings.TrimSuffix(strings.ToLower(strings.TrimSpace(n)), "."))
+	}
+	b, _ := json.Marshal(out)
+	return datatypes.JSON(b)
+}
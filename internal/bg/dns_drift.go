@@ -0,0 +1,232 @@
+package bg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/dns"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// DNSDriftArgs reads back autoglue-owned record sets from their providers
+// and flags the ones changed there since they were applied. dns_reconcile
+// only looks at pending records, so without this an edit made in the
+// provider's console would never be noticed.
+type DNSDriftArgs struct {
+	MaxRecords int `json:"max_records,omitempty"`
+}
+
+func (DNSDriftArgs) Kind() string { return "dns_drift" }
+
+func (DNSDriftArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueMaintenance, MaxAttempts: 1}
+}
+
+type DNSDriftWorker struct {
+	river.WorkerDefaults[DNSDriftArgs]
+	db *gorm.DB
+}
+
+func (w *DNSDriftWorker) Timeout(*river.Job[DNSDriftArgs]) time.Duration {
+	return 5 * time.Minute
+}
+
+func (w *DNSDriftWorker) Work(ctx context.Context, job *river.Job[DNSDriftArgs]) error {
+	max := job.Args.MaxRecords
+	if max <= 0 {
+		max = 500
+	}
+
+	// Least recently checked first, so a large estate is covered over a few
+	// ticks rather than the same records every time.
+	var records []models.RecordSet
+	if err := w.db.
+		Joins("Domain").
+		Where(`record_sets.status IN ? AND record_sets.owner = ? AND "Domain"."status" = ?`,
+			[]string{"ready", "drifted"}, "autoglue", "ready").
+		Order("record_sets.drift_checked_at ASC NULLS FIRST").
+		Limit(max).
+		Find(&records).Error; err != nil {
+		return err
+	}
+
+	byDomain := map[string][]models.RecordSet{}
+	var order []string
+	for _, r := range records {
+		k := r.DomainID.String()
+		if _, ok := byDomain[k]; !ok {
+			order = append(order, k)
+		}
+		byDomain[k] = append(byDomain[k], r)
+	}
+
+	checked, drifted := 0, 0
+	for _, k := range order {
+		recs := byDomain[k]
+		d := recs[0].Domain
+
+		var org models.Organization
+		if err := w.db.Select("id", "dns_auto_reapply").First(&org, "id = ?", d.OrganizationID).Error; err != nil {
+			log.Error().Err(err).Str("domain", d.DomainName).Msg("[dns] drift: load org failed")
+			continue
+		}
+		p, err := DNSProviderFor(ctx, w.db, &d)
+		if err != nil {
+			log.Error().Err(err).Str("domain", d.DomainName).Msg("[dns] drift: provider failed")
+			continue
+		}
+		for i := range recs {
+			found, err := checkRecordDrift(ctx, w.db, p, &d, &recs[i], org.DNSAutoReapply)
+			if err != nil {
+				log.Error().Err(err).Str("domain", d.DomainName).Str("record_id", recs[i].ID.String()).Msg("[dns] drift check failed")
+				continue
+			}
+			checked++
+			if found {
+				drifted++
+			}
+		}
+	}
+
+	log.Debug().Int("checked", checked).Int("drifted", drifted).Msg("[dns] drift tick ok")
+	if err := river.RecordOutput(ctx, map[string]any{"checked": checked, "drifted": drifted}); err != nil {
+		log.Warn().Err(err).Msg("[dns] could not record output")
+	}
+	return nil
+}
+
+// checkRecordDrift compares one record set with what its provider serves and
+// saves the outcome. A drifted record keeps what was observed and, when the
+// org has auto re-apply on, goes back to pending for dns_reconcile to write
+// again. It reports whether the record had drifted.
+func checkRecordDrift(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, r *models.RecordSet, autoReapply bool) (bool, error) {
+	zoneID := strings.TrimSpace(d.ZoneID)
+	fq := recordFQDN(r.Name, d.DomainName)
+	rt := strings.ToUpper(r.Type)
+
+	live, err := p.GetRecord(ctx, zoneID, fq, rt)
+	if err != nil {
+		return false, fmt.Errorf("read record: %w", err)
+	}
+	markers, err := dns.GetMarkers(ctx, p, zoneID, fq)
+	if err != nil {
+		return false, fmt.Errorf("marker lookup: %w", err)
+	}
+	want, err := desiredRecord(r, fq)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	reasons := recordDrift(d, r, want, live, markers)
+	if len(reasons) == 0 {
+		updates := map[string]any{"drift_checked_at": now}
+		if r.Status == "drifted" {
+			updates["status"] = "ready"
+			updates["last_error"] = ""
+			updates["observed_ttl"] = nil
+			updates["observed_values"] = nil
+			updates["observed_alias"] = nil
+			updates["drifted_at"] = nil
+		}
+		_, err := saveDriftCheck(db, r, updates)
+		return false, err
+	}
+
+	var observedTTL *int
+	var observedAlias datatypes.JSON
+	observed := []string{}
+	if live != nil {
+		observedTTL = liveTTL(*live)
+		observed = live.Values
+		observedAlias = aliasColumn(live.Alias)
+	}
+	vals, _ := json.Marshal(observed)
+
+	status := "drifted"
+	if autoReapply {
+		status = "pending"
+	}
+	driftedAt := now
+	if r.DriftedAt != nil {
+		driftedAt = *r.DriftedAt
+	}
+	log.Warn().
+		Str("fqdn", fq).
+		Str("rr_type", rt).
+		Str("record_id", r.ID.String()).
+		Strs("reasons", reasons).
+		Bool("auto_reapply", autoReapply).
+		Msg("[dns] record drifted")
+
+	return saveDriftCheck(db, r, map[string]any{
+		"status":           status,
+		"last_error":       truncateErr("drift: " + strings.Join(reasons, "; ")),
+		"observed_ttl":     observedTTL,
+		"observed_values":  datatypes.JSON(vals),
+		"observed_alias":   observedAlias,
+		"drifted_at":       driftedAt,
+		"drift_checked_at": now,
+	})
+}
+
+// saveDriftCheck writes a drift check's outcome only if the row is still a
+// settled record with the fingerprint that was checked. A row edited, queued
+// or marked deleting while the provider was read is skipped, and false is
+// returned.
+func saveDriftCheck(db *gorm.DB, r *models.RecordSet, updates map[string]any) (bool, error) {
+	res := db.Model(&models.RecordSet{}).
+		Where("id = ? AND status IN ? AND fingerprint = ?", r.ID, []string{"ready", "drifted"}, r.Fingerprint).
+		Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+// recordDrift lists how the live record and marker differ from the row. The
+// marker catches a record rewritten by something else even when the values
+// happen to match, since it carries the fingerprint the row was applied with.
+func recordDrift(d *models.Domain, r *models.RecordSet, want dns.Record, live *dns.Record, markers []dns.Marker) []string {
+	var out []string
+	switch {
+	case live == nil:
+		out = append(out, "record is missing at the provider")
+	case want.Alias != nil || live.Alias != nil:
+		// An alias has no TTL or values of its own; only its target counts.
+		if !dns.SameAlias(live.Alias, want.Alias) {
+			out = append(out, fmt.Sprintf("alias target is %s, want %s", aliasLabel(live.Alias), aliasLabel(want.Alias)))
+		}
+	default:
+		if live.TTL != want.TTL {
+			out = append(out, fmt.Sprintf("ttl is %d, want %d", live.TTL, want.TTL))
+		}
+		have, wantC := dns.CanonicalValues(r.Type, live.Values), dns.CanonicalValues(r.Type, want.Values)
+		if !slices.Equal(have, wantC) {
+			out = append(out, fmt.Sprintf("values are [%s], want [%s]", strings.Join(have, ", "), strings.Join(wantC, ", ")))
+		}
+	}
+
+	ours := false
+	for _, mk := range markers {
+		if mk.Org == d.OrganizationID.String() && mk.Rec == r.ID.String() && mk.Fp == dns.ShortFP(r.Fingerprint) {
+			ours = true
+		}
+	}
+	if !ours {
+		out = append(out, "ownership marker is missing or does not match")
+	}
+	return out
+}
+
+func aliasLabel(a *dns.AliasTarget) string {
+	if a == nil {
+		return "none"
+	}
+	return strings.TrimSuffix(a.DNSName, ".")
+}
@@ -0,0 +1,203 @@
+package bg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/glueops/autoglue/internal/dns"
+	"github.com/glueops/autoglue/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ErrDNSOwnershipConflict is returned by AdoptRecord when something else
+// manages the record at the provider.
+var ErrDNSOwnershipConflict = errors.New("ownership conflict")
+
+// ErrDNSRecordGone is returned by AdoptRecord when the record is no longer
+// at the provider.
+var ErrDNSRecordGone = errors.New("record is no longer at the provider")
+
+// ErrDNSRecordChanged is returned by AdoptRecord when the row was changed,
+// for example marked deleting, while the marker was being written.
+var ErrDNSRecordChanged = errors.New("record set changed while it was adopted")
+
+// importableTypes are the record types a RecordSet can hold.
+var importableTypes = map[string]bool{
+	"A": true, "AAAA": true, "CNAME": true, "TXT": true, "MX": true, "NS": true, "SRV": true, "CAA": true,
+}
+
+// ZoneImport is what ImportZoneRecords did with the zone's record sets.
+type ZoneImport struct {
+	// Imported are the new rows.
+	Imported []models.RecordSet
+	// Existing counts record sets that already had a row.
+	Existing int
+	// Skipped counts record sets that are not imported: the SOA and apex
+	// NS, which belong to the provider; autoglue's ownership markers and
+	// external-dns poison; and types a RecordSet cannot hold.
+	Skipped int
+}
+
+// ImportZoneRecords lists the domain's zone and creates a row, owned by
+// external, for every record set that has none. External rows are read-only
+// until adopted.
+func ImportZoneRecords(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain) (ZoneImport, error) {
+	var out ZoneImport
+	zoneID := strings.TrimSpace(d.ZoneID)
+	if zoneID == "" {
+		return out, errors.New("domain has no zone_id yet")
+	}
+	live, err := p.ListRecords(ctx, zoneID)
+	if err != nil {
+		return out, err
+	}
+
+	var rows []models.RecordSet
+	if err := db.Select("name", "type").Where("domain_id = ?", d.ID).Find(&rows).Error; err != nil {
+		return out, err
+	}
+	tracked := map[string]bool{}
+	for _, r := range rows {
+		tracked[recordFQDN(r.Name, d.DomainName)+" "+strings.ToUpper(r.Type)] = true
+	}
+
+	apex := recordFQDN("@", d.DomainName)
+	for _, rec := range live {
+		name, rt := dns.Fqdn(rec.Name), strings.ToUpper(rec.Type)
+		rel, ok := relativeName(name, apex)
+		if !ok || !importable(rec, name == apex) {
+			out.Skipped++
+			continue
+		}
+		if tracked[name+" "+rt] {
+			out.Existing++
+			continue
+		}
+
+		ttl := liveTTL(rec)
+		vals, _ := json.Marshal(rec.Values)
+		row := models.RecordSet{
+			DomainID:    d.ID,
+			Name:        rel,
+			Type:        rt,
+			TTL:         ttl,
+			Values:      datatypes.JSON(vals),
+			Alias:       aliasColumn(rec.Alias),
+			Fingerprint: dns.Fingerprint(zoneID, strings.TrimSuffix(name, "."), rt, ttl, rec.Values, rec.Alias),
+			Status:      "ready",
+			Owner:       "external",
+		}
+		if err := db.Create(&row).Error; err != nil {
+			return out, err
+		}
+		tracked[name+" "+rt] = true
+		out.Imported = append(out.Imported, row)
+	}
+	return out, nil
+}
+
+func importable(rec dns.Record, atApex bool) bool {
+	rt := strings.ToUpper(rec.Type)
+	switch {
+	case !importableTypes[rt], atApex && rt == "NS":
+		return false
+	case strings.HasPrefix(rec.Name, "_autoglue."):
+		return false
+	case rt == "TXT" && len(rec.Values) == 1 && rec.Values[0] == dns.ExternalDNSPoisonValue:
+		return false
+	}
+	return true
+}
+
+// liveTTL is a live record's TTL for its row; aliases have none.
+func liveTTL(rec dns.Record) *int {
+	if rec.Alias != nil {
+		return nil
+	}
+	ttl := int(rec.TTL)
+	return &ttl
+}
+
+// relativeName is name relative to the apex, "@" for the apex itself, and
+// false for a name outside it.
+func relativeName(name, apex string) (string, bool) {
+	if name == apex {
+		return "@", true
+	}
+	rel, ok := strings.CutSuffix(name, "."+apex)
+	return rel, ok && rel != ""
+}
+
+// AdoptRecord makes an imported record set managed by autoglue. It takes the
+// record as it is at the provider now and writes only the ownership marker
+// and the external-dns poison, so nothing that resolves changes. It refuses a
+// record that external-dns or another autoglue record owns.
+func AdoptRecord(ctx context.Context, db *gorm.DB, p dns.Provider, d *models.Domain, r *models.RecordSet) error {
+	zoneID := strings.TrimSpace(d.ZoneID)
+	fq := recordFQDN(r.Name, d.DomainName)
+	rt := strings.ToUpper(r.Type)
+
+	owned, err := dns.ExternalDNSOwned(ctx, p, zoneID, fq, rt)
+	if err != nil {
+		return fmt.Errorf("external_dns_lookup: %w", err)
+	}
+	if owned {
+		return fmt.Errorf("%w: external-dns manages %s", ErrDNSOwnershipConflict, strings.TrimSuffix(fq, "."))
+	}
+	markers, err := dns.GetMarkers(ctx, p, zoneID, fq)
+	if err != nil {
+		return fmt.Errorf("marker lookup: %w", err)
+	}
+	for _, mk := range markers {
+		if mk.Org != d.OrganizationID.String() || mk.Rec != r.ID.String() {
+			return fmt.Errorf("%w: another autoglue record owns %s", ErrDNSOwnershipConflict, strings.TrimSuffix(fq, "."))
+		}
+	}
+
+	live, err := p.GetRecord(ctx, zoneID, fq, rt)
+	if err != nil {
+		return err
+	}
+	if live == nil {
+		return fmt.Errorf("%w: %s %s", ErrDNSRecordGone, strings.TrimSuffix(fq, "."), rt)
+	}
+
+	ttl := liveTTL(*live)
+	vals, _ := json.Marshal(live.Values)
+	alias := aliasColumn(live.Alias)
+	fp := dns.Fingerprint(zoneID, strings.TrimSuffix(fq, "."), rt, ttl, live.Values, live.Alias)
+
+	changes := []dns.Change{{Action: dns.ActionUpsert, Record: dns.Record{
+		Name: dns.MarkerName(fq), Type: "TXT", TTL: defaultRecordTTLSeconds,
+		Values: []string{dns.MarkerValue(d.OrganizationID.String(), r.ID.String(), fp)},
+	}}}
+	for _, pr := range dns.PoisonRecords(fq, rt, defaultRecordTTLSeconds) {
+		changes = append(changes, dns.Change{Action: dns.ActionUpsert, Record: pr})
+	}
+	if err := p.Apply(ctx, zoneID, changes); err != nil {
+		return err
+	}
+
+	ok, err := updateRecordFrom(db, r, map[string]any{
+		"ttl":         ttl,
+		"values":      datatypes.JSON(vals),
+		"alias":       alias,
+		"fingerprint": fp,
+		"owner":       "autoglue",
+		"status":      "ready",
+		"last_error":  "",
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDNSRecordChanged
+	}
+	r.TTL, r.Values, r.Alias, r.Fingerprint = ttl, datatypes.JSON(vals), alias, fp
+	r.Owner, r.Status, r.LastError = "autoglue", "ready", ""
+	return nil
+}
@@ -0,0 +1,772 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glueops/autoglue/internal/dns"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/testutil/pgtest"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// memProvider is a dns.Provider over one in-memory zone.
+type memProvider struct {
+	mu      sync.Mutex
+	zone    string
+	records map[string]dns.Record // by "<fqdn> <TYPE>"
+	applies int
+	// fail, when set, can refuse a batch before any of it is applied.
+	fail func([]dns.Change) error
+}
+
+func newMemProvider(zone string) *memProvider {
+	return &memProvider{zone: zone, records: map[string]dns.Record{}}
+}
+
+func memKey(name, rrType string) string {
+	return strings.ToLower(dns.Fqdn(name)) + " " + strings.ToUpper(rrType)
+}
+
+func (m *memProvider) Name() string { return "memory" }
+
+func (m *memProvider) FindZone(ctx context.Context, domain string) (string, error) {
+	return m.zone, m.GetZone(ctx, domain)
+}
+
+func (m *memProvider) GetZone(_ context.Context, zoneID string) error {
+	if !strings.EqualFold(dns.Fqdn(zoneID), dns.Fqdn(m.zone)) {
+		return dns.ErrZoneNotFound
+	}
+	return nil
+}
+
+func (m *memProvider) GetRecord(_ context.Context, _ string, name, rrType string) (*dns.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.records[memKey(name, rrType)]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func (m *memProvider) ListRecords(context.Context, string) ([]dns.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]dns.Record, 0, len(m.records))
+	for _, r := range m.records {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return memKey(out[i].Name, out[i].Type) < memKey(out[j].Name, out[j].Type) })
+	return out, nil
+}
+
+func (m *memProvider) Apply(_ context.Context, _ string, changes []dns.Change) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.applies++
+	if m.fail != nil {
+		if err := m.fail(changes); err != nil {
+			return err
+		}
+	}
+	for _, ch := range changes {
+		k := memKey(ch.Record.Name, ch.Record.Type)
+		if ch.Action == dns.ActionDelete {
+			delete(m.records, k)
+			continue
+		}
+		m.records[k] = ch.Record
+	}
+	return nil
+}
+
+// trackedProvider is a memProvider whose changes propagate only once the
+// test marks them synced, like Route 53's.
+type trackedProvider struct {
+	*memProvider
+	changes []string
+	synced  map[string]bool
+}
+
+func (t *trackedProvider) ApplyTracked(ctx context.Context, zoneID string, changes []dns.Change) (string, error) {
+	if err := t.Apply(ctx, zoneID, changes); err != nil {
+		return "", err
+	}
+	t.changes = append(t.changes, fmt.Sprintf("C%d", len(t.changes)+1))
+	return t.changes[len(t.changes)-1], nil
+}
+
+func (t *trackedProvider) ChangeSynced(_ context.Context, changeID string) (bool, error) {
+	return t.synced[changeID], nil
+}
+
+func (m *memProvider) set(r dns.Record) {
+	m.records[memKey(r.Name, r.Type)] = r
+}
+
+func createDNSTestDomain(t *testing.T, db *gorm.DB, name string) models.Domain {
+	t.Helper()
+	org := models.Organization{Name: "dns-" + uuid.NewString()}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	cred := models.Credential{
+		OrganizationID: org.ID, Provider: "aws", Kind: "aws_access_key", ScopeKind: "service",
+		ScopeFingerprint: strings.Repeat("0", 64), EncryptedData: "x", IV: "x", Tag: "x",
+	}
+	if err := db.Create(&cred).Error; err != nil {
+		t.Fatalf("create credential: %v", err)
+	}
+	d := models.Domain{OrganizationID: org.ID, DomainName: name, ZoneID: name + ".", Status: "ready", CredentialID: cred.ID}
+	if err := db.Create(&d).Error; err != nil {
+		t.Fatalf("create domain: %v", err)
+	}
+	return d
+}
+
+func createDNSTestRecord(t *testing.T, db *gorm.DB, d models.Domain, name, status string) models.RecordSet {
+	t.Helper()
+	r := models.RecordSet{
+		DomainID: d.ID, Name: name, Type: "A", Values: datatypes.JSON(`["192.0.2.1"]`),
+		Fingerprint: strings.Repeat("a", 64), Status: status, Owner: "autoglue",
+	}
+	if err := db.Create(&r).Error; err != nil {
+		t.Fatalf("create record: %v", err)
+	}
+	return r
+}
+
+func TestDeleteRecordRemovesOwnRecordsOnly(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	d := createDNSTestDomain(t, db, "example.org")
+	p := newMemProvider("example.org.")
+
+	// Written by autoglue: applying it leaves the record, marker and poison.
+	ours := createDNSTestRecord(t, db, d, "api", "pending")
+	if err := applyRecord(ctx, db, p, &d, &ours); err != nil {
+		t.Fatalf("applyRecord: %v", err)
+	}
+	if len(p.records) < 3 {
+		t.Fatalf("expected record, marker and poison, got %v", p.records)
+	}
+	ours.Status = "deleting"
+	db.Save(&ours)
+
+	// Same name in the zone, but the marker names another record.
+	taken := createDNSTestRecord(t, db, d, "www", "deleting")
+	p.set(dns.Record{Name: "www.example.org.", Type: "A", TTL: 60, Values: []string{"192.0.2.9"}})
+	p.set(dns.Record{Name: dns.MarkerName("www.example.org."), Type: "TXT", TTL: 60,
+		Values: []string{dns.MarkerValue(d.OrganizationID.String(), uuid.NewString(), "fp")}})
+
+	var deleting []models.RecordSet
+	db.Where("domain_id = ? AND status = ?", d.ID, "deleting").Find(&deleting)
+	n, err := deleteRecords(ctx, db, p, &d, deleting)
+	if err != nil || n != 2 {
+		t.Fatalf("deleted %d, err %v", n, err)
+	}
+
+	if r, _ := p.GetRecord(ctx, p.zone, "api.example.org.", "A"); r != nil {
+		t.Fatal("our record should be gone from the provider")
+	}
+	if r, _ := p.GetRecord(ctx, p.zone, dns.MarkerName("api.example.org."), "TXT"); r != nil {
+		t.Fatal("our marker should be gone from the provider")
+	}
+	if r, _ := p.GetRecord(ctx, p.zone, "www.example.org.", "A"); r == nil {
+		t.Fatal("a record with another owner's marker must be left alone")
+	}
+	var left int64
+	db.Model(&models.RecordSet{}).Where("id IN ?", []uuid.UUID{ours.ID, taken.ID}).Count(&left)
+	if left != 0 {
+		t.Fatalf("%d rows left after deletion", left)
+	}
+
+	d.Status = "deleting"
+	db.Save(&d)
+	if err := finishDomainDeletion(db, &d); err != nil {
+		t.Fatalf("finishDomainDeletion: %v", err)
+	}
+	if err := db.First(&models.Domain{}, "id = ?", d.ID).Error; err == nil {
+		t.Fatal("domain should be removed once its records are gone")
+	}
+}
+
+func TestApplyLeavesARecordMarkedDeletingMeanwhile(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	d := createDNSTestDomain(t, db, "example.org")
+	p := newMemProvider("example.org.")
+
+	// The user deletes the record set while its write is in flight.
+	r := createDNSTestRecord(t, db, d, "api", "pending")
+	if err := db.Model(&models.RecordSet{}).Where("id = ?", r.ID).Update("status", "deleting").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := applyRecord(ctx, db, p, &d, &r); err != nil {
+		t.Fatalf("applyRecord: %v", err)
+	}
+	_ = setRecordFailed(db, &r, errors.New("provider said no"))
+
+	var got models.RecordSet
+	db.First(&got, "id = ?", r.ID)
+	if got.Status != "deleting" || got.LastError != "" {
+		t.Fatalf("row after a stale write = %+v", got)
+	}
+}
+
+func TestRecordDrift(t *testing.T) {
+	d := &models.Domain{OrganizationID: uuid.New(), DomainName: "example.org"}
+	r := &models.RecordSet{ID: uuid.New(), Name: "api", Type: "CNAME", Fingerprint: strings.Repeat("b", 64)}
+	ours := []dns.Marker{{Org: d.OrganizationID.String(), Rec: r.ID.String(), Fp: dns.ShortFP(r.Fingerprint)}}
+	want := dns.Record{Name: "api.example.org.", Type: "CNAME", TTL: defaultRecordTTLSeconds, Values: []string{"lb.example.net"}}
+
+	live := &dns.Record{Name: "api.example.org.", Type: "CNAME", TTL: defaultRecordTTLSeconds, Values: []string{"LB.example.net."}}
+	if got := recordDrift(d, r, want, live, ours); len(got) != 0 {
+		t.Fatalf("same record in provider form should not drift: %v", got)
+	}
+
+	live.Values = []string{"other.example.net."}
+	live.TTL = 60
+	if got := recordDrift(d, r, want, live, ours); len(got) != 2 {
+		t.Fatalf("changed ttl and value should both be reported: %v", got)
+	}
+
+	stale := []dns.Marker{{Org: ours[0].Org, Rec: ours[0].Rec, Fp: "0000000000000000"}}
+	if got := recordDrift(d, r, want, nil, stale); len(got) != 2 || !strings.Contains(got[0], "missing") {
+		t.Fatalf("missing record and stale marker: %v", got)
+	}
+}
+
+func TestRecordDriftAlias(t *testing.T) {
+	d := &models.Domain{OrganizationID: uuid.New(), DomainName: "example.org"}
+	r := &models.RecordSet{
+		ID: uuid.New(), Name: "@", Type: "A", Fingerprint: strings.Repeat("c", 64),
+		Alias: datatypes.JSON(`{"hosted_zone_id":"Z35SXDOTRQ7X7K","dns_name":"my-lb-1.us-east-1.elb.amazonaws.com","evaluate_target_health":false}`),
+	}
+	ours := []dns.Marker{{Org: d.OrganizationID.String(), Rec: r.ID.String(), Fp: dns.ShortFP(r.Fingerprint)}}
+	want, err := desiredRecord(r, "example.org.")
+	if err != nil || want.Alias == nil || len(want.Values) != 0 {
+		t.Fatalf("desiredRecord = %+v, %v", want, err)
+	}
+
+	live := &dns.Record{Name: "example.org.", Type: "A", Alias: &dns.AliasTarget{
+		HostedZoneID: "/hostedzone/Z35SXDOTRQ7X7K", DNSName: "My-LB-1.us-east-1.elb.amazonaws.com.",
+	}}
+	if got := recordDrift(d, r, want, live, ours); len(got) != 0 {
+		t.Fatalf("same alias in provider form should not drift: %v", got)
+	}
+
+	live.Alias.DNSName = "other-lb.us-east-1.elb.amazonaws.com."
+	if got := recordDrift(d, r, want, live, ours); len(got) != 1 || !strings.Contains(got[0], "alias target") {
+		t.Fatalf("retargeted alias: %v", got)
+	}
+
+	live.Alias = nil
+	live.TTL, live.Values = 300, []string{"192.0.2.1"}
+	if got := recordDrift(d, r, want, live, ours); len(got) != 1 || !strings.Contains(got[0], "none") {
+		t.Fatalf("alias replaced by a plain record: %v", got)
+	}
+}
+
+func TestCheckRecordDriftFlagsAndRecovers(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	d := createDNSTestDomain(t, db, "example.org")
+	p := newMemProvider("example.org.")
+
+	r := createDNSTestRecord(t, db, d, "api", "pending")
+	if err := applyRecord(ctx, db, p, &d, &r); err != nil {
+		t.Fatalf("applyRecord: %v", err)
+	}
+	if found, err := checkRecordDrift(ctx, db, p, &d, &r, false); err != nil || found {
+		t.Fatalf("fresh record drifted: %v %v", found, err)
+	}
+
+	// Someone edits the record in the provider's console.
+	p.set(dns.Record{Name: "api.example.org.", Type: "A", TTL: 300, Values: []string{"192.0.2.99"}})
+	if found, err := checkRecordDrift(ctx, db, p, &d, &r, false); err != nil || !found {
+		t.Fatalf("edit not detected: %v %v", found, err)
+	}
+	var got models.RecordSet
+	db.First(&got, "id = ?", r.ID)
+	if got.Status != "drifted" || got.DriftedAt == nil || !strings.Contains(string(got.ObservedValues), "192.0.2.99") {
+		t.Fatalf("row after drift = %+v", got)
+	}
+
+	// With auto re-apply on, the next pass hands it back to the reconciler.
+	if _, err := checkRecordDrift(ctx, db, p, &d, &got, true); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&got, "id = ?", r.ID)
+	if got.Status != "pending" {
+		t.Fatalf("status = %s, want pending", got.Status)
+	}
+	if err := applyRecord(ctx, db, p, &d, &got); err != nil {
+		t.Fatalf("re-apply: %v", err)
+	}
+	if found, _ := checkRecordDrift(ctx, db, p, &d, &got, true); found {
+		t.Fatal("re-applied record still drifted")
+	}
+	db.First(&got, "id = ?", r.ID)
+	if got.Status != "ready" || got.DriftedAt != nil {
+		t.Fatalf("row after re-apply = %+v", got)
+	}
+}
+
+func TestCheckRecordDriftSkipsARowChangedMeanwhile(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	d := createDNSTestDomain(t, db, "example.org")
+	p := newMemProvider("example.org.")
+
+	r := createDNSTestRecord(t, db, d, "api", "pending")
+	if err := applyRecord(ctx, db, p, &d, &r); err != nil {
+		t.Fatalf("applyRecord: %v", err)
+	}
+	p.set(dns.Record{Name: "api.example.org.", Type: "A", TTL: 300, Values: []string{"192.0.2.99"}})
+
+	// The user edits the row after the drift worker loaded it.
+	if err := db.Model(&models.RecordSet{}).Where("id = ?", r.ID).
+		Updates(map[string]any{"status": "pending", "fingerprint": strings.Repeat("b", 64)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if found, err := checkRecordDrift(ctx, db, p, &d, &r, false); err != nil || found {
+		t.Fatalf("stale check reported drift: %v %v", found, err)
+	}
+	var got models.RecordSet
+	db.First(&got, "id = ?", r.ID)
+	if got.Status != "pending" || got.DriftedAt != nil {
+		t.Fatalf("row after a stale check = %+v", got)
+	}
+}
+
+func TestImportAndAdoptZoneRecords(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	d := createDNSTestDomain(t, db, "example.org")
+	p := newMemProvider("example.org.")
+
+	p.set(dns.Record{Name: "example.org.", Type: "SOA", TTL: 900, Values: []string{"ns1.example.org. hostmaster.example.org. 1 7200 900 1209600 300"}})
+	p.set(dns.Record{Name: "example.org.", Type: "NS", TTL: 900, Values: []string{"ns1.example.org."}})
+	p.set(dns.Record{Name: "example.org.", Type: "MX", TTL: 900, Values: []string{"10 mail.example.org."}})
+	p.set(dns.Record{Name: "www.example.org.", Type: "A", TTL: 60, Values: []string{"192.0.2.10"}})
+	p.set(dns.Record{Name: "edge.example.org.", Type: "CNAME", TTL: 60, Values: []string{"lb.example.net."}})
+	p.set(dns.Record{Name: "edge.example.org.", Type: "TXT", TTL: 300, Values: []string{"heritage=external-dns,external-dns/owner=k8s"}})
+	p.set(dns.Record{Name: "extdns-edge.example.org.", Type: "TXT", TTL: 300, Values: []string{"heritage=external-dns,external-dns/owner=k8s"}})
+	p.set(dns.Record{Name: "extdns-cname-edge.example.org.", Type: "TXT", TTL: 300, Values: []string{"heritage=external-dns,external-dns/owner=k8s"}})
+	createDNSTestRecord(t, db, d, "api", "ready")
+	p.set(dns.Record{Name: "api.example.org.", Type: "A", TTL: 300, Values: []string{"192.0.2.1"}})
+	p.set(dns.Record{Name: dns.MarkerName("api.example.org."), Type: "TXT", TTL: 300, Values: []string{"v=ag1 org=x rec=y fp=z"}})
+
+	res, err := ImportZoneRecords(ctx, db, p, &d)
+	if err != nil {
+		t.Fatalf("ImportZoneRecords: %v", err)
+	}
+	if res.Existing != 1 || res.Skipped != 3 || len(res.Imported) != 6 {
+		t.Fatalf("imported %d, existing %d, skipped %d", len(res.Imported), res.Existing, res.Skipped)
+	}
+	var www models.RecordSet
+	for _, r := range res.Imported {
+		if r.Owner != "external" {
+			t.Fatalf("%s %s imported with owner %s", r.Name, r.Type, r.Owner)
+		}
+		if r.Name == "www" {
+			www = r
+		}
+	}
+
+	// Importing again finds everything tracked.
+	again, err := ImportZoneRecords(ctx, db, p, &d)
+	if err != nil || len(again.Imported) != 0 || again.Existing != 7 {
+		t.Fatalf("second import = %+v, %v", again, err)
+	}
+
+	// The record changed since the import; adopting takes it as it is now.
+	p.set(dns.Record{Name: "www.example.org.", Type: "A", TTL: 120, Values: []string{"192.0.2.11"}})
+	applies := p.applies
+	if err := AdoptRecord(ctx, db, p, &d, &www); err != nil {
+		t.Fatalf("AdoptRecord: %v", err)
+	}
+	if live, _ := p.GetRecord(ctx, p.zone, "www.example.org.", "A"); live.Values[0] != "192.0.2.11" || p.applies != applies+1 {
+		t.Fatalf("adopting should only write the marker, record now %+v", live)
+	}
+	var got models.RecordSet
+	db.First(&got, "id = ?", www.ID)
+	if got.Owner != "autoglue" || got.TTL == nil || *got.TTL != 120 || !strings.Contains(string(got.Values), "192.0.2.11") {
+		t.Fatalf("adopted row = %+v", got)
+	}
+	if drift := recordDrift(&d, &got, dns.Record{TTL: 120, Values: []string{"192.0.2.11"}}, &dns.Record{TTL: 120, Values: []string{"192.0.2.11"}}, mustMarkers(t, p, "www.example.org.")); len(drift) != 0 {
+		t.Fatalf("adopted record drifted: %v", drift)
+	}
+
+	var edge models.RecordSet
+	db.First(&edge, "domain_id = ? AND name = ? AND type = ?", d.ID, "edge", "CNAME")
+	if err := AdoptRecord(ctx, db, p, &d, &edge); !errors.Is(err, ErrDNSOwnershipConflict) {
+		t.Fatalf("external-dns record adopted: %v", err)
+	}
+}
+
+func mustMarkers(t *testing.T, p dns.Provider, fq string) []dns.Marker {
+	t.Helper()
+	m, err := dns.GetMarkers(context.Background(), p, p.(*memProvider).zone, fq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDedupeChanges(t *testing.T) {
+	var changes []dns.Change
+	for _, rt := range []string{"A", "AAAA"} {
+		for _, pr := range dns.PoisonRecords("api.example.org.", rt, defaultRecordTTLSeconds) {
+			changes = append(changes, dns.Change{Action: dns.ActionUpsert, Record: pr})
+		}
+	}
+	got := dedupeChanges(changes)
+	if len(got) != len(changes)-1 {
+		t.Fatalf("A and AAAA share one name-wide poison record: got %d of %d changes", len(got), len(changes))
+	}
+
+	marker := func(rec string) dns.Change {
+		return dns.Change{Action: dns.ActionUpsert, Record: dns.Record{Name: dns.MarkerName("api.example.org."), Type: "TXT", TTL: 300,
+			Values: []string{dns.MarkerValue("org", rec, strings.Repeat("a", 64))}}}
+	}
+	if got := dedupeChanges([]dns.Change{marker("r1"), marker("r2")}); len(got) != 2 {
+		t.Fatalf("markers with different values must not be merged: %d", len(got))
+	}
+}
+
+func TestApplyRecordsFailsTheSecondRecordSetAtOneName(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	d := createDNSTestDomain(t, db, "example.org")
+	p := newMemProvider("example.org.")
+
+	a := createDNSTestRecord(t, db, d, "api", "pending")
+	aaaa := createDNSTestRecord(t, db, d, "api", "pending")
+	db.Model(&aaaa).Updates(map[string]any{"type": "AAAA", "values": datatypes.JSON(`["2001:db8::1"]`)})
+	aaaa.Type, aaaa.Values = "AAAA", datatypes.JSON(`["2001:db8::1"]`)
+
+	n, err := applyRecords(ctx, db, p, &d, []models.RecordSet{a, aaaa})
+	if err != nil || n != 1 {
+		t.Fatalf("applyRecords = %d, %v", n, err)
+	}
+	markers := mustMarkers(t, p, "api.example.org.")
+	if len(markers) != 1 || markers[0].Rec != a.ID.String() {
+		t.Fatalf("the first record set's marker should be the only one: %+v", markers)
+	}
+	var got models.RecordSet
+	db.First(&got, "id = ?", aaaa.ID)
+	if got.Status != "failed" || !strings.Contains(got.LastError, "ownership_conflict") {
+		t.Fatalf("second record set = %s (%s), want failed before the batch", got.Status, got.LastError)
+	}
+	if _, ok := p.records[memKey("api.example.org.", "AAAA")]; ok {
+		t.Fatal("the failed record set must not be written")
+	}
+}
+
+func TestApplyChangesBacksOffWhileThrottled(t *testing.T) {
+	defer func(d time.Duration) { throttleBackoff = d }(throttleBackoff)
+	throttleBackoff = time.Millisecond
+
+	p := newMemProvider("example.org.")
+	throttled := 2
+	p.fail = func([]dns.Change) error {
+		if throttled > 0 {
+			throttled--
+			return fmt.Errorf("%w: slow down", dns.ErrThrottled)
+		}
+		return nil
+	}
+	rec := dns.Record{Name: "api.example.org.", Type: "A", TTL: 300, Values: []string{"192.0.2.1"}}
+	if _, err := applyChanges(context.Background(), p, p.zone, []dns.Change{{Action: dns.ActionUpsert, Record: rec}}); err != nil {
+		t.Fatalf("applyChanges: %v", err)
+	}
+	if p.applies != 3 {
+		t.Fatalf("applies = %d, want 3", p.applies)
+	}
+
+	p.fail = func([]dns.Change) error { return dns.ErrThrottled }
+	if _, err := applyChanges(context.Background(), p, p.zone, nil); !errors.Is(err, dns.ErrThrottled) {
+		t.Fatalf("err = %v, want throttled after the retries", err)
+	}
+}
+
+func TestApplyRecordsBatchesAndWaitsForSync(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	d := createDNSTestDomain(t, db, "example.org")
+	p := &trackedProvider{memProvider: newMemProvider("example.org."), synced: map[string]bool{}}
+
+	var records []models.RecordSet
+	for _, name := range []string{"a", "b", "c", "taken"} {
+		records = append(records, createDNSTestRecord(t, db, d, name, "pending"))
+	}
+	p.set(dns.Record{Name: dns.MarkerName("taken.example.org."), Type: "TXT", TTL: 300,
+		Values: []string{dns.MarkerValue(uuid.NewString(), uuid.NewString(), strings.Repeat("f", 64))}})
+
+	n, err := applyRecords(ctx, db, p, &d, records)
+	if err != nil || n != 3 {
+		t.Fatalf("applyRecords = %d, %v", n, err)
+	}
+	if p.applies != 1 {
+		t.Fatalf("applies = %d, want the three records in one batch", p.applies)
+	}
+	statuses := func() map[string]string {
+		var rows []models.RecordSet
+		db.Where("domain_id = ?", d.ID).Find(&rows)
+		out := map[string]string{}
+		for _, r := range rows {
+			out[r.Name] = r.Status
+		}
+		return out
+	}
+	if got := statuses(); got["a"] != "provisioning" || got["c"] != "provisioning" || got["taken"] != "failed" {
+		t.Fatalf("after apply: %v", got)
+	}
+
+	if n, err := syncChanges(ctx, db, p, &d, p.changes); err != nil || n != 0 {
+		t.Fatalf("unsynced change moved %d records: %v", n, err)
+	}
+	p.synced[p.changes[0]] = true
+	if n, err := syncChanges(ctx, db, p, &d, p.changes); err != nil || n != 3 {
+		t.Fatalf("syncChanges = %d, %v", n, err)
+	}
+	if got := statuses(); got["a"] != "ready" || got["b"] != "ready" || got["c"] != "ready" {
+		t.Fatalf("after sync: %v", got)
+	}
+}
+
+func TestApplyRecordsIsolatesARejectedRecord(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	d := createDNSTestDomain(t, db, "example.org")
+	p := newMemProvider("example.org.")
+	p.fail = func(changes []dns.Change) error {
+		for _, ch := range changes {
+			if ch.Record.Name == "bad.example.org." {
+				return errors.New("InvalidChangeBatch")
+			}
+		}
+		return nil
+	}
+
+	records := []models.RecordSet{
+		createDNSTestRecord(t, db, d, "good", "pending"),
+		createDNSTestRecord(t, db, d, "bad", "pending"),
+	}
+	if n, err := applyRecords(ctx, db, p, &d, records); err != nil || n != 1 {
+		t.Fatalf("applyRecords = %d, %v", n, err)
+	}
+	var good, bad models.RecordSet
+	db.First(&good, "id = ?", records[0].ID)
+	db.First(&bad, "id = ?", records[1].ID)
+	if good.Status != "ready" || bad.Status != "failed" || !strings.Contains(bad.LastError, "InvalidChangeBatch") {
+		t.Fatalf("good = %s, bad = %s (%s)", good.Status, bad.Status, bad.LastError)
+	}
+}
+
+func TestPlanAndApplyZoneFile(t *testing.T) {
+	db := pgtest.DB(t)
+	d := createDNSTestDomain(t, db, "example.org")
+
+	same := createDNSTestRecord(t, db, d, "same", "ready")
+	changed := createDNSTestRecord(t, db, d, "changed", "ready")
+	theirs := createDNSTestRecord(t, db, d, "theirs", "ready")
+	db.Model(&theirs).Update("owner", "external")
+
+	const zone = `$TTL 300
+@		NS	ns1.example.net.
+_autoglue.same	TXT	"v=ag1 org=x rec=y fp=z"
+same		A	192.0.2.1
+changed	60	A	192.0.2.9
+theirs		A	192.0.2.9
+new		TXT	"hello " "world"
+elsewhere.example.com.	A	192.0.2.1
+@		CNAME	lb.example.net.
+same		CNAME	lb.example.net.
+dup		A	192.0.2.1
+dup		CNAME	lb.example.net.
+`
+	recs, err := dns.ParseZoneFile(strings.NewReader(zone), d.DomainName, 300)
+	if err != nil {
+		t.Fatalf("ParseZoneFile: %v", err)
+	}
+	plan, err := PlanZoneFile(db, &d, recs)
+	if err != nil {
+		t.Fatalf("PlanZoneFile: %v", err)
+	}
+	// dup's A is kept; its CNAME is what clashes.
+	if len(plan.Create) != 2 || plan.Create[0].Name != "dup.example.org." ||
+		plan.Create[1].Name != "new.example.org." || plan.Create[1].Values[0] != "hello world" {
+		t.Fatalf("create = %+v", plan.Create)
+	}
+	if len(plan.Update) != 1 || plan.Update[0].Row.ID != changed.ID {
+		t.Fatalf("update = %+v", plan.Update)
+	}
+	if plan.Unchanged != 1 || len(plan.Conflicts) != 1 || plan.Skipped != 3 {
+		t.Fatalf("unchanged %d, conflicts %+v, skipped %d", plan.Unchanged, plan.Conflicts, plan.Skipped)
+	}
+	rejected := map[string]string{}
+	for _, rj := range plan.Rejected {
+		rejected[rj.Record.Name+" "+rj.Record.Type] = rj.Reason
+	}
+	if len(rejected) != 3 || !strings.Contains(rejected["example.org. CNAME"], "apex") ||
+		rejected["same.example.org. CNAME"] == "" || rejected["dup.example.org. CNAME"] == "" {
+		t.Fatalf("rejected = %v", rejected)
+	}
+
+	rows, err := ApplyZoneFile(db, &d, plan)
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("ApplyZoneFile = %d rows, %v", len(rows), err)
+	}
+	var got models.RecordSet
+	db.First(&got, "id = ?", changed.ID)
+	if got.Status != "pending" || got.TTL == nil || *got.TTL != 60 || !strings.Contains(string(got.Values), "192.0.2.9") {
+		t.Fatalf("changed row = %+v", got)
+	}
+	db.First(&got, "id = ?", same.ID)
+	if got.Status != "ready" {
+		t.Fatalf("unchanged row went %s", got.Status)
+	}
+	db.First(&got, "domain_id = ? AND name = ?", d.ID, "new")
+	if got.Owner != "autoglue" || got.Status != "pending" || got.Type != "TXT" {
+		t.Fatalf("new row = %+v", got)
+	}
+
+	bad := ZoneFilePlan{Create: []dns.Record{{Name: "bad.example.org.", Type: "A", TTL: 300, Values: []string{"not-an-address"}}}}
+	if _, err := ApplyZoneFile(db, &d, bad); err == nil {
+		t.Fatal("a plan with invalid values must not be written")
+	}
+	var n int64
+	db.Model(&models.RecordSet{}).Where("domain_id = ? AND name = ?", d.ID, "bad").Count(&n)
+	if n != 0 {
+		t.Fatal("invalid row was stored")
+	}
+}
+
+// zoneMaker is a memProvider that can also create its zone.
+type zoneMaker struct {
+	*memProvider
+	refs []string
+}
+
+func (z *zoneMaker) CreateZone(_ context.Context, domain, ref string) (string, []string, error) {
+	z.refs = append(z.refs, ref)
+	return "Z" + strings.ToUpper(strings.ReplaceAll(domain, ".", "")), []string{"NS-1.example.net.", "ns-2.example.net"}, nil
+}
+
+func TestCreateZone(t *testing.T) {
+	d := &models.Domain{ID: uuid.New(), DomainName: "new.example.org"}
+	p := &zoneMaker{memProvider: newMemProvider("example.org.")}
+
+	zoneID, err := createZone(context.Background(), p, d)
+	if err != nil || zoneID != "ZNEWEXAMPLEORG" {
+		t.Fatalf("createZone = %q, %v", zoneID, err)
+	}
+	if len(p.refs) != 1 || p.refs[0] != d.ID.String() {
+		t.Fatalf("the domain id should be the request reference: %v", p.refs)
+	}
+	if string(d.NameServers) != `["ns-1.example.net","ns-2.example.net"]` {
+		t.Fatalf("name servers = %s", d.NameServers)
+	}
+
+	if _, err := createZone(context.Background(), newMemProvider("example.org."), d); !errors.Is(err, dns.ErrUnsupported) {
+		t.Fatalf("a provider that cannot create zones: %v", err)
+	}
+}
+
+func TestDelegationDue(t *testing.T) {
+	now := time.Now()
+	long := now.Add(-time.Hour)
+	d := &models.Domain{Status: "ready", NameServers: datatypes.JSON(`["ns1.example.net"]`)}
+	if !delegationDue(d, now) {
+		t.Fatal("never checked")
+	}
+	d.DelegationCheckedAt, d.DelegationStatus = &long, dns.DelegationNone
+	if !delegationDue(d, now) {
+		t.Fatal("not delegated an hour ago")
+	}
+	d.DelegationStatus = dns.DelegationDelegated
+	if delegationDue(d, now) {
+		t.Fatal("delegated an hour ago")
+	}
+	d.NameServers = nil
+	d.DelegationCheckedAt = nil
+	if delegationDue(d, now) {
+		t.Fatal("no name servers to check against")
+	}
+}
+
+func TestCheckDelegation(t *testing.T) {
+	db := pgtest.DB(t)
+	ctx := context.Background()
+	defer func(f func(context.Context, string) ([]string, error)) { lookupNS = f }(lookupNS)
+	var looked []string
+	lookupNS = func(_ context.Context, name string) ([]string, error) {
+		looked = append(looked, name)
+		return []string{"ns-2.example.net.", "ns-1.example.net."}, nil
+	}
+
+	parent := createDNSTestDomain(t, db, "example.org")
+	child := models.Domain{
+		OrganizationID: parent.OrganizationID, CredentialID: parent.CredentialID, DomainName: "dev.example.org",
+		ZoneID: "Z2", Status: "ready", NameServers: datatypes.JSON(`["ns-1.example.net","ns-2.example.net"]`),
+	}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// The parent is in autoglue but has no NS record set for dev yet.
+	if err := updateDelegation(ctx, db, &child); err != nil {
+		t.Fatal(err)
+	}
+	if child.DelegationStatus != dns.DelegationNone || child.DelegationSource != "autoglue" || len(looked) != 0 {
+		t.Fatalf("no NS record in the parent: %s from %s, looked up %v", child.DelegationStatus, child.DelegationSource, looked)
+	}
+
+	ns := models.RecordSet{
+		DomainID: parent.ID, Name: "dev", Type: "NS", Values: datatypes.JSON(`["ns-1.example.net","ns-2.example.net."]`),
+		Fingerprint: strings.Repeat("d", 64), Status: "ready", Owner: "autoglue",
+	}
+	if err := db.Create(&ns).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := updateDelegation(ctx, db, &child); err != nil {
+		t.Fatal(err)
+	}
+	var got models.Domain
+	db.First(&got, "id = ?", child.ID)
+	if got.DelegationStatus != dns.DelegationDelegated || got.DelegationCheckedAt == nil {
+		t.Fatalf("after the parent's NS record: %+v", got)
+	}
+
+	// Without a parent here, DNS is asked.
+	db.Model(&parent).Update("status", "deleting")
+	if err := updateDelegation(ctx, db, &child); err != nil {
+		t.Fatal(err)
+	}
+	if child.DelegationStatus != dns.DelegationDelegated || child.DelegationSource != "dns" || len(looked) != 1 {
+		t.Fatalf("DNS lookup: %s from %s, looked up %v", child.DelegationStatus, child.DelegationSource, looked)
+	}
+
+	// A check of the zone the domain had before it moved is not saved.
+	db.Model(&models.Domain{}).Where("id = ?", child.ID).
+		Updates(map[string]any{"zone_id": "Z3", "delegation_status": dns.DelegationNone})
+	if err := updateDelegation(ctx, db, &child); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&got, "id = ?", child.ID)
+	if got.DelegationStatus != dns.DelegationNone {
+		t.Fatalf("stale check saved over the moved zone: %+v", got)
+	}
+}
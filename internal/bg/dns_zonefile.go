@@ -0,0 +1,211 @@
+package bg
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/glueops/autoglue/internal/dns"
+	"github.com/glueops/autoglue/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ZoneFileRecords are the domain's record sets as a zone file renders them,
+// leaving out those being deleted.
+func ZoneFileRecords(db *gorm.DB, d *models.Domain) ([]dns.Record, error) {
+	var rows []models.RecordSet
+	if err := db.Where("domain_id = ? AND status <> ?", d.ID, "deleting").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]dns.Record, 0, len(rows))
+	for i := range rows {
+		rec, err := desiredRecord(&rows[i], recordFQDN(rows[i].Name, d.DomainName))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	slices.SortFunc(out, func(a, b dns.Record) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+	return out, nil
+}
+
+// ZoneFileUpdate is a record set whose row a zone file changes.
+type ZoneFileUpdate struct {
+	Row    models.RecordSet
+	Record dns.Record
+}
+
+// ZoneFileRejection is a record set from a zone file that cannot be stored,
+// and why.
+type ZoneFileRejection struct {
+	Record dns.Record
+	Reason string
+}
+
+// ZoneFilePlan is how a zone file differs from the domain's record sets.
+// Record sets only in autoglue are left alone, so nothing is ever deleted.
+type ZoneFilePlan struct {
+	// Create are record sets in the file that have no row.
+	Create []dns.Record
+	// Update are autoglue-owned rows the file gives other values or TTL.
+	Update []ZoneFileUpdate
+	// Unchanged counts record sets the rows already match.
+	Unchanged int
+	// Conflicts are record sets the file would change whose rows autoglue
+	// does not manage, or that are being deleted.
+	Conflicts []dns.Record
+	// Rejected are record sets whose values are invalid for their type, or
+	// CNAMEs at the apex or sharing a name with other record sets. The record
+	// set API refuses the same.
+	Rejected []ZoneFileRejection
+	// Skipped counts record sets that are not imported: names outside the
+	// domain, the SOA and apex NS, ownership markers and external-dns
+	// poison, and types a RecordSet cannot hold.
+	Skipped int
+}
+
+// PlanZoneFile compares parsed zone file records with the domain's rows.
+// Values are checked and put in canonical form as the record set API does.
+func PlanZoneFile(db *gorm.DB, d *models.Domain, recs []dns.Record) (ZoneFilePlan, error) {
+	var plan ZoneFilePlan
+	var rows []models.RecordSet
+	if err := db.Where("domain_id = ?", d.ID).Find(&rows).Error; err != nil {
+		return plan, err
+	}
+	byKey := make(map[string]*models.RecordSet, len(rows))
+	// types are the record set types at each name, from the rows and then
+	// from the file's record sets as they are accepted.
+	types := map[string]map[string]bool{}
+	addType := func(name, rrType string) {
+		if types[name] == nil {
+			types[name] = map[string]bool{}
+		}
+		types[name][rrType] = true
+	}
+	for i := range rows {
+		fq, rt := recordFQDN(rows[i].Name, d.DomainName), strings.ToUpper(rows[i].Type)
+		byKey[fq+" "+rt] = &rows[i]
+		addType(fq, rt)
+	}
+
+	apex := recordFQDN("@", d.DomainName)
+	for _, rec := range recs {
+		name := dns.Fqdn(rec.Name)
+		rec.Name, rec.Type = name, strings.ToUpper(rec.Type)
+		if _, ok := relativeName(name, apex); !ok || !importable(rec, name == apex) {
+			plan.Skipped++
+			continue
+		}
+		values, err := dns.NormalizeValues(rec.Type, rec.Values)
+		if err != nil {
+			plan.Rejected = append(plan.Rejected, ZoneFileRejection{Record: rec, Reason: err.Error()})
+			continue
+		}
+		rec.Values = values
+		if reason := cnameClash(types[name], rec.Type, name == apex); reason != "" {
+			plan.Rejected = append(plan.Rejected, ZoneFileRejection{Record: rec, Reason: reason})
+			continue
+		}
+		addType(name, rec.Type)
+
+		row := byKey[name+" "+rec.Type]
+		switch {
+		case row == nil:
+			plan.Create = append(plan.Create, rec)
+		case rowMatches(row, name, rec):
+			plan.Unchanged++
+		case row.Owner != "autoglue" || row.Status == "deleting":
+			plan.Conflicts = append(plan.Conflicts, rec)
+		default:
+			plan.Update = append(plan.Update, ZoneFileUpdate{Row: *row, Record: rec})
+		}
+	}
+	return plan, nil
+}
+
+// cnameClash says why a record set of type rrType cannot join the types
+// already at its name, "" when it can: a CNAME is never at the apex and
+// shares its name with no other record set.
+func cnameClash(existing map[string]bool, rrType string, atApex bool) string {
+	switch {
+	case rrType == "CNAME" && atApex:
+		return "a CNAME cannot be at the zone apex"
+	case rrType == "CNAME":
+		for t := range existing {
+			if t != "CNAME" {
+				return "the name has other record sets; a CNAME cannot share it"
+			}
+		}
+	case existing["CNAME"]:
+		return "the name has a CNAME, which cannot share it with other record sets"
+	}
+	return ""
+}
+
+func rowMatches(row *models.RecordSet, fq string, rec dns.Record) bool {
+	want, err := desiredRecord(row, fq)
+	if err != nil || want.Alias != nil {
+		return false
+	}
+	return want.TTL == rec.TTL &&
+		slices.Equal(dns.CanonicalValues(rec.Type, want.Values), dns.CanonicalValues(rec.Type, rec.Values))
+}
+
+// ApplyZoneFile writes a plan: new rows owned by autoglue and changed rows,
+// all pending so dns_reconcile writes them to the provider. It returns the
+// rows it created and changed.
+func ApplyZoneFile(db *gorm.DB, d *models.Domain, plan ZoneFilePlan) ([]models.RecordSet, error) {
+	zoneID := strings.TrimSpace(d.ZoneID)
+	apex := recordFQDN("@", d.DomainName)
+	var out []models.RecordSet
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, rec := range plan.Create {
+			rel, _ := relativeName(rec.Name, apex)
+			row := models.RecordSet{DomainID: d.ID, Name: rel, Type: rec.Type, Status: "pending", Owner: "autoglue"}
+			if err := setZoneFileValues(&row, zoneID, rec); err != nil {
+				return err
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			out = append(out, row)
+		}
+		for _, u := range plan.Update {
+			row := u.Row
+			if err := setZoneFileValues(&row, zoneID, u.Record); err != nil {
+				return err
+			}
+			row.Alias = nil
+			row.Status = "pending"
+			row.LastError = ""
+			if err := tx.Save(&row).Error; err != nil {
+				return err
+			}
+			out = append(out, row)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// setZoneFileValues stores a zone file record set on its row, in the same
+// canonical form the record set API stores values in. Values that do not
+// validate are never stored.
+func setZoneFileValues(row *models.RecordSet, zoneID string, rec dns.Record) error {
+	values, err := dns.NormalizeValues(rec.Type, rec.Values)
+	if err != nil {
+		return err
+	}
+	ttl := int(rec.TTL)
+	vals, _ := json.Marshal(values)
+	row.TTL = &ttl
+	row.Values = datatypes.JSON(vals)
+	row.Fingerprint = dns.Fingerprint(zoneID, strings.TrimSuffix(rec.Name, "."), rec.Type, &ttl, values, nil)
+	return nil
+}
@@ -0,0 +1,316 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// execSlotPoll is how long a host job waits before trying again when its run
+// is already at its concurrency limit.
+const execSlotPoll = 2 * time.Second
+
+// execSlotGrace is added to a run's per-host timeout when deciding a
+// "running" host is actually dead. A worker that crashed mid-command never
+// releases its slot, and without this the run would stall one slot short
+// forever.
+const execSlotGrace = 2 * time.Minute
+
+// ExecHostArgs runs one ExecRun's command on one host. The handler inserts one
+// of these per server; the run's concurrency limit is enforced by the jobs
+// themselves, since River has no per-group limit to lean on.
+type ExecHostArgs struct {
+	OrgID          uuid.UUID `json:"org_id"`
+	RunID          uuid.UUID `json:"run_id"`
+	HostID         uuid.UUID `json:"host_id"`
+	TimeoutSeconds int       `json:"timeout_seconds"`
+}
+
+func (ExecHostArgs) Kind() string { return "exec_host" }
+
+func (ExecHostArgs) InsertOpts() river.InsertOpts {
+	// Not retried: an ad-hoc command is not known to be idempotent.
+	return river.InsertOpts{Queue: QueueClusters, MaxAttempts: 1}
+}
+
+type ExecHostResult struct {
+	Status   string `json:"status"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ExecHostWorker struct {
+	river.WorkerDefaults[ExecHostArgs]
+	db *gorm.DB
+}
+
+// Timeout leaves room for the dial, which is not part of the command's budget.
+func (w *ExecHostWorker) Timeout(j *river.Job[ExecHostArgs]) time.Duration {
+	return execHostDeadline(j.Args.TimeoutSeconds)
+}
+
+func execHostDeadline(timeoutSeconds int) time.Duration {
+	return time.Duration(timeoutSeconds)*time.Second + sshDialTimeout*2 + time.Minute
+}
+
+func (w *ExecHostWorker) Work(ctx context.Context, j *river.Job[ExecHostArgs]) error {
+	db := w.db
+	args := j.Args
+
+	var host models.ExecRunHost
+	if err := db.Where("id = ? AND exec_run_id = ?", args.HostID, args.RunID).First(&host).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if host.Status != models.ExecHostStatusQueued {
+		return nil
+	}
+
+	run, claimed, err := claimExecSlot(db, args.RunID, host.ID, j.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return river.JobSnooze(execSlotPoll)
+	}
+
+	res := runExecHost(ctx, db, j.ID, run, &host)
+	finishExecHost(db, host.ID, res)
+	finishExecRunIfDone(db, run.ID)
+
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[exec] could not record output")
+	}
+	return nil
+}
+
+// claimExecSlot moves host to running if its run has a free slot. The run row
+// is locked for the count, so two workers cannot both take the last slot.
+func claimExecSlot(db *gorm.DB, runID, hostID uuid.UUID, jobID int64) (*models.ExecRun, bool, error) {
+	var run models.ExecRun
+	claimed := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", runID).First(&run).Error; err != nil {
+			return err
+		}
+
+		stale := time.Now().Add(-(time.Duration(run.TimeoutSeconds)*time.Second + execSlotGrace))
+		var running int64
+		if err := tx.Model(&models.ExecRunHost{}).
+			Where("exec_run_id = ? AND status = ? AND started_at > ?", runID, models.ExecHostStatusRunning, stale).
+			Count(&running).Error; err != nil {
+			return err
+		}
+		if int(running) >= run.Concurrency {
+			return nil
+		}
+
+		now := time.Now()
+		upd := tx.Model(&models.ExecRunHost{}).
+			Where("id = ? AND status = ?", hostID, models.ExecHostStatusQueued).
+			Updates(map[string]any{"status": models.ExecHostStatusRunning, "started_at": now, "job_id": jobID})
+		if upd.Error != nil {
+			return upd.Error
+		}
+		claimed = upd.RowsAffected == 1
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &run, claimed, nil
+}
+
+// runExecHost runs the command on one server, streaming its output to the
+// host's log. Only a command that ran to completion has an exit code.
+func runExecHost(ctx context.Context, db *gorm.DB, jobID int64, run *models.ExecRun, host *models.ExecRunHost) ExecHostResult {
+	sink := NewLogSink(db, jobID, run.OrganizationID, models.JobLogSubjectExecHost, host.ID)
+	defer func() { _ = sink.Close() }()
+
+	fail := func(step string, err error) ExecHostResult {
+		sink.System(step + " failed: " + err.Error())
+		return ExecHostResult{Status: models.ExecHostStatusFailed, Error: step + ": " + err.Error()}
+	}
+
+	var s models.Server
+	if err := db.Preload("SshKey").
+		Where("id = ? AND organization_id = ?", host.ServerID, run.OrganizationID).
+		First(&s).Error; err != nil {
+		return fail("load_server", err)
+	}
+
+	signer, err := signerForKey(db, &s.SshKey)
+	if err != nil {
+		return fail("load_key", err)
+	}
+
+	c, err := dialServerSSH(ctx, db, &s, signer)
+	if err != nil {
+		return fail("connect", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	sess, err := c.NewSession()
+	if err != nil {
+		return fail("session", err)
+	}
+	defer func() { _ = sess.Close() }()
+
+	sink.System(fmt.Sprintf("$ %s   # on %s via %s", run.Command, s.Hostname, c.Via()))
+
+	timeout := time.Duration(run.TimeoutSeconds) * time.Second
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	// Closing the session is the only way to interrupt a running command;
+	// the remote side sees the channel go away and gets SIGHUP.
+	stop := context.AfterFunc(cmdCtx, func() { _ = sess.Close() })
+	defer stop()
+
+	err = runSSHStreaming(sess, run.Command, sink)
+
+	if cmdCtx.Err() != nil && ctx.Err() == nil {
+		return fail("run", fmt.Errorf("timed out after %s", timeout))
+	}
+	code, err := execExitCode(err)
+	if err != nil {
+		return fail("run", err)
+	}
+
+	sink.System(fmt.Sprintf("exit %d", code))
+	status := models.ExecHostStatusSucceeded
+	if code != 0 {
+		status = models.ExecHostStatusFailed
+	}
+	return ExecHostResult{Status: status, ExitCode: &code}
+}
+
+// execExitCode separates "the command ran and exited non-zero", which is a
+// result, from "the command did not run to completion", which is an error.
+func execExitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	var ee *ssh.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitStatus(), nil
+	}
+	return 0, err
+}
+
+func finishExecHost(db *gorm.DB, hostID uuid.UUID, res ExecHostResult) {
+	if err := db.Model(&models.ExecRunHost{}).Where("id = ?", hostID).
+		Updates(map[string]any{
+			"status":      res.Status,
+			"exit_code":   res.ExitCode,
+			"error":       res.Error,
+			"finished_at": time.Now(),
+		}).Error; err != nil {
+		log.Error().Err(err).Str("host_id", hostID.String()).Msg("[exec] could not record host result")
+	}
+}
+
+// failStaleExecHosts fails hosts still shown running well past their job's
+// deadline: the worker died (a restart, or River gave up on the job) without
+// recording a result, and host jobs are not retried, so nothing else would.
+// It returns the runs it touched.
+func failStaleExecHosts(db *gorm.DB) ([]uuid.UUID, error) {
+	slack := (execHostDeadline(0) + execSlotGrace).Seconds()
+	var rows []struct{ ExecRunID uuid.UUID }
+	err := db.Raw(`
+UPDATE exec_run_hosts h SET
+  status = ?,
+  error = ?,
+  finished_at = now()
+FROM exec_runs r
+WHERE h.exec_run_id = r.id
+  AND h.status = ?
+  AND h.started_at < now() - make_interval(secs => r.timeout_seconds + ?)
+RETURNING h.exec_run_id`,
+		models.ExecHostStatusFailed, "worker lost: no result was recorded before the job's deadline",
+		models.ExecHostStatusRunning, slack,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	seen := map[uuid.UUID]bool{}
+	var out []uuid.UUID
+	for _, r := range rows {
+		if !seen[r.ExecRunID] {
+			seen[r.ExecRunID] = true
+			out = append(out, r.ExecRunID)
+		}
+	}
+	return out, nil
+}
+
+// finishExecRunIfDone closes the run once no host is still queued or running.
+// A single guarded UPDATE, so whichever host finishes last closes it exactly
+// once.
+func finishExecRunIfDone(db *gorm.DB, runID uuid.UUID) {
+	if err := db.Exec(`
+UPDATE exec_runs SET
+  status = CASE WHEN EXISTS (
+             SELECT 1 FROM exec_run_hosts WHERE exec_run_id = ? AND status = ?
+           ) THEN ? ELSE ? END,
+  finished_at = now(),
+  updated_at = now()
+WHERE id = ? AND status = ?
+  AND NOT EXISTS (
+    SELECT 1 FROM exec_run_hosts WHERE exec_run_id = ? AND status IN (?, ?)
+  )`,
+		runID, models.ExecHostStatusFailed,
+		models.ExecRunStatusFailed, models.ExecRunStatusSucceeded,
+		runID, models.ExecRunStatusRunning,
+		runID, models.ExecHostStatusQueued, models.ExecHostStatusRunning,
+	).Error; err != nil {
+		log.Error().Err(err).Str("run_id", runID.String()).Msg("[exec] could not close run")
+	}
+}
+
+// ExecSweepArgs fails exec hosts whose worker died and closes the runs they
+// were holding open.
+type ExecSweepArgs struct{}
+
+func (ExecSweepArgs) Kind() string { return "exec_sweep" }
+
+func (ExecSweepArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueDefault, MaxAttempts: 1}
+}
+
+type ExecSweepResult struct {
+	Status string      `json:"status"`
+	Runs   []uuid.UUID `json:"runs"`
+}
+
+type ExecSweepWorker struct {
+	river.WorkerDefaults[ExecSweepArgs]
+	db *gorm.DB
+}
+
+func (w *ExecSweepWorker) Work(ctx context.Context, j *river.Job[ExecSweepArgs]) error {
+	runs, err := failStaleExecHosts(w.db)
+	if err != nil {
+		return fmt.Errorf("fail stale hosts: %w", err)
+	}
+	for _, id := range runs {
+		log.Warn().Str("run_id", id.String()).Msg("[exec] failed hosts whose worker was lost")
+		finishExecRunIfDone(w.db, id)
+	}
+	if err := river.RecordOutput(ctx, ExecSweepResult{Status: "ok", Runs: runs}); err != nil {
+		log.Warn().Err(err).Msg("[exec] could not record sweep output")
+	}
+	return nil
+}
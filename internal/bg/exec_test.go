@@ -0,0 +1,125 @@
+package bg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/testutil/pgtest"
+	"github.com/google/uuid"
+)
+
+func TestExecExitCodeSeparatesResultsFromErrors(t *testing.T) {
+	if code, err := execExitCode(nil); code != 0 || err != nil {
+		t.Errorf("nil: got %d, %v", code, err)
+	}
+	boom := errors.New("connection reset")
+	if _, err := execExitCode(boom); !errors.Is(err, boom) {
+		t.Errorf("transport error was not passed through: %v", err)
+	}
+}
+
+// The concurrency limit is only as good as the slot claim: with a limit of
+// one, a second host must wait until the first has finished.
+func TestClaimExecSlotHonoursConcurrency(t *testing.T) {
+	db := pgtest.DB(t)
+
+	org := models.Organization{Name: "exec-slot-org"}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	run := models.ExecRun{
+		OrganizationID: org.ID,
+		Command:        "true",
+		Concurrency:    1,
+		TimeoutSeconds: 60,
+		Status:         models.ExecRunStatusRunning,
+		Hosts: []models.ExecRunHost{
+			{ServerID: uuid.New(), Status: models.ExecHostStatusQueued},
+			{ServerID: uuid.New(), Status: models.ExecHostStatusQueued},
+		},
+	}
+	if err := db.Create(&run).Error; err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	a, b := run.Hosts[0].ID, run.Hosts[1].ID
+
+	if _, ok, err := claimExecSlot(db, run.ID, a, 1); err != nil || !ok {
+		t.Fatalf("first claim: ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := claimExecSlot(db, run.ID, b, 2); err != nil || ok {
+		t.Fatalf("second claim at limit: ok=%v err=%v, want refused", ok, err)
+	}
+	// A host can only be claimed once.
+	if _, ok, _ := claimExecSlot(db, run.ID, a, 3); ok {
+		t.Fatal("running host claimed again")
+	}
+
+	code := 0
+	finishExecHost(db, a, ExecHostResult{Status: models.ExecHostStatusSucceeded, ExitCode: &code})
+	finishExecRunIfDone(db, run.ID)
+	assertExecRunStatus(t, run.ID, models.ExecRunStatusRunning)
+
+	if _, ok, err := claimExecSlot(db, run.ID, b, 2); err != nil || !ok {
+		t.Fatalf("claim after slot freed: ok=%v err=%v", ok, err)
+	}
+	code = 3
+	finishExecHost(db, b, ExecHostResult{Status: models.ExecHostStatusFailed, ExitCode: &code})
+	finishExecRunIfDone(db, run.ID)
+	assertExecRunStatus(t, run.ID, models.ExecRunStatusFailed)
+}
+
+func assertExecRunStatus(t *testing.T, id uuid.UUID, want string) {
+	t.Helper()
+	var got models.ExecRun
+	if err := pgtest.DB(t).Where("id = ?", id).First(&got).Error; err != nil {
+		t.Fatalf("load run: %v", err)
+	}
+	if got.Status != want {
+		t.Errorf("run status = %q, want %q", got.Status, want)
+	}
+	if want != models.ExecRunStatusRunning && got.FinishedAt == nil {
+		t.Error("finished run has no finished_at")
+	}
+}
+
+func TestFailStaleExecHostsClosesTheRun(t *testing.T) {
+	db := pgtest.DB(t)
+
+	org := models.Organization{Name: "exec-stale-org"}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	lost := time.Now().Add(-time.Hour)
+	recent := time.Now()
+	run := models.ExecRun{
+		OrganizationID: org.ID,
+		Command:        "sleep 1",
+		Concurrency:    2,
+		TimeoutSeconds: 60,
+		Status:         models.ExecRunStatusRunning,
+		Hosts: []models.ExecRunHost{
+			{ServerID: uuid.New(), Status: models.ExecHostStatusRunning, StartedAt: &lost},
+			{ServerID: uuid.New(), Status: models.ExecHostStatusRunning, StartedAt: &recent},
+		},
+	}
+	if err := db.Create(&run).Error; err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+
+	runs, err := failStaleExecHosts(db)
+	if err != nil || len(runs) != 1 || runs[0] != run.ID {
+		t.Fatalf("failStaleExecHosts = %v, %v", runs, err)
+	}
+	var hosts []models.ExecRunHost
+	db.Where("exec_run_id = ?", run.ID).Order("started_at").Find(&hosts)
+	if hosts[0].Status != models.ExecHostStatusFailed || hosts[1].Status != models.ExecHostStatusRunning {
+		t.Fatalf("statuses = %s, %s; only the lost host should fail", hosts[0].Status, hosts[1].Status)
+	}
+
+	code := 0
+	finishExecHost(db, hosts[1].ID, ExecHostResult{Status: models.ExecHostStatusSucceeded, ExitCode: &code})
+	finishExecRunIfDone(db, run.ID)
+	assertExecRunStatus(t, run.ID, models.ExecRunStatusFailed)
+}
@@ -0,0 +1,133 @@
+package bg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"gorm.io/gorm"
+)
+
+// nodeNamePattern is a DNS-1123 subdomain, which every Kubernetes node name
+// is. Matching it is also what makes a name safe to put on a shell line.
+var nodeNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+
+var errNoKubeconfig = errors.New("cluster has no kubeconfig yet")
+
+// nodeName is the Kubernetes node a server registers as: its hostname, which
+// kubeadm lowercases.
+func nodeName(s *models.Server) (string, error) {
+	n := strings.ToLower(strings.TrimSpace(s.Hostname))
+	if !nodeNamePattern.MatchString(n) {
+		return "", fmt.Errorf("server %s has no usable node name (hostname %q)", s.ID, s.Hostname)
+	}
+	return n, nil
+}
+
+// kubectlOnBastion runs kubectl with args on the cluster's bastion, against
+// the cluster's stored kubeconfig, and returns the tail of its output.
+func kubectlOnBastion(ctx context.Context, db *gorm.DB, c *models.Cluster, args string, w io.Writer) (string, error) {
+	tail := &tailBuffer{max: logMaxTailBytes}
+	var out io.Writer = tail
+	if w != nil {
+		out = io.MultiWriter(tail, w)
+	}
+	if err := runKubectl(ctx, db, c, args, out, out); err != nil {
+		return tail.String(), wrapSSHError(err, tail.String())
+	}
+	return tail.String(), nil
+}
+
+// kubectlJSON runs a kubectl read with -o json and decodes its stdout into
+// out. Stderr is kept apart so warnings cannot corrupt the document.
+func kubectlJSON(ctx context.Context, db *gorm.DB, c *models.Cluster, args string, out any) error {
+	var stdout bytes.Buffer
+	stderr := &tailBuffer{max: logMaxTailBytes}
+	if err := runKubectl(ctx, db, c, args+" -o json", &stdout, stderr); err != nil {
+		return wrapSSHError(err, stderr.String())
+	}
+	if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+		return fmt.Errorf("decode kubectl output: %w", err)
+	}
+	return nil
+}
+
+// runKubectl is the transport under both. The kubeconfig goes over the
+// session's stdin into a mode 0600 temp file that is removed on exit, so it
+// never lands on the bastion's disk for longer than the command, and never on
+// a command line. args must already be shell-safe; see shellQuote.
+func runKubectl(ctx context.Context, db *gorm.DB, c *models.Cluster, args string, stdout, stderr io.Writer) error {
+	if c.BastionServer == nil {
+		return errors.New("cluster has no bastion")
+	}
+	if c.EncryptedKubeconfig == "" || c.KubeIV == "" || c.KubeTag == "" {
+		return errNoKubeconfig
+	}
+	kubeconfig, err := utils.DecryptForOrg(c.OrganizationID, c.EncryptedKubeconfig, c.KubeIV, c.KubeTag, db)
+	if err != nil {
+		return fmt.Errorf("decrypt kubeconfig: %w", err)
+	}
+
+	signer, err := signerForKey(db, &c.BastionServer.SshKey)
+	if err != nil {
+		return err
+	}
+	client, err := dialServerSSH(ctx, db, c.BastionServer, signer)
+	if err != nil {
+		return fmt.Errorf("bastion: %w", err)
+	}
+	defer client.Close()
+
+	sess, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	defer sess.Close()
+	stop := context.AfterFunc(ctx, func() { _ = sess.Close() })
+	defer stop()
+
+	sess.Stdin = strings.NewReader(kubeconfig)
+	sess.Stdout = stdout
+	sess.Stderr = stderr
+	return sess.Run(`kc=$(mktemp) && trap 'rm -f "$kc"' EXIT && cat > "$kc" && kubectl --kubeconfig "$kc" ` + args)
+}
+
+// shellQuote makes s a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// drainNode cordons node and evicts its pods, waiting at most timeout for the
+// evictions. DaemonSet pods stay, as they would be recreated at once anyway.
+func drainNode(ctx context.Context, db *gorm.DB, c *models.Cluster, node string, timeout time.Duration, w io.Writer) error {
+	if !nodeNamePattern.MatchString(node) {
+		return fmt.Errorf("invalid node name %q", node)
+	}
+	if _, err := kubectlOnBastion(ctx, db, c, "cordon "+node, w); err != nil {
+		return fmt.Errorf("cordon %s: %w", node, err)
+	}
+	args := fmt.Sprintf("drain %s --ignore-daemonsets --delete-emptydir-data --timeout=%ds", node, int(timeout.Seconds()))
+	if _, err := kubectlOnBastion(ctx, db, c, args, w); err != nil {
+		return fmt.Errorf("drain %s: %w", node, err)
+	}
+	return nil
+}
+
+// deleteNode removes the node object, tolerating one that is already gone.
+func deleteNode(ctx context.Context, db *gorm.DB, c *models.Cluster, node string, w io.Writer) error {
+	if !nodeNamePattern.MatchString(node) {
+		return fmt.Errorf("invalid node name %q", node)
+	}
+	if _, err := kubectlOnBastion(ctx, db, c, "delete node "+node+" --ignore-not-found", w); err != nil {
+		return fmt.Errorf("delete node %s: %w", node, err)
+	}
+	return nil
+}
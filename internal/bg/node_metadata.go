@@ -0,0 +1,492 @@
+package bg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// managedMetadataAnnotation records on each node which labels, annotations
+// and taints autoglue put there, so that detaching one from a pool removes it
+// from the node without touching anything kubeadm or an operator set.
+const managedMetadataAnnotation = "autoglue.glueops.dev/managed-metadata"
+
+// NodeMetadataSweepArgs re-checks every ready cluster, which is what turns up
+// drift made by hand on the cluster side.
+type NodeMetadataSweepArgs struct{}
+
+func (NodeMetadataSweepArgs) Kind() string { return "node_metadata_sweep" }
+
+func (NodeMetadataSweepArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueMaintenance, MaxAttempts: 2}
+}
+
+// NodeMetadataArgs brings the labels, annotations and taints of one cluster's
+// nodes in line with their node pools.
+type NodeMetadataArgs struct {
+	ClusterID uuid.UUID `json:"cluster_id"`
+}
+
+func (NodeMetadataArgs) Kind() string { return "node_metadata" }
+
+func (NodeMetadataArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       QueueClusters,
+		MaxAttempts: 3,
+		// Only waiting jobs count: a change made while a run is in flight may
+		// have been read before it, so it needs a run of its own.
+		UniqueOpts: river.UniqueOpts{
+			ByArgs: true,
+			ByState: []rivertype.JobState{
+				rivertype.JobStateAvailable, rivertype.JobStateScheduled,
+				rivertype.JobStateRetryable, rivertype.JobStatePending,
+			},
+		},
+	}
+}
+
+type NodeMetadataResult struct {
+	Status    string    `json:"status"`
+	ClusterID uuid.UUID `json:"cluster_id"`
+	Nodes     int       `json:"nodes"`
+	Drifted   int       `json:"drifted"`
+	Failed    int       `json:"failed"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// QueueNodeMetadataSync queues a node_metadata run for every cluster that
+// one of the pools belongs to.
+func QueueNodeMetadataSync(ctx context.Context, db *gorm.DB, client *Client, poolIDs ...uuid.UUID) error {
+	if len(poolIDs) == 0 {
+		return nil
+	}
+	var clusterIDs []uuid.UUID
+	if err := db.Table("cluster_node_pools").
+		Where("node_pool_id IN ?", poolIDs).
+		Distinct().
+		Pluck("cluster_id", &clusterIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range clusterIDs {
+		if _, err := client.Insert(ctx, NodeMetadataArgs{ClusterID: id}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type nodeTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// id is how kubectl tells taints apart: a key can carry one taint per effect.
+func (t nodeTaint) id() string { return t.Key + ":" + t.Effect }
+
+func (t nodeTaint) String() string {
+	if t.Value == "" {
+		return t.id()
+	}
+	return t.Key + "=" + t.Value + ":" + t.Effect
+}
+
+// nodeMeta is the autoglue-owned part of a node: taints are keyed by id.
+type nodeMeta struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	Taints      map[string]nodeTaint
+}
+
+// managedMeta is the value of managedMetadataAnnotation.
+type managedMeta struct {
+	Labels      []string `json:"labels"`
+	Annotations []string `json:"annotations"`
+	Taints      []string `json:"taints"`
+}
+
+// desiredNodeMeta merges the metadata of all of a server's node pools. Where
+// two pools set the same key differently, the pool whose name sorts last wins,
+// which is arbitrary but at least stable from run to run.
+func desiredNodeMeta(pools []models.NodePool) nodeMeta {
+	sorted := append([]models.NodePool(nil), pools...)
+	sort.SliceStable(sorted, func(i, k int) bool { return sorted[i].Name < sorted[k].Name })
+
+	m := nodeMeta{Labels: map[string]string{}, Annotations: map[string]string{}, Taints: map[string]nodeTaint{}}
+	for _, p := range sorted {
+		for _, l := range p.Labels {
+			m.Labels[l.Key] = l.Value
+		}
+		for _, a := range p.Annotations {
+			m.Annotations[a.Key] = a.Value
+		}
+		for _, t := range p.Taints {
+			nt := nodeTaint{Key: t.Key, Value: t.Value, Effect: t.Effect}
+			m.Taints[nt.id()] = nt
+		}
+	}
+	return m
+}
+
+// kubeNode is the part of a kubectl node document the reconciler reads.
+type kubeNode struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Taints        []nodeTaint `json:"taints"`
+		Unschedulable bool        `json:"unschedulable"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+		NodeInfo struct {
+			BootID string `json:"bootID"`
+		} `json:"nodeInfo"`
+	} `json:"status"`
+}
+
+// ready reports whether the kubelet says the node is Ready.
+func (n kubeNode) ready() bool {
+	for _, c := range n.Status.Conditions {
+		if c.Type == "Ready" {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+type kubeNodeList struct {
+	Items []kubeNode `json:"items"`
+}
+
+// nodeMetaChange is what has to happen to one node. Drift describes it for
+// people; the rest is what gets applied.
+type nodeMetaChange struct {
+	Drift             []string
+	SetLabels         map[string]string
+	RemoveLabels      []string
+	SetAnnotations    map[string]string
+	RemoveAnnotations []string
+	SetTaints         []nodeTaint
+	RemoveTaints      []string
+}
+
+func (c nodeMetaChange) empty() bool {
+	return len(c.SetLabels) == 0 && len(c.RemoveLabels) == 0 &&
+		len(c.SetAnnotations) == 0 && len(c.RemoveAnnotations) == 0 &&
+		len(c.SetTaints) == 0 && len(c.RemoveTaints) == 0
+}
+
+// diffNodeMeta compares a live node with what its pools want. Only keys
+// autoglue manages, going by the node's managed annotation, are ever removed.
+// The managed annotation itself is rewritten whenever the set of keys
+// changes, without that counting as drift.
+func diffNodeMeta(want nodeMeta, node kubeNode) nodeMetaChange {
+	ch := nodeMetaChange{SetLabels: map[string]string{}, SetAnnotations: map[string]string{}}
+
+	var managed managedMeta
+	if raw := node.Metadata.Annotations[managedMetadataAnnotation]; raw != "" {
+		_ = json.Unmarshal([]byte(raw), &managed)
+	}
+
+	for _, k := range sortedKeys(want.Labels) {
+		v := want.Labels[k]
+		if have, ok := node.Metadata.Labels[k]; !ok || have != v {
+			ch.SetLabels[k] = v
+			ch.Drift = append(ch.Drift, describeDrift("label", k, v, have, ok))
+		}
+	}
+	for _, k := range managed.Labels {
+		if _, keep := want.Labels[k]; keep {
+			continue
+		}
+		if have, ok := node.Metadata.Labels[k]; ok {
+			ch.RemoveLabels = append(ch.RemoveLabels, k)
+			ch.Drift = append(ch.Drift, fmt.Sprintf("label %s=%s is no longer in any node pool", k, have))
+		}
+	}
+
+	for _, k := range sortedKeys(want.Annotations) {
+		v := want.Annotations[k]
+		if have, ok := node.Metadata.Annotations[k]; !ok || have != v {
+			ch.SetAnnotations[k] = v
+			ch.Drift = append(ch.Drift, describeDrift("annotation", k, v, have, ok))
+		}
+	}
+	for _, k := range managed.Annotations {
+		if _, keep := want.Annotations[k]; keep {
+			continue
+		}
+		if _, ok := node.Metadata.Annotations[k]; ok {
+			ch.RemoveAnnotations = append(ch.RemoveAnnotations, k)
+			ch.Drift = append(ch.Drift, fmt.Sprintf("annotation %s is no longer in any node pool", k))
+		}
+	}
+
+	live := map[string]nodeTaint{}
+	for _, t := range node.Spec.Taints {
+		live[t.id()] = t
+	}
+	for _, id := range sortedKeys(want.Taints) {
+		t := want.Taints[id]
+		have, ok := live[id]
+		if !ok || have.Value != t.Value {
+			ch.SetTaints = append(ch.SetTaints, t)
+			if ok {
+				ch.Drift = append(ch.Drift, fmt.Sprintf("taint %s: want %s, have %s", id, t, have))
+			} else {
+				ch.Drift = append(ch.Drift, fmt.Sprintf("taint %s missing", t))
+			}
+		}
+	}
+	for _, id := range managed.Taints {
+		if _, keep := want.Taints[id]; keep {
+			continue
+		}
+		if _, ok := live[id]; ok {
+			ch.RemoveTaints = append(ch.RemoveTaints, id)
+			ch.Drift = append(ch.Drift, fmt.Sprintf("taint %s is no longer in any node pool", id))
+		}
+	}
+
+	record := managedMeta{
+		Labels:      sortedKeys(want.Labels),
+		Annotations: sortedKeys(want.Annotations),
+		Taints:      sortedKeys(want.Taints),
+	}
+	if b, _ := json.Marshal(record); string(b) != node.Metadata.Annotations[managedMetadataAnnotation] {
+		ch.SetAnnotations[managedMetadataAnnotation] = string(b)
+	}
+	return ch
+}
+
+func describeDrift(kind, key, want, have string, present bool) string {
+	if !present {
+		return fmt.Sprintf("%s %s missing (want %q)", kind, key, want)
+	}
+	return fmt.Sprintf("%s %s: want %q, have %q", kind, key, want, have)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// kubectlCommands turns a change into at most three kubectl invocations, one
+// per kind, each with --overwrite so a changed value replaces the old one.
+func (c nodeMetaChange) kubectlCommands(node string) []string {
+	var cmds []string
+	word := func(s string) string { return " " + shellQuote(s) }
+
+	if len(c.SetLabels) > 0 || len(c.RemoveLabels) > 0 {
+		cmd := "label node " + shellQuote(node) + " --overwrite"
+		for _, k := range sortedKeys(c.SetLabels) {
+			cmd += word(k + "=" + c.SetLabels[k])
+		}
+		for _, k := range c.RemoveLabels {
+			cmd += word(k + "-")
+		}
+		cmds = append(cmds, cmd)
+	}
+	if len(c.SetAnnotations) > 0 || len(c.RemoveAnnotations) > 0 {
+		cmd := "annotate node " + shellQuote(node) + " --overwrite"
+		for _, k := range sortedKeys(c.SetAnnotations) {
+			cmd += word(k + "=" + c.SetAnnotations[k])
+		}
+		for _, k := range c.RemoveAnnotations {
+			cmd += word(k + "-")
+		}
+		cmds = append(cmds, cmd)
+	}
+	if len(c.SetTaints) > 0 || len(c.RemoveTaints) > 0 {
+		cmd := "taint node " + shellQuote(node) + " --overwrite"
+		for _, t := range c.SetTaints {
+			cmd += word(t.String())
+		}
+		for _, id := range c.RemoveTaints {
+			cmd += word(id + "-")
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+type NodeMetadataSweepWorker struct {
+	river.WorkerDefaults[NodeMetadataSweepArgs]
+	db *gorm.DB
+}
+
+func (w *NodeMetadataSweepWorker) Timeout(*river.Job[NodeMetadataSweepArgs]) time.Duration {
+	return time.Minute
+}
+
+func (w *NodeMetadataSweepWorker) Work(ctx context.Context, j *river.Job[NodeMetadataSweepArgs]) error {
+	var ids []uuid.UUID
+	if err := w.db.Model(&models.Cluster{}).
+		Where("status = ? AND encrypted_kubeconfig <> ''", models.ClusterStatusReady).
+		Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("list clusters: %w", err)
+	}
+	client := river.ClientFromContext[pgx.Tx](ctx)
+	for _, id := range ids {
+		if _, err := client.Insert(ctx, NodeMetadataArgs{ClusterID: id}, nil); err != nil {
+			log.Error().Err(err).Str("cluster_id", id.String()).Msg("[node-metadata] could not dispatch")
+		}
+	}
+	return nil
+}
+
+type NodeMetadataWorker struct {
+	river.WorkerDefaults[NodeMetadataArgs]
+	db *gorm.DB
+}
+
+func (w *NodeMetadataWorker) Timeout(*river.Job[NodeMetadataArgs]) time.Duration {
+	return 10 * time.Minute
+}
+
+func (w *NodeMetadataWorker) Work(ctx context.Context, j *river.Job[NodeMetadataArgs]) error {
+	db := w.db
+	res := NodeMetadataResult{Status: "ok", ClusterID: j.Args.ClusterID}
+
+	var c models.Cluster
+	if err := db.Preload("BastionServer.SshKey").Where("id = ?", j.Args.ClusterID).First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	// Before the cluster is up there is nothing to push to; the bootstrap
+	// applies the pools' metadata itself.
+	if c.Status != models.ClusterStatusReady || c.EncryptedKubeconfig == "" || c.BastionServer == nil {
+		res.Status = "skipped"
+		recordNodeMetadataOutput(ctx, res)
+		return nil
+	}
+
+	var servers []models.Server
+	if err := db.Preload("NodePools.Labels").
+		Preload("NodePools.Annotations").
+		Preload("NodePools.Taints").
+		Where("role <> ?", "bastion").
+		Where("id IN (?)", db.Table("node_servers ns").
+			Select("ns.server_id").
+			Joins("JOIN cluster_node_pools cnp ON cnp.node_pool_id = ns.node_pool_id").
+			Where("cnp.cluster_id = ?", c.ID)).
+		Order("hostname").
+		Find(&servers).Error; err != nil {
+		return fmt.Errorf("load servers: %w", err)
+	}
+
+	var nodes kubeNodeList
+	if err := kubectlJSON(ctx, db, &c, "get nodes", &nodes); err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	live := make(map[string]kubeNode, len(nodes.Items))
+	for _, n := range nodes.Items {
+		live[n.Metadata.Name] = n
+	}
+
+	keep := make([]uuid.UUID, 0, len(servers))
+	for i := range servers {
+		s := &servers[i]
+		keep = append(keep, s.ID)
+		res.Nodes++
+
+		st := models.NodeMetadataStatus{
+			ClusterID:      c.ID,
+			ServerID:       s.ID,
+			OrganizationID: c.OrganizationID,
+			CheckedAt:      time.Now(),
+		}
+		var drift []string
+
+		name, err := nodeName(s)
+		if err == nil {
+			st.NodeName = name
+			node, ok := live[name]
+			if !ok {
+				err = errors.New("node is not registered in the cluster")
+			} else {
+				ch := diffNodeMeta(desiredNodeMeta(s.NodePools), node)
+				drift = ch.Drift
+				for _, cmd := range ch.kubectlCommands(name) {
+					if _, err = kubectlOnBastion(ctx, db, &c, cmd, nil); err != nil {
+						break
+					}
+				}
+				if err == nil && !ch.empty() && len(drift) > 0 {
+					now := time.Now()
+					st.AppliedAt = &now
+				}
+			}
+		}
+
+		if err != nil {
+			st.LastError = truncateErr(err.Error())
+			res.Failed++
+		} else {
+			st.InSync = true
+		}
+		if len(drift) > 0 {
+			res.Drifted++
+		}
+		if drift == nil {
+			drift = []string{}
+		}
+		st.Drift, _ = json.Marshal(drift)
+
+		assign := clause.AssignmentColumns([]string{"node_name", "in_sync", "drift", "last_error", "checked_at"})
+		if st.AppliedAt != nil {
+			assign = clause.AssignmentColumns([]string{"node_name", "in_sync", "drift", "last_error", "checked_at", "applied_at"})
+		}
+		if err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "cluster_id"}, {Name: "server_id"}},
+			DoUpdates: assign,
+		}).Create(&st).Error; err != nil {
+			return fmt.Errorf("record status: %w", err)
+		}
+	}
+
+	// Servers that left the cluster's pools have nothing to report any more.
+	q := db.Where("cluster_id = ?", c.ID)
+	if len(keep) > 0 {
+		q = q.Where("server_id NOT IN ?", keep)
+	}
+	if err := q.Delete(&models.NodeMetadataStatus{}).Error; err != nil {
+		return err
+	}
+
+	if res.Failed > 0 {
+		res.Status = "partial"
+	}
+	recordNodeMetadataOutput(ctx, res)
+	return nil
+}
+
+func recordNodeMetadataOutput(ctx context.Context, res NodeMetadataResult) {
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[node-metadata] could not record output")
+	}
+}
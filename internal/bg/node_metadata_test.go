@@ -0,0 +1,96 @@
+package bg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/glueops/autoglue/internal/models"
+)
+
+func liveNode(labels, annotations map[string]string, taints ...nodeTaint) kubeNode {
+	var n kubeNode
+	n.Metadata.Name = "node-a"
+	n.Metadata.Labels = labels
+	n.Metadata.Annotations = annotations
+	n.Spec.Taints = taints
+	return n
+}
+
+func TestDesiredNodeMetaLastPoolByNameWins(t *testing.T) {
+	pools := []models.NodePool{
+		{Name: "zeta", Labels: []models.Label{{Key: "tier", Value: "z"}}},
+		{Name: "alpha", Labels: []models.Label{{Key: "tier", Value: "a"}, {Key: "gpu", Value: "yes"}},
+			Taints: []models.Taint{{Key: "gpu", Effect: "NoSchedule"}}},
+	}
+	m := desiredNodeMeta(pools)
+	if m.Labels["tier"] != "z" || m.Labels["gpu"] != "yes" {
+		t.Fatalf("labels = %v", m.Labels)
+	}
+	if _, ok := m.Taints["gpu:NoSchedule"]; !ok {
+		t.Fatalf("taints = %v", m.Taints)
+	}
+}
+
+func TestDiffNodeMetaInSync(t *testing.T) {
+	want := nodeMeta{
+		Labels:      map[string]string{"tier": "web"},
+		Annotations: map[string]string{},
+		Taints:      map[string]nodeTaint{"gpu:NoSchedule": {Key: "gpu", Effect: "NoSchedule"}},
+	}
+	node := liveNode(
+		map[string]string{"tier": "web", "kubernetes.io/hostname": "node-a"},
+		map[string]string{managedMetadataAnnotation: `{"labels":["tier"],"annotations":[],"taints":["gpu:NoSchedule"]}`},
+		nodeTaint{Key: "gpu", Effect: "NoSchedule"},
+	)
+	ch := diffNodeMeta(want, node)
+	if !ch.empty() || len(ch.Drift) != 0 {
+		t.Fatalf("expected no change, got %+v", ch)
+	}
+}
+
+func TestDiffNodeMetaOnlyRemovesManagedKeys(t *testing.T) {
+	want := nodeMeta{
+		Labels:      map[string]string{"tier": "web"},
+		Annotations: map[string]string{"owner": "team-a"},
+		Taints:      map[string]nodeTaint{},
+	}
+	node := liveNode(
+		map[string]string{"tier": "db", "old": "x", "kubernetes.io/hostname": "node-a"},
+		map[string]string{managedMetadataAnnotation: `{"labels":["tier","old"],"annotations":[],"taints":["gpu:NoSchedule"]}`},
+		nodeTaint{Key: "gpu", Effect: "NoSchedule"},
+		nodeTaint{Key: "node-role.kubernetes.io/control-plane", Effect: "NoSchedule"},
+	)
+	ch := diffNodeMeta(want, node)
+
+	if !reflect.DeepEqual(ch.SetLabels, map[string]string{"tier": "web"}) || !reflect.DeepEqual(ch.RemoveLabels, []string{"old"}) {
+		t.Fatalf("labels: set %v remove %v", ch.SetLabels, ch.RemoveLabels)
+	}
+	if !reflect.DeepEqual(ch.RemoveTaints, []string{"gpu:NoSchedule"}) {
+		t.Fatalf("remove taints = %v", ch.RemoveTaints)
+	}
+	if ch.SetAnnotations["owner"] != "team-a" || ch.SetAnnotations[managedMetadataAnnotation] == "" {
+		t.Fatalf("annotations = %v", ch.SetAnnotations)
+	}
+	if len(ch.Drift) != 4 {
+		t.Fatalf("drift = %v", ch.Drift)
+	}
+}
+
+func TestNodeMetaKubectlCommands(t *testing.T) {
+	ch := nodeMetaChange{
+		SetLabels:      map[string]string{"tier": "it's"},
+		RemoveLabels:   []string{"old"},
+		SetAnnotations: map[string]string{},
+		SetTaints:      []nodeTaint{{Key: "gpu", Value: "true", Effect: "NoSchedule"}},
+		RemoveTaints:   []string{"spot:NoExecute"},
+	}
+	got := ch.kubectlCommands("node-a")
+	want := []string{
+		`label node 'node-a' --overwrite 'tier=it'\''s' 'old-'`,
+		`taint node 'node-a' --overwrite 'gpu=true:NoSchedule' 'spot:NoExecute-'`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands:\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,404 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+const (
+	RolloutOperationReboot = "reboot"
+	RolloutOperationAction = "action"
+)
+
+// rolloutReadyPoll is how often a server coming back is checked for Ready.
+const rolloutReadyPoll = 10 * time.Second
+
+// NodePoolRolloutArgs takes a node pool's servers through an operation a batch
+// at a time: cordon and drain, reboot or run the action, wait for Ready,
+// uncordon. The ClusterRun and its ClusterRunServer rows, batches included,
+// are written by the handler; the job only works through them.
+type NodePoolRolloutArgs struct {
+	RunID      uuid.UUID `json:"run_id"`
+	OrgID      uuid.UUID `json:"org_id"`
+	ClusterID  uuid.UUID `json:"cluster_id"`
+	NodePoolID uuid.UUID `json:"node_pool_id"`
+	Operation  string    `json:"operation"`
+	// MakeTarget is the action run for each server when Operation is
+	// "action". It is called with NODE=<node name>.
+	MakeTarget          string `json:"make_target,omitempty"`
+	MaxUnavailable      int    `json:"max_unavailable"`
+	ReadyTimeoutSeconds int    `json:"ready_timeout_seconds"`
+}
+
+func (NodePoolRolloutArgs) Kind() string { return "node_pool_rollout" }
+
+func (NodePoolRolloutArgs) InsertOpts() river.InsertOpts {
+	// Not retried: a second attempt would start over on nodes the first may
+	// have left cordoned, and the operator should look at those first.
+	return river.InsertOpts{Queue: QueueClusters, MaxAttempts: 1}
+}
+
+type NodePoolRolloutResult struct {
+	Status    string `json:"status"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	Skipped   int    `json:"skipped"`
+}
+
+type NodePoolRolloutWorker struct {
+	river.WorkerDefaults[NodePoolRolloutArgs]
+	db *gorm.DB
+}
+
+// Timeout matches ClusterActionWorker: each step carries its own budget, and
+// a large pool one node at a time can legitimately take days.
+func (w *NodePoolRolloutWorker) Timeout(*river.Job[NodePoolRolloutArgs]) time.Duration {
+	return 168 * time.Hour
+}
+
+func (w *NodePoolRolloutWorker) Work(ctx context.Context, j *river.Job[NodePoolRolloutArgs]) error {
+	db := w.db
+	args := j.Args
+
+	sink := NewLogSink(db, j.ID, args.OrgID, models.JobLogSubjectClusterRun, args.RunID)
+	defer func() { _ = sink.Close() }()
+
+	claim := db.Model(&models.ClusterRun{}).
+		Where("id = ? AND status = ?", args.RunID, models.ClusterRunStatusQueued).
+		Updates(map[string]any{"status": models.ClusterRunStatusRunning, "job_id": j.ID})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+
+	finish := func(err error) NodePoolRolloutResult {
+		now := time.Now()
+		db.Model(&models.ClusterRunServer{}).
+			Where("cluster_run_id = ? AND status = ?", args.RunID, models.ClusterRunServerStatusQueued).
+			Updates(map[string]any{"status": models.ClusterRunServerStatusSkipped, "finished_at": now})
+
+		updates := map[string]any{"status": models.ClusterRunStatusSuccess, "error": "", "finished_at": now}
+		if err != nil {
+			updates["status"] = models.ClusterRunStatusFailed
+			updates["error"] = err.Error()
+			sink.System("halted: " + err.Error())
+		} else {
+			sink.System("completed")
+		}
+		db.Model(&models.ClusterRun{}).Where("id = ?", args.RunID).Updates(updates)
+		return rolloutResult(db, args.RunID, updates["status"].(string))
+	}
+
+	err := w.roll(ctx, j.ID, args, sink)
+	res := finish(err)
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[node_pool_rollout] could not record output")
+	}
+	return nil
+}
+
+// roll works through the run's batches in order and stops at the first
+// failure. Servers already started finish; none after them start.
+func (w *NodePoolRolloutWorker) roll(ctx context.Context, jobID int64, args NodePoolRolloutArgs, sink *LogSink) error {
+	db := w.db
+
+	var c models.Cluster
+	if err := db.Preload("BastionServer.SshKey").
+		Where("id = ? AND organization_id = ?", args.ClusterID, args.OrgID).
+		First(&c).Error; err != nil {
+		return fmt.Errorf("load cluster: %w", err)
+	}
+	if c.Status != clusterStatusReady {
+		return fmt.Errorf("cluster is %s, not ready", c.Status)
+	}
+
+	var rows []models.ClusterRunServer
+	if err := db.Where("cluster_run_id = ?", args.RunID).
+		Order("batch, hostname").Find(&rows).Error; err != nil {
+		return fmt.Errorf("load servers: %w", err)
+	}
+
+	pool := map[string]bool{}
+	for _, r := range rows {
+		pool[strings.ToLower(r.Hostname)] = true
+	}
+
+	batches := rolloutBatches(rows)
+	for i, batch := range batches {
+		inBatch := map[string]bool{}
+		names := make([]string, 0, len(batch))
+		for _, r := range batch {
+			inBatch[strings.ToLower(r.Hostname)] = true
+			names = append(names, r.Hostname)
+		}
+
+		var nodes kubeNodeList
+		if err := kubectlJSON(ctx, db, &c, "get nodes", &nodes); err != nil {
+			return fmt.Errorf("list nodes: %w", err)
+		}
+		down := countUnavailable(nodes.Items, pool, inBatch)
+		par := rolloutParallelism(len(batch), args.MaxUnavailable, down)
+		if par < 1 {
+			return fmt.Errorf("%d other nodes in the pool are already unavailable (max_unavailable %d)", down, args.MaxUnavailable)
+		}
+
+		sink.System(fmt.Sprintf("batch %d/%d: %s (%d at a time)", i+1, len(batches), strings.Join(names, ", "), par))
+		if err := w.rollBatch(ctx, jobID, args, &c, batch, par); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *NodePoolRolloutWorker) rollBatch(ctx context.Context, jobID int64, args NodePoolRolloutArgs, c *models.Cluster, batch []models.ClusterRunServer, par int) error {
+	sem := make(chan struct{}, par)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	for i := range batch {
+		sem <- struct{}{}
+		if failed() {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(row *models.ClusterRunServer) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := w.rollServer(ctx, jobID, args, c, row); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", row.Hostname, err)
+				}
+				mu.Unlock()
+			}
+		}(&batch[i])
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// rollServer takes one server through the operation. A server that fails
+// after the drain is left cordoned, so nothing is scheduled back onto a node
+// in an unknown state.
+func (w *NodePoolRolloutWorker) rollServer(ctx context.Context, jobID int64, args NodePoolRolloutArgs, c *models.Cluster, row *models.ClusterRunServer) error {
+	db := w.db
+	sink := NewLogSink(db, jobID, args.OrgID, models.JobLogSubjectClusterRunServer, row.ID)
+	defer func() { _ = sink.Close() }()
+
+	setStatus := func(status string) {
+		updates := map[string]any{"status": status}
+		if status == models.ClusterRunServerStatusDraining {
+			updates["started_at"] = time.Now()
+		}
+		db.Model(&models.ClusterRunServer{}).Where("id = ?", row.ID).Updates(updates)
+	}
+	fail := func(step string, err error) error {
+		err = fmt.Errorf("%s: %w", step, err)
+		sink.System(err.Error())
+		db.Model(&models.ClusterRunServer{}).Where("id = ?", row.ID).
+			Updates(map[string]any{
+				"status":      models.ClusterRunServerStatusFailed,
+				"error":       truncateErr(err.Error()),
+				"finished_at": time.Now(),
+			})
+		return err
+	}
+
+	setStatus(models.ClusterRunServerStatusDraining)
+
+	var s models.Server
+	if err := db.Preload("SshKey").
+		Where("id = ? AND organization_id = ?", row.ServerID, args.OrgID).
+		First(&s).Error; err != nil {
+		return fail("load_server", err)
+	}
+	node, err := nodeName(&s)
+	if err != nil {
+		return fail("node_name", err)
+	}
+
+	var before kubeNode
+	if err := kubectlJSON(ctx, db, c, "get node "+node, &before); err != nil {
+		return fail("get_node", err)
+	}
+
+	sink.System("draining " + node)
+	if err := drainNode(ctx, db, c, node, interval("node_pools.drain_timeout_seconds", 5*time.Minute), sink); err != nil {
+		return fail("drain", err)
+	}
+
+	setStatus(models.ClusterRunServerStatusRunning)
+	bootID := ""
+	switch args.Operation {
+	case RolloutOperationReboot:
+		sink.System("rebooting " + s.Hostname)
+		if err := rebootServer(ctx, db, &s, sink); err != nil {
+			return fail("reboot", err)
+		}
+		bootID = before.Status.NodeInfo.BootID
+	case RolloutOperationAction:
+		target := args.MakeTarget + " NODE=" + node
+		sink.System("running make " + target)
+		runCtx, cancel := context.WithTimeout(ctx, 60*time.Minute)
+		_, err := runMakeOnBastion(runCtx, db, c, args.RunID, target, sink)
+		cancel()
+		if err != nil {
+			return fail("action", err)
+		}
+	default:
+		return fail("operation", fmt.Errorf("unknown operation %q", args.Operation))
+	}
+
+	setStatus(models.ClusterRunServerStatusWaitingReady)
+	sink.System("waiting for " + node + " to be Ready")
+	timeout := time.Duration(args.ReadyTimeoutSeconds) * time.Second
+	if err := waitNodeReady(ctx, db, c, node, bootID, timeout); err != nil {
+		return fail("wait_ready", err)
+	}
+
+	setStatus(models.ClusterRunServerStatusUncordoning)
+	if _, err := kubectlOnBastion(ctx, db, c, "uncordon "+node, sink); err != nil {
+		return fail("uncordon", err)
+	}
+
+	sink.System("done")
+	db.Model(&models.ClusterRunServer{}).Where("id = ?", row.ID).
+		Updates(map[string]any{"status": models.ClusterRunServerStatusSucceeded, "finished_at": time.Now()})
+	return nil
+}
+
+// rebootServer asks the server to reboot. The connection dropping under the
+// command is the expected outcome; only the command refusing is an error.
+func rebootServer(ctx context.Context, db *gorm.DB, s *models.Server, w io.Writer) error {
+	signer, err := signerForKey(db, &s.SshKey)
+	if err != nil {
+		return err
+	}
+	c, err := dialServerSSH(ctx, db, s, signer)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	sess, err := c.NewSession()
+	if err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	defer func() { _ = sess.Close() }()
+
+	err = runSSHStreaming(sess, "sudo systemctl reboot", w)
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return err
+	}
+	return nil
+}
+
+// waitNodeReady polls until node reports Ready. With bootID set, the node must
+// also have booted since, so a kubelet that has not noticed the reboot yet is
+// not mistaken for one that came back.
+func waitNodeReady(ctx context.Context, db *gorm.DB, c *models.Cluster, node, bootID string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		var n kubeNode
+		err := kubectlJSON(ctx, db, c, "get node "+node, &n)
+		switch {
+		case err != nil:
+		case bootID != "" && n.Status.NodeInfo.BootID == bootID:
+			err = errors.New("node has not rebooted yet")
+		case !n.ready():
+			err = errors.New("node is not Ready")
+		default:
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not ready after %s: %w", timeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(rolloutReadyPoll):
+		}
+	}
+}
+
+// rolloutBatches groups rows, already ordered by batch, into their batches.
+func rolloutBatches(rows []models.ClusterRunServer) [][]models.ClusterRunServer {
+	var out [][]models.ClusterRunServer
+	for i, r := range rows {
+		if i == 0 || r.Batch != rows[i-1].Batch {
+			out = append(out, nil)
+		}
+		out[len(out)-1] = append(out[len(out)-1], r)
+	}
+	return out
+}
+
+// countUnavailable counts the pool's nodes outside the batch that are not
+// serving: NotReady, cordoned, or missing from the cluster altogether.
+func countUnavailable(nodes []kubeNode, pool, batch map[string]bool) int {
+	seen := map[string]bool{}
+	down := 0
+	for _, n := range nodes {
+		name := n.Metadata.Name
+		if !pool[name] {
+			continue
+		}
+		seen[name] = true
+		if !batch[name] && (n.Spec.Unschedulable || !n.ready()) {
+			down++
+		}
+	}
+	for name := range pool {
+		if !seen[name] && !batch[name] {
+			down++
+		}
+	}
+	return down
+}
+
+// rolloutParallelism is how many of a batch may be out at once: the batch,
+// capped by what max_unavailable leaves after nodes already down.
+func rolloutParallelism(batch, maxUnavailable, down int) int {
+	return min(batch, maxUnavailable-down)
+}
+
+func rolloutResult(db *gorm.DB, runID uuid.UUID, status string) NodePoolRolloutResult {
+	res := NodePoolRolloutResult{Status: status}
+	var rows []models.ClusterRunServer
+	db.Select("status").Where("cluster_run_id = ?", runID).Find(&rows)
+	for _, r := range rows {
+		switch r.Status {
+		case models.ClusterRunServerStatusSucceeded:
+			res.Succeeded++
+		case models.ClusterRunServerStatusFailed:
+			res.Failed++
+		case models.ClusterRunServerStatusSkipped:
+			res.Skipped++
+		}
+	}
+	return res
+}
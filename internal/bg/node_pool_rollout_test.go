@@ -0,0 +1,75 @@
+package bg
+
+import (
+	"testing"
+
+	"github.com/glueops/autoglue/internal/models"
+)
+
+func readyNode(name string, ready, cordoned bool) kubeNode {
+	var n kubeNode
+	n.Metadata.Name = name
+	n.Spec.Unschedulable = cordoned
+	status := "False"
+	if ready {
+		status = "True"
+	}
+	n.Status.Conditions = append(n.Status.Conditions, struct {
+		Type   string `json:"type"`
+		Status string `json:"status"`
+	}{"Ready", status})
+	return n
+}
+
+func TestRolloutBatches(t *testing.T) {
+	rows := []models.ClusterRunServer{
+		{Hostname: "a", Batch: 0}, {Hostname: "b", Batch: 0},
+		{Hostname: "c", Batch: 1}, {Hostname: "d", Batch: 1},
+		{Hostname: "e", Batch: 2},
+	}
+	got := rolloutBatches(rows)
+	if len(got) != 3 || len(got[0]) != 2 || len(got[1]) != 2 || len(got[2]) != 1 || got[2][0].Hostname != "e" {
+		t.Fatalf("batches = %+v", got)
+	}
+	if rolloutBatches(nil) != nil {
+		t.Fatal("no rows should mean no batches")
+	}
+}
+
+func TestCountUnavailable(t *testing.T) {
+	pool := map[string]bool{"a": true, "b": true, "c": true, "d": true, "gone": true}
+	batch := map[string]bool{"a": true}
+	nodes := []kubeNode{
+		readyNode("a", false, true),  // in the batch: not counted
+		readyNode("b", true, false),  // serving
+		readyNode("c", false, false), // NotReady
+		readyNode("d", true, true),   // cordoned
+		readyNode("other", false, false),
+	}
+	if got := countUnavailable(nodes, pool, batch); got != 3 {
+		t.Fatalf("countUnavailable = %d, want 3 (c, d and the missing node)", got)
+	}
+}
+
+func TestRolloutParallelism(t *testing.T) {
+	cases := []struct{ batch, maxUnavailable, down, want int }{
+		{3, 5, 0, 3},
+		{3, 2, 0, 2},
+		{3, 2, 1, 1},
+		{1, 1, 1, 0},
+	}
+	for _, c := range cases {
+		if got := rolloutParallelism(c.batch, c.maxUnavailable, c.down); got != c.want {
+			t.Errorf("rolloutParallelism(%d, %d, %d) = %d, want %d", c.batch, c.maxUnavailable, c.down, got, c.want)
+		}
+	}
+}
+
+func TestKubeNodeReady(t *testing.T) {
+	if !readyNode("a", true, false).ready() || readyNode("a", false, false).ready() {
+		t.Fatal("ready() should follow the Ready condition")
+	}
+	if (kubeNode{}).ready() {
+		t.Fatal("a node with no conditions is not ready")
+	}
+}
@@ -0,0 +1,480 @@
+package bg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/compute"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// NodePoolScaleSweepArgs finds the node pools with a desired size and fans
+// out one NodePoolScaleArgs each.
+type NodePoolScaleSweepArgs struct{}
+
+func (NodePoolScaleSweepArgs) Kind() string { return "node_pool_scale_sweep" }
+
+func (NodePoolScaleSweepArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueMaintenance, MaxAttempts: 2}
+}
+
+// NodePoolScaleArgs moves one pool one step towards its desired size.
+type NodePoolScaleArgs struct {
+	NodePoolID uuid.UUID `json:"node_pool_id"`
+}
+
+func (NodePoolScaleArgs) Kind() string { return "node_pool_scale" }
+
+func (NodePoolScaleArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       QueueClusters,
+		MaxAttempts: 1,
+		// Two reconcilers on the same pool would both see the same shortfall
+		// and both create servers for it.
+		UniqueOpts: river.UniqueOpts{
+			ByArgs: true,
+			ByState: []rivertype.JobState{
+				rivertype.JobStateAvailable, rivertype.JobStateScheduled,
+				rivertype.JobStateRunning, rivertype.JobStateRetryable,
+				rivertype.JobStatePending,
+			},
+		},
+	}
+}
+
+type NodePoolScaleResult struct {
+	Status     string    `json:"status"`
+	NodePoolID uuid.UUID `json:"node_pool_id"`
+	Created    int       `json:"created"`
+	Removed    int       `json:"removed"`
+	Joined     int       `json:"joined"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// PlannedServer is a pool member the plan acts on.
+type PlannedServer struct {
+	ID       uuid.UUID `json:"id"`
+	Hostname string    `json:"hostname"`
+	Status   string    `json:"status"`
+}
+
+// NodePoolPlan is the difference between a pool's desired size and its
+// members. Blockers explain why part of it will not be carried out; the rest
+// still is.
+type NodePoolPlan struct {
+	Desired *int
+	// Current counts members that are, or are becoming, part of the pool:
+	// everything except servers being deleted and cloud servers whose
+	// creation failed.
+	Current  int
+	Creating int
+	Deleting int
+	Create   int
+	Remove   []PlannedServer
+	Blockers []string
+}
+
+// planNodePool works out what the reconciler would do to np, given its
+// members. Only servers autoglue created itself (those with an Instance) are
+// ever removed, and among them the ones that are least far along go first:
+// still creating, then not yet joined, then the newest.
+func planNodePool(np *models.NodePool, members []models.Server) NodePoolPlan {
+	plan := NodePoolPlan{Desired: np.DesiredSize}
+
+	var counted, failed []models.Server
+	for _, s := range members {
+		switch {
+		case s.Status == "deleting":
+			plan.Deleting++
+		case s.Status == "failed" && s.Instance != nil:
+			failed = append(failed, s)
+		default:
+			counted = append(counted, s)
+			if s.Status == "creating" {
+				plan.Creating++
+			}
+		}
+	}
+	plan.Current = len(counted)
+
+	// A cloud server that never came up is cleaned up whatever the size.
+	for _, s := range failed {
+		plan.Remove = append(plan.Remove, plannedServer(s))
+	}
+
+	if np.DesiredSize == nil {
+		return plan
+	}
+	diff := *np.DesiredSize - plan.Current
+
+	if diff > 0 {
+		plan.Create = diff
+		if missing := templateMissing(np.Template); len(missing) > 0 {
+			plan.Blockers = append(plan.Blockers, "server template is missing "+strings.Join(missing, ", "))
+		}
+		return plan
+	}
+	if diff == 0 {
+		return plan
+	}
+
+	switch np.Role {
+	case models.NodePoolRoleMaster:
+		plan.Blockers = append(plan.Blockers, fmt.Sprintf("%d excess control plane servers; shrinking a master pool is not automated", -diff))
+		return plan
+	case models.NodePoolRoleEtcd:
+		plan.Blockers = append(plan.Blockers, fmt.Sprintf("%d excess etcd members; shrinking an etcd pool is not automated", -diff))
+		return plan
+	}
+
+	var managed []models.Server
+	for _, s := range counted {
+		if s.Instance != nil {
+			managed = append(managed, s)
+		}
+	}
+	sort.SliceStable(managed, func(i, k int) bool {
+		ri, rk := removalRank(managed[i].Status), removalRank(managed[k].Status)
+		if ri != rk {
+			return ri < rk
+		}
+		return managed[i].CreatedAt.After(managed[k].CreatedAt)
+	})
+
+	n := -diff
+	if n > len(managed) {
+		plan.Blockers = append(plan.Blockers, fmt.Sprintf("%d excess servers were not created by autoglue; detach or delete them by hand", n-len(managed)))
+		n = len(managed)
+	}
+	for _, s := range managed[:n] {
+		plan.Remove = append(plan.Remove, plannedServer(s))
+	}
+	return plan
+}
+
+func removalRank(status string) int {
+	switch status {
+	case "creating":
+		return 0
+	case "ready":
+		return 2
+	default:
+		return 1
+	}
+}
+
+func plannedServer(s models.Server) PlannedServer {
+	return PlannedServer{ID: s.ID, Hostname: s.Hostname, Status: s.Status}
+}
+
+// templateMissing lists the template fields a new server cannot do without.
+func templateMissing(t models.ServerTemplate) []string {
+	var missing []string
+	if t.CredentialID == nil {
+		missing = append(missing, "credential_id")
+	}
+	if t.Size == "" {
+		missing = append(missing, "size")
+	}
+	if t.Image == "" {
+		missing = append(missing, "image")
+	}
+	if t.SshKeyID == nil {
+		missing = append(missing, "ssh_key_id")
+	}
+	return missing
+}
+
+// LoadNodePoolPlan computes the plan for one of an org's pools.
+func LoadNodePoolPlan(db *gorm.DB, orgID, poolID uuid.UUID) (*models.NodePool, NodePoolPlan, error) {
+	var np models.NodePool
+	if err := db.Preload("Servers.Instance").
+		Where("id = ? AND organization_id = ?", poolID, orgID).
+		First(&np).Error; err != nil {
+		return nil, NodePoolPlan{}, err
+	}
+	return &np, planNodePool(&np, np.Servers), nil
+}
+
+type NodePoolScaleSweepWorker struct {
+	river.WorkerDefaults[NodePoolScaleSweepArgs]
+	db *gorm.DB
+}
+
+func (w *NodePoolScaleSweepWorker) Timeout(*river.Job[NodePoolScaleSweepArgs]) time.Duration {
+	return time.Minute
+}
+
+func (w *NodePoolScaleSweepWorker) Work(ctx context.Context, j *river.Job[NodePoolScaleSweepArgs]) error {
+	var ids []uuid.UUID
+	if err := w.db.Model(&models.NodePool{}).
+		Where("desired_size IS NOT NULL OR join_pending").
+		Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("list scaled node pools: %w", err)
+	}
+
+	client := river.ClientFromContext[pgx.Tx](ctx)
+	for _, id := range ids {
+		if _, err := client.Insert(ctx, NodePoolScaleArgs{NodePoolID: id}, nil); err != nil {
+			log.Error().Err(err).Str("node_pool_id", id.String()).Msg("[scale] could not dispatch")
+		}
+	}
+	return nil
+}
+
+type NodePoolScaleWorker struct {
+	river.WorkerDefaults[NodePoolScaleArgs]
+	db *gorm.DB
+}
+
+// Timeout leaves room for draining several nodes, each of which may wait out
+// its own drain timeout.
+func (w *NodePoolScaleWorker) Timeout(*river.Job[NodePoolScaleArgs]) time.Duration {
+	return time.Hour
+}
+
+func (w *NodePoolScaleWorker) Work(ctx context.Context, j *river.Job[NodePoolScaleArgs]) error {
+	db := w.db
+	client := river.ClientFromContext[pgx.Tx](ctx)
+
+	var np models.NodePool
+	if err := db.Preload("Servers.Instance").
+		Preload("Clusters.BastionServer.SshKey").
+		Where("id = ?", j.Args.NodePoolID).
+		First(&np).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	plan := planNodePool(&np, np.Servers)
+	res := NodePoolScaleResult{Status: "ok", NodePoolID: np.ID}
+	var problems []string
+
+	if plan.Create > 0 && len(templateMissing(np.Template)) == 0 {
+		n, err := createPoolServers(ctx, db, client, &np, plan.Create)
+		res.Created = n
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	for _, ps := range plan.Remove {
+		if err := removePoolServer(ctx, db, client, &np, ps); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		res.Removed++
+	}
+
+	if res.Created > 0 {
+		np.JoinPending = true
+	}
+	if np.JoinPending && plan.Creating == 0 && res.Created == 0 {
+		n, err := joinPoolClusters(ctx, db, client, &np)
+		res.Joined = n
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			np.JoinPending = false
+		}
+	}
+
+	problems = append(problems, plan.Blockers...)
+	scaleErr := truncateErr(strings.Join(problems, "; "))
+	updates := map[string]any{"join_pending": np.JoinPending, "scale_error": scaleErr}
+	if res.Created > 0 || res.Removed > 0 {
+		updates["scaled_at"] = time.Now()
+	}
+	if err := db.Model(&models.NodePool{}).Where("id = ?", np.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	if scaleErr != "" {
+		res.Status, res.Error = "partial", scaleErr
+	}
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[scale] could not record output")
+	}
+	return nil
+}
+
+// createPoolServers creates n cloud servers from the pool template, attaches
+// them to the pool and queues their machines. A server the org's placement
+// rules or the pool's labels and taints would refuse is not created.
+func createPoolServers(ctx context.Context, db *gorm.DB, client *Client, np *models.NodePool, n int) (int, error) {
+	t := np.Template
+
+	var cred models.Credential
+	if err := db.Where("id = ? AND organization_id = ?", *t.CredentialID, np.OrganizationID).First(&cred).Error; err != nil {
+		return 0, fmt.Errorf("template credential: %w", err)
+	}
+	p, err := compute.New(cred.Provider, "")
+	if err != nil {
+		return 0, err
+	}
+	user := t.SSHUser
+	if user == "" {
+		user = p.DefaultUser()
+	}
+
+	// A bare copy of the pool, so attaching does not grow np.Servers.
+	pool := models.NodePool{}
+	pool.ID, pool.OrganizationID = np.ID, np.OrganizationID
+
+	created := 0
+	for range n {
+		s := models.Server{
+			OrganizationID: np.OrganizationID,
+			Hostname:       poolHostname(np.Name),
+			SSHUser:        user,
+			SshKeyID:       *t.SshKeyID,
+			Role:           np.Role,
+			Status:         "creating",
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Instance", "Facts").Create(&s).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.ServerInstance{
+				ServerID:       s.ID,
+				OrganizationID: np.OrganizationID,
+				Provider:       cred.Provider,
+				CredentialID:   cred.ID,
+				Size:           t.Size,
+				Image:          t.Image,
+				Location:       t.Region,
+				Network:        t.Network,
+				Status:         models.ServerInstanceCreating,
+			}).Error; err != nil {
+				return err
+			}
+			return models.AttachPoolServers(tx, &pool, []models.Server{{ID: s.ID}})
+		})
+		if err != nil {
+			return created, fmt.Errorf("create server: %w", err)
+		}
+		if _, err := client.Insert(ctx, ComputeCreateArgs{ServerID: s.ID}, nil); err != nil {
+			markComputeFailed(db, &s, errors.New("could not queue creation"))
+			return created, fmt.Errorf("queue server creation: %w", err)
+		}
+		created++
+		log.Info().Str("node_pool_id", np.ID.String()).Str("server_id", s.ID.String()).Msg("[scale] server added")
+	}
+	return created, nil
+}
+
+// removePoolServer takes a member out of every cluster of the pool and then
+// deletes it. A node that may be running workloads is drained first; if that
+// fails the server stays, and the next run tries again.
+func removePoolServer(ctx context.Context, db *gorm.DB, client *Client, np *models.NodePool, ps PlannedServer) error {
+	if ps.Status == "ready" || ps.Status == "unreachable" {
+		s := models.Server{ID: ps.ID, Hostname: ps.Hostname}
+		node, err := nodeName(&s)
+		if err != nil {
+			return err
+		}
+		timeout := interval("node_pools.drain_timeout_seconds", 5*time.Minute)
+		for i := range np.Clusters {
+			c := &np.Clusters[i]
+			if c.EncryptedKubeconfig == "" {
+				continue
+			}
+			if err := drainNode(ctx, db, c, node, timeout, nil); err != nil {
+				return fmt.Errorf("cluster %s: %w", c.Name, err)
+			}
+			if err := deleteNode(ctx, db, c, node, nil); err != nil {
+				return fmt.Errorf("cluster %s: %w", c.Name, err)
+			}
+		}
+	}
+	if err := QueueServerDeletion(ctx, db, client, ps.ID); err != nil {
+		return fmt.Errorf("delete %s: %w", ps.Hostname, err)
+	}
+	log.Info().Str("node_pool_id", np.ID.String()).Str("server_id", ps.ID.String()).Msg("[scale] server removed")
+	return nil
+}
+
+// joinPoolClusters starts the join action on every ready cluster the pool
+// belongs to. The action is looked up by its make target, which defaults to
+// join-nodes and can be changed with node_pools.join_make_target.
+func joinPoolClusters(ctx context.Context, db *gorm.DB, client *Client, np *models.NodePool) (int, error) {
+	target := viper.GetString("node_pools.join_make_target")
+	if target == "" {
+		target = "join-nodes"
+	}
+	var action models.Action
+	if err := db.Where("make_target = ?", target).First(&action).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("no action with make target %q to join new servers", target)
+		}
+		return 0, err
+	}
+
+	started := 0
+	for _, c := range np.Clusters {
+		if c.Status != models.ClusterStatusReady {
+			continue
+		}
+		run := models.ClusterRun{
+			OrganizationID: c.OrganizationID,
+			ClusterID:      c.ID,
+			Action:         action.MakeTarget,
+			Status:         models.ClusterRunStatusQueued,
+		}
+		if err := db.Create(&run).Error; err != nil {
+			return started, err
+		}
+		ins, err := client.Insert(ctx, ClusterActionArgs{
+			RunID:      run.ID,
+			OrgID:      c.OrganizationID,
+			ClusterID:  c.ID,
+			Action:     action.MakeTarget,
+			MakeTarget: action.MakeTarget,
+		}, nil)
+		if err != nil {
+			_ = db.Model(&models.ClusterRun{}).Where("id = ?", run.ID).
+				Updates(map[string]any{
+					"status":      models.ClusterRunStatusFailed,
+					"error":       "failed to enqueue job: " + err.Error(),
+					"finished_at": time.Now().UTC(),
+				}).Error
+			return started, fmt.Errorf("cluster %s: queue join: %w", c.Name, err)
+		}
+		_ = db.Model(&models.ClusterRun{}).Where("id = ?", run.ID).Update("job_id", ins.Job.ID).Error
+		started++
+	}
+	return started, nil
+}
+
+var hostnameUnsafe = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// poolHostname names a new member after its pool, with a random suffix. It
+// doubles as the Kubernetes node name, so it has to be a valid DNS label.
+func poolHostname(pool string) string {
+	base := strings.Trim(hostnameUnsafe.ReplaceAllString(strings.ToLower(pool), "-"), "-")
+	if len(base) > 48 {
+		base = strings.TrimRight(base[:48], "-")
+	}
+	if base == "" {
+		base = "node"
+	}
+	var b [3]byte
+	_, _ = rand.Read(b[:])
+	return base + "-" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,104 @@
+package bg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+)
+
+func member(host, status string, managed bool, age time.Duration) models.Server {
+	s := models.Server{ID: uuid.New(), Hostname: host, Status: status, CreatedAt: time.Now().Add(-age)}
+	if managed {
+		s.Instance = &models.ServerInstance{ServerID: s.ID}
+	}
+	return s
+}
+
+func fullTemplate() models.ServerTemplate {
+	cred, key := uuid.New(), uuid.New()
+	return models.ServerTemplate{CredentialID: &cred, SshKeyID: &key, Size: "cx22", Image: "ubuntu-24.04"}
+}
+
+func size(n int) *int { return &n }
+
+func TestPlanNodePoolScaleUp(t *testing.T) {
+	np := &models.NodePool{Role: "worker", DesiredSize: size(3), Template: fullTemplate()}
+	members := []models.Server{
+		member("a", "ready", false, time.Hour),
+		member("b", "deleting", true, time.Hour),
+	}
+	plan := planNodePool(np, members)
+	if plan.Current != 1 || plan.Deleting != 1 || plan.Create != 2 || len(plan.Remove) != 0 || len(plan.Blockers) != 0 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	np.Template = models.ServerTemplate{Size: "cx22"}
+	plan = planNodePool(np, members)
+	if plan.Create != 2 || len(plan.Blockers) != 1 || !strings.Contains(plan.Blockers[0], "credential_id, image, ssh_key_id") {
+		t.Fatalf("incomplete template should block creation: %+v", plan)
+	}
+}
+
+func TestPlanNodePoolScaleDownPrefersLeastFarAlong(t *testing.T) {
+	oldReady := member("old", "ready", true, 2*time.Hour)
+	newReady := member("new", "ready", true, time.Hour)
+	pending := member("pending", "pending", true, 3*time.Hour)
+	creating := member("creating", "creating", true, 4*time.Hour)
+	manual := member("manual", "ready", false, time.Minute)
+
+	np := &models.NodePool{Role: "worker", DesiredSize: size(2)}
+	plan := planNodePool(np, []models.Server{oldReady, newReady, pending, creating, manual})
+
+	var got []string
+	for _, s := range plan.Remove {
+		got = append(got, s.Hostname)
+	}
+	if strings.Join(got, ",") != "creating,pending,new" {
+		t.Fatalf("remove order = %v", got)
+	}
+	if plan.Creating != 1 || len(plan.Blockers) != 0 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestPlanNodePoolBlockers(t *testing.T) {
+	members := []models.Server{
+		member("a", "ready", true, time.Hour),
+		member("b", "ready", false, time.Hour),
+		member("c", "ready", false, time.Hour),
+	}
+
+	plan := planNodePool(&models.NodePool{Role: "worker", DesiredSize: size(0)}, members)
+	if len(plan.Remove) != 1 || len(plan.Blockers) != 1 || !strings.Contains(plan.Blockers[0], "2 excess servers") {
+		t.Fatalf("unmanaged excess should be reported, not removed: %+v", plan)
+	}
+
+	plan = planNodePool(&models.NodePool{Role: "master", DesiredSize: size(1)}, members)
+	if len(plan.Remove) != 0 || len(plan.Blockers) != 1 {
+		t.Fatalf("master pools must not shrink: %+v", plan)
+	}
+}
+
+func TestPlanNodePoolCleansUpFailedServers(t *testing.T) {
+	failed := member("broken", "failed", true, time.Hour)
+	plan := planNodePool(&models.NodePool{Role: "worker"}, []models.Server{
+		failed,
+		member("manual", "failed", false, time.Hour),
+	})
+	if plan.Current != 1 || len(plan.Remove) != 1 || plan.Remove[0].ID != failed.ID {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestPoolHostname(t *testing.T) {
+	h := poolHostname("GPU Workers_EU")
+	if !strings.HasPrefix(h, "gpu-workers-eu-") || !nodeNamePattern.MatchString(h) {
+		t.Fatalf("poolHostname = %q", h)
+	}
+	if h := poolHostname("!!!"); !strings.HasPrefix(h, "node-") {
+		t.Fatalf("poolHostname = %q", h)
+	}
+}
@@ -65,6 +65,10 @@ func validateClusterForPrepare(c *models.Cluster) error {
 		return fmt.Errorf("cluster has no servers attached to node pools")
 	}
 
+	if err := c.ValidateTopology(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
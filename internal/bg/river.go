@@ -52,10 +52,25 @@ func NewWorkerClient(pool *pgxpool.Pool, d Deps) (*Client, error) {
 	river.AddWorker(workers, &BastionSweepWorker{db: d.DB})
 	river.AddWorker(workers, &BastionBootstrapWorker{db: d.DB})
 	river.AddWorker(workers, &ClusterActionWorker{db: d.DB, baseURL: d.BaseURL})
+	river.AddWorker(workers, &ComputeCreateWorker{db: d.DB})
+	river.AddWorker(workers, &ComputeDeleteWorker{db: d.DB})
 	river.AddWorker(workers, &DNSReconcileWorker{db: d.DB})
+	river.AddWorker(workers, &DNSDriftWorker{db: d.DB})
 	river.AddWorker(workers, &DbBackupWorker{db: d.DB})
+	river.AddWorker(workers, &ExecHostWorker{db: d.DB})
+	river.AddWorker(workers, &ExecSweepWorker{db: d.DB})
 	river.AddWorker(workers, &JobLogsCleanupWorker{db: d.DB})
+	river.AddWorker(workers, &NodeMetadataSweepWorker{db: d.DB})
+	river.AddWorker(workers, &NodeMetadataWorker{db: d.DB})
+	river.AddWorker(workers, &NodePoolRolloutWorker{db: d.DB})
+	river.AddWorker(workers, &NodePoolScaleSweepWorker{db: d.DB})
+	river.AddWorker(workers, &NodePoolScaleWorker{db: d.DB})
 	river.AddWorker(workers, &OrgKeySweeperWorker{db: d.DB})
+	river.AddWorker(workers, &ServerFactsSweepWorker{db: d.DB})
+	river.AddWorker(workers, &ServerFactsWorker{db: d.DB})
+	river.AddWorker(workers, &ServerHealthWorker{db: d.DB})
+	river.AddWorker(workers, &SSHCAInstallWorker{db: d.DB})
+	river.AddWorker(workers, &SSHKeyRotateWorker{db: d.DB})
 	river.AddWorker(workers, &TokensCleanupWorker{db: d.DB})
 	river.AddWorker(workers, &VacuumWorker{db: d.DB})
 
@@ -106,6 +121,50 @@ func periodicJobs() []*river.PeriodicJob {
 			},
 			&river.PeriodicJobOpts{ID: "dns_reconcile", RunOnStart: true},
 		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(interval("dns.drift_interval_seconds", 15*time.Minute)),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return DNSDriftArgs{}, &river.InsertOpts{UniqueOpts: tickUnique}
+			},
+			&river.PeriodicJobOpts{ID: "dns_drift"},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(interval("server_facts.interval_seconds", 15*time.Minute)),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return ServerFactsSweepArgs{}, &river.InsertOpts{UniqueOpts: tickUnique}
+			},
+			&river.PeriodicJobOpts{ID: "server_facts_sweep"},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(interval("server_health.interval_seconds", time.Minute)),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return ServerHealthArgs{}, &river.InsertOpts{UniqueOpts: tickUnique}
+			},
+			&river.PeriodicJobOpts{ID: "server_health"},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(interval("exec.sweep_interval_seconds", time.Minute)),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return ExecSweepArgs{}, &river.InsertOpts{UniqueOpts: tickUnique}
+			},
+			&river.PeriodicJobOpts{ID: "exec_sweep"},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(interval("node_pools.scale_interval_seconds", time.Minute)),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return NodePoolScaleSweepArgs{}, &river.InsertOpts{UniqueOpts: tickUnique}
+			},
+			&river.PeriodicJobOpts{ID: "node_pool_scale_sweep"},
+		),
+		// Changes made through the API queue their own runs; the sweep is
+		// what catches changes made to the nodes directly.
+		river.NewPeriodicJob(
+			river.PeriodicInterval(interval("node_metadata.interval_seconds", 15*time.Minute)),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return NodeMetadataSweepArgs{}, &river.InsertOpts{UniqueOpts: tickUnique}
+			},
+			&river.PeriodicJobOpts{ID: "node_metadata_sweep"},
+		),
 		river.NewPeriodicJob(
 			river.PeriodicInterval(interval("org_key_sweeper.interval_seconds", time.Hour)),
 			func() (river.JobArgs, *river.InsertOpts) {
@@ -199,7 +258,8 @@ func monthlyAt(day, hour, minute int) river.PeriodicSchedule {
 }
 
 // maxWorkerTimeout is the longest Timeout any registered worker returns. Keep
-// this in step with the Timeout methods; ClusterActionWorker is the outlier.
+// this in step with the Timeout methods; ClusterActionWorker and
+// NodePoolRolloutWorker are the outliers.
 const maxWorkerTimeout = 168 * time.Hour
 
 // rescueWindow keeps stuck-job rescue comfortably clear of legitimately
@@ -0,0 +1,284 @@
+package bg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+	"github.com/rs/zerolog/log"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ServerFactsSweepArgs finds ready servers whose facts are missing or older
+// than server_facts.max_age_seconds and fans out one ServerFactsArgs each.
+type ServerFactsSweepArgs struct{}
+
+func (ServerFactsSweepArgs) Kind() string { return "server_facts_sweep" }
+
+func (ServerFactsSweepArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueMaintenance, MaxAttempts: 2}
+}
+
+// ServerFactsArgs gathers facts from one server.
+type ServerFactsArgs struct {
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+func (ServerFactsArgs) Kind() string { return "server_facts" }
+
+func (ServerFactsArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       QueueClusters,
+		MaxAttempts: 1,
+		// A refresh requested while one is already queued is the same refresh.
+		UniqueOpts: river.UniqueOpts{
+			ByArgs: true,
+			ByState: []rivertype.JobState{
+				rivertype.JobStateAvailable, rivertype.JobStateScheduled,
+				rivertype.JobStateRunning, rivertype.JobStateRetryable,
+				rivertype.JobStatePending,
+			},
+		},
+	}
+}
+
+type ServerFactsSweepResult struct {
+	Status     string `json:"status"`
+	Dispatched int    `json:"dispatched"`
+}
+
+type ServerFactsResult struct {
+	Status   string    `json:"status"`
+	ServerID uuid.UUID `json:"server_id"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type ServerFactsSweepWorker struct {
+	river.WorkerDefaults[ServerFactsSweepArgs]
+	db *gorm.DB
+}
+
+func (w *ServerFactsSweepWorker) Timeout(*river.Job[ServerFactsSweepArgs]) time.Duration {
+	return time.Minute
+}
+
+func (w *ServerFactsSweepWorker) Work(ctx context.Context, j *river.Job[ServerFactsSweepArgs]) error {
+	stale := time.Now().Add(-interval("server_facts.max_age_seconds", 24*time.Hour))
+
+	// Keyed on the last attempt, not the last success, so a host that is down
+	// is retried once per window rather than on every tick.
+	var ids []uuid.UUID
+	if err := w.db.Model(&models.Server{}).
+		Joins("LEFT JOIN server_facts sf ON sf.server_id = servers.id").
+		Where("servers.status = ?", "ready").
+		Where("sf.server_id IS NULL OR sf.last_attempt_at < ?", stale).
+		Order("sf.last_attempt_at NULLS FIRST").
+		Limit(200).
+		Pluck("servers.id", &ids).Error; err != nil {
+		return fmt.Errorf("list stale servers: %w", err)
+	}
+
+	client := river.ClientFromContext[pgx.Tx](ctx)
+	dispatched := 0
+	for _, id := range ids {
+		if _, err := client.Insert(ctx, ServerFactsArgs{ServerID: id}, nil); err != nil {
+			log.Error().Err(err).Str("server_id", id.String()).Msg("[facts] could not dispatch")
+			continue
+		}
+		dispatched++
+	}
+
+	if err := river.RecordOutput(ctx, ServerFactsSweepResult{Status: "ok", Dispatched: dispatched}); err != nil {
+		log.Warn().Err(err).Msg("[facts] could not record sweep output")
+	}
+	return nil
+}
+
+type ServerFactsWorker struct {
+	river.WorkerDefaults[ServerFactsArgs]
+	db *gorm.DB
+}
+
+func (w *ServerFactsWorker) Timeout(*river.Job[ServerFactsArgs]) time.Duration {
+	return 3 * time.Minute
+}
+
+func (w *ServerFactsWorker) Work(ctx context.Context, j *river.Job[ServerFactsArgs]) error {
+	db := w.db
+
+	var s models.Server
+	if err := db.Preload("SshKey").Where("id = ?", j.Args.ServerID).First(&s).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	res := ServerFactsResult{Status: "ok", ServerID: s.ID}
+	facts, err := collectServerFacts(ctx, db, &s)
+	if err != nil {
+		res.Status = "failed"
+		res.Error = err.Error()
+		log.Warn().Err(err).Str("server_id", s.ID.String()).Msg("[facts] collection failed")
+		if err := recordServerFactsFailure(db, &s, err); err != nil {
+			return err
+		}
+	} else if err := saveServerFacts(db, facts); err != nil {
+		return fmt.Errorf("save facts: %w", err)
+	}
+
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[facts] could not record output")
+	}
+	return nil
+}
+
+func collectServerFacts(ctx context.Context, db *gorm.DB, s *models.Server) (*models.ServerFacts, error) {
+	signer, err := signerForKey(db, &s.SshKey)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dialServerSSH(ctx, db, s, signer)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = c.Close() }()
+
+	out, err := runSSHCommand(ctx, c.Client, serverFactsScript)
+	if err != nil {
+		return nil, wrapSSHError(err, out)
+	}
+
+	facts := parseServerFacts(out)
+	facts.ServerID = s.ID
+	facts.OrganizationID = s.OrganizationID
+	now := time.Now()
+	facts.CollectedAt = &now
+	facts.LastAttemptAt = now
+	return facts, nil
+}
+
+func saveServerFacts(db *gorm.DB, f *models.ServerFacts) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "server_id"}},
+		UpdateAll: true,
+	}).Create(f).Error
+}
+
+// recordServerFactsFailure stamps the attempt without disturbing the last good
+// snapshot.
+func recordServerFactsFailure(db *gorm.DB, s *models.Server, cause error) error {
+	row := models.ServerFacts{
+		ServerID:       s.ID,
+		OrganizationID: s.OrganizationID,
+		Disks:          datatypes.JSON("[]"),
+		LastAttemptAt:  time.Now(),
+		LastError:      cause.Error(),
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "server_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_attempt_at", "last_error"}),
+	}).Create(&row).Error
+}
+
+// serverFactsScript prints one key=value per line. It only reads, needs no
+// root, and every probe tolerates its tool being absent: a missing runtime or
+// timedatectl is a fact, not a failure.
+const serverFactsScript = `sed 's/^/os./' /etc/os-release 2>/dev/null || true
+echo "kernel=$(uname -r)"
+echo "arch=$(uname -m)"
+echo "cpus=$(nproc 2>/dev/null || getconf _NPROCESSORS_ONLN)"
+awk '/^MemTotal:/ {print "mem_kb=" $2}' /proc/meminfo
+df -P -k -x tmpfs -x devtmpfs -x overlay -x squashfs 2>/dev/null | awk 'NR>1 {print "disk=" $6 " " $1 " " $2 " " $4}'
+if command -v containerd >/dev/null 2>&1; then
+  echo "runtime=containerd $(containerd --version 2>/dev/null | awk '{print $3}')"
+elif command -v docker >/dev/null 2>&1; then
+  echo "runtime=docker $(docker --version 2>/dev/null | awk '{print $3}' | tr -d ,)"
+elif command -v crio >/dev/null 2>&1; then
+  echo "runtime=cri-o $(crio --version 2>/dev/null | awk 'NR==1 {print $3}')"
+fi
+if command -v timedatectl >/dev/null 2>&1; then
+  echo "ntp_synced=$(timedatectl show -p NTPSynchronized --value 2>/dev/null)"
+fi
+`
+
+// parseServerFacts reads serverFactsScript output. Unknown keys and malformed
+// lines are skipped: a distro that prints something odd should lose that one
+// fact, not the snapshot.
+func parseServerFacts(out string) *models.ServerFacts {
+	f := &models.ServerFacts{}
+	disks := []models.ServerDisk{}
+
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		k, v, ok := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "os.ID":
+			f.OSID = unquoteOSRelease(v)
+		case "os.VERSION_ID":
+			f.OSVersion = unquoteOSRelease(v)
+		case "os.PRETTY_NAME":
+			f.OSPrettyName = unquoteOSRelease(v)
+		case "kernel":
+			f.Kernel = v
+		case "arch":
+			f.Arch = v
+		case "cpus":
+			f.CPUCount, _ = strconv.Atoi(v)
+		case "mem_kb":
+			if kb, err := strconv.ParseInt(v, 10, 64); err == nil {
+				f.MemoryBytes = kb * 1024
+			}
+		case "disk":
+			p := strings.Fields(v)
+			if len(p) != 4 {
+				continue
+			}
+			size, err1 := strconv.ParseInt(p[2], 10, 64)
+			avail, err2 := strconv.ParseInt(p[3], 10, 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			disks = append(disks, models.ServerDisk{Mount: p[0], Device: p[1], SizeBytes: size * 1024, AvailBytes: avail * 1024})
+		case "runtime":
+			name, ver, _ := strings.Cut(v, " ")
+			f.ContainerRuntime = name
+			f.ContainerRuntimeVersion = strings.TrimPrefix(strings.TrimSpace(ver), "v")
+		case "ntp_synced":
+			switch v {
+			case "yes":
+				b := true
+				f.TimeSynced = &b
+			case "no":
+				b := false
+				f.TimeSynced = &b
+			}
+		}
+	}
+
+	b, _ := json.Marshal(disks)
+	f.Disks = datatypes.JSON(b)
+	return f
+}
+
+func unquoteOSRelease(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
@@ -0,0 +1,67 @@
+package bg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/glueops/autoglue/internal/models"
+)
+
+func TestParseServerFacts(t *testing.T) {
+	out := `os.PRETTY_NAME="Ubuntu 24.04.1 LTS"
+os.NAME="Ubuntu"
+os.VERSION_ID="24.04"
+os.ID=ubuntu
+os.ID_LIKE=debian
+kernel=6.8.0-45-generic
+arch=x86_64
+cpus=4
+mem_kb=8123456
+disk=/ /dev/sda1 81106868 60000000
+disk=/boot/efi /dev/sda15 106858 100000
+disk=garbage
+runtime=containerd v1.7.22
+ntp_synced=yes
+something unexpected
+`
+	f := parseServerFacts(out)
+
+	if f.OSID != "ubuntu" || f.OSVersion != "24.04" || f.OSPrettyName != "Ubuntu 24.04.1 LTS" {
+		t.Errorf("os = %q %q %q", f.OSID, f.OSVersion, f.OSPrettyName)
+	}
+	if f.Kernel != "6.8.0-45-generic" || f.Arch != "x86_64" {
+		t.Errorf("kernel/arch = %q %q", f.Kernel, f.Arch)
+	}
+	if f.CPUCount != 4 {
+		t.Errorf("cpus = %d", f.CPUCount)
+	}
+	if f.MemoryBytes != 8123456*1024 {
+		t.Errorf("memory = %d", f.MemoryBytes)
+	}
+	if f.ContainerRuntime != "containerd" || f.ContainerRuntimeVersion != "1.7.22" {
+		t.Errorf("runtime = %q %q", f.ContainerRuntime, f.ContainerRuntimeVersion)
+	}
+	if f.TimeSynced == nil || !*f.TimeSynced {
+		t.Errorf("time_synced = %v", f.TimeSynced)
+	}
+
+	var disks []models.ServerDisk
+	if err := json.Unmarshal(f.Disks, &disks); err != nil {
+		t.Fatalf("disks json: %v", err)
+	}
+	if len(disks) != 2 || disks[0].Mount != "/" || disks[0].SizeBytes != 81106868*1024 {
+		t.Errorf("disks = %+v", disks)
+	}
+}
+
+// A host with no runtime and no timedatectl reports neither, and that must not
+// read as "runtime installed" or "clock not synced".
+func TestParseServerFactsMissingProbes(t *testing.T) {
+	f := parseServerFacts("kernel=5.15.0\n")
+	if f.ContainerRuntime != "" || f.TimeSynced != nil {
+		t.Errorf("runtime %q, time_synced %v; want empty and nil", f.ContainerRuntime, f.TimeSynced)
+	}
+	if string(f.Disks) != "[]" {
+		t.Errorf("disks = %s, want []", f.Disks)
+	}
+}
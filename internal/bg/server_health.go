@@ -0,0 +1,278 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	serverStatusReady       = "ready"
+	serverStatusUnreachable = "unreachable"
+)
+
+// ServerHealthArgs probes every ready or unreachable server over SSH and moves
+// it between the two states.
+type ServerHealthArgs struct{}
+
+func (ServerHealthArgs) Kind() string { return "server_health" }
+
+func (ServerHealthArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueDefault, MaxAttempts: 1}
+}
+
+type ServerHealthResult struct {
+	Status      string `json:"status"`
+	Probed      int    `json:"probed"`
+	Reachable   int    `json:"reachable"`
+	WentDown    int    `json:"went_down"`
+	Recovered   int    `json:"recovered"`
+	Degraded    int    `json:"degraded_clusters"`
+	ElapsedMs   int64  `json:"elapsed_ms"`
+	FailedSaves int    `json:"failed_saves,omitempty"`
+}
+
+type ServerHealthWorker struct {
+	river.WorkerDefaults[ServerHealthArgs]
+	db *gorm.DB
+}
+
+func (w *ServerHealthWorker) Timeout(*river.Job[ServerHealthArgs]) time.Duration {
+	return 10 * time.Minute
+}
+
+func (w *ServerHealthWorker) Work(ctx context.Context, j *river.Job[ServerHealthArgs]) error {
+	db := w.db
+	start := time.Now()
+
+	var servers []models.Server
+	if err := db.Preload("SshKey").
+		Where("status IN ?", []string{serverStatusReady, serverStatusUnreachable}).
+		Order("id").
+		Find(&servers).Error; err != nil {
+		return fmt.Errorf("list servers: %w", err)
+	}
+
+	down, up := healthThresholds()
+	res := ServerHealthResult{Status: "ok", Probed: len(servers)}
+	var mu sync.Mutex
+
+	// Probes are nearly all waiting on the network, so a fixed number of
+	// goroutines is what bounds the load on bastions, not the queue.
+	sem := make(chan struct{}, healthConcurrency())
+	var wg sync.WaitGroup
+	for i := range servers {
+		s := &servers[i]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			latency, perr := probeServer(ctx, db, s)
+			if ctx.Err() != nil {
+				// The sweep itself is being cancelled; a probe cut short by
+				// that says nothing about the host.
+				return
+			}
+			next, err := recordProbe(db, s, latency, perr, down, up)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				res.FailedSaves++
+				log.Error().Err(err).Str("server_id", s.ID.String()).Msg("[health] could not record probe")
+				return
+			}
+			if perr == nil {
+				res.Reachable++
+			}
+			switch {
+			case s.Status == serverStatusReady && next == serverStatusUnreachable:
+				res.WentDown++
+				log.Warn().Err(perr).Str("server_id", s.ID.String()).Str("host", s.Hostname).Msg("[health] server unreachable")
+			case s.Status == serverStatusUnreachable && next == serverStatusReady:
+				res.Recovered++
+				log.Info().Str("server_id", s.ID.String()).Str("host", s.Hostname).Msg("[health] server reachable again")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	degraded, err := reconcileClusterHealth(db)
+	if err != nil {
+		return fmt.Errorf("cluster health: %w", err)
+	}
+	res.Degraded = degraded
+	res.ElapsedMs = time.Since(start).Milliseconds()
+
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[health] could not record output")
+	}
+	return nil
+}
+
+// probeServer logs in to s and returns how long that took. A bastion, or any
+// server outside a cluster, is dialled directly. A cluster node is always
+// probed through its bastion even if it has a public IP: that is the path
+// every other job takes to it, so it is the one whose health matters.
+func probeServer(ctx context.Context, db *gorm.DB, s *models.Server) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*sshDialTimeout)
+	defer cancel()
+
+	signer, err := signerForKey(db, &s.SshKey)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	var c *serverSSHClient
+	if s.Role == "bastion" {
+		c, err = dialServerSSH(ctx, db, s, signer)
+	} else {
+		bastion, berr := findServerBastion(db, s.ID)
+		switch {
+		case berr == nil:
+			c, err = dialServerSSHVia(ctx, db, s, bastion, signer)
+		case errors.Is(berr, errNoBastion):
+			c, err = dialServerSSH(ctx, db, s, signer)
+		default:
+			return 0, berr
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	return time.Since(start), nil
+}
+
+// recordProbe stores the outcome of one probe and applies the state change
+// nextHealthStatus decides on. The update is conditional on the status the
+// sweep read, so a server that was meanwhile moved elsewhere — deleted, reset
+// to pending for a re-bootstrap — is left alone.
+func recordProbe(db *gorm.DB, s *models.Server, latency time.Duration, perr error, down, up int) (string, error) {
+	now := time.Now()
+	failures, successes := s.ProbeFailures, s.ProbeSuccesses
+	updates := map[string]any{"last_probe_at": now}
+	if perr == nil {
+		failures, successes = 0, successes+1
+		updates["last_seen_at"] = now
+		updates["probe_latency_ms"] = latency.Milliseconds()
+		updates["probe_error"] = ""
+	} else {
+		failures, successes = failures+1, 0
+		updates["probe_error"] = perr.Error()
+	}
+	updates["probe_failures"] = failures
+	updates["probe_successes"] = successes
+
+	next := nextHealthStatus(s.Status, failures, successes, down, up)
+	if next != s.Status {
+		updates["status"] = next
+		updates["updated_at"] = now
+	}
+
+	err := db.Model(&models.Server{}).
+		Where("id = ? AND status = ?", s.ID, s.Status).
+		Updates(updates).Error
+	return next, err
+}
+
+// nextHealthStatus is the hysteresis: a ready server is only declared
+// unreachable after down consecutive failed probes, and an unreachable one
+// only comes back after up consecutive successes, so a single dropped packet
+// or a bastion restart does not flap every node in a cluster.
+func nextHealthStatus(status string, failures, successes, down, up int) string {
+	switch status {
+	case serverStatusReady:
+		if failures >= down {
+			return serverStatusUnreachable
+		}
+	case serverStatusUnreachable:
+		if successes >= up {
+			return serverStatusReady
+		}
+	}
+	return status
+}
+
+// reconcileClusterHealth flags every cluster with an unreachable node or
+// bastion as degraded and clears the flag on the rest. It returns how many
+// clusters are degraded.
+func reconcileClusterHealth(db *gorm.DB) (int, error) {
+	type row struct {
+		ClusterID uuid.UUID
+		Down      int
+	}
+	var rows []row
+	if err := db.Raw(`
+		SELECT cluster_id, COUNT(DISTINCT server_id) AS down
+		FROM (
+			SELECT cnp.cluster_id, ns.server_id
+			FROM cluster_node_pools cnp
+			JOIN node_servers ns ON ns.node_pool_id = cnp.node_pool_id
+			JOIN servers s ON s.id = ns.server_id
+			WHERE s.status = ?
+			UNION ALL
+			SELECT c.id, c.bastion_server_id
+			FROM clusters c
+			JOIN servers s ON s.id = c.bastion_server_id
+			WHERE s.status = ?
+		) u
+		GROUP BY cluster_id`, serverStatusUnreachable, serverStatusUnreachable).
+		Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ClusterID)
+		reason := fmt.Sprintf("%d unreachable server(s)", r.Down)
+		if err := db.Model(&models.Cluster{}).
+			Where("id = ? AND (NOT degraded OR degraded_reason <> ?)", r.ClusterID, reason).
+			Updates(map[string]any{"degraded": true, "degraded_reason": reason}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	clear := db.Model(&models.Cluster{}).Where("degraded")
+	if len(ids) > 0 {
+		clear = clear.Where("id NOT IN ?", ids)
+	}
+	if err := clear.Updates(map[string]any{"degraded": false, "degraded_reason": ""}).Error; err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+func healthThresholds() (down, up int) {
+	down, up = 3, 2
+	if n := viper.GetInt("server_health.failures_to_unreachable"); n > 0 {
+		down = n
+	}
+	if n := viper.GetInt("server_health.successes_to_ready"); n > 0 {
+		up = n
+	}
+	return down, up
+}
+
+func healthConcurrency() int {
+	if n := viper.GetInt("server_health.concurrency"); n > 0 {
+		return n
+	}
+	return 20
+}
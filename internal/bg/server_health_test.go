@@ -0,0 +1,26 @@
+package bg
+
+import "testing"
+
+func TestNextHealthStatus(t *testing.T) {
+	cases := []struct {
+		name                string
+		status              string
+		failures, successes int
+		want                string
+	}{
+		{"ready stays ready below threshold", "ready", 2, 0, "ready"},
+		{"ready goes unreachable at threshold", "ready", 3, 0, "unreachable"},
+		{"unreachable stays on first success", "unreachable", 0, 1, "unreachable"},
+		{"unreachable recovers at threshold", "unreachable", 0, 2, "ready"},
+		{"unreachable stays while failing", "unreachable", 9, 0, "unreachable"},
+		{"other statuses are never touched", "provisioning", 9, 0, "provisioning"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := nextHealthStatus(c.status, c.failures, c.successes, 3, 2); got != c.want {
+				t.Errorf("nextHealthStatus(%q, %d, %d) = %q, want %q", c.status, c.failures, c.successes, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package bg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/sshca"
+	"github.com/google/uuid"
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// SSHCAInstallArgs makes servers trust the org's SSH user CA. An empty
+// ServerIDs means every ready server in the org.
+type SSHCAInstallArgs struct {
+	OrgID     uuid.UUID   `json:"org_id"`
+	ServerIDs []uuid.UUID `json:"server_ids,omitempty"`
+}
+
+func (SSHCAInstallArgs) Kind() string { return "ssh_ca_install" }
+
+func (SSHCAInstallArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: QueueClusters, MaxAttempts: 1}
+}
+
+type SSHCAInstallResult struct {
+	Status    string      `json:"status"`
+	Installed []uuid.UUID `json:"installed"`
+	Failed    []uuid.UUID `json:"failed"`
+}
+
+type SSHCAInstallWorker struct {
+	river.WorkerDefaults[SSHCAInstallArgs]
+	db *gorm.DB
+}
+
+func (w *SSHCAInstallWorker) Timeout(*river.Job[SSHCAInstallArgs]) time.Duration {
+	return time.Hour
+}
+
+func (w *SSHCAInstallWorker) Work(ctx context.Context, j *river.Job[SSHCAInstallArgs]) error {
+	db := w.db
+
+	ca, err := sshca.EnsureOrgCA(db, j.Args.OrgID)
+	if err != nil {
+		return fmt.Errorf("org ca: %w", err)
+	}
+
+	q := db.Preload("SshKey").
+		Where("organization_id = ? AND status = ?", j.Args.OrgID, "ready")
+	if len(j.Args.ServerIDs) > 0 {
+		q = q.Where("id IN ?", j.Args.ServerIDs)
+	}
+	var servers []models.Server
+	if err := q.Order("CASE WHEN role = 'bastion' THEN 0 ELSE 1 END, created_at").Find(&servers).Error; err != nil {
+		return fmt.Errorf("list servers: %w", err)
+	}
+
+	res := SSHCAInstallResult{Status: "ok", Installed: []uuid.UUID{}, Failed: []uuid.UUID{}}
+	for i := range servers {
+		if ctx.Err() != nil {
+			break
+		}
+		s := &servers[i]
+		sink := NewLogSink(db, j.ID, s.OrganizationID, models.JobLogSubjectServer, s.ID)
+		sink.System("installing ssh user ca " + ca.Fingerprint)
+		if err := installUserCA(ctx, db, s, ca); err != nil {
+			sink.System("ssh ca install failed: " + err.Error())
+			log.Error().Err(err).Str("server_id", s.ID.String()).Msg("[ssh_ca] install failed")
+			res.Failed = append(res.Failed, s.ID)
+		} else {
+			sink.System("ssh ca trusted; principals file admits " + sshca.AdminPrincipal + " as " + s.SSHUser)
+			res.Installed = append(res.Installed, s.ID)
+		}
+		_ = sink.Close()
+	}
+	if len(res.Failed) > 0 {
+		res.Status = "partial"
+	}
+
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[ssh_ca] could not record output")
+	}
+	return nil
+}
+
+// installUserCA connects to s with its own key and writes the CA trust
+// configuration.
+func installUserCA(ctx context.Context, db *gorm.DB, s *models.Server, ca *models.SshCertificateAuthority) error {
+	cmd, err := trustUserCACmd(ca.PublicKey, s.SSHUser)
+	if err != nil {
+		return err
+	}
+	signer, err := signerForKey(db, &s.SshKey)
+	if err != nil {
+		return err
+	}
+	c, err := dialServerSSH(ctx, db, s, signer)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	if out, err := runSSHCommand(ctx, c.Client, cmd); err != nil {
+		return wrapSSHError(err, out)
+	}
+	return nil
+}
+
+// sshUserPattern is the POSIX portable user name set. The user name is
+// written into a path and a shell command, so anything else is refused rather
+// than quoted.
+var sshUserPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// trustUserCACmd writes the CA public key, a principals file admitting
+// sshca.AdminPrincipal as sshUser, and an sshd drop-in pointing at both.
+//
+// A separate drop-in rather than an edit to 10-bastion.conf: the bootstrap
+// rewrites that file wholesale, and this also has to work on cluster nodes
+// that never ran the bastion bootstrap. AuthorizedPrincipalsFile only governs
+// certificate logins, so existing authorized_keys access is untouched.
+func trustUserCACmd(caPub, sshUser string) (string, error) {
+	if !sshUserPattern.MatchString(sshUser) {
+		return "", fmt.Errorf("refusing to configure ssh user %q", sshUser)
+	}
+	if strings.ContainsAny(caPub, "'\n") {
+		return "", fmt.Errorf("malformed ca public key")
+	}
+	return fmt.Sprintf(`set -eu
+confd=/etc/ssh/sshd_config.d
+if ! sudo test -d "$confd"; then
+  echo "FATAL: $confd does not exist, so the CA drop-in cannot apply" >&2
+  exit 1
+fi
+sudo install -d -m 0755 /etc/ssh/autoglue_principals
+printf '%%s\n' '%[1]s' | sudo tee /etc/ssh/autoglue_user_ca.pub >/dev/null
+sudo chmod 0644 /etc/ssh/autoglue_user_ca.pub
+printf '%%s\n' '%[3]s' | sudo tee /etc/ssh/autoglue_principals/%[2]s >/dev/null
+sudo chmod 0644 /etc/ssh/autoglue_principals/%[2]s
+sudo tee "$confd/20-autoglue-ca.conf" >/dev/null <<'EOF'
+# SSH user CA. Managed by autoglue; local edits are overwritten.
+TrustedUserCAKeys /etc/ssh/autoglue_user_ca.pub
+AuthorizedPrincipalsFile /etc/ssh/autoglue_principals/%%u
+EOF
+sudo chmod 0644 "$confd/20-autoglue-ca.conf"
+sshd_bin=""
+for p in /usr/sbin/sshd /sbin/sshd /usr/local/sbin/sshd; do
+  if sudo test -x "$p"; then sshd_bin="$p"; break; fi
+done
+if [ -z "$sshd_bin" ]; then
+  echo "FATAL: cannot locate sshd to validate the CA drop-in" >&2
+  exit 1
+fi
+sudo "$sshd_bin" -t
+sudo systemctl reload ssh 2>/dev/null || sudo systemctl reload sshd 2>/dev/null || \
+  sudo systemctl is-active --quiet ssh.socket 2>/dev/null
+`, caPub, sshUser, sshca.AdminPrincipal), nil
+}
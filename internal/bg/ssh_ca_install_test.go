@@ -0,0 +1,37 @@
+package bg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrustUserCACmd(t *testing.T) {
+	const pub = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample autoglue-user-ca"
+
+	cmd, err := trustUserCACmd(pub, "ubuntu")
+	if err != nil {
+		t.Fatalf("trustUserCACmd: %v", err)
+	}
+	for _, want := range []string{
+		"TrustedUserCAKeys /etc/ssh/autoglue_user_ca.pub",
+		"AuthorizedPrincipalsFile /etc/ssh/autoglue_principals/%u",
+		"/etc/ssh/autoglue_principals/ubuntu",
+		"'autoglue-admin'",
+		"'" + pub + "'",
+		"-t",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command missing %q", want)
+		}
+	}
+
+	// The user name lands in a path and a shell command unquoted.
+	for _, bad := range []string{"", "root; rm -rf /", "../etc", "a b", "$(id)"} {
+		if _, err := trustUserCACmd(pub, bad); err == nil {
+			t.Errorf("user %q accepted", bad)
+		}
+	}
+	if _, err := trustUserCACmd("ssh-ed25519 AAAA' ; reboot '", "ubuntu"); err == nil {
+		t.Error("public key containing a quote accepted")
+	}
+}
@@ -0,0 +1,277 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// SSHKeyRotateArgs moves every server off OldKeyID and onto NewKeyID. The
+// replacement key already exists when this is enqueued: the API generates it
+// so the caller gets its id back immediately.
+type SSHKeyRotateArgs struct {
+	OrgID    uuid.UUID `json:"org_id"`
+	OldKeyID uuid.UUID `json:"old_key_id" river:"unique"`
+	NewKeyID uuid.UUID `json:"new_key_id"`
+}
+
+func (SSHKeyRotateArgs) Kind() string { return "ssh_key_rotate" }
+
+func (SSHKeyRotateArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue: QueueClusters,
+		// Not retried: a second attempt would find half the servers already
+		// moved, which is handled, but it would also re-run against hosts that
+		// failed for a reason a retry does not fix. The operator re-rotates.
+		MaxAttempts: 1,
+		// One rotation per key at a time, keyed on the old key alone: two
+		// concurrent rotations would each append their own replacement and
+		// then race to strip the old key out from under the other's verify.
+		UniqueOpts: river.UniqueOpts{
+			ByArgs: true,
+			ByState: []rivertype.JobState{
+				rivertype.JobStateAvailable, rivertype.JobStateScheduled,
+				rivertype.JobStateRunning, rivertype.JobStateRetryable,
+				rivertype.JobStatePending,
+			},
+		},
+	}
+}
+
+type SSHKeyRotateServerResult struct {
+	ServerID uuid.UUID `json:"server_id"`
+	Status   string    `json:"status"`
+	Step     string    `json:"step,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type SSHKeyRotateResult struct {
+	Status    string                     `json:"status"`
+	OldKeyID  uuid.UUID                  `json:"old_key_id"`
+	NewKeyID  uuid.UUID                  `json:"new_key_id"`
+	Rotated   int                        `json:"rotated"`
+	Failed    int                        `json:"failed"`
+	Servers   []SSHKeyRotateServerResult `json:"servers"`
+	ElapsedMs int                        `json:"elapsed_ms"`
+}
+
+type SSHKeyRotateWorker struct {
+	river.WorkerDefaults[SSHKeyRotateArgs]
+	db *gorm.DB
+}
+
+// Timeout covers every server on the key, one after another. Each host is a
+// handful of short commands, so this is generous even for a large fleet.
+func (w *SSHKeyRotateWorker) Timeout(*river.Job[SSHKeyRotateArgs]) time.Duration {
+	return 2 * time.Hour
+}
+
+func (w *SSHKeyRotateWorker) Work(ctx context.Context, j *river.Job[SSHKeyRotateArgs]) error {
+	db := w.db
+	args := j.Args
+	start := time.Now()
+
+	var oldKey, newKey models.SshKey
+	if err := db.Where("id = ? AND organization_id = ?", args.OldKeyID, args.OrgID).First(&oldKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn().Str("ssh_key_id", args.OldKeyID.String()).Msg("[ssh_rotate] old key vanished before rotation")
+			return nil
+		}
+		return err
+	}
+	if err := db.Where("id = ? AND organization_id = ?", args.NewKeyID, args.OrgID).First(&newKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn().Str("ssh_key_id", args.NewKeyID.String()).Msg("[ssh_rotate] replacement key vanished before rotation")
+			return nil
+		}
+		return err
+	}
+
+	oldSigner, err := signerForKey(db, &oldKey)
+	if err != nil {
+		return fmt.Errorf("old key: %w", err)
+	}
+	newSigner, err := signerForKey(db, &newKey)
+	if err != nil {
+		return fmt.Errorf("new key: %w", err)
+	}
+
+	// Bastions first. A private node is reached through its bastion, and
+	// rotating the bastion up front means every later hop already runs on the
+	// key the bastion will be left with.
+	var servers []models.Server
+	if err := db.
+		Where("organization_id = ? AND ssh_key_id = ?", args.OrgID, args.OldKeyID).
+		Order("CASE WHEN role = 'bastion' THEN 0 ELSE 1 END, created_at").
+		Find(&servers).Error; err != nil {
+		return fmt.Errorf("list servers: %w", err)
+	}
+
+	res := SSHKeyRotateResult{
+		Status:   "ok",
+		OldKeyID: args.OldKeyID,
+		NewKeyID: args.NewKeyID,
+		Servers:  make([]SSHKeyRotateServerResult, 0, len(servers)),
+	}
+
+	for i := range servers {
+		if ctx.Err() != nil {
+			break
+		}
+		r := rotateServerKey(ctx, db, j.ID, &servers[i], &oldKey, &newKey, oldSigner, newSigner)
+		if r.Status == "ok" {
+			res.Rotated++
+		} else {
+			res.Failed++
+		}
+		res.Servers = append(res.Servers, r)
+	}
+
+	if res.Failed > 0 {
+		res.Status = "partial"
+	}
+	res.ElapsedMs = int(time.Since(start).Milliseconds())
+
+	log.Info().
+		Str("old_key_id", args.OldKeyID.String()).
+		Str("new_key_id", args.NewKeyID.String()).
+		Int("rotated", res.Rotated).
+		Int("failed", res.Failed).
+		Msg("[ssh_rotate] rotation finished")
+
+	if err := river.RecordOutput(ctx, res); err != nil {
+		log.Warn().Err(err).Msg("[ssh_rotate] could not record output")
+	}
+	return nil
+}
+
+// rotateServerKey moves one server from oldKey to newKey. The order is what
+// makes it safe to stop anywhere: the old key is only removed once a login
+// with the new one has succeeded and the row points at it, so a failure at
+// any step leaves a host that the row's key can still reach.
+func rotateServerKey(
+	ctx context.Context,
+	db *gorm.DB,
+	jobID int64,
+	s *models.Server,
+	oldKey, newKey *models.SshKey,
+	oldSigner, newSigner ssh.Signer,
+) SSHKeyRotateServerResult {
+	sink := NewLogSink(db, jobID, s.OrganizationID, models.JobLogSubjectServer, s.ID)
+	defer func() { _ = sink.Close() }()
+
+	out := SSHKeyRotateServerResult{ServerID: s.ID}
+	fail := func(step string, err error) SSHKeyRotateServerResult {
+		sink.System("key rotation failed at " + step + ": " + err.Error())
+		log.Error().Err(err).Str("server_id", s.ID.String()).Str("step", step).Msg("[ssh_rotate] server failed")
+		out.Status = "failed"
+		out.Step = step
+		out.Error = err.Error()
+		return out
+	}
+
+	sink.System(fmt.Sprintf("rotating ssh key %s -> %s on %s (%s)", oldKey.Fingerprint, newKey.Fingerprint, s.ID, s.Hostname))
+
+	// 1) Append the new key using the old one.
+	{
+		c, err := dialServerSSH(ctx, db, s, oldSigner)
+		if err != nil {
+			return fail("connect_old", err)
+		}
+		sink.System("connected with current key via " + c.Via())
+		outStr, err := runSSHCommand(ctx, c.Client, appendAuthorizedKeyCmd(newKey.PublicKey))
+		_ = c.Close()
+		if err != nil {
+			return fail("append_new", wrapSSHError(err, outStr))
+		}
+		sink.System("appended new public key to authorized_keys")
+	}
+
+	// 2) Prove the new key works before anything depends on it.
+	c, err := dialServerSSH(ctx, db, s, newSigner)
+	if err != nil {
+		return fail("verify_new", err)
+	}
+	defer func() { _ = c.Close() }()
+	if outStr, err := runSSHCommand(ctx, c.Client, "true"); err != nil {
+		return fail("verify_new", wrapSSHError(err, outStr))
+	}
+	sink.System("verified login with new key")
+
+	// 3) Repoint the row. Guarded on the old key so a concurrent manual edit
+	// is not overwritten.
+	upd := db.Model(&models.Server{}).
+		Where("id = ? AND ssh_key_id = ?", s.ID, oldKey.ID).
+		Updates(map[string]any{"ssh_key_id": newKey.ID, "updated_at": time.Now()})
+	if upd.Error != nil {
+		return fail("repoint", upd.Error)
+	}
+	if upd.RowsAffected == 0 {
+		return fail("repoint", fmt.Errorf("server no longer uses key %s", oldKey.ID))
+	}
+	sink.System("server now uses key " + newKey.ID.String())
+
+	// 4) Only now retire the old key, over the new key's session.
+	if outStr, err := runSSHCommand(ctx, c.Client, removeAuthorizedKeyCmd(oldKey.PublicKey)); err != nil {
+		// The server is already on the new key; a leftover line in
+		// authorized_keys is worth reporting but is not a failed rotation.
+		sink.System("warning: could not remove old key from authorized_keys: " + wrapSSHError(err, outStr).Error())
+		out.Status = "ok"
+		out.Step = "remove_old"
+		out.Error = err.Error()
+		return out
+	}
+	sink.System("removed old public key from authorized_keys")
+
+	out.Status = "ok"
+	return out
+}
+
+// authorizedKeyBody returns the "type base64" part of an authorized_keys
+// line, dropping any comment. Matching on this rather than the whole line is
+// what finds the key again when someone has edited its comment on the host.
+func authorizedKeyBody(pub string) string {
+	f := strings.Fields(pub)
+	if len(f) < 2 {
+		return strings.TrimSpace(pub)
+	}
+	return f[0] + " " + f[1]
+}
+
+// appendAuthorizedKeyCmd adds pub to authorized_keys unless it is already
+// there, so re-running a rotation is harmless. The key is interpolated inside
+// single quotes: its type and base64 body cannot contain one, and the comment
+// is dropped.
+func appendAuthorizedKeyCmd(pub string) string {
+	body := authorizedKeyBody(pub)
+	return fmt.Sprintf(`set -eu
+umask 077
+mkdir -p "$HOME/.ssh"
+touch "$HOME/.ssh/authorized_keys"
+grep -qF '%[1]s' "$HOME/.ssh/authorized_keys" || printf '%%s autoglue\n' '%[1]s' >> "$HOME/.ssh/authorized_keys"
+`, body)
+}
+
+// removeAuthorizedKeyCmd strips every line carrying pub. It rewrites the file
+// in place with cat rather than mv so ownership, mode and any SELinux label on
+// authorized_keys survive.
+func removeAuthorizedKeyCmd(pub string) string {
+	body := authorizedKeyBody(pub)
+	return fmt.Sprintf(`set -eu
+f="$HOME/.ssh/authorized_keys"
+tmp="$(mktemp)"
+grep -vF '%[1]s' "$f" > "$tmp" || true
+cat "$tmp" > "$f"
+rm -f "$tmp"
+`, body)
+}
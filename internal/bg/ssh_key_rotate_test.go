@@ -0,0 +1,37 @@
+package bg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuthorizedKeyBodyDropsComment(t *testing.T) {
+	cases := map[string]string{
+		"ssh-ed25519 AAAAC3Nza deploy@autoglue":     "ssh-ed25519 AAAAC3Nza",
+		"ssh-rsa AAAAB3Nza":                         "ssh-rsa AAAAB3Nza",
+		"  ssh-rsa   AAAAB3Nza   two word comment ": "ssh-rsa AAAAB3Nza",
+	}
+	for in, want := range cases {
+		if got := authorizedKeyBody(in); got != want {
+			t.Errorf("authorizedKeyBody(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// The remote commands interpolate the key inside single quotes. That is only
+// safe because the comment — the one free-text part of the line — is dropped.
+func TestAuthorizedKeyCommandsNeverCarryTheComment(t *testing.T) {
+	pub := "ssh-ed25519 AAAAC3Nza it's-mine"
+
+	for name, cmd := range map[string]string{
+		"append": appendAuthorizedKeyCmd(pub),
+		"remove": removeAuthorizedKeyCmd(pub),
+	} {
+		if strings.Contains(cmd, "it's-mine") {
+			t.Errorf("%s command carries the key comment:\n%s", name, cmd)
+		}
+		if !strings.Contains(cmd, "'ssh-ed25519 AAAAC3Nza'") {
+			t.Errorf("%s command does not match on the key body:\n%s", name, cmd)
+		}
+	}
+}
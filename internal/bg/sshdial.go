@@ -0,0 +1,211 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// sshDialTimeout bounds the TCP connect and the handshake of a single hop.
+const sshDialTimeout = 30 * time.Second
+
+// serverSSHClient is an SSH connection to one server. For a private-only
+// cluster node it is tunnelled through the cluster's bastion, and Close tears
+// down both hops: leaving the bastion connection open would leak one TCP
+// connection per node for every sweep.
+type serverSSHClient struct {
+	*ssh.Client
+	jump *ssh.Client
+}
+
+func (c *serverSSHClient) Close() error {
+	err := c.Client.Close()
+	if c.jump != nil {
+		_ = c.jump.Close()
+	}
+	return err
+}
+
+// Via reports how the connection was made, for log lines.
+func (c *serverSSHClient) Via() string {
+	if c.jump != nil {
+		return "bastion"
+	}
+	return "direct"
+}
+
+// signerForKey decrypts an org ssh key and parses it into a signer.
+func signerForKey(db *gorm.DB, k *models.SshKey) (ssh.Signer, error) {
+	priv, err := utils.DecryptForOrg(k.OrganizationID, k.EncryptedPrivateKey, k.PrivateIV, k.PrivateTag, db)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt key %s: %w", k.ID, err)
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(priv))
+	if err != nil {
+		return nil, fmt.Errorf("parse key %s: %w", k.ID, err)
+	}
+	return signer, nil
+}
+
+// dialServerSSH connects to s as s.SSHUser, authenticating with signer.
+//
+// signer is a parameter rather than derived from s.SshKey because key rotation
+// has to log in to the same host with two different keys. The host key is
+// always checked against the server row via makeDBHostKeyCallback, on both
+// hops.
+//
+// A server with a public IP is dialled directly. Otherwise it must belong to
+// a node pool attached to a cluster with a bastion, and the connection is
+// tunnelled through that bastion to the private IP — the same path the
+// generated ssh-config takes from the bastion.
+func dialServerSSH(ctx context.Context, db *gorm.DB, s *models.Server, signer ssh.Signer) (*serverSSHClient, error) {
+	if s.PublicIPAddress != nil && strings.TrimSpace(*s.PublicIPAddress) != "" {
+		host := net.JoinHostPort(strings.TrimSpace(*s.PublicIPAddress), "22")
+		c, err := dialSSHDirect(ctx, db, s, host, signer)
+		if err != nil {
+			return nil, err
+		}
+		return &serverSSHClient{Client: c}, nil
+	}
+
+	if strings.TrimSpace(s.PrivateIPAddress) == "" {
+		return nil, fmt.Errorf("server %s has neither a public nor a private ip", s.ID)
+	}
+
+	bastion, err := findServerBastion(db, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	return dialServerSSHVia(ctx, db, s, bastion, signer)
+}
+
+// dialServerSSHVia tunnels to s's private IP through bastion, whatever public
+// address s may also have. bastion must have its SshKey loaded, as
+// findServerBastion returns it.
+func dialServerSSHVia(ctx context.Context, db *gorm.DB, s, bastion *models.Server, signer ssh.Signer) (*serverSSHClient, error) {
+	if strings.TrimSpace(s.PrivateIPAddress) == "" {
+		return nil, fmt.Errorf("server %s has no private ip", s.ID)
+	}
+	bastionSigner, err := signerForKey(db, &bastion.SshKey)
+	if err != nil {
+		return nil, fmt.Errorf("bastion %s: %w", bastion.ID, err)
+	}
+	jump, err := dialSSHDirect(ctx, db, bastion, net.JoinHostPort(*bastion.PublicIPAddress, "22"), bastionSigner)
+	if err != nil {
+		return nil, fmt.Errorf("bastion %s: %w", bastion.ID, err)
+	}
+
+	target := net.JoinHostPort(strings.TrimSpace(s.PrivateIPAddress), "22")
+	conn, err := jump.DialContext(ctx, "tcp", target)
+	if err != nil {
+		_ = jump.Close()
+		return nil, fmt.Errorf("dial %s via bastion: %w", target, err)
+	}
+
+	c, err := sshHandshake(conn, target, s, db, signer)
+	if err != nil {
+		_ = conn.Close()
+		_ = jump.Close()
+		return nil, err
+	}
+	return &serverSSHClient{Client: c, jump: jump}, nil
+}
+
+// dialSSHDirect opens a context-aware TCP connection to host and completes
+// the SSH handshake against s.
+func dialSSHDirect(ctx context.Context, db *gorm.DB, s *models.Server, host string, signer ssh.Signer) (*ssh.Client, error) {
+	dialer := &net.Dialer{Timeout: sshDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", host, err)
+	}
+	c, err := sshHandshake(conn, host, s, db, signer)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func sshHandshake(conn net.Conn, host string, s *models.Server, db *gorm.DB, signer ssh.Signer) (*ssh.Client, error) {
+	config := &ssh.ClientConfig{
+		User:            s.SSHUser,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: makeDBHostKeyCallback(db, s),
+		Timeout:         sshDialTimeout,
+	}
+
+	// ssh.ClientConfig.Timeout only covers the TCP dial, which has already
+	// happened; a deadline on the conn is what bounds a handshake that
+	// stalls halfway, as one through a dead bastion tunnel does.
+	_ = conn.SetDeadline(time.Now().Add(sshDialTimeout))
+	cc, chans, reqs, err := ssh.NewClientConn(conn, host, config)
+	if err != nil {
+		return nil, fmt.Errorf("ssh handshake %s: %w", host, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return ssh.NewClient(cc, chans, reqs), nil
+}
+
+// errNoBastion is returned when a private-only server cannot be reached
+// because none of its clusters has a bastion.
+var errNoBastion = errors.New("server has no public ip and no cluster bastion to reach it through")
+
+// findServerBastion returns the bastion of a cluster the server belongs to
+// through its node pools, with the bastion's key loaded. A server in several
+// clusters takes the first one with a ready bastion, ordered by cluster id so
+// repeated calls pick the same one.
+func findServerBastion(db *gorm.DB, serverID uuid.UUID) (*models.Server, error) {
+	var bastionIDs []uuid.UUID
+	if err := db.Raw(`
+		SELECT c.bastion_server_id
+		FROM clusters c
+		JOIN cluster_node_pools cnp ON cnp.cluster_id = c.id
+		JOIN node_servers ns ON ns.node_pool_id = cnp.node_pool_id
+		JOIN servers b ON b.id = c.bastion_server_id
+		WHERE ns.server_id = ? AND b.status = 'ready'
+		ORDER BY c.id`, serverID).
+		Scan(&bastionIDs).Error; err != nil {
+		return nil, fmt.Errorf("find bastion: %w", err)
+	}
+	if len(bastionIDs) == 0 {
+		return nil, errNoBastion
+	}
+
+	var b models.Server
+	if err := db.Preload("SshKey").Where("id = ?", bastionIDs[0]).First(&b).Error; err != nil {
+		return nil, fmt.Errorf("load bastion: %w", err)
+	}
+	if b.PublicIPAddress == nil || strings.TrimSpace(*b.PublicIPAddress) == "" {
+		return nil, fmt.Errorf("bastion %s missing public ip", b.ID)
+	}
+	return &b, nil
+}
+
+// runSSHCommand runs cmd in a fresh session and returns its combined output,
+// bounded to logMaxTailBytes. For short commands where streaming buys nothing.
+func runSSHCommand(ctx context.Context, c *ssh.Client, cmd string) (string, error) {
+	sess, err := c.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("session: %w", err)
+	}
+	defer sess.Close()
+
+	// A session does not observe ctx on its own; closing it is the only way
+	// to unblock a Wait on a command that never returns.
+	stop := context.AfterFunc(ctx, func() { _ = sess.Close() })
+	defer stop()
+
+	tail := &tailBuffer{max: logMaxTailBytes}
+	err = runSSHStreaming(sess, cmd, tail)
+	return tail.String(), err
+}
@@ -0,0 +1,93 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// Terminal is an interactive login shell on a server, reached the same way the
+// background jobs reach it: the server's own stored key, TOFU host keys, and
+// the cluster bastion when the server has no public address.
+//
+// It lives here rather than in handlers because this is where the dialing and
+// host key machinery is; the HTTP side only shuttles bytes.
+type Terminal struct {
+	client *serverSSHClient
+	sess   *ssh.Session
+
+	Stdin  io.WriteCloser
+	Stdout io.Reader
+}
+
+// OpenTerminal starts a login shell on s behind a PTY of the given size. s
+// must have SshKey loaded.
+func OpenTerminal(ctx context.Context, db *gorm.DB, s *models.Server, cols, rows int) (*Terminal, error) {
+	if s.SshKey.ID == uuid.Nil {
+		return nil, errors.New("server ssh key not loaded")
+	}
+	signer, err := signerForKey(db, &s.SshKey)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dialServerSSH(ctx, db, s, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := c.NewSession()
+	if err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("ssh session: %w", err)
+	}
+	t := &Terminal{client: c, sess: sess}
+
+	fail := func(step string, err error) (*Terminal, error) {
+		_ = t.Close()
+		return nil, fmt.Errorf("%s: %w", step, err)
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := sess.RequestPty("xterm-256color", rows, cols, modes); err != nil {
+		return fail("pty", err)
+	}
+	if t.Stdin, err = sess.StdinPipe(); err != nil {
+		return fail("stdin", err)
+	}
+	// With a PTY the remote side merges stderr into the terminal, so stdout
+	// is the whole screen.
+	if t.Stdout, err = sess.StdoutPipe(); err != nil {
+		return fail("stdout", err)
+	}
+	if err := sess.Shell(); err != nil {
+		return fail("shell", err)
+	}
+	return t, nil
+}
+
+// Via reports whether the session went through a bastion.
+func (t *Terminal) Via() string { return t.client.Via() }
+
+// Resize tells the remote PTY the browser window changed size.
+func (t *Terminal) Resize(cols, rows int) error {
+	return t.sess.WindowChange(rows, cols)
+}
+
+// Wait blocks until the remote shell exits.
+func (t *Terminal) Wait() error { return t.sess.Wait() }
+
+// Close ends the shell and both SSH hops. Safe to call more than once.
+func (t *Terminal) Close() error {
+	_ = t.sess.Close()
+	return t.client.Close()
+}
@@ -0,0 +1,73 @@
+// Package compute creates and deletes servers at cloud providers.
+//
+// A Provider only knows how to talk to one cloud's API; the server rows, the
+// credentials and the state machine around them live in internal/bg. Each
+// implementation takes its API base URL as a field so tests can point it at
+// an httptest fake of the provider.
+package compute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by Get and Delete for an instance the provider no
+// longer has. Delete callers usually treat it as success.
+var ErrNotFound = errors.New("instance not found")
+
+// ErrUnsupported is returned by New for a provider with no implementation.
+var ErrUnsupported = errors.New("compute provider not supported")
+
+// Spec describes the server to create. Size, Image and Location are the
+// provider's own identifiers (for Hetzner: cx22, ubuntu-24.04, fsn1).
+type Spec struct {
+	Name     string
+	Size     string
+	Image    string
+	Location string
+	// Network, when set, attaches the server to that private network so it
+	// gets a private address.
+	Network string
+	// SSHPublicKey is installed for the provider's default login user.
+	SSHPublicKey string
+	Labels       map[string]string
+}
+
+// Instance is a provider's view of one server.
+type Instance struct {
+	ID        string
+	Status    string
+	PublicIP  string
+	PrivateIP string
+	// Ready is true once the server is running with a public address. A
+	// private network address can trail that by a few seconds, so callers
+	// that asked for a Network also wait for PrivateIP.
+	Ready bool
+}
+
+type Provider interface {
+	// Name is the credential provider this implementation serves.
+	Name() string
+	// DefaultUser is the login user the SSH key is installed for.
+	DefaultUser() string
+	Create(ctx context.Context, spec Spec) (*Instance, error)
+	Get(ctx context.Context, id string) (*Instance, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// New returns the provider for a credential provider name, authenticated with
+// its API token.
+func New(provider, token string) (Provider, error) {
+	switch provider {
+	case "hetzner":
+		return NewHetzner(token), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, provider)
+	}
+}
+
+// Supported reports whether New has an implementation for provider.
+func Supported(provider string) bool {
+	return provider == "hetzner"
+}
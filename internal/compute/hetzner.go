@@ -0,0 +1,209 @@
+package compute
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const hetznerBaseURL = "https://api.hetzner.cloud/v1"
+
+// Hetzner talks to the Hetzner Cloud API.
+type Hetzner struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+func NewHetzner(token string) *Hetzner {
+	return &Hetzner{
+		BaseURL: hetznerBaseURL,
+		Token:   token,
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (h *Hetzner) Name() string { return "hetzner" }
+
+// DefaultUser is root: Hetzner installs the keys given at create time for
+// root only.
+func (h *Hetzner) DefaultUser() string { return "root" }
+
+type hetznerServer struct {
+	ID        int64  `json:"id"`
+	Status    string `json:"status"`
+	PublicNet struct {
+		IPv4 *struct {
+			IP string `json:"ip"`
+		} `json:"ipv4"`
+	} `json:"public_net"`
+	PrivateNet []struct {
+		Network int64  `json:"network"`
+		IP      string `json:"ip"`
+	} `json:"private_net"`
+}
+
+type hetznerSSHKey struct {
+	ID          int64  `json:"id"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// hetznerError is the body of every non-2xx response.
+type hetznerError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (h *Hetzner) Create(ctx context.Context, spec Spec) (*Instance, error) {
+	keyID, err := h.ensureSSHKey(ctx, spec.SSHPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]any{
+		"name":               spec.Name,
+		"server_type":        spec.Size,
+		"image":              spec.Image,
+		"ssh_keys":           []int64{keyID},
+		"start_after_create": true,
+	}
+	if spec.Location != "" {
+		body["location"] = spec.Location
+	}
+	if len(spec.Labels) > 0 {
+		body["labels"] = spec.Labels
+	}
+	if spec.Network != "" {
+		nid, err := strconv.ParseInt(spec.Network, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("hetzner network must be a numeric id, got %q", spec.Network)
+		}
+		body["networks"] = []int64{nid}
+	}
+
+	var out struct {
+		Server hetznerServer `json:"server"`
+	}
+	if err := h.do(ctx, http.MethodPost, "/servers", body, &out); err != nil {
+		return nil, fmt.Errorf("create server: %w", err)
+	}
+	return h.instance(out.Server), nil
+}
+
+func (h *Hetzner) Get(ctx context.Context, id string) (*Instance, error) {
+	var out struct {
+		Server hetznerServer `json:"server"`
+	}
+	if err := h.do(ctx, http.MethodGet, "/servers/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, err
+	}
+	return h.instance(out.Server), nil
+}
+
+func (h *Hetzner) Delete(ctx context.Context, id string) error {
+	return h.do(ctx, http.MethodDelete, "/servers/"+url.PathEscape(id), nil, nil)
+}
+
+func (h *Hetzner) instance(s hetznerServer) *Instance {
+	in := &Instance{ID: strconv.FormatInt(s.ID, 10), Status: s.Status}
+	if s.PublicNet.IPv4 != nil {
+		in.PublicIP = s.PublicNet.IPv4.IP
+	}
+	if len(s.PrivateNet) > 0 {
+		in.PrivateIP = s.PrivateNet[0].IP
+	}
+	in.Ready = s.Status == "running" && in.PublicIP != ""
+	return in
+}
+
+// ensureSSHKey returns the id of the project key matching pub, uploading it
+// first if the project does not have it yet. Hetzner refuses a second copy of
+// the same key, so looking it up by fingerprint is what makes this
+// idempotent across servers.
+func (h *Hetzner) ensureSSHKey(ctx context.Context, pub string) (int64, error) {
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pub))
+	if err != nil {
+		return 0, fmt.Errorf("parse ssh public key: %w", err)
+	}
+	fp := strings.TrimPrefix(ssh.FingerprintLegacyMD5(parsed), "MD5:")
+
+	var list struct {
+		SSHKeys []hetznerSSHKey `json:"ssh_keys"`
+	}
+	if err := h.do(ctx, http.MethodGet, "/ssh_keys?fingerprint="+url.QueryEscape(fp), nil, &list); err != nil {
+		return 0, fmt.Errorf("find ssh key: %w", err)
+	}
+	if len(list.SSHKeys) > 0 {
+		return list.SSHKeys[0].ID, nil
+	}
+
+	var created struct {
+		SSHKey hetznerSSHKey `json:"ssh_key"`
+	}
+	body := map[string]any{
+		"name":       "autoglue-" + strings.ReplaceAll(fp, ":", "")[:16],
+		"public_key": strings.TrimSpace(pub),
+	}
+	if err := h.do(ctx, http.MethodPost, "/ssh_keys", body, &created); err != nil {
+		return 0, fmt.Errorf("upload ssh key: %w", err)
+	}
+	return created.SSHKey.ID, nil
+}
+
+func (h *Hetzner) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(h.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+h.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var he hetznerError
+		if json.Unmarshal(raw, &he) == nil && he.Error.Code != "" {
+			return fmt.Errorf("hetzner %s %s: %s: %s", method, path, he.Error.Code, he.Error.Message)
+		}
+		return fmt.Errorf("hetzner %s %s: http %d", method, path, resp.StatusCode)
+	}
+	if out == nil || len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("hetzner %s %s: decode: %w", method, path, err)
+	}
+	return nil
+}
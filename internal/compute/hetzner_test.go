@@ -0,0 +1,199 @@
+package compute
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeHetzner is just enough of the Hetzner Cloud API for the provider: ssh
+// keys by fingerprint, and servers that come up running on the second GET.
+type fakeHetzner struct {
+	mu      sync.Mutex
+	keys    map[string]int64 // fingerprint -> id
+	servers map[string]int   // id -> GETs served
+	created []map[string]any
+	deleted []string
+}
+
+func newFakeHetzner(t *testing.T) (*fakeHetzner, *Hetzner) {
+	f := &fakeHetzner{keys: map[string]int64{}, servers: map[string]int{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	h := NewHetzner("tok")
+	h.BaseURL = srv.URL
+	return f, h
+}
+
+func (f *fakeHetzner) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer tok" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"code":"unauthorized","message":"unable to authenticate"}}`))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/ssh_keys":
+		list := []map[string]any{}
+		if id, ok := f.keys[r.URL.Query().Get("fingerprint")]; ok {
+			list = append(list, map[string]any{"id": id})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ssh_keys": list})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/ssh_keys":
+		var body struct {
+			PublicKey string `json:"public_key"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		pk, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(body.PublicKey))
+		fp := strings.TrimPrefix(ssh.FingerprintLegacyMD5(pk), "MD5:")
+		id := int64(100 + len(f.keys))
+		f.keys[fp] = id
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"ssh_key": map[string]any{"id": id}})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/servers":
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["server_type"] == "nope" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"error":{"code":"invalid_input","message":"server type nope not found"}}`))
+			return
+		}
+		f.created = append(f.created, body)
+		f.servers["42"] = 0
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"server":{"id":42,"status":"initializing","public_net":{"ipv4":null},"private_net":[]}}`))
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/servers/"):
+		id := strings.TrimPrefix(r.URL.Path, "/servers/")
+		n, ok := f.servers[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"not_found","message":"server not found"}}`))
+			return
+		}
+		f.servers[id] = n + 1
+		if n == 0 {
+			_, _ = w.Write([]byte(`{"server":{"id":42,"status":"starting","public_net":{"ipv4":{"ip":"203.0.113.7"}},"private_net":[]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"server":{"id":42,"status":"running","public_net":{"ipv4":{"ip":"203.0.113.7"}},"private_net":[{"network":7,"ip":"10.0.0.2"}]}}`))
+
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/servers/"):
+		id := strings.TrimPrefix(r.URL.Path, "/servers/")
+		if _, ok := f.servers[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"not_found","message":"server not found"}}`))
+			return
+		}
+		delete(f.servers, id)
+		f.deleted = append(f.deleted, id)
+		_, _ = w.Write([]byte(`{"action":{"id":1,"status":"running"}}`))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testPublicKey(t *testing.T) string {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(ssh.MarshalAuthorizedKey(sp))
+}
+
+func TestHetznerLifecycle(t *testing.T) {
+	f, h := newFakeHetzner(t)
+	ctx := context.Background()
+	pub := testPublicKey(t)
+
+	spec := Spec{Name: "w1", Size: "cx22", Image: "ubuntu-24.04", Location: "fsn1", Network: "7", SSHPublicKey: pub}
+	in, err := h.Create(ctx, spec)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if in.ID != "42" || in.Ready {
+		t.Fatalf("created = %+v", in)
+	}
+
+	body := f.created[0]
+	if body["server_type"] != "cx22" || body["image"] != "ubuntu-24.04" || body["location"] != "fsn1" {
+		t.Errorf("create body = %v", body)
+	}
+	if keys, _ := body["ssh_keys"].([]any); len(keys) != 1 || keys[0] != float64(100) {
+		t.Errorf("ssh_keys = %v", body["ssh_keys"])
+	}
+	if nets, _ := body["networks"].([]any); len(nets) != 1 || nets[0] != float64(7) {
+		t.Errorf("networks = %v", body["networks"])
+	}
+
+	// The same key is found by fingerprint rather than uploaded twice.
+	if _, err := h.Create(ctx, spec); err != nil {
+		t.Fatalf("second create: %v", err)
+	}
+	if len(f.keys) != 1 {
+		t.Errorf("keys uploaded = %d, want 1", len(f.keys))
+	}
+
+	in, err = h.Get(ctx, "42")
+	if err != nil || in.Status != "starting" || in.Ready {
+		t.Fatalf("first get = %+v, %v", in, err)
+	}
+	in, err = h.Get(ctx, "42")
+	if err != nil || !in.Ready || in.PublicIP != "203.0.113.7" || in.PrivateIP != "10.0.0.2" {
+		t.Fatalf("second get = %+v, %v", in, err)
+	}
+
+	if err := h.Delete(ctx, "42"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := h.Delete(ctx, "42"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete err = %v, want ErrNotFound", err)
+	}
+	if _, err := h.Get(ctx, "42"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get after delete err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestHetznerSurfacesAPIErrors(t *testing.T) {
+	_, h := newFakeHetzner(t)
+	ctx := context.Background()
+
+	_, err := h.Create(ctx, Spec{Name: "w1", Size: "nope", Image: "ubuntu-24.04", SSHPublicKey: testPublicKey(t)})
+	if err == nil || !strings.Contains(err.Error(), "invalid_input: server type nope not found") {
+		t.Errorf("err = %v", err)
+	}
+
+	h.Token = "wrong"
+	if _, err := h.Get(ctx, "42"); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestNewRejectsUnsupportedProviders(t *testing.T) {
+	if _, err := New("digitalocean", "tok"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("err = %v, want ErrUnsupported", err)
+	}
+	if p, err := New("hetzner", "tok"); err != nil || p.Name() != "hetzner" {
+		t.Errorf("New(hetzner) = %v, %v", p, err)
+	}
+}
@@ -0,0 +1,409 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cloudflareBaseURL = "https://api.cloudflare.com/client/v4"
+
+// Cloudflare talks to the Cloudflare API v4 with an API token. Cloudflare
+// keeps one record per value rather than record sets, so a change is applied
+// record by record and a failure part way through a batch leaves the earlier
+// changes in place.
+type Cloudflare struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+func NewCloudflare(token string) *Cloudflare {
+	return &Cloudflare{
+		BaseURL: cloudflareBaseURL,
+		Token:   token,
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *Cloudflare) Name() string { return "cloudflare" }
+
+type cloudflareZone struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	NameServers []string `json:"name_servers,omitempty"`
+}
+
+type cloudflareRecord struct {
+	ID       string         `json:"id,omitempty"`
+	Type     string         `json:"type"`
+	Name     string         `json:"name"`
+	Content  string         `json:"content,omitempty"`
+	TTL      int64          `json:"ttl"`
+	Priority *int           `json:"priority,omitempty"`
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+// cloudflareEnvelope wraps every response.
+type cloudflareEnvelope struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo *struct {
+		Page       int `json:"page"`
+		TotalPages int `json:"total_pages"`
+	} `json:"result_info"`
+}
+
+func (c *Cloudflare) FindZone(ctx context.Context, domain string) (string, error) {
+	d := strings.TrimSuffix(Fqdn(domain), ".")
+	var zones []cloudflareZone
+	if _, err := c.do(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(d), nil, &zones); err != nil {
+		return "", err
+	}
+	for _, z := range zones {
+		if strings.EqualFold(z.Name, d) {
+			return z.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrZoneNotFound, d)
+}
+
+func (c *Cloudflare) GetZone(ctx context.Context, zoneID string) error {
+	status, err := c.do(ctx, http.MethodGet, "/zones/"+url.PathEscape(zoneID), nil, nil)
+	if status == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrZoneNotFound, zoneID)
+	}
+	return err
+}
+
+func (c *Cloudflare) NameServers(ctx context.Context, zoneID string) ([]string, error) {
+	var z cloudflareZone
+	status, err := c.do(ctx, http.MethodGet, "/zones/"+url.PathEscape(zoneID), nil, &z)
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrZoneNotFound, zoneID)
+	}
+	return z.NameServers, err
+}
+
+func (c *Cloudflare) GetRecord(ctx context.Context, zoneID, name, rrType string) (*Record, error) {
+	name, rrType = Fqdn(name), strings.ToUpper(rrType)
+	recs, err := c.list(ctx, zoneID, name, rrType)
+	if err != nil || len(recs) == 0 {
+		return nil, err
+	}
+	out := &Record{Name: name, Type: rrType, TTL: recs[0].TTL}
+	for _, r := range recs {
+		out.Values = append(out.Values, cloudflareValue(r))
+	}
+	return out, nil
+}
+
+func (c *Cloudflare) ListRecords(ctx context.Context, zoneID string) ([]Record, error) {
+	recs, err := c.list(ctx, zoneID, "", "")
+	if err != nil {
+		return nil, err
+	}
+	var sets recordSets
+	for _, r := range recs {
+		sets.add(r.Name, r.Type, r.TTL, cloudflareValue(r))
+	}
+	return sets.list(), nil
+}
+
+func (c *Cloudflare) Apply(ctx context.Context, zoneID string, changes []Change) error {
+	for _, ch := range changes {
+		var err error
+		switch {
+		case ch.Action == ActionUpsert && ch.Record.Alias != nil:
+			err = fmt.Errorf("%w: alias records need Route 53", ErrUnsupported)
+		case ch.Action == ActionUpsert:
+			err = c.upsert(ctx, zoneID, ch.Record)
+		case ch.Action == ActionDelete:
+			err = c.delete(ctx, zoneID, ch.Record)
+		default:
+			err = fmt.Errorf("unknown change action %q", ch.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s %s: %w", ch.Action, Fqdn(ch.Record.Name), strings.ToUpper(ch.Record.Type), err)
+		}
+	}
+	return nil
+}
+
+// upsert makes the records at name/type hold exactly rec's values. Records
+// whose value is already wanted are kept, the rest are rewritten in place
+// before any is created or deleted, so a CNAME is changed rather than
+// briefly doubled.
+func (c *Cloudflare) upsert(ctx context.Context, zoneID string, rec Record) error {
+	name, rrType := Fqdn(rec.Name), strings.ToUpper(rec.Type)
+	ttl := rec.TTL
+	if ttl <= 0 {
+		ttl = 1 // automatic
+	}
+
+	// Compare values the way Cloudflare will hand them back, so an MX
+	// target written without its trailing dot is not rewritten every time.
+	var order []string
+	want := map[string]cloudflareRecord{}
+	for _, v := range rec.Values {
+		body, err := cloudflareBody(name, rrType, v, ttl)
+		if err != nil {
+			return err
+		}
+		key := cloudflareValue(body)
+		if _, dup := want[key]; !dup {
+			want[key] = body
+			order = append(order, key)
+		}
+	}
+
+	cur, err := c.list(ctx, zoneID, name, rrType)
+	if err != nil {
+		return err
+	}
+	var stale []cloudflareRecord
+	for _, r := range cur {
+		key := cloudflareValue(r)
+		if _, ok := want[key]; !ok {
+			stale = append(stale, r)
+			continue
+		}
+		delete(want, key)
+		if r.TTL != ttl {
+			if _, err := c.do(ctx, http.MethodPatch, c.recordPath(zoneID, r.ID), map[string]any{"ttl": ttl}, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, key := range order {
+		body, ok := want[key]
+		if !ok {
+			continue
+		}
+		if len(stale) > 0 {
+			_, err = c.do(ctx, http.MethodPut, c.recordPath(zoneID, stale[0].ID), body, nil)
+			stale = stale[1:]
+		} else {
+			_, err = c.do(ctx, http.MethodPost, c.recordPath(zoneID, ""), body, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, r := range stale {
+		if _, err := c.do(ctx, http.MethodDelete, c.recordPath(zoneID, r.ID), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cloudflare) delete(ctx context.Context, zoneID string, rec Record) error {
+	cur, err := c.list(ctx, zoneID, Fqdn(rec.Name), strings.ToUpper(rec.Type))
+	if err != nil {
+		return err
+	}
+	for _, r := range cur {
+		status, err := c.do(ctx, http.MethodDelete, c.recordPath(zoneID, r.ID), nil, nil)
+		if err != nil && status != http.StatusNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// list returns the zone's records, only those at name and of rrType when
+// they are set.
+func (c *Cloudflare) list(ctx context.Context, zoneID, name, rrType string) ([]cloudflareRecord, error) {
+	q := url.Values{}
+	if name != "" {
+		q.Set("name", strings.TrimSuffix(name, "."))
+	}
+	if rrType != "" {
+		q.Set("type", rrType)
+	}
+	q.Set("per_page", "100")
+
+	var out []cloudflareRecord
+	for page := 1; ; page++ {
+		q.Set("page", strconv.Itoa(page))
+		var recs []cloudflareRecord
+		var env cloudflareEnvelope
+		if _, err := c.call(ctx, http.MethodGet, c.recordPath(zoneID, "")+"?"+q.Encode(), nil, &env); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(env.Result, &recs); err != nil {
+			return nil, fmt.Errorf("cloudflare list records: decode: %w", err)
+		}
+		out = append(out, recs...)
+		if env.ResultInfo == nil || page >= env.ResultInfo.TotalPages {
+			return out, nil
+		}
+	}
+}
+
+func (c *Cloudflare) recordPath(zoneID, recordID string) string {
+	p := "/zones/" + url.PathEscape(zoneID) + "/dns_records"
+	if recordID != "" {
+		p += "/" + url.PathEscape(recordID)
+	}
+	return p
+}
+
+// cloudflareValue is a record's value in the package's presentation form.
+// Cloudflare keeps the MX preference and the SRV and CAA fields apart from
+// the content.
+func cloudflareValue(r cloudflareRecord) string {
+	switch strings.ToUpper(r.Type) {
+	case "TXT":
+		return UnquoteTXT(r.Content)
+	case "MX":
+		if r.Priority != nil {
+			return fmt.Sprintf("%d %s", *r.Priority, Fqdn(r.Content))
+		}
+		return r.Content
+	case "CNAME", "NS":
+		return Fqdn(r.Content)
+	case "SRV":
+		if r.Data != nil {
+			return fmt.Sprintf("%v %v %v %s", r.Data["priority"], r.Data["weight"], r.Data["port"], Fqdn(fmt.Sprint(r.Data["target"])))
+		}
+	case "CAA":
+		if r.Data != nil {
+			return fmt.Sprintf("%v %v %s", r.Data["flags"], r.Data["tag"], strconv.Quote(fmt.Sprint(r.Data["value"])))
+		}
+	}
+	return r.Content
+}
+
+// cloudflareTarget is an MX or SRV target without its trailing dot, except
+// for the root, which Cloudflare takes as ".".
+func cloudflareTarget(name string) string {
+	if name == "." {
+		return name
+	}
+	return strings.TrimSuffix(name, ".")
+}
+
+// cloudflareBody is the create or replace body for one value.
+func cloudflareBody(name, rrType, value string, ttl int64) (cloudflareRecord, error) {
+	body := cloudflareRecord{Type: rrType, Name: strings.TrimSuffix(name, "."), TTL: ttl}
+	fields := strings.Fields(value)
+	bad := func() (cloudflareRecord, error) {
+		return cloudflareRecord{}, fmt.Errorf("invalid %s value %q", rrType, value)
+	}
+	switch rrType {
+	case "TXT":
+		body.Content = QuoteTXT(value)
+	case "MX":
+		if len(fields) != 2 {
+			return bad()
+		}
+		pref, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return bad()
+		}
+		body.Priority = &pref
+		body.Content = cloudflareTarget(fields[1])
+	case "SRV":
+		if len(fields) != 4 {
+			return bad()
+		}
+		nums := make([]int, 3)
+		for i := range nums {
+			n, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return bad()
+			}
+			nums[i] = n
+		}
+		body.Data = map[string]any{"priority": nums[0], "weight": nums[1], "port": nums[2], "target": cloudflareTarget(fields[3])}
+	case "CAA":
+		if len(fields) < 3 {
+			return bad()
+		}
+		flags, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return bad()
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(value, fields[0])), fields[1]))
+		if unq, err := strconv.Unquote(rest); err == nil {
+			rest = unq
+		}
+		body.Data = map[string]any{"flags": flags, "tag": fields[1], "value": rest}
+	case "CNAME", "NS":
+		body.Content = strings.TrimSuffix(value, ".")
+	default:
+		body.Content = value
+	}
+	return body, nil
+}
+
+// do makes one call and decodes its result into out. It returns the HTTP
+// status alongside any error, so callers can tell a missing object apart.
+func (c *Cloudflare) do(ctx context.Context, method, path string, in, out any) (int, error) {
+	var env cloudflareEnvelope
+	status, err := c.call(ctx, method, path, in, &env)
+	if err != nil {
+		return status, err
+	}
+	if out != nil && len(env.Result) > 0 {
+		if err := json.Unmarshal(env.Result, out); err != nil {
+			return status, fmt.Errorf("cloudflare %s %s: decode: %w", method, path, err)
+		}
+	}
+	return status, nil
+}
+
+func (c *Cloudflare) call(ctx context.Context, method, path string, in any, env *cloudflareEnvelope) (int, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	_ = json.Unmarshal(raw, env)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return resp.StatusCode, fmt.Errorf("cloudflare %s %s: %w", method, path, ErrThrottled)
+	}
+	if resp.StatusCode >= 300 || !env.Success {
+		if len(env.Errors) > 0 {
+			return resp.StatusCode, fmt.Errorf("cloudflare %s %s: %d: %s", method, path, env.Errors[0].Code, env.Errors[0].Message)
+		}
+		return resp.StatusCode, fmt.Errorf("cloudflare %s %s: http %d", method, path, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,284 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeCloudflare is just enough of the Cloudflare API v4 for the provider:
+// one zone, and its records listed by name and type.
+type fakeCloudflare struct {
+	mu      sync.Mutex
+	zone    cloudflareZone
+	records map[string]cloudflareRecord // id -> record
+	nextID  int
+	calls   []string
+}
+
+func newFakeCloudflare(t *testing.T) (*fakeCloudflare, *Cloudflare) {
+	f := &fakeCloudflare{
+		zone:    cloudflareZone{ID: "z1", Name: "example.com", NameServers: []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"}},
+		records: map[string]cloudflareRecord{},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	c := NewCloudflare("tok")
+	c.BaseURL = srv.URL
+	return f, c
+}
+
+func (f *fakeCloudflare) add(r cloudflareRecord) {
+	f.nextID++
+	r.ID = fmt.Sprintf("r%d", f.nextID)
+	f.records[r.ID] = r
+}
+
+func (f *fakeCloudflare) reply(w http.ResponseWriter, status int, result any) {
+	w.WriteHeader(status)
+	body := map[string]any{"success": status < 300, "errors": []any{}, "result": result}
+	if status >= 300 {
+		body["errors"] = []any{map[string]any{"code": 1000 + status, "message": http.StatusText(status)}}
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeCloudflare) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer tok" {
+		f.reply(w, http.StatusForbidden, nil)
+		return
+	}
+	if r.Method != http.MethodGet {
+		f.calls = append(f.calls, r.Method)
+	}
+
+	recordsPath := "/zones/" + f.zone.ID + "/dns_records"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/zones":
+		zones := []cloudflareZone{}
+		if r.URL.Query().Get("name") == f.zone.Name {
+			zones = append(zones, f.zone)
+		}
+		f.reply(w, http.StatusOK, zones)
+
+	case r.Method == http.MethodGet && r.URL.Path == "/zones/"+f.zone.ID:
+		f.reply(w, http.StatusOK, f.zone)
+
+	case r.Method == http.MethodGet && r.URL.Path == recordsPath:
+		q := r.URL.Query()
+		out := []cloudflareRecord{}
+		for _, rec := range f.records {
+			if (q.Get("name") == "" || rec.Name == q.Get("name")) && (q.Get("type") == "" || rec.Type == q.Get("type")) {
+				out = append(out, rec)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+		f.reply(w, http.StatusOK, out)
+
+	case r.Method == http.MethodPost && r.URL.Path == recordsPath:
+		var rec cloudflareRecord
+		_ = json.NewDecoder(r.Body).Decode(&rec)
+		if rec.Type == "CNAME" {
+			for _, o := range f.records {
+				if o.Name == rec.Name && o.Type == "CNAME" {
+					f.reply(w, http.StatusBadRequest, nil)
+					return
+				}
+			}
+		}
+		f.add(rec)
+		f.reply(w, http.StatusOK, rec)
+
+	case strings.HasPrefix(r.URL.Path, recordsPath+"/"):
+		id := strings.TrimPrefix(r.URL.Path, recordsPath+"/")
+		rec, ok := f.records[id]
+		if !ok {
+			f.reply(w, http.StatusNotFound, nil)
+			return
+		}
+		switch r.Method {
+		case http.MethodDelete:
+			delete(f.records, id)
+		case http.MethodPatch:
+			var patch struct {
+				TTL int64 `json:"ttl"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&patch)
+			rec.TTL = patch.TTL
+			f.records[id] = rec
+		case http.MethodPut:
+			var put cloudflareRecord
+			_ = json.NewDecoder(r.Body).Decode(&put)
+			put.ID = id
+			f.records[id] = put
+		}
+		f.reply(w, http.StatusOK, rec)
+
+	default:
+		f.reply(w, http.StatusNotFound, nil)
+	}
+}
+
+func TestCloudflareZones(t *testing.T) {
+	_, c := newFakeCloudflare(t)
+	ctx := context.Background()
+
+	id, err := c.FindZone(ctx, "Example.com.")
+	if err != nil || id != "z1" {
+		t.Fatalf("FindZone = %q, %v", id, err)
+	}
+	if _, err := c.FindZone(ctx, "other.com"); !errors.Is(err, ErrZoneNotFound) {
+		t.Fatalf("unknown zone: %v", err)
+	}
+	if err := c.GetZone(ctx, "z1"); err != nil {
+		t.Fatalf("GetZone: %v", err)
+	}
+	if err := c.GetZone(ctx, "nope"); !errors.Is(err, ErrZoneNotFound) {
+		t.Fatalf("GetZone of a missing zone: %v", err)
+	}
+	if ns, err := c.NameServers(ctx, "z1"); err != nil || len(ns) != 2 {
+		t.Fatalf("NameServers = %v, %v", ns, err)
+	}
+
+	c.Token = "wrong"
+	if _, err := c.FindZone(ctx, "example.com"); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+		t.Fatalf("bad token should surface the API error, got %v", err)
+	}
+}
+
+func TestCloudflareRateLimitIsThrottled(t *testing.T) {
+	f := &fakeCloudflare{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		f.reply(w, http.StatusTooManyRequests, nil)
+	}))
+	defer srv.Close()
+	c := NewCloudflare("tok")
+	c.BaseURL = srv.URL
+
+	if err := c.GetZone(context.Background(), "z1"); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("429 should be ErrThrottled, got %v", err)
+	}
+}
+
+func TestCloudflareApplyConvergesRecords(t *testing.T) {
+	f, c := newFakeCloudflare(t)
+	ctx := context.Background()
+	f.add(cloudflareRecord{Type: "A", Name: "www.example.com", Content: "192.0.2.1", TTL: 60})
+	f.add(cloudflareRecord{Type: "A", Name: "www.example.com", Content: "192.0.2.9", TTL: 60})
+	f.add(cloudflareRecord{Type: "CNAME", Name: "app.example.com", Content: "old.example.net", TTL: 300})
+
+	err := c.Apply(ctx, "z1", []Change{
+		{Action: ActionUpsert, Record: Record{Name: "www.example.com.", Type: "A", TTL: 300, Values: []string{"192.0.2.1", "192.0.2.2"}}},
+		{Action: ActionUpsert, Record: Record{Name: "app.example.com.", Type: "CNAME", TTL: 300, Values: []string{"new.example.net."}}},
+		{Action: ActionUpsert, Record: Record{Name: "example.com.", Type: "MX", TTL: 300, Values: []string{"10 mail.example.com"}}},
+		{Action: ActionUpsert, Record: Record{Name: "_autoglue.www.example.com.", Type: "TXT", TTL: 300, Values: []string{`v=ag1 "quoted"`}}},
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	www, _ := c.GetRecord(ctx, "z1", "www.example.com", "A")
+	sort.Strings(www.Values)
+	if www.TTL != 300 || strings.Join(www.Values, ",") != "192.0.2.1,192.0.2.2" {
+		t.Fatalf("www = %+v", www)
+	}
+	app, _ := c.GetRecord(ctx, "z1", "app.example.com.", "CNAME")
+	if len(app.Values) != 1 || app.Values[0] != "new.example.net." {
+		t.Fatalf("app = %+v", app)
+	}
+	mx, _ := c.GetRecord(ctx, "z1", "example.com.", "MX")
+	if len(mx.Values) != 1 || mx.Values[0] != "10 mail.example.com." {
+		t.Fatalf("mx = %+v", mx)
+	}
+	txt, _ := c.GetRecord(ctx, "z1", "_autoglue.www.example.com.", "TXT")
+	if len(txt.Values) != 1 || txt.Values[0] != `v=ag1 "quoted"` {
+		t.Fatalf("txt = %+v", txt)
+	}
+
+	all, err := c.ListRecords(ctx, "z1")
+	if err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+	var sets []string
+	for _, r := range all {
+		sets = append(sets, fmt.Sprintf("%s %s %d", r.Name, r.Type, len(r.Values)))
+	}
+	if got := strings.Join(sets, ","); !strings.Contains(got, "example.com. MX 1") || !strings.Contains(got, "www.example.com. A 2") {
+		t.Fatalf("ListRecords = %s", got)
+	}
+
+	// Applying the same state again changes nothing.
+	f.calls = nil
+	if err := c.Apply(ctx, "z1", []Change{
+		{Action: ActionUpsert, Record: Record{Name: "example.com.", Type: "MX", TTL: 300, Values: []string{"10 mail.example.com"}}},
+	}); err != nil || len(f.calls) != 0 {
+		t.Fatalf("idempotent upsert made calls %v, err %v", f.calls, err)
+	}
+
+	if err := c.Apply(ctx, "z1", []Change{
+		{Action: ActionDelete, Record: Record{Name: "www.example.com.", Type: "A"}},
+		{Action: ActionDelete, Record: Record{Name: "gone.example.com.", Type: "A"}},
+	}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if rec, err := c.GetRecord(ctx, "z1", "www.example.com.", "A"); err != nil || rec != nil {
+		t.Fatalf("www after delete = %+v, %v", rec, err)
+	}
+}
+
+func TestOwnershipThroughProvider(t *testing.T) {
+	f, c := newFakeCloudflare(t)
+	ctx := context.Background()
+	fq := "api.example.com."
+
+	owned, err := ExternalDNSOwned(ctx, c, "z1", fq, "A")
+	if err != nil || owned {
+		t.Fatalf("empty zone: owned=%v err=%v", owned, err)
+	}
+
+	// Our own poison records do not count as external-dns owning the name.
+	var changes []Change
+	for _, r := range PoisonRecords(fq, "A", 300) {
+		changes = append(changes, Change{Action: ActionUpsert, Record: r})
+	}
+	changes = append(changes, Change{Action: ActionUpsert, Record: Record{
+		Name: MarkerName(fq), Type: "TXT", TTL: 300, Values: []string{MarkerValue("org", "rec", "0123456789abcdef0123")},
+	}})
+	if err := c.Apply(ctx, "z1", changes); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if owned, _ := ExternalDNSOwned(ctx, c, "z1", fq, "A"); owned {
+		t.Fatal("autoglue's poison records were taken for a real external-dns owner")
+	}
+	markers, err := GetMarkers(ctx, c, "z1", fq)
+	if err != nil || len(markers) != 1 || markers[0].Rec != "rec" || markers[0].Fp != "0123456789abcdef" {
+		t.Fatalf("markers = %+v, %v", markers, err)
+	}
+
+	f.add(cloudflareRecord{Type: "TXT", Name: "extdns-a-api.example.com", TTL: 300,
+		Content: `"heritage=external-dns,external-dns/owner=prod-cluster"`})
+	if owned, _ := ExternalDNSOwned(ctx, c, "z1", fq, "A"); !owned {
+		t.Fatal("a real external-dns registry entry should be detected")
+	}
+}
+
+func TestQuoteTXTRoundTrip(t *testing.T) {
+	for _, v := range []string{"plain", `with "quotes"`, `back\slash`, ""} {
+		if got := UnquoteTXT(QuoteTXT(v)); got != v {
+			t.Errorf("round trip of %q = %q", v, got)
+		}
+	}
+	if got := UnquoteTXT(`"first" "second"`); got != "firstsecond" {
+		t.Errorf("multi-string TXT = %q", got)
+	}
+}
@@ -0,0 +1,68 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+)
+
+// Delegation states, as DelegationStatus reports them.
+const (
+	// DelegationDelegated means the parent delegates to exactly the name
+	// servers the zone was assigned.
+	DelegationDelegated = "delegated"
+	// DelegationPartial means some, but not all, of them.
+	DelegationPartial = "partial"
+	// DelegationNone means none of them: the name is not delegated, or is
+	// delegated somewhere else.
+	DelegationNone = "not_delegated"
+	// DelegationUnknown means the parent could not be asked.
+	DelegationUnknown = "unknown"
+)
+
+// DelegationStatus compares the name servers a zone was assigned with those
+// its parent delegates it to.
+func DelegationStatus(assigned, delegated []string) string {
+	want, have := nameServerSet(assigned), nameServerSet(delegated)
+	if len(have) == 0 {
+		return DelegationNone
+	}
+	if slices.Equal(want, have) {
+		return DelegationDelegated
+	}
+	for _, ns := range have {
+		if slices.Contains(want, ns) {
+			return DelegationPartial
+		}
+	}
+	return DelegationNone
+}
+
+func nameServerSet(ns []string) []string {
+	out := make([]string, 0, len(ns))
+	for _, n := range ns {
+		out = append(out, Fqdn(n))
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// LookupNS resolves name's NS records through r. A name that does not
+// exist has none, and is not an error. Resolvers cache, so a delegation
+// that just changed can take the parent's NS TTL to show.
+func LookupNS(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+	recs, err := r.LookupNS(ctx, Fqdn(name))
+	var de *net.DNSError
+	if errors.As(err, &de) && de.IsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(recs))
+	for _, ns := range recs {
+		out = append(out, Fqdn(ns.Host))
+	}
+	return nameServerSet(out), nil
+}
@@ -0,0 +1,21 @@
+package dns
+
+import "testing"
+
+func TestDelegationStatus(t *testing.T) {
+	assigned := []string{"ns-1.awsdns-01.org", "ns-2.awsdns-02.com."}
+	cases := []struct {
+		delegated []string
+		want      string
+	}{
+		{[]string{"NS-2.awsdns-02.com.", "ns-1.awsdns-01.org."}, DelegationDelegated},
+		{[]string{"ns-1.awsdns-01.org."}, DelegationPartial},
+		{[]string{"ns1.old-host.net.", "ns2.old-host.net."}, DelegationNone},
+		{nil, DelegationNone},
+	}
+	for _, c := range cases {
+		if got := DelegationStatus(assigned, c.delegated); got != c.want {
+			t.Errorf("DelegationStatus(%v) = %s, want %s", c.delegated, got, c.want)
+		}
+	}
+}
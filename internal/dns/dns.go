@@ -0,0 +1,264 @@
+// Package dns reads and writes records at DNS providers.
+//
+// A Provider only knows how to talk to one provider's API; the domain and
+// record set rows, the credentials and the reconcile loop around them live
+// in internal/bg. Record names are fully qualified with a trailing dot and
+// TXT values are unquoted everywhere in this package: each implementation
+// converts to its provider's own conventions. Implementations that speak
+// HTTP take their API base URL as a field so tests can point them at an
+// httptest fake of the provider.
+package dns
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// ErrZoneNotFound is returned by FindZone and GetZone when the provider has
+// no such zone, or the credential cannot see it.
+var ErrZoneNotFound = errors.New("zone not found")
+
+// ErrUnsupported is returned for a credential provider with no
+// implementation.
+var ErrUnsupported = errors.New("dns provider not supported")
+
+// ErrThrottled wraps a provider's rate limiting error, so callers can back
+// off and try again.
+var ErrThrottled = errors.New("dns provider rate limit")
+
+// Record is one record set: every value for a name and type.
+type Record struct {
+	// Name is fully qualified and ends with a dot.
+	Name string
+	Type string
+	TTL  int64
+	// Values are in presentation format, except that TXT values are the
+	// unquoted text.
+	Values []string
+	// Alias, for an A or AAAA record, points it at another name in place of
+	// Values and TTL. Only Route 53 has aliases.
+	Alias *AliasTarget
+}
+
+// AliasTarget is a Route 53 alias: a load balancer, CloudFront
+// distribution, S3 website or another record, identified by its DNS name
+// and the hosted zone that name lives in.
+type AliasTarget struct {
+	HostedZoneID         string
+	DNSName              string
+	EvaluateTargetHealth bool
+}
+
+// SameAlias reports whether two aliases point at the same target.
+func SameAlias(a, b *AliasTarget) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return strings.TrimPrefix(a.HostedZoneID, "/hostedzone/") == strings.TrimPrefix(b.HostedZoneID, "/hostedzone/") &&
+		Fqdn(a.DNSName) == Fqdn(b.DNSName) &&
+		a.EvaluateTargetHealth == b.EvaluateTargetHealth
+}
+
+type Action string
+
+const (
+	// ActionUpsert creates the record set, or replaces all its values.
+	ActionUpsert Action = "UPSERT"
+	// ActionDelete removes the record set. Only Name and Type are needed,
+	// and deleting one that does not exist is not an error.
+	ActionDelete Action = "DELETE"
+)
+
+type Change struct {
+	Action Action
+	Record Record
+}
+
+type Provider interface {
+	// Name is the credential provider this implementation serves.
+	Name() string
+	// FindZone returns the provider's id for the zone named domain.
+	FindZone(ctx context.Context, domain string) (string, error)
+	// GetZone checks that the zone exists and the credential can read it.
+	GetZone(ctx context.Context, zoneID string) error
+	// GetRecord returns the record set for name and type, or nil when
+	// there is none.
+	GetRecord(ctx context.Context, zoneID, name, rrType string) (*Record, error)
+	// ListRecords returns every record set in the zone, ordered by name
+	// and type. Record sets a Record cannot represent, such as Route 53
+	// routing policy sets, are left out.
+	ListRecords(ctx context.Context, zoneID string) ([]Record, error)
+	// Apply makes the changes, all at once where the provider supports
+	// that and otherwise in order, stopping at the first failure.
+	Apply(ctx context.Context, zoneID string, changes []Change) error
+}
+
+// ChangeTracker is implemented by providers whose applied changes take a
+// while to reach all of their name servers, such as Route 53.
+type ChangeTracker interface {
+	// ApplyTracked is Apply, returning the id of the provider's change or
+	// "" when there was nothing to change.
+	ApplyTracked(ctx context.Context, zoneID string, changes []Change) (string, error)
+	// ChangeSynced reports whether the change has reached every name server.
+	ChangeSynced(ctx context.Context, changeID string) (bool, error)
+}
+
+// ZoneCreator is implemented by providers that can create zones.
+type ZoneCreator interface {
+	// CreateZone creates the zone named domain and returns its id and the
+	// name servers the provider assigned it. ref identifies the request,
+	// so retrying one that failed part way does not make a second zone.
+	CreateZone(ctx context.Context, domain, ref string) (string, []string, error)
+}
+
+// NameServerLister is implemented by providers that assign each zone name
+// servers of its own.
+type NameServerLister interface {
+	NameServers(ctx context.Context, zoneID string) ([]string, error)
+}
+
+// CanCreateZones reports whether provider's implementation is a
+// ZoneCreator.
+func CanCreateZones(provider string) bool {
+	return provider == "aws"
+}
+
+// Supported reports whether provider has an implementation here.
+func Supported(provider string) bool {
+	switch provider {
+	case "aws", "cloudflare", "rfc2136":
+		return true
+	}
+	return false
+}
+
+// Fqdn returns name in lowercase with exactly one trailing dot.
+func Fqdn(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".") + "."
+}
+
+// UnquoteTXT turns a TXT value in zone file form, one or more quoted
+// strings, into its text. A value that is not quoted is returned as is.
+func UnquoteTXT(v string) string {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, `"`) {
+		return v
+	}
+	var out strings.Builder
+	inQuote, escaped := false, false
+	for _, r := range v {
+		switch {
+		case escaped:
+			out.WriteRune(r)
+			escaped = false
+		case inQuote && r == '\\':
+			escaped = true
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// QuoteTXT is the zone file form of a TXT value: one quoted string, or
+// several for text longer than the 255 bytes a string holds.
+func QuoteTXT(v string) string {
+	parts := splitTXT(v)
+	for i, p := range parts {
+		p = strings.ReplaceAll(p, `\`, `\\`)
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `\"`) + `"`
+	}
+	return strings.Join(parts, " ")
+}
+
+// maxTXTString is the longest character-string a TXT record holds; longer
+// text is split over several strings in one record.
+const maxTXTString = 255
+
+// splitTXT cuts text into character-strings of at most 255 bytes.
+func splitTXT(v string) []string {
+	if v == "" {
+		return []string{""}
+	}
+	var out []string
+	for len(v) > maxTXTString {
+		out = append(out, v[:maxTXTString])
+		v = v[maxTXTString:]
+	}
+	return append(out, v)
+}
+
+// CanonicalValues puts values in one form so what was written and what a
+// provider reads back compare equal: TXT unquoted, addresses in their
+// canonical text, names lowercase without trailing dots, and sorted.
+func CanonicalValues(rrType string, values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		switch strings.ToUpper(rrType) {
+		case "TXT":
+			v = UnquoteTXT(v)
+		case "A", "AAAA":
+			if a, err := netip.ParseAddr(v); err == nil {
+				v = a.String()
+			}
+		default:
+			fields := strings.Fields(strings.ToLower(v))
+			for i, f := range fields {
+				if f != "." {
+					fields[i] = strings.TrimSuffix(f, ".")
+				}
+			}
+			v = strings.Join(fields, " ")
+		}
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// recordSets gathers single values into record sets, keeping the first TTL
+// seen for each, ordered by name and type.
+type recordSets struct {
+	sets map[string]*Record
+}
+
+func (rs *recordSets) add(name, rrType string, ttl int64, value string) {
+	if rs.sets == nil {
+		rs.sets = map[string]*Record{}
+	}
+	name, rrType = Fqdn(name), strings.ToUpper(rrType)
+	k := name + " " + rrType
+	r, ok := rs.sets[k]
+	if !ok {
+		r = &Record{Name: name, Type: rrType, TTL: ttl}
+		rs.sets[k] = r
+	}
+	r.Values = append(r.Values, value)
+}
+
+func (rs *recordSets) list() []Record {
+	out := make([]Record, 0, len(rs.sets))
+	for _, r := range rs.sets {
+		out = append(out, *r)
+	}
+	sortRecords(out)
+	return out
+}
+
+func sortRecords(recs []Record) {
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Name != recs[j].Name {
+			return recs[i].Name < recs[j].Name
+		}
+		return recs[i].Type < recs[j].Type
+	})
+}
@@ -0,0 +1,57 @@
+package dns
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanonicalValues(t *testing.T) {
+	cases := []struct {
+		rrType string
+		a, b   []string
+	}{
+		{"AAAA", []string{"2001:DB8:0::1"}, []string{"2001:db8::1"}},
+		{"CNAME", []string{"Target.Example.com."}, []string{"target.example.com"}},
+		{"MX", []string{"20 b.example.com.", "10 a.example.com"}, []string{"10 a.example.com.", "20 b.example.com"}},
+		{"TXT", []string{`"v=spf1 -all"`}, []string{"v=spf1 -all"}},
+	}
+	for _, c := range cases {
+		if a, b := CanonicalValues(c.rrType, c.a), CanonicalValues(c.rrType, c.b); !slices.Equal(a, b) {
+			t.Errorf("%s: %v and %v should compare equal", c.rrType, a, b)
+		}
+	}
+	if slices.Equal(CanonicalValues("TXT", []string{"Abc"}), CanonicalValues("TXT", []string{"abc"})) {
+		t.Error("TXT text is case sensitive")
+	}
+}
+
+func TestRoute53AliasRoundTrip(t *testing.T) {
+	rec := Record{Name: "example.org", Type: "A", Alias: &AliasTarget{
+		HostedZoneID: "/hostedzone/Z35SXDOTRQ7X7K", DNSName: "my-lb-1.us-east-1.elb.amazonaws.com",
+	}}
+	rrset := route53RecordSet(rec)
+	if rrset.TTL != nil || len(rrset.ResourceRecords) != 0 {
+		t.Fatalf("alias record set must carry neither TTL nor records: %+v", rrset)
+	}
+	back := route53Record(*rrset)
+	if back.Alias == nil || back.Alias.HostedZoneID != "Z35SXDOTRQ7X7K" || !SameAlias(back.Alias, rec.Alias) {
+		t.Fatalf("round trip = %+v", back.Alias)
+	}
+}
+
+func TestFingerprintAlias(t *testing.T) {
+	ttl := 300
+	plain := Fingerprint("Z1", "example.org", "A", &ttl, []string{"192.0.2.1"}, nil)
+	if plain != Fingerprint("Z1", "example.org", "A", &ttl, []string{"192.0.2.1"}, nil) {
+		t.Fatal("fingerprint is not stable")
+	}
+	a := &AliasTarget{HostedZoneID: "Z35SXDOTRQ7X7K", DNSName: "lb.example.net"}
+	withAlias := Fingerprint("Z1", "example.org", "A", nil, nil, a)
+	if withAlias == plain {
+		t.Fatal("alias should change the fingerprint")
+	}
+	a.EvaluateTargetHealth = true
+	if Fingerprint("Z1", "example.org", "A", nil, nil, a) == withAlias {
+		t.Fatal("evaluate_target_health should change the fingerprint")
+	}
+}
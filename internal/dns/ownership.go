@@ -0,0 +1,190 @@
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Ownership is recorded next to each record autoglue manages, in a TXT
+// record at _autoglue.<fqdn>. Records external-dns manages are recognised
+// from its own TXT registry, and autoglue writes a registry entry naming an
+// owner no real external-dns uses, so external-dns leaves ours alone too.
+
+// Marker is the content of an ownership marker.
+type Marker struct {
+	Ver string
+	Org string
+	Rec string
+	// Fp is the first 16 characters of the record's fingerprint.
+	Fp string
+}
+
+// ExternalDNSPoisonOwner MUST NOT match any real external-dns --txt-owner-id.
+const ExternalDNSPoisonOwner = "autoglue-lock"
+
+// ExternalDNSPoisonValue is a fake owner so real external-dns skips the
+// record.
+const ExternalDNSPoisonValue = "heritage=external-dns,external-dns/owner=" + ExternalDNSPoisonOwner + ",external-dns/resource=manual/autoglue"
+
+// MarkerName is where the ownership marker for fqdn lives.
+func MarkerName(fqdn string) string {
+	return "_autoglue." + strings.TrimSuffix(fqdn, ".") + "."
+}
+
+func ShortFP(full string) string {
+	if len(full) > 16 {
+		return full[:16]
+	}
+	return full
+}
+
+func MarkerValue(orgID, recID, fp string) string {
+	return "v=ag1 org=" + orgID + " rec=" + recID + " fp=" + ShortFP(fp)
+}
+
+func ParseMarker(s string) (Marker, bool) {
+	out := Marker{}
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return out, false
+	}
+	kv := map[string]string{}
+	for _, f := range fields {
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) == 2 {
+			kv[parts[0]] = parts[1]
+		}
+	}
+	if kv["v"] == "" || kv["org"] == "" || kv["rec"] == "" || kv["fp"] == "" {
+		return out, false
+	}
+	out.Ver, out.Org, out.Rec, out.Fp = kv["v"], kv["org"], kv["rec"], kv["fp"]
+	return out, true
+}
+
+// GetMarkers returns the ownership markers at fqdn's marker name. Values
+// that are not markers are skipped.
+func GetMarkers(ctx context.Context, p Provider, zoneID, fqdn string) ([]Marker, error) {
+	rec, err := p.GetRecord(ctx, zoneID, MarkerName(fqdn), "TXT")
+	if err != nil || rec == nil {
+		return nil, err
+	}
+	var out []Marker
+	for _, v := range rec.Values {
+		if m, ok := ParseMarker(v); ok {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// externalDNSNames are the registry names external-dns writes with
+// txtPrefix=extdns-: extdns-<fqdn> and extdns-<rrtype-lc>-<fqdn>.
+func externalDNSNames(fqdn, rrType string) []string {
+	base := strings.TrimSuffix(fqdn, ".")
+	return []string{
+		"extdns-" + base + ".",
+		"extdns-" + strings.ToLower(rrType) + "-" + base + ".",
+	}
+}
+
+// ExternalDNSOwned reports whether a real external-dns claims fqdn/rrType.
+func ExternalDNSOwned(ctx context.Context, p Provider, zoneID, fqdn, rrType string) (bool, error) {
+	for _, name := range externalDNSNames(fqdn, rrType) {
+		rec, err := p.GetRecord(ctx, zoneID, name, "TXT")
+		if err != nil {
+			return false, err
+		}
+		if rec == nil {
+			continue
+		}
+		for _, v := range rec.Values {
+			meta := ParseExternalDNSMeta(strings.TrimSpace(v))
+			if meta == nil {
+				continue
+			}
+			if meta["heritage"] == "external-dns" &&
+				meta["external-dns/owner"] != "" &&
+				meta["external-dns/owner"] != ExternalDNSPoisonOwner {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ParseExternalDNSMeta parses the comma-separated external-dns TXT format
+// into a small map.
+func ParseExternalDNSMeta(v string) map[string]string {
+	parts := strings.Split(v, ",")
+	if len(parts) == 0 {
+		return nil
+	}
+	meta := make(map[string]string, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		meta[kv[0]] = kv[1]
+	}
+	if len(meta) == 0 {
+		return nil
+	}
+	return meta
+}
+
+// PoisonRecords are the external-dns registry records that keep a real
+// external-dns off fqdn/rrType.
+func PoisonRecords(fqdn, rrType string, ttl int64) []Record {
+	names := externalDNSNames(fqdn, rrType)
+	out := make([]Record, 0, len(names))
+	for _, n := range names {
+		out = append(out, Record{Name: n, Type: "TXT", TTL: ttl, Values: []string{ExternalDNSPoisonValue}})
+	}
+	return out
+}
+
+// fingerprintInput is hashed by Fingerprint. Its fields are in key order, so
+// the JSON is canonical without re-sorting.
+type fingerprintInput struct {
+	Alias  *fingerprintAlias `json:"alias,omitempty"`
+	FQDN   string            `json:"fqdn"`
+	TTL    *int              `json:"ttl,omitempty"`
+	Type   string            `json:"type"`
+	Values []string          `json:"values,omitempty"`
+	ZoneID string            `json:"zone_id"`
+}
+
+type fingerprintAlias struct {
+	DNSName              string `json:"dns_name"`
+	EvaluateTargetHealth bool   `json:"evaluate_target_health"`
+	HostedZoneID         string `json:"hosted_zone_id"`
+}
+
+// Fingerprint identifies the desired state of a record set. Its first
+// characters go in the ownership marker, so a record rewritten by anything
+// else no longer matches. fqdn is written without the trailing dot.
+func Fingerprint(zoneID, fqdn, rrType string, ttl *int, values []string, alias *AliasTarget) string {
+	vals := append([]string(nil), values...)
+	sort.Strings(vals)
+	in := fingerprintInput{FQDN: fqdn, TTL: ttl, Type: strings.ToUpper(rrType), Values: vals, ZoneID: zoneID}
+	if alias != nil {
+		in.Alias = &fingerprintAlias{
+			DNSName:              Fqdn(alias.DNSName),
+			EvaluateTargetHealth: alias.EvaluateTargetHealth,
+			HostedZoneID:         strings.TrimPrefix(alias.HostedZoneID, "/hostedzone/"),
+		}
+	}
+	b, _ := json.Marshal(in)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,254 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	mdns "github.com/miekg/dns"
+)
+
+// tsigAlgorithms are the TSIG algorithms a key may use, by their short names.
+var tsigAlgorithms = map[string]string{
+	"hmac-sha1":   mdns.HmacSHA1,
+	"hmac-sha224": mdns.HmacSHA224,
+	"hmac-sha256": mdns.HmacSHA256,
+	"hmac-sha384": mdns.HmacSHA384,
+	"hmac-sha512": mdns.HmacSHA512,
+}
+
+// RFC2136 talks to a self-hosted authoritative server, such as BIND or
+// PowerDNS, with DNS UPDATE messages signed with a TSIG key. Records are
+// read back with ordinary queries, signed with the same key so servers that
+// restrict queries to it still answer. The zone id is the zone's name. A
+// change batch is one UPDATE message, which the server applies atomically.
+type RFC2136 struct {
+	// Server is the primary's address, host:port.
+	Server  string
+	KeyName string
+	// Algorithm is the TSIG algorithm name in canonical form.
+	Algorithm string
+	// Secret is the base64 key.
+	Secret string
+	// Net is the transport, "tcp" unless set otherwise.
+	Net     string
+	Timeout time.Duration
+}
+
+// NewRFC2136 validates the TSIG key. server defaults to port 53.
+func NewRFC2136(server, keyName, algorithm, secret string) (*RFC2136, error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return nil, fmt.Errorf("rfc2136: server is required")
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	alg, err := TSIGAlgorithm(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(keyName) == "" {
+		return nil, fmt.Errorf("rfc2136: key name is required")
+	}
+	if _, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret)); err != nil {
+		return nil, fmt.Errorf("rfc2136: secret must be base64: %w", err)
+	}
+	return &RFC2136{
+		Server:    server,
+		KeyName:   Fqdn(keyName),
+		Algorithm: alg,
+		Secret:    strings.TrimSpace(secret),
+		Net:       "tcp",
+		Timeout:   10 * time.Second,
+	}, nil
+}
+
+// TSIGAlgorithm returns the canonical name for a TSIG algorithm given by
+// its short name, such as hmac-sha256, with or without the trailing dot.
+func TSIGAlgorithm(name string) (string, error) {
+	alg, ok := tsigAlgorithms[strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")]
+	if !ok {
+		return "", fmt.Errorf("rfc2136: unsupported TSIG algorithm %q", name)
+	}
+	return alg, nil
+}
+
+func (p *RFC2136) Name() string { return "rfc2136" }
+
+func (p *RFC2136) FindZone(ctx context.Context, domain string) (string, error) {
+	zone := Fqdn(domain)
+	if err := p.GetZone(ctx, zone); err != nil {
+		return "", err
+	}
+	return zone, nil
+}
+
+// GetZone checks that the server answers authoritatively for the zone's SOA.
+func (p *RFC2136) GetZone(ctx context.Context, zoneID string) error {
+	zone := Fqdn(zoneID)
+	m := new(mdns.Msg)
+	m.SetQuestion(zone, mdns.TypeSOA)
+	in, err := p.exchange(ctx, m)
+	if err != nil {
+		return err
+	}
+	if in.Rcode == mdns.RcodeNameError || in.Rcode == mdns.RcodeRefused || in.Rcode == mdns.RcodeNotAuth {
+		return fmt.Errorf("%w: %s (%s)", ErrZoneNotFound, zone, mdns.RcodeToString[in.Rcode])
+	}
+	if in.Rcode != mdns.RcodeSuccess {
+		return fmt.Errorf("rfc2136: SOA %s: %s", zone, mdns.RcodeToString[in.Rcode])
+	}
+	for _, rr := range in.Answer {
+		if soa, ok := rr.(*mdns.SOA); ok && Fqdn(soa.Hdr.Name) == zone {
+			if !in.Authoritative {
+				return fmt.Errorf("%w: %s is not authoritative for %s", ErrZoneNotFound, p.Server, zone)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s has no SOA at %s", ErrZoneNotFound, p.Server, zone)
+}
+
+func (p *RFC2136) GetRecord(ctx context.Context, zoneID, name, rrType string) (*Record, error) {
+	name, rrType = Fqdn(name), strings.ToUpper(rrType)
+	qtype, ok := mdns.StringToType[rrType]
+	if !ok {
+		return nil, fmt.Errorf("rfc2136: unknown record type %q", rrType)
+	}
+	m := new(mdns.Msg)
+	m.SetQuestion(name, qtype)
+	in, err := p.exchange(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	if in.Rcode == mdns.RcodeNameError {
+		return nil, nil
+	}
+	if in.Rcode != mdns.RcodeSuccess {
+		return nil, fmt.Errorf("rfc2136: query %s %s: %s", name, rrType, mdns.RcodeToString[in.Rcode])
+	}
+
+	var out *Record
+	for _, rr := range in.Answer {
+		h := rr.Header()
+		if h.Rrtype != qtype || Fqdn(h.Name) != name {
+			continue
+		}
+		if out == nil {
+			out = &Record{Name: name, Type: rrType, TTL: int64(h.Ttl)}
+		}
+		out.Values = append(out.Values, rrValue(rr))
+	}
+	return out, nil
+}
+
+// ListRecords reads the zone with a TSIG-signed AXFR, so the server has to
+// allow transfers to the key. The SOA and DNSSEC records are left out. The
+// transfer is bounded by Timeout rather than ctx.
+func (p *RFC2136) ListRecords(_ context.Context, zoneID string) ([]Record, error) {
+	zone := Fqdn(zoneID)
+	m := new(mdns.Msg)
+	m.SetAxfr(zone)
+	m.SetTsig(p.KeyName, p.Algorithm, 300, time.Now().Unix())
+
+	t := &mdns.Transfer{
+		DialTimeout:  p.Timeout,
+		ReadTimeout:  p.Timeout,
+		WriteTimeout: p.Timeout,
+		TsigSecret:   map[string]string{p.KeyName: p.Secret},
+	}
+	envelopes, err := t.In(m, p.Server)
+	if err != nil {
+		return nil, fmt.Errorf("rfc2136: axfr %s: %w", zone, err)
+	}
+
+	var sets recordSets
+	for env := range envelopes {
+		if env.Error != nil {
+			return nil, fmt.Errorf("rfc2136: axfr %s: %w", zone, env.Error)
+		}
+		for _, rr := range env.RR {
+			h := rr.Header()
+			switch h.Rrtype {
+			case mdns.TypeSOA, mdns.TypeRRSIG, mdns.TypeNSEC, mdns.TypeNSEC3, mdns.TypeNSEC3PARAM, mdns.TypeDNSKEY:
+				continue
+			}
+			sets.add(h.Name, mdns.TypeToString[h.Rrtype], int64(h.Ttl), rrValue(rr))
+		}
+	}
+	return sets.list(), nil
+}
+
+func (p *RFC2136) Apply(ctx context.Context, zoneID string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	m := new(mdns.Msg)
+	m.SetUpdate(Fqdn(zoneID))
+	for _, ch := range changes {
+		rec := ch.Record
+		name, rrType := Fqdn(rec.Name), strings.ToUpper(rec.Type)
+		qtype, ok := mdns.StringToType[rrType]
+		if !ok {
+			return fmt.Errorf("rfc2136: unknown record type %q", rrType)
+		}
+		// An upsert replaces the whole RRset: remove it, then add the
+		// wanted values, in the same message.
+		m.RemoveRRset([]mdns.RR{&mdns.ANY{Hdr: mdns.RR_Header{Name: name, Rrtype: qtype, Class: mdns.ClassINET}}})
+		if ch.Action == ActionDelete {
+			continue
+		}
+		if rec.Alias != nil {
+			return fmt.Errorf("rfc2136: %w: alias records need Route 53", ErrUnsupported)
+		}
+		rrs := make([]mdns.RR, 0, len(rec.Values))
+		for _, v := range rec.Values {
+			if rrType == "TXT" {
+				v = QuoteTXT(v)
+			}
+			rr, err := mdns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, rec.TTL, rrType, v))
+			if err != nil {
+				return fmt.Errorf("rfc2136: %s %s value %q: %w", name, rrType, v, err)
+			}
+			rrs = append(rrs, rr)
+		}
+		m.Insert(rrs)
+	}
+
+	in, err := p.exchange(ctx, m)
+	if err != nil {
+		return err
+	}
+	if in.Rcode != mdns.RcodeSuccess {
+		return fmt.Errorf("rfc2136: update %s refused: %s", Fqdn(zoneID), mdns.RcodeToString[in.Rcode])
+	}
+	return nil
+}
+
+// exchange signs m with the TSIG key, sends it and checks the answer's
+// signature.
+func (p *RFC2136) exchange(ctx context.Context, m *mdns.Msg) (*mdns.Msg, error) {
+	c := &mdns.Client{
+		Net:        p.Net,
+		Timeout:    p.Timeout,
+		TsigSecret: map[string]string{p.KeyName: p.Secret},
+	}
+	m.SetTsig(p.KeyName, p.Algorithm, 300, time.Now().Unix())
+	in, _, err := c.ExchangeContext(ctx, m, p.Server)
+	if err != nil {
+		return nil, fmt.Errorf("rfc2136: %s: %w", p.Server, err)
+	}
+	return in, nil
+}
+
+// rrValue is an RR's data in presentation form, with TXT unquoted.
+func rrValue(rr mdns.RR) string {
+	if txt, ok := rr.(*mdns.TXT); ok {
+		return strings.Join(txt.Txt, "")
+	}
+	return strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+}
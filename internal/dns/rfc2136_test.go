@@ -0,0 +1,238 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	mdns "github.com/miekg/dns"
+)
+
+const testTSIGSecret = "c2VjcmV0LWtleS1mb3ItdGVzdHM=" // "secret-key-for-tests"
+
+// fakeAuthServer is an in-process authoritative server for one zone that
+// answers queries from memory and applies RFC 2136 updates signed with the
+// test key.
+type fakeAuthServer struct {
+	mu      sync.Mutex
+	zone    string
+	rrs     []mdns.RR
+	updates int
+}
+
+func newFakeAuthServer(t *testing.T, zone string) (*fakeAuthServer, string) {
+	f := &fakeAuthServer{zone: zone}
+	soa, _ := mdns.NewRR(zone + " 3600 IN SOA ns1." + zone + " hostmaster." + zone + " 1 7200 900 1209600 300")
+	f.rrs = append(f.rrs, soa)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	started := make(chan struct{})
+	srv := &mdns.Server{
+		Listener:          l,
+		Handler:           f,
+		TsigSecret:        map[string]string{"autoglue.": testTSIGSecret},
+		NotifyStartedFunc: func() { close(started) },
+		// The default accept func answers UPDATE with NOTIMP.
+		MsgAcceptFunc: func(mdns.Header) mdns.MsgAcceptAction { return mdns.MsgAccept },
+	}
+	go func() { _ = srv.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return f, l.Addr().String()
+}
+
+func (f *fakeAuthServer) ServeDNS(w mdns.ResponseWriter, req *mdns.Msg) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resp := new(mdns.Msg)
+	resp.SetReply(req)
+	if t := req.IsTsig(); t != nil {
+		defer func() {
+			resp.SetTsig(t.Hdr.Name, t.Algorithm, 300, int64(t.TimeSigned))
+			_ = w.WriteMsg(resp)
+		}()
+	} else {
+		defer func() { _ = w.WriteMsg(resp) }()
+	}
+	if req.IsTsig() == nil || w.TsigStatus() != nil {
+		resp.Rcode = mdns.RcodeNotAuth
+		return
+	}
+
+	q := req.Question[0]
+	if req.Opcode == mdns.OpcodeUpdate {
+		if !strings.EqualFold(q.Name, f.zone) {
+			resp.Rcode = mdns.RcodeNotZone
+			return
+		}
+		f.updates++
+		for _, rr := range req.Ns {
+			h := rr.Header()
+			switch h.Class {
+			case mdns.ClassANY: // delete an RRset
+				f.remove(func(o mdns.RR) bool {
+					return strings.EqualFold(o.Header().Name, h.Name) && o.Header().Rrtype == h.Rrtype
+				})
+			case mdns.ClassINET: // add to an RRset
+				f.remove(func(o mdns.RR) bool { return mdns.IsDuplicate(o, rr) })
+				f.rrs = append(f.rrs, rr)
+			}
+		}
+		return
+	}
+
+	if q.Qtype == mdns.TypeAXFR {
+		soa := f.rrs[0]
+		resp.Answer = append([]mdns.RR{soa}, f.rrs[1:]...)
+		resp.Answer = append(resp.Answer, soa)
+		return
+	}
+
+	if !mdns.IsSubDomain(f.zone, q.Name) {
+		resp.Rcode = mdns.RcodeRefused
+		return
+	}
+	resp.Authoritative = true
+	found := false
+	for _, rr := range f.rrs {
+		if strings.EqualFold(rr.Header().Name, q.Name) {
+			found = true
+			if rr.Header().Rrtype == q.Qtype {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+	}
+	if !found && !strings.EqualFold(q.Name, f.zone) {
+		resp.Rcode = mdns.RcodeNameError
+	}
+}
+
+func (f *fakeAuthServer) remove(match func(mdns.RR) bool) {
+	kept := f.rrs[:0]
+	for _, rr := range f.rrs {
+		if !match(rr) {
+			kept = append(kept, rr)
+		}
+	}
+	f.rrs = kept
+}
+
+func newTestRFC2136(t *testing.T, addr string) *RFC2136 {
+	p, err := NewRFC2136(addr, "autoglue", "hmac-sha256", testTSIGSecret)
+	if err != nil {
+		t.Fatalf("NewRFC2136: %v", err)
+	}
+	return p
+}
+
+func TestRFC2136Zones(t *testing.T) {
+	_, addr := newFakeAuthServer(t, "example.org.")
+	p := newTestRFC2136(t, addr)
+	ctx := context.Background()
+
+	zone, err := p.FindZone(ctx, "Example.org")
+	if err != nil || zone != "example.org." {
+		t.Fatalf("FindZone = %q, %v", zone, err)
+	}
+	if err := p.GetZone(ctx, "other.net"); !errors.Is(err, ErrZoneNotFound) {
+		t.Fatalf("GetZone of a zone the server does not serve: %v", err)
+	}
+
+	bad, _ := NewRFC2136(addr, "autoglue", "hmac-sha256", "d3Jvbmc=")
+	if err := bad.GetZone(ctx, "example.org"); err == nil {
+		t.Fatal("a wrong TSIG secret should fail")
+	}
+}
+
+func TestRFC2136ApplyAndOwnership(t *testing.T) {
+	f, addr := newFakeAuthServer(t, "example.org.")
+	p := newTestRFC2136(t, addr)
+	ctx := context.Background()
+	fq := "api.example.org."
+
+	changes := []Change{
+		{Action: ActionUpsert, Record: Record{Name: fq, Type: "A", TTL: 300, Values: []string{"192.0.2.1", "192.0.2.2"}}},
+		{Action: ActionUpsert, Record: Record{Name: "example.org.", Type: "MX", TTL: 300, Values: []string{"10 mail.example.org."}}},
+		{Action: ActionUpsert, Record: Record{Name: MarkerName(fq), Type: "TXT", TTL: 300, Values: []string{MarkerValue("org", "rec", "fp")}}},
+	}
+	for _, r := range PoisonRecords(fq, "A", 300) {
+		changes = append(changes, Change{Action: ActionUpsert, Record: r})
+	}
+	if err := p.Apply(ctx, "example.org.", changes); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if f.updates != 1 {
+		t.Fatalf("a batch should be one UPDATE message, got %d", f.updates)
+	}
+
+	a, err := p.GetRecord(ctx, "example.org.", fq, "A")
+	if err != nil || a == nil {
+		t.Fatalf("GetRecord A = %+v, %v", a, err)
+	}
+	sort.Strings(a.Values)
+	if a.TTL != 300 || strings.Join(a.Values, ",") != "192.0.2.1,192.0.2.2" {
+		t.Fatalf("A = %+v", a)
+	}
+	mx, _ := p.GetRecord(ctx, "example.org.", "example.org.", "MX")
+	if mx == nil || mx.Values[0] != "10 mail.example.org." {
+		t.Fatalf("MX = %+v", mx)
+	}
+
+	markers, err := GetMarkers(ctx, p, "example.org.", fq)
+	if err != nil || len(markers) != 1 || markers[0].Org != "org" {
+		t.Fatalf("markers = %+v, %v", markers, err)
+	}
+	if owned, err := ExternalDNSOwned(ctx, p, "example.org.", fq, "A"); err != nil || owned {
+		t.Fatalf("own poison records read as external-dns ownership: %v %v", owned, err)
+	}
+
+	all, err := p.ListRecords(ctx, "example.org.")
+	if err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+	var sets []string
+	for _, r := range all {
+		sets = append(sets, r.Name+" "+r.Type)
+	}
+	if got := strings.Join(sets, ","); !strings.HasPrefix(got, "_autoglue.api.example.org. TXT,") || !strings.Contains(got, "api.example.org. A") || strings.Contains(got, "SOA") {
+		t.Fatalf("ListRecords = %s", got)
+	}
+
+	// An upsert replaces the RRset rather than adding to it.
+	if err := p.Apply(ctx, "example.org.", []Change{
+		{Action: ActionUpsert, Record: Record{Name: fq, Type: "A", TTL: 60, Values: []string{"192.0.2.3"}}},
+	}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	a, _ = p.GetRecord(ctx, "example.org.", fq, "A")
+	if a == nil || a.TTL != 60 || len(a.Values) != 1 || a.Values[0] != "192.0.2.3" {
+		t.Fatalf("A after replace = %+v", a)
+	}
+
+	if err := p.Apply(ctx, "example.org.", []Change{
+		{Action: ActionDelete, Record: Record{Name: fq, Type: "A"}},
+		{Action: ActionDelete, Record: Record{Name: MarkerName(fq), Type: "TXT"}},
+	}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if a, err := p.GetRecord(ctx, "example.org.", fq, "A"); err != nil || a != nil {
+		t.Fatalf("A after delete = %+v, %v", a, err)
+	}
+}
+
+func TestTSIGAlgorithm(t *testing.T) {
+	if alg, err := TSIGAlgorithm("HMAC-SHA512."); err != nil || alg != mdns.HmacSHA512 {
+		t.Fatalf("TSIGAlgorithm = %q, %v", alg, err)
+	}
+	if _, err := TSIGAlgorithm("hmac-md5"); err == nil {
+		t.Fatal("hmac-md5 should be refused")
+	}
+}
@@ -0,0 +1,258 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+)
+
+// Route53 talks to AWS Route 53. A change batch is applied atomically, and
+// its propagation can be followed with ChangeSynced.
+type Route53 struct {
+	Client *r53.Client
+}
+
+// NewRoute53 builds a client from static keys. Route 53 is global, but the
+// SDK still wants a region; it defaults to us-east-1.
+func NewRoute53(ctx context.Context, accessKeyID, secretAccessKey, region string) (*Route53, error) {
+	region = strings.TrimSpace(region)
+	if region == "" {
+		region = "us-east-1"
+	}
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			accessKeyID, secretAccessKey, "",
+		)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Route53{Client: r53.NewFromConfig(cfg)}, nil
+}
+
+func (p *Route53) Name() string { return "aws" }
+
+func (p *Route53) FindZone(ctx context.Context, domain string) (string, error) {
+	d := strings.TrimSuffix(Fqdn(domain), ".")
+	out, err := p.Client.ListHostedZonesByName(ctx, &r53.ListHostedZonesByNameInput{
+		DNSName: aws.String(d),
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, hz := range out.HostedZones {
+		if strings.TrimSuffix(aws.ToString(hz.Name), ".") == d {
+			return trimZoneID(aws.ToString(hz.Id)), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrZoneNotFound, d)
+}
+
+func (p *Route53) GetZone(ctx context.Context, zoneID string) error {
+	_, err := p.Client.GetHostedZone(ctx, &r53.GetHostedZoneInput{Id: aws.String(zoneID)})
+	var nf *r53types.NoSuchHostedZone
+	if errors.As(err, &nf) {
+		return fmt.Errorf("%w: %s", ErrZoneNotFound, zoneID)
+	}
+	return err
+}
+
+func (p *Route53) CreateZone(ctx context.Context, domain, ref string) (string, []string, error) {
+	out, err := p.Client.CreateHostedZone(ctx, &r53.CreateHostedZoneInput{
+		Name:            aws.String(strings.TrimSuffix(Fqdn(domain), ".")),
+		CallerReference: aws.String(ref),
+		HostedZoneConfig: &r53types.HostedZoneConfig{
+			Comment: aws.String("created by autoglue"),
+		},
+	})
+	if err != nil {
+		return "", nil, route53Err(err)
+	}
+	var ns []string
+	if out.DelegationSet != nil {
+		ns = out.DelegationSet.NameServers
+	}
+	return trimZoneID(aws.ToString(out.HostedZone.Id)), ns, nil
+}
+
+func (p *Route53) NameServers(ctx context.Context, zoneID string) ([]string, error) {
+	out, err := p.Client.GetHostedZone(ctx, &r53.GetHostedZoneInput{Id: aws.String(zoneID)})
+	if err != nil {
+		return nil, route53Err(err)
+	}
+	if out.DelegationSet == nil {
+		// Private zones have no delegation set.
+		return nil, nil
+	}
+	return out.DelegationSet.NameServers, nil
+}
+
+func (p *Route53) GetRecord(ctx context.Context, zoneID, name, rrType string) (*Record, error) {
+	name, rrType = Fqdn(name), strings.ToUpper(rrType)
+	out, err := p.Client.ListResourceRecordSets(ctx, &r53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zoneID),
+		StartRecordName: aws.String(name),
+		StartRecordType: r53types.RRType(rrType),
+		MaxItems:        aws.Int32(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.ResourceRecordSets) == 0 {
+		return nil, nil
+	}
+	rrset := out.ResourceRecordSets[0]
+	if Fqdn(aws.ToString(rrset.Name)) != name || string(rrset.Type) != rrType {
+		return nil, nil
+	}
+	rec := route53Record(rrset)
+	return &rec, nil
+}
+
+func (p *Route53) ListRecords(ctx context.Context, zoneID string) ([]Record, error) {
+	in := &r53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)}
+	var out []Record
+	for {
+		page, err := p.Client.ListResourceRecordSets(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		for _, rrset := range page.ResourceRecordSets {
+			if rrset.SetIdentifier != nil {
+				continue
+			}
+			out = append(out, route53Record(rrset))
+		}
+		if !page.IsTruncated {
+			break
+		}
+		in.StartRecordName = page.NextRecordName
+		in.StartRecordType = page.NextRecordType
+		in.StartRecordIdentifier = page.NextRecordIdentifier
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+func (p *Route53) Apply(ctx context.Context, zoneID string, changes []Change) error {
+	_, err := p.ApplyTracked(ctx, zoneID, changes)
+	return err
+}
+
+func (p *Route53) ApplyTracked(ctx context.Context, zoneID string, changes []Change) (string, error) {
+	batch := make([]r53types.Change, 0, len(changes))
+	for _, ch := range changes {
+		rec := ch.Record
+		if ch.Action == ActionDelete {
+			// Route 53 only deletes a record set given its exact current
+			// values, and fails the whole batch for one that is gone.
+			cur, err := p.GetRecord(ctx, zoneID, rec.Name, rec.Type)
+			if err != nil {
+				return "", route53Err(err)
+			}
+			if cur == nil {
+				continue
+			}
+			rec = *cur
+		}
+		batch = append(batch, r53types.Change{
+			Action:            r53types.ChangeAction(ch.Action),
+			ResourceRecordSet: route53RecordSet(rec),
+		})
+	}
+	if len(batch) == 0 {
+		return "", nil
+	}
+	out, err := p.Client.ChangeResourceRecordSets(ctx, &r53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch:  &r53types.ChangeBatch{Changes: batch},
+	})
+	if err != nil {
+		return "", route53Err(err)
+	}
+	return strings.TrimPrefix(aws.ToString(out.ChangeInfo.Id), "/change/"), nil
+}
+
+// ChangeSynced asks GetChange whether the change is INSYNC, which Route 53
+// reports once every one of its name servers serves it.
+func (p *Route53) ChangeSynced(ctx context.Context, changeID string) (bool, error) {
+	out, err := p.Client.GetChange(ctx, &r53.GetChangeInput{Id: aws.String(changeID)})
+	if err != nil {
+		return false, route53Err(err)
+	}
+	return out.ChangeInfo.Status == r53types.ChangeStatusInsync, nil
+}
+
+// route53Err marks the errors Route 53 throttles with. The SDK has already
+// retried them a few times by the time they get here.
+func route53Err(err error) error {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		switch ae.ErrorCode() {
+		case "Throttling", "ThrottlingException", "PriorRequestNotComplete":
+			return fmt.Errorf("%w: %w", ErrThrottled, err)
+		}
+	}
+	return err
+}
+
+func route53Record(rrset r53types.ResourceRecordSet) Record {
+	rec := Record{Name: Fqdn(aws.ToString(rrset.Name)), Type: string(rrset.Type), TTL: aws.ToInt64(rrset.TTL)}
+	if at := rrset.AliasTarget; at != nil {
+		rec.Alias = &AliasTarget{
+			HostedZoneID:         trimZoneID(aws.ToString(at.HostedZoneId)),
+			DNSName:              aws.ToString(at.DNSName),
+			EvaluateTargetHealth: at.EvaluateTargetHealth,
+		}
+		return rec
+	}
+	for _, rr := range rrset.ResourceRecords {
+		v := aws.ToString(rr.Value)
+		if rec.Type == "TXT" {
+			v = UnquoteTXT(v)
+		}
+		rec.Values = append(rec.Values, v)
+	}
+	return rec
+}
+
+func route53RecordSet(rec Record) *r53types.ResourceRecordSet {
+	rrType := strings.ToUpper(rec.Type)
+	if at := rec.Alias; at != nil {
+		return &r53types.ResourceRecordSet{
+			Name: aws.String(Fqdn(rec.Name)),
+			Type: r53types.RRType(rrType),
+			AliasTarget: &r53types.AliasTarget{
+				HostedZoneId:         aws.String(trimZoneID(at.HostedZoneID)),
+				DNSName:              aws.String(Fqdn(at.DNSName)),
+				EvaluateTargetHealth: at.EvaluateTargetHealth,
+			},
+		}
+	}
+	rrs := make([]r53types.ResourceRecord, 0, len(rec.Values))
+	for _, v := range rec.Values {
+		if rrType == "TXT" {
+			v = QuoteTXT(v)
+		}
+		rrs = append(rrs, r53types.ResourceRecord{Value: aws.String(v)})
+	}
+	return &r53types.ResourceRecordSet{
+		Name:            aws.String(Fqdn(rec.Name)),
+		Type:            r53types.RRType(rrType),
+		TTL:             aws.Int64(rec.TTL),
+		ResourceRecords: rrs,
+	}
+}
+
+func trimZoneID(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
@@ -0,0 +1,157 @@
+package dns
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	mdns "github.com/miekg/dns"
+)
+
+// maxTXTLength bounds a TXT value, all of its 255-byte strings together.
+// It leaves room for their quotes within Route 53's 4000 characters.
+const maxTXTLength = 3800
+
+// NormalizeValues checks values against what rrType holds and returns them
+// in canonical form: addresses in their shortest text, names lowercase
+// without a trailing dot, numbers without leading zeros, TXT unquoted and
+// CAA values quoted. Duplicates are dropped and the order kept, so equal
+// record sets get equal fingerprints however they were written.
+func NormalizeValues(rrType string, values []string) ([]string, error) {
+	rrType = strings.ToUpper(rrType)
+	out := make([]string, 0, len(values))
+	for _, raw := range values {
+		v, err := normalizeValue(rrType, strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s value %q: %w", rrType, raw, err)
+		}
+		if !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s needs at least one value", rrType)
+	}
+	if rrType == "CNAME" && len(out) != 1 {
+		return nil, fmt.Errorf("CNAME takes exactly one value")
+	}
+	return out, nil
+}
+
+func normalizeValue(rrType, v string) (string, error) {
+	if v == "" {
+		return "", fmt.Errorf("empty value")
+	}
+	switch rrType {
+	case "A", "AAAA":
+		a, err := netip.ParseAddr(v)
+		if err != nil || a.Zone() != "" {
+			return "", fmt.Errorf("not an IP address")
+		}
+		if rrType == "A" && !a.Is4() {
+			return "", fmt.Errorf("not an IPv4 address")
+		}
+		if rrType == "AAAA" && (!a.Is6() || a.Is4In6()) {
+			return "", fmt.Errorf("not an IPv6 address")
+		}
+		return a.String(), nil
+	case "CNAME", "NS":
+		return hostname(v)
+	case "MX":
+		f := strings.Fields(v)
+		if len(f) != 2 {
+			return "", fmt.Errorf(`want "<preference> <mail server>"`)
+		}
+		pref, err := uint16Field("preference", f[0])
+		if err != nil {
+			return "", err
+		}
+		host, err := target(f[1])
+		if err != nil {
+			return "", err
+		}
+		return pref + " " + host, nil
+	case "SRV":
+		f := strings.Fields(v)
+		if len(f) != 4 {
+			return "", fmt.Errorf(`want "<priority> <weight> <port> <target>"`)
+		}
+		nums := make([]string, 3)
+		for i, name := range []string{"priority", "weight", "port"} {
+			n, err := uint16Field(name, f[i])
+			if err != nil {
+				return "", err
+			}
+			nums[i] = n
+		}
+		host, err := target(f[3])
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(append(nums, host), " "), nil
+	case "CAA":
+		f := strings.Fields(v)
+		if len(f) < 3 {
+			return "", fmt.Errorf(`want "<flags> <tag> <value>"`)
+		}
+		flags, err := strconv.ParseUint(f[0], 10, 8)
+		if err != nil {
+			return "", fmt.Errorf("flags must be 0-255")
+		}
+		tag := strings.ToLower(f[1])
+		if tag == "" || strings.IndexFunc(tag, func(r rune) bool { return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') }) >= 0 {
+			return "", fmt.Errorf("tag must be letters and digits, such as issue, issuewild or iodef")
+		}
+		return fmt.Sprintf("%d %s %s", flags, tag, QuoteTXT(UnquoteTXT(caaValue(v)))), nil
+	case "TXT":
+		text := UnquoteTXT(v)
+		if len(text) > maxTXTLength {
+			return "", fmt.Errorf("longer than %d bytes", maxTXTLength)
+		}
+		return text, nil
+	}
+	return "", fmt.Errorf("unsupported record type")
+}
+
+// hostname checks a domain name and returns it lowercase without the
+// trailing dot.
+func hostname(v string) (string, error) {
+	name := strings.TrimSuffix(strings.ToLower(v), ".")
+	if name == "" || len(name) > 253 || strings.HasSuffix(name, ".") {
+		return "", fmt.Errorf("%q is not a host name", v)
+	}
+	if _, ok := mdns.IsDomainName(name); !ok || strings.ContainsAny(name, " \t\"\\") {
+		return "", fmt.Errorf("%q is not a host name", v)
+	}
+	return name, nil
+}
+
+// target is an MX or SRV target: a host name, or "." for the null MX of
+// RFC 7505 and the "no service" SRV of RFC 2782.
+func target(v string) (string, error) {
+	if v == "." {
+		return v, nil
+	}
+	return hostname(v)
+}
+
+// caaValue is what follows a CAA record's flags and tag, however they were
+// spaced.
+func caaValue(v string) string {
+	for range 2 {
+		v = strings.TrimLeftFunc(v, unicode.IsSpace)
+		v = strings.TrimLeftFunc(v, func(r rune) bool { return !unicode.IsSpace(r) })
+	}
+	return strings.TrimSpace(v)
+}
+
+func uint16Field(name, v string) (string, error) {
+	n, err := strconv.ParseUint(v, 10, 16)
+	if err != nil {
+		return "", fmt.Errorf("%s must be 0-65535", name)
+	}
+	return strconv.FormatUint(n, 10), nil
+}
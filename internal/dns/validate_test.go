@@ -0,0 +1,74 @@
+package dns
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeValues(t *testing.T) {
+	good := []struct {
+		rrType string
+		in     []string
+		want   []string
+	}{
+		{"A", []string{" 192.0.2.1 ", "192.0.2.1", "192.0.2.2"}, []string{"192.0.2.1", "192.0.2.2"}},
+		{"AAAA", []string{"2001:DB8:0::1"}, []string{"2001:db8::1"}},
+		{"CNAME", []string{"LB.Example.net."}, []string{"lb.example.net"}},
+		{"MX", []string{"010 Mx1.example.org."}, []string{"10 mx1.example.org"}},
+		{"SRV", []string{"10 5 5060 sip.example.org."}, []string{"10 5 5060 sip.example.org"}},
+		{"CAA", []string{"0 ISSUE letsencrypt.org"}, []string{`0 issue "letsencrypt.org"`}},
+		{"CAA", []string{"0\tissue   \"ca.example.net;  account=1\""}, []string{`0 issue "ca.example.net;  account=1"`}},
+		{"MX", []string{"0 ."}, []string{"0 ."}},
+		{"SRV", []string{"0 0 0 ."}, []string{"0 0 0 ."}},
+		{"TXT", []string{`"v=spf1 " "-all"`}, []string{"v=spf1 -all"}},
+	}
+	for _, c := range good {
+		got, err := NormalizeValues(c.rrType, c.in)
+		if err != nil {
+			t.Errorf("%s %v: %v", c.rrType, c.in, err)
+			continue
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s %v = %v, want %v", c.rrType, c.in, got, c.want)
+		}
+	}
+
+	bad := []struct {
+		rrType string
+		in     []string
+	}{
+		{"A", []string{"2001:db8::1"}},
+		{"A", []string{"192.0.2.300"}},
+		{"AAAA", []string{"192.0.2.1"}},
+		{"AAAA", []string{"::ffff:192.0.2.1"}},
+		{"CNAME", []string{"a.example.org", "b.example.org"}},
+		{"CNAME", []string{"not a host"}},
+		{"MX", []string{"mx1.example.org"}},
+		{"MX", []string{"70000 mx1.example.org"}},
+		{"SRV", []string{"10 5 sip.example.org"}},
+		{"MX", []string{"10 .."}},
+		{"CAA", []string{"0  issue"}},
+		{"CAA", []string{"256 issue letsencrypt.org"}},
+		{"CAA", []string{"0 is-sue letsencrypt.org"}},
+		{"TXT", []string{strings.Repeat("x", maxTXTLength+1)}},
+		{"TXT", []string{}},
+		{"TXT", []string{" "}},
+	}
+	for _, c := range bad {
+		if got, err := NormalizeValues(c.rrType, c.in); err == nil {
+			t.Errorf("%s %v should be rejected, got %v", c.rrType, c.in, got)
+		}
+	}
+}
+
+func TestQuoteTXTSplitsLongValues(t *testing.T) {
+	long := strings.Repeat("a", 255) + strings.Repeat("b", 45)
+	quoted := QuoteTXT(long)
+	if want := `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("b", 45) + `"`; quoted != want {
+		t.Fatalf("QuoteTXT = %q, want two strings", quoted)
+	}
+	if got := UnquoteTXT(quoted); got != long {
+		t.Fatalf("round trip lost data: %d bytes back", len(got))
+	}
+}
@@ -0,0 +1,67 @@
+package dns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	mdns "github.com/miekg/dns"
+)
+
+// WriteZoneFile writes recs as an RFC 1035 master file for origin, one line
+// per value, with $ORIGIN and $TTL directives first. Aliases have no master
+// file form and are written as comments, as are values that do not parse.
+func WriteZoneFile(w io.Writer, origin string, defaultTTL int64, recs []Record) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "$ORIGIN %s\n$TTL %d\n", Fqdn(origin), defaultTTL)
+	for _, rec := range recs {
+		name, rrType := Fqdn(rec.Name), strings.ToUpper(rec.Type)
+		if rec.Alias != nil {
+			fmt.Fprintf(bw, "; %s %s alias to %s (Route 53 only)\n", name, rrType, Fqdn(rec.Alias.DNSName))
+			continue
+		}
+		for _, v := range rec.Values {
+			rr, err := zoneFileRR(name, rrType, rec.TTL, v)
+			if err != nil {
+				fmt.Fprintf(bw, "; %s %s %q: %v\n", name, rrType, v, err)
+				continue
+			}
+			fmt.Fprintln(bw, rr.String())
+		}
+	}
+	return bw.Flush()
+}
+
+func zoneFileRR(name, rrType string, ttl int64, value string) (mdns.RR, error) {
+	if rrType == "TXT" {
+		return &mdns.TXT{
+			Hdr: mdns.RR_Header{Name: name, Rrtype: mdns.TypeTXT, Class: mdns.ClassINET, Ttl: uint32(ttl)},
+			Txt: splitTXT(value),
+		}, nil
+	}
+	return mdns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, ttl, rrType, value))
+}
+
+// ParseZoneFile reads an RFC 1035 master file into record sets, ordered by
+// name and type. Relative names are completed with origin until an $ORIGIN
+// directive changes it, records without a TTL take $TTL or else defaultTTL,
+// and the strings of a TXT record are joined into one value. $INCLUDE is
+// refused.
+func ParseZoneFile(r io.Reader, origin string, defaultTTL int64) ([]Record, error) {
+	zp := mdns.NewZoneParser(r, Fqdn(origin), "")
+	zp.SetDefaultTTL(uint32(defaultTTL))
+
+	var sets recordSets
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		h := rr.Header()
+		if h.Class != mdns.ClassINET {
+			continue
+		}
+		sets.add(h.Name, mdns.TypeToString[h.Rrtype], int64(h.Ttl), rrValue(rr))
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	return sets.list(), nil
+}
@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseZoneFile(t *testing.T) {
+	const zone = `$TTL 600
+@	IN	SOA	ns1.example.org. hostmaster.example.org. 1 7200 900 1209600 300
+@		NS	ns1.example.org.
+www	300	A	192.0.2.1
+www		A	192.0.2.2
+mail		MX	10 mx1
+spf		TXT	"v=spf1 " "include:_spf.example.net ~all"
+$ORIGIN sub.example.org.
+api		CNAME	lb.example.net.
+`
+	recs, err := ParseZoneFile(strings.NewReader(zone), "example.org", 300)
+	if err != nil {
+		t.Fatalf("ParseZoneFile: %v", err)
+	}
+	got := map[string]Record{}
+	for _, r := range recs {
+		got[r.Name+" "+r.Type] = r
+	}
+
+	if r := got["www.example.org. A"]; r.TTL != 300 || !slices.Equal(r.Values, []string{"192.0.2.1", "192.0.2.2"}) {
+		t.Fatalf("www A = %+v", r)
+	}
+	if r := got["mail.example.org. MX"]; r.TTL != 600 || !slices.Equal(r.Values, []string{"10 mx1.example.org."}) {
+		t.Fatalf("MX should take $TTL and complete its relative target: %+v", r)
+	}
+	if r := got["spf.example.org. TXT"]; !slices.Equal(r.Values, []string{"v=spf1 include:_spf.example.net ~all"}) {
+		t.Fatalf("TXT strings should be joined: %+v", r)
+	}
+	if _, ok := got["api.sub.example.org. CNAME"]; !ok {
+		t.Fatalf("$ORIGIN should apply to the names after it: %v", recs)
+	}
+	if _, ok := got["example.org. SOA"]; !ok {
+		t.Fatal("the SOA is returned; callers decide what to keep")
+	}
+
+	if _, err := ParseZoneFile(strings.NewReader("www IN A not-an-address\n"), "example.org", 300); err == nil {
+		t.Fatal("a bad record should fail the parse")
+	}
+	if _, err := ParseZoneFile(strings.NewReader("$INCLUDE /etc/passwd\n"), "example.org", 300); err == nil {
+		t.Fatal("$INCLUDE should be refused")
+	}
+}
+
+func TestZoneFileRoundTrip(t *testing.T) {
+	long := strings.Repeat("k", 300)
+	recs := []Record{
+		{Name: "example.org.", Type: "MX", TTL: 3600, Values: []string{"10 mx1.example.org."}},
+		{Name: "dkim.example.org.", Type: "TXT", TTL: 300, Values: []string{long}},
+		{Name: "www.example.org.", Type: "A", TTL: 60, Values: []string{"192.0.2.1", "192.0.2.2"}},
+		{Name: "lb.example.org.", Type: "A", Alias: &AliasTarget{HostedZoneID: "Z1", DNSName: "elb.amazonaws.com"}},
+	}
+	var buf bytes.Buffer
+	if err := WriteZoneFile(&buf, "example.org", 300, recs); err != nil {
+		t.Fatalf("WriteZoneFile: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "$ORIGIN example.org.\n$TTL 300\n") {
+		t.Fatalf("missing directives:\n%s", out)
+	}
+	if !strings.Contains(out, `"`+strings.Repeat("k", 255)+`" "`+strings.Repeat("k", 45)+`"`) {
+		t.Fatalf("long TXT should be split into 255-byte strings:\n%s", out)
+	}
+	if !strings.Contains(out, "; lb.example.org. A alias to elb.amazonaws.com.") {
+		t.Fatalf("alias should be a comment:\n%s", out)
+	}
+
+	back, err := ParseZoneFile(strings.NewReader(out), "example.org", 300)
+	if err != nil {
+		t.Fatalf("parse export: %v\n%s", err, out)
+	}
+	if len(back) != 3 {
+		t.Fatalf("got %d record sets back, want 3: %+v", len(back), back)
+	}
+	for i, want := range []Record{recs[1], recs[0], recs[2]} {
+		if back[i].Name != want.Name || back[i].TTL != want.TTL || !slices.Equal(back[i].Values, want.Values) {
+			t.Fatalf("record %d = %+v, want %+v", i, back[i], want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/common"
 	"github.com/glueops/autoglue/internal/handlers/dto"
 	"github.com/glueops/autoglue/internal/models"
@@ -125,7 +126,7 @@ func GetAnnotation(db *gorm.DB) http.HandlerFunc {
 //	@Param			X-Org-ID	header		string						false	"Organization UUID"
 //	@Param			body		body		dto.CreateAnnotationRequest	true	"Annotation payload"
 //	@Success		201			{object}	dto.AnnotationResponse
-//	@Failure		400			{string}	string	"invalid json / missing fields"
+//	@Failure		400			{string}	string	"invalid json / missing fields / key not valid for Kubernetes"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		500			{string}	string	"create failed"
@@ -160,6 +161,10 @@ func CreateAnnotation(db *gorm.DB) http.HandlerFunc {
 			Key:         req.Key,
 			Value:       req.Value,
 		}
+		if err := a.Validate(); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
 
 		if err := db.Create(&a).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
@@ -187,7 +192,7 @@ func CreateAnnotation(db *gorm.DB) http.HandlerFunc {
 //	@Param			id			path		string						true	"Annotation ID (UUID)"
 //	@Param			body		body		dto.UpdateAnnotationRequest	true	"Fields to update"
 //	@Success		200			{object}	dto.AnnotationResponse
-//	@Failure		400			{string}	string	"invalid id / invalid json"
+//	@Failure		400			{string}	string	"invalid id / invalid json / key not valid for Kubernetes"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		404			{string}	string	"not found"
@@ -196,7 +201,7 @@ func CreateAnnotation(db *gorm.DB) http.HandlerFunc {
 //	@Security		BearerAuth
 //	@Security		OrgKeyAuth
 //	@Security		OrgSecretAuth
-func UpdateAnnotation(db *gorm.DB) http.HandlerFunc {
+func UpdateAnnotation(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -233,6 +238,11 @@ func UpdateAnnotation(db *gorm.DB) http.HandlerFunc {
 			a.Value = strings.TrimSpace(*req.Value)
 		}
 
+		if err := a.Validate(); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+
 		if err := db.Save(&a).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
 			return
@@ -243,6 +253,7 @@ func UpdateAnnotation(db *gorm.DB) http.HandlerFunc {
 			Key:         a.Key,
 			Value:       a.Value,
 		}
+		syncNodeMetadata(r.Context(), db, jobs, poolsUsing(db, orgID, "node_annotations", "annotation_id", id)...)
 		utils.WriteJSON(w, http.StatusOK, out)
 	}
 }
@@ -265,7 +276,7 @@ func UpdateAnnotation(db *gorm.DB) http.HandlerFunc {
 //	@Security		BearerAuth
 //	@Security		OrgKeyAuth
 //	@Security		OrgSecretAuth
-func DeleteAnnotation(db *gorm.DB) http.HandlerFunc {
+func DeleteAnnotation(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -279,10 +290,13 @@ func DeleteAnnotation(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		// The join rows go with it, so find the pools first.
+		pools := poolsUsing(db, orgID, "node_annotations", "annotation_id", id)
 		if err := db.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.Annotation{}).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, pools...)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -40,6 +40,7 @@ const (
 	colClusterName                    = "name"
 	colClusterProvider                = "provider"
 	colClusterRegion                  = "region"
+	colClusterTopology                = "topology"
 	colClusterStatus                  = "status"
 	colClusterLastError               = "last_error"
 	colClusterCaptainDomainID         = "captain_domain_id"
@@ -59,6 +60,7 @@ var clusterWritableColumns = []string{
 	colClusterName,
 	colClusterProvider,
 	colClusterRegion,
+	colClusterTopology,
 	colClusterStatus,
 	colClusterLastError,
 	colClusterCaptainDomainID,
@@ -83,6 +85,9 @@ var clusterImmutableColumns = []string{
 	"certificate_key",
 	"created_at",
 	"updated_at",
+	// Owned by the server_health sweep.
+	"degraded",
+	"degraded_reason",
 }
 
 // ListClusters godoc
@@ -234,7 +239,7 @@ func GetCluster(db *gorm.DB, cfg config.Config) http.HandlerFunc {
 //	@Param			X-Org-ID	header		string						false	"Organization UUID"
 //	@Param			body		body		dto.CreateClusterRequest	true	"payload"
 //	@Success		201			{object}	dto.ClusterResponse
-//	@Failure		400			{string}	string	"invalid json"
+//	@Failure		400			{string}	string	"invalid json / invalid topology"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		500			{string}	string	"create failed"
@@ -256,6 +261,15 @@ func CreateCluster(db *gorm.DB, cfg config.Config) http.HandlerFunc {
 			return
 		}
 
+		topology := strings.TrimSpace(in.Topology)
+		if topology == "" {
+			topology = models.ClusterTopologyStacked
+		}
+		if err := models.ValidateClusterTopology(topology); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_topology", err.Error())
+			return
+		}
+
 		certificateKey, err := GenerateSecureHex(32)
 		if err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "internal_error", "failed to generate certificate key")
@@ -273,6 +287,7 @@ func CreateCluster(db *gorm.DB, cfg config.Config) http.HandlerFunc {
 			Name:           in.Name,
 			Provider:       in.ClusterProvider,
 			Region:         in.Region,
+			Topology:       topology,
 			Status:         models.ClusterStatusPrePending,
 			LastError:      "",
 			CertificateKey: certificateKey,
@@ -294,7 +309,7 @@ func CreateCluster(db *gorm.DB, cfg config.Config) http.HandlerFunc {
 //
 //	@ID				UpdateCluster
 //	@Summary		Update basic cluster details (org scoped)
-//	@Description	Updates the cluster name, provider, and/or region. Status is managed by the system.
+//	@Description	Updates the cluster name, provider, region, topology and/or image. Status is managed by the system. The topology can only change before the cluster has been bootstrapped, and not to stacked while etcd node pools are attached.
 //	@Tags			Clusters
 //	@Accept			json
 //	@Produce		json
@@ -306,6 +321,7 @@ func CreateCluster(db *gorm.DB, cfg config.Config) http.HandlerFunc {
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		404			{string}	string	"cluster not found"
+//	@Failure		409			{string}	string	"topology cannot change"
 //	@Failure		500			{string}	string	"db error"
 //	@Router			/clusters/{clusterID} [patch]
 //	@Security		BearerAuth
@@ -354,6 +370,35 @@ func UpdateCluster(db *gorm.DB, cfg config.Config) http.HandlerFunc {
 		if in.Region != nil {
 			updates[colClusterRegion] = *in.Region
 		}
+		if in.Topology != nil && *in.Topology != cluster.Topology {
+			topology := strings.TrimSpace(*in.Topology)
+			if err := models.ValidateClusterTopology(topology); err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_topology", err.Error())
+				return
+			}
+			// kubeadm fixes where etcd lives at init; moving it afterwards is a
+			// migration, not a setting.
+			if cluster.EncryptedKubeconfig != "" {
+				utils.WriteError(w, http.StatusConflict, "topology_immutable", "topology cannot change once the cluster has been bootstrapped")
+				return
+			}
+			if topology == models.ClusterTopologyStacked {
+				var etcdPools []string
+				if err := db.Model(&models.NodePool{}).
+					Joins("JOIN cluster_node_pools cnp ON cnp.node_pool_id = node_pools.id").
+					Where("cnp.cluster_id = ? AND node_pools.role = ?", clusterID, models.NodePoolRoleEtcd).
+					Pluck("node_pools.name", &etcdPools).Error; err != nil {
+					utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+					return
+				}
+				if len(etcdPools) > 0 {
+					utils.WriteError(w, http.StatusConflict, "topology_conflict",
+						fmt.Sprintf("detach etcd node pools (%s) before switching to the stacked topology", strings.Join(etcdPools, ", ")))
+					return
+				}
+			}
+			updates[colClusterTopology] = topology
+		}
 		if in.DockerImage != nil {
 			updates[colClusterDockerImage] = *in.DockerImage
 		}
@@ -1142,6 +1187,7 @@ func ClearClusterKubeconfig(db *gorm.DB, cfg config.Config) http.HandlerFunc {
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		404			{string}	string	"cluster or node pool not found"
+//	@Failure		409			{string}	string	"etcd node pool on a stacked cluster / a placement rule would be broken"
 //	@Failure		500			{string}	string	"db error"
 //	@Router			/clusters/{clusterID}/node-pools [post]
 //	@Security		BearerAuth
@@ -1195,9 +1241,24 @@ func AttachNodePool(db *gorm.DB, cfg config.Config) http.HandlerFunc {
 			return
 		}
 
+		if np.Role == models.NodePoolRoleEtcd && cluster.Topology != models.ClusterTopologyExternal {
+			utils.WriteError(w, http.StatusConflict, "topology_conflict",
+				fmt.Sprintf("node pool %s has the etcd role, which needs a cluster with the external topology", np.Name))
+			return
+		}
+
+		// Every server in the pool joins the cluster, so each is checked.
+		placed, err := poolServerIDs(db, np.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
 		// Create association in join table
-		if err := db.Model(&cluster).Association("NodePools").Append(&np); err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to attach node pool")
+		if err := savePlacement(db, orgID, placed, func(tx *gorm.DB) error {
+			return tx.Model(&cluster).Association("NodePools").Append(&np)
+		}); err != nil {
+			writeConflictOrDBError(w, err)
 			return
 		}
 
@@ -1333,6 +1394,10 @@ func clusterToDTO(c models.Cluster, cfg config.Config) dto.ClusterResponse {
 	for _, m := range c.Metadata {
 		metadata[m.Key] = m.Value
 	}
+	var etcd []dto.ServerResponse
+	for _, s := range c.EtcdMembers() {
+		etcd = append(etcd, serverToDTO(s))
+	}
 	return dto.ClusterResponse{
 		ID:                    c.ID,
 		Name:                  c.Name,
@@ -1345,8 +1410,12 @@ func clusterToDTO(c models.Cluster, cfg config.Config) dto.ClusterResponse {
 		BastionServer:         bastion,
 		Provider:              c.Provider,
 		Region:                c.Region,
+		Topology:              c.Topology,
+		EtcdMembers:           etcd,
 		Status:                c.Status,
 		LastError:             c.LastError,
+		Degraded:              c.Degraded,
+		DegradedReason:        c.DegradedReason,
 		RandomToken:           c.RandomToken,
 		CertificateKey:        c.CertificateKey,
 		NodePools:             nps,
@@ -1402,6 +1471,11 @@ func nodePoolToDTO(np models.NodePool) dto.NodePoolResponse {
 		Annotations: annotations,
 		Taints:      taints,
 		Servers:     servers,
+		DesiredSize: np.DesiredSize,
+		Template:    nodePoolTemplateToDTO(np.Template),
+		JoinPending: np.JoinPending,
+		ScaleError:  np.ScaleError,
+		ScaledAt:    np.ScaledAt,
 	}
 }
 
@@ -1415,6 +1489,10 @@ func serverToDTO(s models.Server) dto.ServerResponse {
 		Status:           s.Status,
 		SSHUser:          s.SSHUser,
 		SshKeyID:         s.SshKeyID,
+		LastSeenAt:       s.LastSeenAt,
+		LastProbeAt:      s.LastProbeAt,
+		ProbeLatencyMs:   s.ProbeLatencyMs,
+		ProbeError:       s.ProbeError,
 		CreatedAt:        s.CreatedAt.UTC().Format(time.RFC3339),
 		UpdatedAt:        s.UpdatedAt.UTC().Format(time.RFC3339),
 	}
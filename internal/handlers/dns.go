@@ -1,9 +1,7 @@
 package handlers
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +10,8 @@ import (
 	"time"
 
 	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/dns"
 	"github.com/glueops/autoglue/internal/handlers/dto"
 	"github.com/glueops/autoglue/internal/models"
 	"github.com/glueops/autoglue/internal/utils"
@@ -38,103 +38,84 @@ func fqdn(domain string, rel string) string {
 	return r + "." + d
 }
 
-func canonicalJSONAny(v any) ([]byte, error) {
-	b, err := json.Marshal(v)
-	if err != nil {
-		return nil, err
-	}
-	var anyv any
-	if err := json.Unmarshal(b, &anyv); err != nil {
-		return nil, err
-	}
-	return marshalSortedDNS(anyv)
-}
+// dnsProviderCallTimeout bounds the handlers that call the DNS provider
+// while the client waits.
+const dnsProviderCallTimeout = time.Minute
 
-func marshalSortedDNS(v any) ([]byte, error) {
-	switch vv := v.(type) {
-	case map[string]any:
-		keys := make([]string, 0, len(vv))
-		for k := range vv {
-			keys = append(keys, k)
-		}
-		sortStrings(keys)
-		var buf bytes.Buffer
-		buf.WriteByte('{')
-		for i, k := range keys {
-			if i > 0 {
-				buf.WriteByte(',')
-			}
-			kb, _ := json.Marshal(k)
-			buf.Write(kb)
-			buf.WriteByte(':')
-			b, err := marshalSortedDNS(vv[k])
-			if err != nil {
-				return nil, err
-			}
-			buf.Write(b)
-		}
-		buf.WriteByte('}')
-		return buf.Bytes(), nil
-	case []any:
-		var buf bytes.Buffer
-		buf.WriteByte('[')
-		for i, e := range vv {
-			if i > 0 {
-				buf.WriteByte(',')
-			}
-			b, err := marshalSortedDNS(e)
-			if err != nil {
-				return nil, err
-			}
-			buf.Write(b)
+// computeFingerprint is dns.Fingerprint over a row's JSON values and alias.
+func computeFingerprint(zoneID, fqdn, typ string, ttl *int, values, alias datatypes.JSON) (string, error) {
+	var vals []string
+	if len(values) > 0 && string(values) != "null" {
+		if err := json.Unmarshal(values, &vals); err != nil {
+			return "", err
 		}
-		buf.WriteByte(']')
-		return buf.Bytes(), nil
-	default:
-		return json.Marshal(v)
 	}
-}
-
-func sortStrings(a []string) {
-	for i := 0; i < len(a); i++ {
-		for j := i + 1; j < len(a); j++ {
-			if a[j] < a[i] {
-				a[i], a[j] = a[j], a[i]
-			}
-		}
+	at, err := dto.AliasFromJSON(alias)
+	if err != nil {
+		return "", err
 	}
+	return dns.Fingerprint(zoneID, fqdn, typ, ttl, vals, at.DNS()), nil
 }
 
-func sha256HexBytes(b []byte) string {
-	sum := sha256.Sum256(b)
-	return hex.EncodeToString(sum[:])
+// aliasProblem says why a record set cannot be an alias, "" when it can.
+// Aliases are Route 53's, for A and AAAA, and take neither values nor a TTL.
+func aliasProblem(db *gorm.DB, domain *models.Domain, rrType string, ttl *int, values int) (string, error) {
+	if rrType != "A" && rrType != "AAAA" {
+		return "alias is only for A and AAAA records", nil
+	}
+	if values > 0 || ttl != nil {
+		return "an alias takes neither values nor ttl", nil
+	}
+	var cred models.Credential
+	if err := db.Select("provider").Where("id = ?", domain.CredentialID).First(&cred).Error; err != nil {
+		return "", err
+	}
+	if cred.Provider != "aws" {
+		return "alias records need a domain on Route 53", nil
+	}
+	return "", nil
 }
 
-/* Fingerprint (provider-agnostic) */
-type desiredRecord struct {
-	ZoneID string   `json:"zone_id"`
-	FQDN   string   `json:"fqdn"`
-	Type   string   `json:"type"`
-	TTL    *int     `json:"ttl,omitempty"`
-	Values []string `json:"values,omitempty"`
+// isApex reports whether a relative record name is the zone apex.
+func isApex(rel string) bool {
+	return rel == "" || rel == "@"
 }
 
-func computeFingerprint(zoneID, fqdn, typ string, ttl *int, values datatypes.JSON) (string, error) {
-	var vals []string
-	if len(values) > 0 && string(values) != "null" {
-		if err := json.Unmarshal(values, &vals); err != nil {
-			return "", err
-		}
-		sortStrings(vals)
+// cnameConflict says why a record set of type rrType cannot go at rel next
+// to the domain's other record sets, "" when it can: a CNAME shares its name
+// with no other record set.
+func cnameConflict(db *gorm.DB, domainID uuid.UUID, rel, rrType string, self uuid.UUID) (string, error) {
+	names := []string{strings.ToLower(rel)}
+	if isApex(rel) {
+		names = []string{"", "@"}
 	}
-	payload := &desiredRecord{
-		ZoneID: zoneID, FQDN: fqdn, Type: strings.ToUpper(typ), TTL: ttl, Values: vals,
+	q := db.Model(&models.RecordSet{}).
+		Where("domain_id = ? AND LOWER(name) IN ? AND id <> ? AND type <> ?", domainID, names, self, rrType)
+	if rrType != "CNAME" {
+		q = q.Where("type = ?", "CNAME")
 	}
-	can, err := canonicalJSONAny(payload)
-	if err != nil {
+	var n int64
+	if err := q.Count(&n).Error; err != nil {
 		return "", err
 	}
-	return sha256HexBytes(can), nil
+	switch {
+	case n == 0:
+		return "", nil
+	case rrType == "CNAME":
+		return "the name has other record sets; a CNAME cannot share it", nil
+	default:
+		return "the name has a CNAME, which cannot share it with other record sets", nil
+	}
+}
+
+func aliasJSON(a *dto.AliasTarget) datatypes.JSON {
+	if a == nil {
+		return nil
+	}
+	a.HostedZoneID = strings.TrimPrefix(strings.TrimSpace(a.HostedZoneID), "/hostedzone/")
+	a.DNSName = normLowerNoDot(a.DNSName)
+	b, _ := json.Marshal(a)
+	return datatypes.JSON(b)
 }
 
 func mustSameOrgDomainWithCredential(db *gorm.DB, orgID uuid.UUID, credID uuid.UUID) error {
@@ -145,15 +126,28 @@ func mustSameOrgDomainWithCredential(db *gorm.DB, orgID uuid.UUID, credID uuid.U
 		}
 		return err
 	}
-	if cred.Provider != "aws" || cred.ScopeKind != "service" {
-		return fmt.Errorf("credential must be AWS Route 53 service scoped")
-	}
-	var scope map[string]any
-	if err := json.Unmarshal(cred.Scope, &scope); err != nil {
-		return fmt.Errorf("credential scope invalid json: %w", err)
-	}
-	if strings.ToLower(fmt.Sprint(scope["service"])) != "route53" {
-		return fmt.Errorf("credential scope.service must be route53")
+	switch cred.Provider {
+	case "aws":
+		if cred.ScopeKind != "service" {
+			return fmt.Errorf("credential must be AWS Route 53 service scoped")
+		}
+		var scope map[string]any
+		if err := json.Unmarshal(cred.Scope, &scope); err != nil {
+			return fmt.Errorf("credential scope invalid json: %w", err)
+		}
+		if strings.ToLower(fmt.Sprint(scope["service"])) != "route53" {
+			return fmt.Errorf("credential scope.service must be route53")
+		}
+	case "cloudflare":
+		if cred.Kind != "api_token" {
+			return fmt.Errorf("cloudflare credential must be an api_token")
+		}
+	case "rfc2136":
+		if cred.Kind != "tsig" {
+			return fmt.Errorf("rfc2136 credential must be a tsig key")
+		}
+	default:
+		return fmt.Errorf("credential must be AWS Route 53 service scoped, a Cloudflare API token or an RFC 2136 TSIG key")
 	}
 	return nil
 }
@@ -169,7 +163,7 @@ func mustSameOrgDomainWithCredential(db *gorm.DB, orgID uuid.UUID, credID uuid.U
 //	@Produce		json
 //	@Param			X-Org-ID	header		string	false	"Organization UUID"
 //	@Param			domain_name	query		string	false	"Exact domain name (lowercase, no trailing dot)"
-//	@Param			status		query		string	false	"pending|provisioning|ready|failed"
+//	@Param			status		query		string	false	"pending|provisioning|ready|failed|deleting"
 //	@Param			q			query		string	false	"Domain contains (case-insensitive)"
 //	@Success		200			{array}		dto.DomainResponse
 //	@Failure		401			{string}	string	"Unauthorized"
@@ -255,7 +249,7 @@ func GetDomain(db *gorm.DB) http.HandlerFunc {
 //
 //	@ID				CreateDomain
 //	@Summary		Create a domain (org scoped)
-//	@Description	Creates a domain bound to a Route 53 scoped credential. The dns_reconcile worker will backfill ZoneID if omitted.
+//	@Description	Creates a domain bound to a Route 53 scoped AWS credential, a Cloudflare API token or an RFC 2136 TSIG key for a self-hosted server. The dns_reconcile worker will backfill ZoneID if omitted; with create_zone it creates the hosted zone when none exists (Route 53 only). The response then lists the zone's name_servers, and delegation reports whether the parent zone delegates to them, read from the parent's NS record set when the parent is a domain in autoglue and looked up in DNS otherwise.
 //	@Tags			DNS
 //	@Accept			json
 //	@Produce		json
@@ -291,6 +285,21 @@ func CreateDomain(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "invalid_credential", err.Error())
 			return
 		}
+		if in.CreateZone {
+			if strings.TrimSpace(in.ZoneID) != "" {
+				utils.WriteError(w, http.StatusBadRequest, "validation_error", "give either zone_id or create_zone, not both")
+				return
+			}
+			var cred models.Credential
+			if err := db.Select("provider").Where("id = ?", credID).First(&cred).Error; err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+				return
+			}
+			if !dns.CanCreateZones(cred.Provider) {
+				utils.WriteError(w, http.StatusBadRequest, "validation_error", "create_zone needs a Route 53 credential")
+				return
+			}
+		}
 
 		row := &models.Domain{
 			OrganizationID: orgID,
@@ -299,6 +308,7 @@ func CreateDomain(db *gorm.DB) http.HandlerFunc {
 			Status:         "pending",
 			LastError:      "",
 			CredentialID:   credID,
+			CreateZone:     in.CreateZone,
 		}
 		if err := db.Create(row).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
@@ -322,6 +332,7 @@ func CreateDomain(db *gorm.DB) http.HandlerFunc {
 //	@Failure	400			{string}	string	"validation error"
 //	@Failure	403			{string}	string	"organization required"
 //	@Failure	404			{string}	string	"not found"
+//	@Failure	409			{string}	string	"domain is being deleted"
 //	@Router		/dns/domains/{id} [patch]
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
@@ -347,6 +358,10 @@ func UpdateDomain(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
 			return
 		}
+		if row.Status == "deleting" {
+			utils.WriteError(w, http.StatusConflict, "deleting", "domain is being deleted")
+			return
+		}
 		var in dto.UpdateDomainRequest
 		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 			utils.WriteError(w, http.StatusBadRequest, "bad_json", err.Error())
@@ -356,6 +371,7 @@ func UpdateDomain(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
 			return
 		}
+		zoneBefore, nameBefore := row.ZoneID, row.DomainName
 		if in.DomainName != nil {
 			row.DomainName = normLowerNoDot(*in.DomainName)
 		}
@@ -365,6 +381,11 @@ func UpdateDomain(db *gorm.DB) http.HandlerFunc {
 				utils.WriteError(w, http.StatusBadRequest, "invalid_credential", err.Error())
 				return
 			}
+			// A zone id belongs to one provider account; the reconciler
+			// looks it up again unless a new one is given too.
+			if credID != row.CredentialID && in.ZoneID == nil {
+				row.ZoneID = ""
+			}
 			row.CredentialID = credID
 			row.Status = "pending"
 			row.LastError = ""
@@ -372,6 +393,13 @@ func UpdateDomain(db *gorm.DB) http.HandlerFunc {
 		if in.ZoneID != nil {
 			row.ZoneID = strings.TrimSpace(*in.ZoneID)
 		}
+		if row.ZoneID != zoneBefore || row.DomainName != nameBefore {
+			// Another zone has other name servers; the reconciler reads
+			// them and checks delegation again.
+			row.NameServers = nil
+			row.DelegationStatus, row.DelegationSource = "", ""
+			row.DelegatedNameServers, row.DelegationCheckedAt = nil, nil
+		}
 		if in.Status != nil {
 			row.Status = *in.Status
 			if row.Status == "pending" {
@@ -388,19 +416,22 @@ func UpdateDomain(db *gorm.DB) http.HandlerFunc {
 
 // DeleteDomain godoc
 //
-//	@ID			DeleteDomain
-//	@Summary	Delete a domain
-//	@Tags		DNS
-//	@Produce	json
-//	@Param		X-Org-ID	header	string	false	"Organization UUID"
-//	@Param		id			path	string	true	"Domain ID (UUID)"
-//	@Success	204
-//	@Failure	403	{string}	string	"organization required"
-//	@Failure	404	{string}	string	"not found"
-//	@Router		/dns/domains/{id} [delete]
-//	@Security	BearerAuth
-//	@Security	OrgKeyAuth
-//	@Security	OrgSecretAuth
+//	@ID				DeleteDomain
+//	@Summary		Delete a domain
+//	@Description	A domain with a zone is marked `deleting` along with its autoglue-owned record sets; the dns_reconcile worker deletes those at the provider and then removes the domain. Record sets owned by something else are only removed from autoglue. A domain that never got a zone is removed at once.
+//	@Tags			DNS
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Domain ID (UUID)"
+//	@Success		202			{object}	dto.DomainResponse
+//	@Success		204
+//	@Failure		403	{string}	string	"organization required"
+//	@Failure		404	{string}	string	"not found"
+//	@Failure		409	{string}	string	"a record set is in use by a cluster"
+//	@Router			/dns/domains/{id} [delete]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
 func DeleteDomain(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
@@ -413,16 +444,203 @@ func DeleteDomain(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "bad_id", "invalid UUID")
 			return
 		}
-		res := db.Where("organization_id = ? AND id = ?", orgID, id).Delete(&models.Domain{})
-		if res.Error != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", res.Error.Error())
+		var row models.Domain
+		if err := db.Where("organization_id = ? AND id = ?", orgID, id).First(&row).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "domain not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+
+		inUse := db.Model(&models.RecordSet{}).Select("id").Where("domain_id = ?", row.ID)
+		if cluster, err := recordSetInUseBy(db, inUse); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		} else if cluster != "" {
+			utils.WriteError(w, http.StatusConflict, "record_in_use",
+				fmt.Sprintf("record set is the control plane record of cluster %s; detach it first", cluster))
+			return
+		}
+
+		// Nothing can have been written without a zone.
+		if strings.TrimSpace(row.ZoneID) == "" {
+			if err := db.Delete(&models.Domain{}, "id = ?", row.ID).Error; err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		row.Status = "deleting"
+		row.LastError = ""
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("domain_id = ? AND owner <> ?", row.ID, "autoglue").Delete(&models.RecordSet{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.RecordSet{}).Where("domain_id = ?", row.ID).
+				Updates(map[string]any{"status": "deleting", "last_error": ""}).Error; err != nil {
+				return err
+			}
+			return tx.Save(&row).Error
+		})
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusAccepted, domainOut(&row))
+	}
+}
+
+// GetDomainDrift godoc
+//
+//	@ID				GetDomainDrift
+//	@Summary		Record sets changed at the provider
+//	@Description	Lists the domain's record sets the dns_drift worker found different at the provider from what autoglue applied, with the values it observed. With the org's `dns_auto_reapply` setting on they are already pending re-apply.
+//	@Tags			DNS
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Domain ID (UUID)"
+//	@Success		200			{object}	dto.DomainDriftResponse
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Router			/dns/domains/{id}/drift [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func GetDomainDrift(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_id", "invalid UUID")
+			return
+		}
+		var domain models.Domain
+		if err := db.Where("organization_id = ? AND id = ?", orgID, id).First(&domain).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "domain not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+
+		var rows []models.RecordSet
+		if err := db.Where("domain_id = ? AND drifted_at IS NOT NULL", domain.ID).
+			Order("name ASC, type ASC").Find(&rows).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+		var checked *time.Time
+		if err := db.Model(&models.RecordSet{}).Where("domain_id = ?", domain.ID).
+			Select("MAX(drift_checked_at)").Scan(&checked).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+
+		out := dto.DomainDriftResponse{DomainID: domain.ID.String(), Records: make([]dto.RecordDriftResponse, 0, len(rows))}
+		if checked != nil {
+			out.CheckedAt = checked.UTC().Format(time.RFC3339)
+		}
+		for i := range rows {
+			rec := recordOut(&rows[i])
+			observed := rows[i].ObservedValues
+			if len(observed) == 0 {
+				observed = datatypes.JSON("[]")
+			}
+			observedAlias, _ := dto.AliasFromJSON(rows[i].ObservedAlias)
+			out.Records = append(out.Records, dto.RecordDriftResponse{
+				ID:             rec.ID,
+				Name:           rec.Name,
+				Type:           rec.Type,
+				Status:         rec.Status,
+				TTL:            rec.TTL,
+				Values:         rec.Values,
+				ObservedTTL:    rows[i].ObservedTTL,
+				ObservedValues: []byte(observed),
+				Alias:          rec.Alias,
+				ObservedAlias:  observedAlias,
+				Reason:         rows[i].LastError,
+				DriftedAt:      rows[i].DriftedAt.UTC().Format(time.RFC3339),
+			})
+		}
+		utils.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// ImportDomainRecords godoc
+//
+//	@ID				ImportDomainRecords
+//	@Summary		Import the zone's existing records
+//	@Description	Lists every record set in the domain's zone at the provider and creates a record set owned by `external` for each one autoglue does not track yet. External record sets are read-only until adopted. The SOA and apex NS records, autoglue's ownership markers and record types autoglue does not manage are skipped.
+//	@Tags			DNS
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Domain ID (UUID)"
+//	@Success		200			{object}	dto.DomainImportResponse
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"domain is not ready"
+//	@Failure		502			{string}	string	"provider error"
+//	@Router			/dns/domains/{id}/import [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ImportDomainRecords(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_id", "invalid UUID")
+			return
+		}
+		var domain models.Domain
+		if err := db.Where("organization_id = ? AND id = ?", orgID, id).First(&domain).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "domain not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+		if domain.Status != "ready" {
+			utils.WriteError(w, http.StatusConflict, "domain_not_ready", "domain must be ready, with its zone found, before importing")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), dnsProviderCallTimeout)
+		defer cancel()
+		p, err := bg.DNSProviderFor(ctx, db, &domain)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadGateway, "provider_error", err.Error())
 			return
 		}
-		if res.RowsAffected == 0 {
-			utils.WriteError(w, http.StatusNotFound, "not_found", "domain not found")
+		res, err := bg.ImportZoneRecords(ctx, db, p, &domain)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadGateway, "provider_error", err.Error())
 			return
 		}
-		w.WriteHeader(http.StatusNoContent)
+
+		out := dto.DomainImportResponse{
+			Imported: make([]dto.RecordSetResponse, 0, len(res.Imported)),
+			Existing: res.Existing,
+			Skipped:  res.Skipped,
+		}
+		for i := range res.Imported {
+			out.Imported = append(out.Imported, recordOut(&res.Imported[i]))
+		}
+		utils.WriteJSON(w, http.StatusOK, out)
 	}
 }
 
@@ -432,14 +650,15 @@ func DeleteDomain(db *gorm.DB) http.HandlerFunc {
 //
 //	@ID				ListRecordSets
 //	@Summary		List record sets for a domain
-//	@Description	Filters: `name`, `type`, `status`.
+//	@Description	Filters: `name`, `type`, `status`, `owner`.
 //	@Tags			DNS
 //	@Produce		json
 //	@Param			X-Org-ID	header		string	false	"Organization UUID"
 //	@Param			domain_id	path		string	true	"Domain ID (UUID)"
 //	@Param			name		query		string	false	"Exact relative name or FQDN (server normalizes)"
 //	@Param			type		query		string	false	"RR type (A, AAAA, CNAME, TXT, MX, NS, SRV, CAA)"
-//	@Param			status		query		string	false	"pending|provisioning|ready|failed"
+//	@Param			status		query		string	false	"pending|provisioning|ready|drifted|failed|deleting"
+//	@Param			owner		query		string	false	"autoglue|external|unknown"
 //	@Success		200			{array}		dto.RecordSetResponse
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		404			{string}	string	"domain not found"
@@ -489,6 +708,9 @@ func ListRecordSets(db *gorm.DB) http.HandlerFunc {
 		if v := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("status"))); v != "" {
 			q = q.Where("status = ?", v)
 		}
+		if v := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("owner"))); v != "" {
+			q = q.Where("owner = ?", v)
+		}
 
 		var rows []models.RecordSet
 		if err := q.Order("created_at DESC").Find(&rows).Error; err != nil {
@@ -549,22 +771,24 @@ func GetRecordSet(db *gorm.DB) http.HandlerFunc {
 
 // CreateRecordSet godoc
 //
-//	@ID			CreateRecordSet
-//	@Summary	Create a record set (pending; the dns_reconcile worker will UPSERT to Route 53)
-//	@Tags		DNS
-//	@Accept		json
-//	@Produce	json
-//	@Param		X-Org-ID	header		string						false	"Organization UUID"
-//	@Param		domain_id	path		string						true	"Domain ID (UUID)"
-//	@Param		body		body		dto.CreateRecordSetRequest	true	"Record set payload"
-//	@Success	201			{object}	dto.RecordSetResponse
-//	@Failure	400			{string}	string	"validation error"
-//	@Failure	403			{string}	string	"organization required"
-//	@Failure	404			{string}	string	"domain not found"
-//	@Router		/dns/domains/{domain_id}/records [post]
-//	@Security	BearerAuth
-//	@Security	OrgKeyAuth
-//	@Security	OrgSecretAuth
+//	@ID				CreateRecordSet
+//	@Summary		Create a record set (pending; the dns_reconcile worker will UPSERT it at the DNS provider)
+//	@Description	On a Route 53 domain an A or AAAA record set may give `alias` (a load balancer, CloudFront distribution or another record) instead of `values` and `ttl`. Values are checked against their type and stored in canonical form: addresses in their shortest text, names lowercase without a trailing dot, MX as `<preference> <host>`, SRV as `<priority> <weight> <port> <target>`, CAA as `<flags> <tag> <quoted value>` and TXT as plain text, which is split into 255-byte strings when written to the provider. A CNAME cannot be at the apex or share its name with other record sets.
+//	@Tags			DNS
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string						false	"Organization UUID"
+//	@Param			domain_id	path		string						true	"Domain ID (UUID)"
+//	@Param			body		body		dto.CreateRecordSetRequest	true	"Record set payload"
+//	@Success		201			{object}	dto.RecordSetResponse
+//	@Failure		400			{string}	string	"validation error"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"domain not found"
+//	@Failure		409			{string}	string	"conflict"
+//	@Router			/dns/domains/{domain_id}/records [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
 func CreateRecordSet(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
@@ -586,6 +810,10 @@ func CreateRecordSet(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
 			return
 		}
+		if domain.Status == "deleting" {
+			utils.WriteError(w, http.StatusConflict, "deleting", "domain is being deleted")
+			return
+		}
 
 		var in dto.CreateRecordSetRequest
 		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
@@ -597,13 +825,38 @@ func CreateRecordSet(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 		t := strings.ToUpper(in.Type)
-		if t == "CNAME" && len(in.Values) != 1 {
-			utils.WriteError(w, http.StatusBadRequest, "validation_error", "CNAME requires exactly one value")
-			return
+		values := []string{}
+		if in.Alias != nil {
+			problem, err := aliasProblem(db, &domain, t, in.TTL, len(in.Values))
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+				return
+			}
+			if problem != "" {
+				utils.WriteError(w, http.StatusBadRequest, "validation_error", problem)
+				return
+			}
+		} else {
+			values, err = dns.NormalizeValues(t, in.Values)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
+				return
+			}
 		}
 
 		rel := normLowerNoDot(in.Name)
 		fq := fqdn(domain.DomainName, rel)
+		if t == "CNAME" && isApex(rel) {
+			utils.WriteError(w, http.StatusBadRequest, "validation_error", "a CNAME cannot be at the zone apex")
+			return
+		}
+		if problem, err := cnameConflict(db, domain.ID, rel, t, uuid.Nil); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		} else if problem != "" {
+			utils.WriteError(w, http.StatusConflict, "cname_conflict", problem)
+			return
+		}
 
 		// Pre-flight: block duplicate tuple and protect from non-autoglue rows
 		var existing models.RecordSet
@@ -614,6 +867,11 @@ func CreateRecordSet(db *gorm.DB) http.HandlerFunc {
 					"record with the same (name,type) exists but is not owned by autoglue")
 				return
 			}
+			if existing.Status == "deleting" {
+				utils.WriteError(w, http.StatusConflict, "already_exists",
+					"a record with the same (name,type) is still being deleted; try again once it is gone")
+				return
+			}
 			utils.WriteError(w, http.StatusConflict, "already_exists",
 				"a record with the same (name,type) already exists; use PATCH to modify")
 			return
@@ -622,8 +880,9 @@ func CreateRecordSet(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		valuesJSON, _ := json.Marshal(in.Values)
-		fp, err := computeFingerprint(domain.ZoneID, fq, t, in.TTL, datatypes.JSON(valuesJSON))
+		valuesJSON, _ := json.Marshal(values)
+		alias := aliasJSON(in.Alias)
+		fp, err := computeFingerprint(domain.ZoneID, fq, t, in.TTL, datatypes.JSON(valuesJSON), alias)
 		if err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "fingerprint_error", err.Error())
 			return
@@ -635,6 +894,7 @@ func CreateRecordSet(db *gorm.DB) http.HandlerFunc {
 			Type:        t,
 			TTL:         in.TTL,
 			Values:      datatypes.JSON(valuesJSON),
+			Alias:       alias,
 			Fingerprint: fp,
 			Status:      "pending",
 			LastError:   "",
@@ -662,6 +922,7 @@ func CreateRecordSet(db *gorm.DB) http.HandlerFunc {
 //	@Failure	400			{string}	string	"validation error"
 //	@Failure	403			{string}	string	"organization required"
 //	@Failure	404			{string}	string	"not found"
+//	@Failure	409			{string}	string	"not owned by autoglue / CNAME conflict"
 //	@Router		/dns/records/{id} [patch]
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
@@ -711,6 +972,10 @@ func UpdateRecordSet(db *gorm.DB) http.HandlerFunc {
 				"record is not owned by autoglue; refuse to modify")
 			return
 		}
+		if row.Status == "deleting" {
+			utils.WriteError(w, http.StatusConflict, "deleting", "record set is being deleted")
+			return
+		}
 
 		// Mutations
 		if in.Name != nil {
@@ -723,17 +988,56 @@ func UpdateRecordSet(db *gorm.DB) http.HandlerFunc {
 			row.TTL = in.TTL
 		}
 		if in.Values != nil {
-			t := row.Type
-			if in.Type != nil {
-				t = strings.ToUpper(*in.Type)
+			b, _ := json.Marshal(*in.Values)
+			row.Values = datatypes.JSON(b)
+			row.Alias = nil
+		}
+		if in.Alias != nil {
+			if in.Values != nil {
+				utils.WriteError(w, http.StatusBadRequest, "validation_error", "give either values or alias, not both")
+				return
 			}
-			if t == "CNAME" && len(*in.Values) != 1 {
-				utils.WriteError(w, http.StatusBadRequest, "validation_error", "CNAME requires exactly one value")
+			row.Alias = aliasJSON(in.Alias)
+			row.Values = datatypes.JSON("[]")
+			row.TTL = nil
+		}
+		if len(row.Alias) > 0 {
+			problem, err := aliasProblem(db, &domain, row.Type, in.TTL, 0)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
 				return
 			}
-			b, _ := json.Marshal(*in.Values)
+			if problem != "" {
+				utils.WriteError(w, http.StatusBadRequest, "validation_error", problem)
+				return
+			}
+		} else if in.Values != nil || in.Type != nil {
+			var vals []string
+			if err := json.Unmarshal(row.Values, &vals); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "bad_values", err.Error())
+				return
+			}
+			vals, err := dns.NormalizeValues(row.Type, vals)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
+				return
+			}
+			b, _ := json.Marshal(vals)
 			row.Values = datatypes.JSON(b)
 		}
+		if in.Name != nil || in.Type != nil {
+			if row.Type == "CNAME" && isApex(row.Name) {
+				utils.WriteError(w, http.StatusBadRequest, "validation_error", "a CNAME cannot be at the zone apex")
+				return
+			}
+			if problem, err := cnameConflict(db, row.DomainID, row.Name, row.Type, row.ID); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+				return
+			} else if problem != "" {
+				utils.WriteError(w, http.StatusConflict, "cname_conflict", problem)
+				return
+			}
+		}
 
 		if in.Status != nil {
 			row.Status = *in.Status
@@ -743,7 +1047,7 @@ func UpdateRecordSet(db *gorm.DB) http.HandlerFunc {
 		}
 
 		fq := fqdn(domain.DomainName, row.Name)
-		fp, err := computeFingerprint(domain.ZoneID, fq, row.Type, row.TTL, row.Values)
+		fp, err := computeFingerprint(domain.ZoneID, fq, row.Type, row.TTL, row.Values, row.Alias)
 		if err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "fingerprint_error", err.Error())
 			return
@@ -758,21 +1062,100 @@ func UpdateRecordSet(db *gorm.DB) http.HandlerFunc {
 	}
 }
 
+// AdoptRecordSet godoc
+//
+//	@ID				AdoptRecordSet
+//	@Summary		Adopt an imported record set
+//	@Description	Makes an `external` record set managed by autoglue: its values and TTL are refreshed from the provider and an ownership marker is written next to it. The record itself is not changed. Refused when external-dns or another autoglue record owns it.
+//	@Tags			DNS
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Record Set ID (UUID)"
+//	@Success		200			{object}	dto.RecordSetResponse
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"already managed, owned elsewhere, gone or changed meanwhile"
+//	@Failure		502			{string}	string	"provider error"
+//	@Router			/dns/records/{id}/adopt [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func AdoptRecordSet(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_id", "invalid UUID")
+			return
+		}
+		var row models.RecordSet
+		if err := db.
+			Joins("Domain").
+			Where(`record_sets.id = ? AND "Domain"."organization_id" = ?`, id, orgID).
+			First(&row).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "record set not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+		if row.Owner == "autoglue" {
+			utils.WriteError(w, http.StatusConflict, "already_managed", "record set is already managed by autoglue")
+			return
+		}
+		domain := row.Domain
+		if domain.Status != "ready" {
+			utils.WriteError(w, http.StatusConflict, "domain_not_ready", "domain must be ready to adopt its records")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), dnsProviderCallTimeout)
+		defer cancel()
+		p, err := bg.DNSProviderFor(ctx, db, &domain)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadGateway, "provider_error", err.Error())
+			return
+		}
+		if err := bg.AdoptRecord(ctx, db, p, &domain, &row); err != nil {
+			switch {
+			case errors.Is(err, bg.ErrDNSOwnershipConflict):
+				utils.WriteError(w, http.StatusConflict, "ownership_conflict", err.Error())
+			case errors.Is(err, bg.ErrDNSRecordGone):
+				utils.WriteError(w, http.StatusConflict, "record_gone", err.Error())
+			case errors.Is(err, bg.ErrDNSRecordChanged):
+				utils.WriteError(w, http.StatusConflict, "record_changed", err.Error())
+			default:
+				utils.WriteError(w, http.StatusBadGateway, "provider_error", err.Error())
+			}
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, recordOut(&row))
+	}
+}
+
 // DeleteRecordSet godoc
 //
-//	@ID			DeleteRecordSet
-//	@Summary	Delete a record set (API removes row; worker can optionally handle external deletion policy)
-//	@Tags		DNS
-//	@Produce	json
-//	@Param		X-Org-ID	header	string	false	"Organization UUID"
-//	@Param		id			path	string	true	"Record Set ID (UUID)"
-//	@Success	204
-//	@Failure	403	{string}	string	"organization required"
-//	@Failure	404	{string}	string	"not found"
-//	@Router		/dns/records/{id} [delete]
-//	@Security	BearerAuth
-//	@Security	OrgKeyAuth
-//	@Security	OrgSecretAuth
+//	@ID				DeleteRecordSet
+//	@Summary		Delete a record set
+//	@Description	An autoglue-owned record set is marked `deleting`; the dns_reconcile worker deletes it and its ownership marker at the provider, then removes the row. A record set owned by something else is only removed from autoglue.
+//	@Tags			DNS
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Record Set ID (UUID)"
+//	@Success		202			{object}	dto.RecordSetResponse
+//	@Success		204
+//	@Failure		403	{string}	string	"organization required"
+//	@Failure		404	{string}	string	"not found"
+//	@Failure		409	{string}	string	"record set is in use by a cluster"
+//	@Router			/dns/records/{id} [delete]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
 func DeleteRecordSet(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
@@ -785,28 +1168,62 @@ func DeleteRecordSet(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "bad_id", "invalid UUID")
 			return
 		}
-		sub := db.Model(&models.RecordSet{}).
-			Select("record_sets.id").
-			Joins("JOIN domains ON domains.id = record_sets.domain_id").
-			Where("record_sets.id = ? AND domains.organization_id = ?", id, orgID)
+		var row models.RecordSet
+		if err := db.
+			Joins("Domain").
+			Where(`record_sets.id = ? AND "Domain"."organization_id" = ?`, id, orgID).
+			First(&row).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "record set not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+
+		if cluster, err := recordSetInUseBy(db, []uuid.UUID{row.ID}); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		} else if cluster != "" {
+			utils.WriteError(w, http.StatusConflict, "record_in_use",
+				fmt.Sprintf("record set is the control plane record of cluster %s; detach it first", cluster))
+			return
+		}
 
-		res := db.Where("id IN (?)", sub).Delete(&models.RecordSet{})
-		if res.Error != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", res.Error.Error())
+		if row.Owner != "autoglue" {
+			if err := db.Delete(&models.RecordSet{}, "id = ?", row.ID).Error; err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		if res.RowsAffected == 0 {
-			utils.WriteError(w, http.StatusNotFound, "not_found", "record set not found")
+
+		if err := db.Model(&row).Updates(map[string]any{"status": "deleting", "last_error": ""}).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
 			return
 		}
-		w.WriteHeader(http.StatusNoContent)
+		row.Status = "deleting"
+		row.LastError = ""
+		utils.WriteJSON(w, http.StatusAccepted, recordOut(&row))
 	}
 }
 
+// recordSetInUseBy names the first cluster that uses one of ids (a list or a
+// subquery) as its control plane record set, or "" if none does.
+func recordSetInUseBy(db *gorm.DB, ids any) (string, error) {
+	var c models.Cluster
+	err := db.Select("name").Where("control_plane_record_set_id IN (?)", ids).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return c.Name, err
+}
+
 // ---------- Out mappers ----------
 
 func domainOut(m *models.Domain) dto.DomainResponse {
-	return dto.DomainResponse{
+	out := dto.DomainResponse{
 		ID:             m.ID.String(),
 		OrganizationID: m.OrganizationID.String(),
 		DomainName:     m.DomainName,
@@ -814,9 +1231,22 @@ func domainOut(m *models.Domain) dto.DomainResponse {
 		Status:         m.Status,
 		LastError:      m.LastError,
 		CredentialID:   m.CredentialID.String(),
+		CreateZone:     m.CreateZone,
+		NameServers:    []string{},
 		CreatedAt:      m.CreatedAt.UTC().Format(time.RFC3339),
 		UpdatedAt:      m.UpdatedAt.UTC().Format(time.RFC3339),
 	}
+	_ = json.Unmarshal(m.NameServers, &out.NameServers)
+	if m.DelegationCheckedAt != nil {
+		out.Delegation = &dto.DomainDelegation{
+			Status:      m.DelegationStatus,
+			Source:      m.DelegationSource,
+			NameServers: []string{},
+			CheckedAt:   m.DelegationCheckedAt.UTC().Format(time.RFC3339),
+		}
+		_ = json.Unmarshal(m.DelegatedNameServers, &out.Delegation.NameServers)
+	}
+	return out
 }
 
 func recordOut(r *models.RecordSet) dto.RecordSetResponse {
@@ -824,6 +1254,7 @@ func recordOut(r *models.RecordSet) dto.RecordSetResponse {
 	if len(vals) == 0 {
 		vals = datatypes.JSON("[]")
 	}
+	alias, _ := dto.AliasFromJSON(r.Alias)
 	return dto.RecordSetResponse{
 		ID:          r.ID.String(),
 		DomainID:    r.DomainID.String(),
@@ -831,6 +1262,7 @@ func recordOut(r *models.RecordSet) dto.RecordSetResponse {
 		Type:        r.Type,
 		TTL:         r.TTL,
 		Values:      []byte(vals),
+		Alias:       alias,
 		Fingerprint: r.Fingerprint,
 		Status:      r.Status,
 		LastError:   r.LastError,
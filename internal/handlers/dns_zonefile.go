@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/dns"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxZoneFileBytes   = 4 << 20
+	maxZoneFileRecords = 5000
+	// zoneFileDefaultTTL is the $TTL written on export and assumed on
+	// import for a file without one.
+	zoneFileDefaultTTL = 300
+)
+
+// ExportZoneFile godoc
+//
+//	@ID				ExportZoneFile
+//	@Summary		Export a domain as a zone file
+//	@Description	Renders the domain's record sets in RFC 1035 master file format, with $ORIGIN set to the domain. Long TXT values are split into 255-byte strings. Route 53 aliases have no zone file form and are written as comments.
+//	@Tags			DNS
+//	@Produce		plain
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Domain ID (UUID)"
+//	@Success		200			{string}	string	"zone file"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Router			/dns/domains/{id}/zonefile [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ExportZoneFile(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		domain, ok := zoneFileDomain(w, r, db)
+		if !ok {
+			return
+		}
+		recs, err := bg.ZoneFileRecords(db, &domain)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/dns; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zone"`, domain.DomainName))
+		w.WriteHeader(http.StatusOK)
+		_ = dns.WriteZoneFile(w, domain.DomainName, zoneFileDefaultTTL, recs)
+	}
+}
+
+// ImportZoneFile godoc
+//
+//	@ID				ImportZoneFile
+//	@Summary		Import record sets from a zone file
+//	@Description	Parses an RFC 1035 master file sent as the request body and compares it with the domain's record sets. Relative names are taken relative to the domain until an $ORIGIN directive changes it; records without a TTL take $TTL, else 300. The strings of a TXT record are joined into one value. Record sets the file adds are created pending and owned by autoglue, and autoglue-owned record sets it changes go back to pending; the dns_reconcile worker then writes them to the provider. Record sets owned by something else are reported as conflicts and left alone, and nothing is deleted. Values are checked per record type as the record set API checks them; invalid record sets, CNAMEs at the apex and CNAMEs sharing a name with other record sets are reported as rejected and not stored. By default only the diff is returned and nothing is written; send the same file again with apply=true to make the changes.
+//	@Tags			DNS
+//	@Accept			plain
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Domain ID (UUID)"
+//	@Param			apply		query		bool	false	"Write the changes instead of only previewing them"
+//	@Param			body		body		string	true	"Zone file"
+//	@Success		200			{object}	dto.ZoneFileImportResponse	"preview"
+//	@Success		201			{object}	dto.ZoneFileImportResponse	"applied"
+//	@Failure		400			{string}	string	"unreadable zone file / too many records"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"domain is being deleted"
+//	@Router			/dns/domains/{id}/zonefile [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ImportZoneFile(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apply := false
+		if v := strings.TrimSpace(r.URL.Query().Get("apply")); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "bad_request", "apply must be a boolean")
+				return
+			}
+			apply = b
+		}
+
+		domain, ok := zoneFileDomain(w, r, db)
+		if !ok {
+			return
+		}
+		if domain.Status == "deleting" {
+			utils.WriteError(w, http.StatusConflict, "deleting", "domain is being deleted")
+			return
+		}
+
+		recs, err := dns.ParseZoneFile(http.MaxBytesReader(w, r.Body, maxZoneFileBytes), domain.DomainName, zoneFileDefaultTTL)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_zonefile", err.Error())
+			return
+		}
+		if len(recs) == 0 {
+			utils.WriteError(w, http.StatusBadRequest, "bad_zonefile", "zone file has no records")
+			return
+		}
+		if len(recs) > maxZoneFileRecords {
+			utils.WriteError(w, http.StatusBadRequest, "too_many_records", fmt.Sprintf("at most %d record sets per import", maxZoneFileRecords))
+			return
+		}
+
+		plan, err := bg.PlanZoneFile(db, &domain, recs)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+		out := zoneFilePlanOut(&domain, plan)
+		if !apply {
+			utils.WriteJSON(w, http.StatusOK, out)
+			return
+		}
+		out.Applied = true
+
+		rows, err := bg.ApplyZoneFile(db, &domain, plan)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+		for i := range rows {
+			out.Records = append(out.Records, recordOut(&rows[i]))
+		}
+		utils.WriteJSON(w, http.StatusCreated, out)
+	}
+}
+
+func zoneFileDomain(w http.ResponseWriter, r *http.Request, db *gorm.DB) (models.Domain, bool) {
+	var domain models.Domain
+	orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+		return domain, false
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "bad_id", "invalid UUID")
+		return domain, false
+	}
+	if err := db.Where("organization_id = ? AND id = ?", orgID, id).First(&domain).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "not_found", "domain not found")
+			return domain, false
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+		return domain, false
+	}
+	return domain, true
+}
+
+func zoneFilePlanOut(d *models.Domain, plan bg.ZoneFilePlan) dto.ZoneFileImportResponse {
+	out := dto.ZoneFileImportResponse{
+		Create:    make([]dto.ZoneFileRecord, 0, len(plan.Create)),
+		Update:    make([]dto.ZoneFileUpdate, 0, len(plan.Update)),
+		Unchanged: plan.Unchanged,
+		Conflicts: make([]dto.ZoneFileRecord, 0, len(plan.Conflicts)),
+		Rejected:  make([]dto.ZoneFileRejection, 0, len(plan.Rejected)),
+		Skipped:   plan.Skipped,
+		Records:   []dto.RecordSetResponse{},
+	}
+	for _, rec := range plan.Create {
+		out.Create = append(out.Create, zoneFileRecordOut(d, rec))
+	}
+	for _, u := range plan.Update {
+		from := dto.ZoneFileRecord{Name: u.Row.Name, Type: u.Row.Type, TTL: zoneFileDefaultTTL, Values: []string{}}
+		if u.Row.TTL != nil {
+			from.TTL = *u.Row.TTL
+		}
+		_ = json.Unmarshal(u.Row.Values, &from.Values)
+		out.Update = append(out.Update, dto.ZoneFileUpdate{ID: u.Row.ID.String(), From: from, To: zoneFileRecordOut(d, u.Record)})
+	}
+	for _, rec := range plan.Conflicts {
+		out.Conflicts = append(out.Conflicts, zoneFileRecordOut(d, rec))
+	}
+	for _, rj := range plan.Rejected {
+		out.Rejected = append(out.Rejected, dto.ZoneFileRejection{Record: zoneFileRecordOut(d, rj.Record), Reason: rj.Reason})
+	}
+	return out
+}
+
+func zoneFileRecordOut(d *models.Domain, rec dns.Record) dto.ZoneFileRecord {
+	name := strings.TrimSuffix(rec.Name, ".")
+	apex := normLowerNoDot(d.DomainName)
+	switch {
+	case name == apex:
+		name = "@"
+	default:
+		name = strings.TrimSuffix(name, "."+apex)
+	}
+	return dto.ZoneFileRecord{Name: name, Type: rec.Type, TTL: int(rec.TTL), Values: rec.Values}
+}
@@ -17,3 +17,19 @@ type ClusterRunResponse struct {
 	UpdatedAt      time.Time  `json:"updated_at" format:"date-time"`
 	FinishedAt     *time.Time `json:"finished_at,omitempty" format:"date-time"`
 }
+
+type NodePoolRolloutRequest struct {
+	// Operation is "reboot" or "action".
+	Operation string `json:"operation" example:"reboot" enums:"reboot,action"`
+	// ActionID is the action run for each server; required with "action".
+	// Its make target is called with NODE=<node name>.
+	ActionID *uuid.UUID `json:"action_id,omitempty" format:"uuid"`
+	// BatchSize is how many servers are taken out together. Defaults to 1, max 100.
+	BatchSize *int `json:"batch_size,omitempty" example:"1"`
+	// MaxUnavailable caps the pool's nodes out of service at once, counting
+	// ones that were already NotReady or cordoned. Defaults to 1, max 100.
+	MaxUnavailable *int `json:"max_unavailable,omitempty" example:"1"`
+	// ReadyTimeoutSeconds bounds the wait for each node to come back Ready.
+	// Defaults to 600, between 60 and 3600.
+	ReadyTimeoutSeconds *int `json:"ready_timeout_seconds,omitempty" example:"600"`
+}
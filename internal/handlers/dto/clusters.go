@@ -18,8 +18,12 @@ type ClusterResponse struct {
 	BastionServer         *ServerResponse       `json:"bastion_server,omitempty"`
 	Provider              string                `json:"cluster_provider"`
 	Region                string                `json:"region"`
+	Topology              string                `json:"topology" enums:"stacked,external"`
+	EtcdMembers           []ServerResponse      `json:"etcd_members,omitempty"`
 	Status                string                `json:"status"`
 	LastError             string                `json:"last_error"`
+	Degraded              bool                  `json:"degraded"`
+	DegradedReason        string                `json:"degraded_reason,omitempty"`
 	RandomToken           string                `json:"random_token"`
 	CertificateKey        string                `json:"certificate_key"`
 	NodePools             []NodePoolResponse    `json:"node_pools,omitempty"`
@@ -37,6 +41,7 @@ type CreateClusterRequest struct {
 	Name            string `json:"name"`
 	ClusterProvider string `json:"cluster_provider"`
 	Region          string `json:"region"`
+	Topology        string `json:"topology,omitempty" enums:"stacked,external"`
 	DockerImage     string `json:"docker_image"`
 	DockerTag       string `json:"docker_tag"`
 }
@@ -45,6 +50,7 @@ type UpdateClusterRequest struct {
 	Name            *string `json:"name,omitempty"`
 	ClusterProvider *string `json:"cluster_provider,omitempty"`
 	Region          *string `json:"region,omitempty"`
+	Topology        *string `json:"topology,omitempty" enums:"stacked,external"`
 	DockerImage     *string `json:"docker_image,omitempty"`
 	DockerTag       *string `json:"docker_tag,omitempty"`
 }
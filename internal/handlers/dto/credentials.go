@@ -2,8 +2,14 @@ package dto
 
 import (
 	"encoding/json"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/go-playground/validator/v10"
+	mdns "github.com/miekg/dns"
 )
 
 // RawJSON is a swagger-friendly wrapper for json.RawMessage.
@@ -16,6 +22,37 @@ func init() {
 		v := fl.Field().String()
 		return len(v) > 10 && len(v) < 2048 && len(v) >= 4 && v[:4] == "arn:"
 	})
+	// TSIG key names are DNS names but need not be fully qualified; BIND's
+	// own examples use single labels.
+	_ = Validate.RegisterValidation("dnsname", func(fl validator.FieldLevel) bool {
+		v := fl.Field().String()
+		_, ok := mdns.IsDomainName(v)
+		return ok && !strings.ContainsFunc(v, unicode.IsSpace)
+	})
+	_ = Validate.RegisterValidation("dnsserver", func(fl validator.FieldLevel) bool {
+		return validDNSServer(fl.Field().String())
+	})
+}
+
+// validDNSServer accepts a host or IP with an optional port, the way the
+// RFC 2136 provider reads it: port 53 is used when none is given, and a bare
+// IPv6 address needs no brackets.
+func validDNSServer(v string) bool {
+	host := v
+	if h, port, err := net.SplitHostPort(v); err == nil {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return false
+		}
+		host = h
+	}
+	if host == "" {
+		return false
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return true
+	}
+	return Validate.Var(host, "hostname_rfc1123") == nil
 }
 
 /*** Shapes for secrets ***/
@@ -35,6 +72,15 @@ type APIToken struct {
 	Token string `json:"token" validate:"required"`
 }
 
+// TSIGCredential signs DNS UPDATE messages to a self-hosted server. Server
+// is the primary's host, with an optional port; Secret is the base64 key.
+type TSIGCredential struct {
+	KeyName   string `json:"key_name" validate:"required,dnsname"`
+	Algorithm string `json:"algorithm" validate:"required,oneof=hmac-sha1 hmac-sha224 hmac-sha256 hmac-sha384 hmac-sha512"`
+	Secret    string `json:"secret" validate:"required,base64"`
+	Server    string `json:"server" validate:"required,dnsserver"`
+}
+
 type OAuth2Credential struct {
 	ClientID     string `json:"client_id" validate:"required"`
 	ClientSecret string `json:"client_secret" validate:"required"`
@@ -77,6 +123,7 @@ var CredentialRegistry = map[string]map[string]map[int]ProviderDef{
 	"cloudflare":   {"api_token": {1: {New: func() any { return &APIToken{} }, Validate: func(x any) error { return Validate.Struct(x) }}}},
 	"hetzner":      {"api_token": {1: {New: func() any { return &APIToken{} }, Validate: func(x any) error { return Validate.Struct(x) }}}},
 	"digitalocean": {"api_token": {1: {New: func() any { return &APIToken{} }, Validate: func(x any) error { return Validate.Struct(x) }}}},
+	"rfc2136":      {"tsig": {1: {New: func() any { return &TSIGCredential{} }, Validate: func(x any) error { return Validate.Struct(x) }}}},
 	"generic": {
 		"basic_auth": {1: {New: func() any { return &BasicAuth{} }, Validate: func(x any) error { return Validate.Struct(x) }}},
 		"oauth2":     {1: {New: func() any { return &OAuth2Credential{} }, Validate: func(x any) error { return Validate.Struct(x) }}},
@@ -91,14 +138,22 @@ var ScopeRegistry = map[string]map[string]map[int]ScopeDef{
 		"service":  {1: {New: func() any { return &AWSServiceScope{} }, Validate: func(x any) error { return Validate.Struct(x) }, Specificity: 1}},
 		"resource": {1: {New: func() any { return &AWSResourceScope{} }, Validate: func(x any) error { return Validate.Struct(x) }, Specificity: 2}},
 	},
+	// DNS providers with token or key credentials have no finer scopes: one
+	// credential covers the whole account or server.
+	"cloudflare": {"credential_provider": {1: providerWideScope}},
+	"rfc2136":    {"credential_provider": {1: providerWideScope}},
 }
 
+type ProviderWideScope struct{}
+
+var providerWideScope = ScopeDef{New: func() any { return &ProviderWideScope{} }, Validate: func(any) error { return nil }, Specificity: 0}
+
 /*** API DTOs used by swagger ***/
 
 // CreateCredentialRequest represents the POST /credentials payload
 type CreateCredentialRequest struct {
-	CredentialProvider string  `json:"credential_provider" validate:"required,oneof=aws cloudflare hetzner digitalocean generic"`
-	Kind               string  `json:"kind" validate:"required"`                 // aws_access_key, api_token, basic_auth, oauth2
+	CredentialProvider string  `json:"credential_provider" validate:"required,oneof=aws cloudflare hetzner digitalocean rfc2136 generic"`
+	Kind               string  `json:"kind" validate:"required"`                 // aws_access_key, api_token, tsig, basic_auth, oauth2
 	SchemaVersion      int     `json:"schema_version" validate:"required,gte=1"` // secret schema version
 	Name               string  `json:"name" validate:"omitempty,max=100"`        // human label
 	ScopeKind          string  `json:"scope_kind" validate:"required,oneof=credential_provider service resource"`
@@ -0,0 +1,39 @@
+package dto
+
+import "testing"
+
+func TestTSIGCredentialValidation(t *testing.T) {
+	valid := func() TSIGCredential {
+		return TSIGCredential{KeyName: "autoglue", Algorithm: "hmac-sha256", Secret: "c2VjcmV0", Server: "ns1.example.com"}
+	}
+
+	for _, server := range []string{"ns1.example.com", "ns1.example.com:5353", "192.0.2.53", "192.0.2.53:53", "2001:db8::53", "[2001:db8::53]:53"} {
+		c := valid()
+		c.Server = server
+		if err := Validate.Struct(&c); err != nil {
+			t.Errorf("server %q: %v", server, err)
+		}
+	}
+	for _, server := range []string{"", "ns1.example.com:", "ns1.example.com:0", "ns1.example.com:dns", "bad host"} {
+		c := valid()
+		c.Server = server
+		if err := Validate.Struct(&c); err == nil {
+			t.Errorf("server %q: accepted", server)
+		}
+	}
+
+	for _, name := range []string{"autoglue", "tsig-key.example.com.", "key.example.com"} {
+		c := valid()
+		c.KeyName = name
+		if err := Validate.Struct(&c); err != nil {
+			t.Errorf("key name %q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "two words", "a..b"} {
+		c := valid()
+		c.KeyName = name
+		if err := Validate.Struct(&c); err == nil {
+			t.Errorf("key name %q: accepted", name)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 
+	"github.com/glueops/autoglue/internal/dns"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -35,6 +36,9 @@ type CreateDomainRequest struct {
 	DomainName   string `json:"domain_name" validate:"required,fqdn"`
 	CredentialID string `json:"credential_id" validate:"required,uuid4"`
 	ZoneID       string `json:"zone_id,omitempty" validate:"omitempty,max=128"`
+	// CreateZone creates the hosted zone when the provider has none for
+	// the domain. Route 53 only; not with zone_id.
+	CreateZone bool `json:"create_zone,omitempty"`
 }
 
 type UpdateDomainRequest struct {
@@ -52,18 +56,58 @@ type DomainResponse struct {
 	Status         string `json:"status"`
 	LastError      string `json:"last_error"`
 	CredentialID   string `json:"credential_id"`
-	CreatedAt      string `json:"created_at"`
-	UpdatedAt      string `json:"updated_at"`
+	CreateZone     bool   `json:"create_zone"`
+	// NameServers are the ones the provider assigned the zone; the parent
+	// zone has to delegate to them.
+	NameServers []string `json:"name_servers"`
+	// Delegation is unset until the zone's name servers are known.
+	Delegation *DomainDelegation `json:"delegation,omitempty"`
+	CreatedAt  string            `json:"created_at"`
+	UpdatedAt  string            `json:"updated_at"`
+}
+
+type DomainDelegation struct {
+	// Status is delegated, partial, not_delegated or unknown.
+	Status string `json:"status" enums:"delegated,partial,not_delegated,unknown"`
+	// Source is autoglue when the NS record set of a parent domain managed
+	// here was read, dns when the name was looked up.
+	Source      string   `json:"source" enums:"autoglue,dns"`
+	NameServers []string `json:"name_servers"`
+	CheckedAt   string   `json:"checked_at"`
 }
 
 // ---- Record Sets ----
 
+// AliasTarget points a Route 53 A or AAAA record at a load balancer,
+// CloudFront distribution, S3 website or another record. HostedZoneID is the
+// target's zone, such as the ELB's canonical hosted zone id.
 type AliasTarget struct {
-	HostedZoneID         string `json:"hosted_zone_id" validate:"required"`
-	DNSName              string `json:"dns_name" validate:"required"`
+	HostedZoneID         string `json:"hosted_zone_id" validate:"required,max=128"`
+	DNSName              string `json:"dns_name" validate:"required,fqdn"`
 	EvaluateTargetHealth bool   `json:"evaluate_target_health"`
 }
 
+// AliasFromJSON decodes a record set's alias column, nil when it has none.
+func AliasFromJSON(raw []byte) (*AliasTarget, error) {
+	s := strings.TrimSpace(string(raw))
+	if s == "" || s == "null" {
+		return nil, nil
+	}
+	var a AliasTarget
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// DNS is the target in the dns package's form.
+func (a *AliasTarget) DNS() *dns.AliasTarget {
+	if a == nil {
+		return nil
+	}
+	return &dns.AliasTarget{HostedZoneID: a.HostedZoneID, DNSName: a.DNSName, EvaluateTargetHealth: a.EvaluateTargetHealth}
+}
+
 type CreateRecordSetRequest struct {
 	// Name relative to domain ("endpoint") OR FQDN ("endpoint.example.com").
 	// Server normalizes to relative.
@@ -71,6 +115,9 @@ type CreateRecordSetRequest struct {
 	Type   string   `json:"type" validate:"required,rrtype"`
 	TTL    *int     `json:"ttl,omitempty" validate:"omitempty,gte=1,lte=86400"`
 	Values []string `json:"values" validate:"omitempty,dive,min=1,max=1024"`
+	// Alias makes an A or AAAA record a Route 53 alias, given instead of
+	// values and ttl.
+	Alias *AliasTarget `json:"alias,omitempty"`
 }
 
 type UpdateRecordSetRequest struct {
@@ -80,6 +127,8 @@ type UpdateRecordSetRequest struct {
 	TTL    *int      `json:"ttl,omitempty" validate:"omitempty,gte=1,lte=86400"`
 	Values *[]string `json:"values,omitempty" validate:"omitempty,dive,min=1,max=1024"`
 	Status *string   `json:"status,omitempty" validate:"omitempty,oneof=pending provisioning ready failed"`
+	// Alias turns the record into an alias; values turn it back.
+	Alias *AliasTarget `json:"alias,omitempty"`
 }
 
 type RecordSetResponse struct {
@@ -89,6 +138,7 @@ type RecordSetResponse struct {
 	Type        string          `json:"type"`
 	TTL         *int            `json:"ttl,omitempty"`
 	Values      json.RawMessage `json:"values" swaggertype:"object"` // []string JSON
+	Alias       *AliasTarget    `json:"alias,omitempty"`
 	Fingerprint string          `json:"fingerprint"`
 	Status      string          `json:"status"`
 	LastError   string          `json:"last_error"`
@@ -97,6 +147,89 @@ type RecordSetResponse struct {
 	UpdatedAt   string          `json:"updated_at"`
 }
 
+// ---- Import ----
+
+type DomainImportResponse struct {
+	// Imported are the new record sets, owned by external.
+	Imported []RecordSetResponse `json:"imported"`
+	// Existing counts record sets autoglue already tracked.
+	Existing int `json:"existing"`
+	// Skipped counts the SOA, apex NS, ownership markers and unsupported
+	// types.
+	Skipped int `json:"skipped"`
+}
+
+// ---- Zone file ----
+
+// ZoneFileRecord is a record set as read from a zone file. Name is relative
+// to the domain, "@" for the apex.
+type ZoneFileRecord struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	TTL    int      `json:"ttl"`
+	Values []string `json:"values"`
+}
+
+type ZoneFileUpdate struct {
+	ID   string         `json:"id"`
+	From ZoneFileRecord `json:"from"`
+	To   ZoneFileRecord `json:"to"`
+}
+
+// ZoneFileRejection is a record set from a zone file that cannot be stored.
+type ZoneFileRejection struct {
+	Record ZoneFileRecord `json:"record"`
+	Reason string         `json:"reason"`
+}
+
+type ZoneFileImportResponse struct {
+	// Applied is false for a preview, which writes nothing.
+	Applied bool `json:"applied"`
+	// Create are record sets the file adds, as pending and owned by
+	// autoglue.
+	Create []ZoneFileRecord `json:"create"`
+	// Update are autoglue-owned record sets the file changes.
+	Update []ZoneFileUpdate `json:"update"`
+	// Unchanged counts record sets that already match the file.
+	Unchanged int `json:"unchanged"`
+	// Conflicts are record sets the file would change that autoglue does
+	// not manage; they are left as they are.
+	Conflicts []ZoneFileRecord `json:"conflicts"`
+	// Rejected are record sets with values invalid for their type, and
+	// CNAMEs at the apex or beside other record sets; they are not stored.
+	Rejected []ZoneFileRejection `json:"rejected"`
+	// Skipped counts names outside the domain, the SOA and apex NS,
+	// ownership markers and unsupported types.
+	Skipped int `json:"skipped"`
+	// Records are the rows created and changed; empty on a preview.
+	Records []RecordSetResponse `json:"records"`
+}
+
+// ---- Drift ----
+
+type RecordDriftResponse struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	Status         string          `json:"status"`
+	TTL            *int            `json:"ttl,omitempty"`
+	Values         json.RawMessage `json:"values" swaggertype:"object"`
+	ObservedTTL    *int            `json:"observed_ttl,omitempty"`
+	ObservedValues json.RawMessage `json:"observed_values" swaggertype:"object"` // [] when the record is gone
+	Alias          *AliasTarget    `json:"alias,omitempty"`
+	ObservedAlias  *AliasTarget    `json:"observed_alias,omitempty"`
+	Reason         string          `json:"reason"`
+	DriftedAt      string          `json:"drifted_at"`
+}
+
+type DomainDriftResponse struct {
+	DomainID string `json:"domain_id"`
+	// CheckedAt is the last time any of the domain's record sets was read
+	// back from the provider; empty if none has been yet.
+	CheckedAt string                `json:"checked_at,omitempty"`
+	Records   []RecordDriftResponse `json:"records"`
+}
+
 // DNSValidate Quick helper to validate DTOs in handlers
 func DNSValidate(i any) error {
 	return dnsValidate.Struct(i)
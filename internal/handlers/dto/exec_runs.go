@@ -0,0 +1,9 @@
+package dto
+
+type ExecRequest struct {
+	Command string `json:"command" example:"systemctl status kubelet --no-pager"`
+	// Concurrency is how many hosts run at once. Defaults to 10, max 100.
+	Concurrency *int `json:"concurrency,omitempty" example:"10"`
+	// TimeoutSeconds bounds the command on each host. Defaults to 60, max 3600.
+	TimeoutSeconds *int `json:"timeout_seconds,omitempty" example:"60"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NodeMetadataStatusResponse is what the last node metadata run found on one
+// node of a cluster.
+type NodeMetadataStatusResponse struct {
+	ServerID uuid.UUID `json:"server_id" format:"uuid"`
+	Hostname string    `json:"hostname"`
+	NodeName string    `json:"node_name"`
+	InSync   bool      `json:"in_sync"`
+	// Drift lists what differed from the node pools before it was corrected.
+	Drift     []string   `json:"drift"`
+	LastError string     `json:"last_error,omitempty"`
+	CheckedAt time.Time  `json:"checked_at" format:"date-time"`
+	AppliedAt *time.Time `json:"applied_at,omitempty" format:"date-time"`
+}
@@ -1,32 +1,94 @@
 package dto
 
-import "github.com/glueops/autoglue/internal/common"
+import (
+	"time"
+
+	"github.com/glueops/autoglue/internal/common"
+	"github.com/google/uuid"
+)
 
 type NodeRole string
 
 const (
 	NodeRoleMaster NodeRole = "master"
 	NodeRoleWorker NodeRole = "worker"
+	NodeRoleEtcd   NodeRole = "etcd"
 )
 
 type CreateNodePoolRequest struct {
 	Name string   `json:"name"`
-	Role NodeRole `json:"role" enums:"master,worker" swaggertype:"string"`
+	Role NodeRole `json:"role" enums:"master,worker,etcd" swaggertype:"string"`
 }
 
 type UpdateNodePoolRequest struct {
 	Name *string   `json:"name"`
-	Role *NodeRole `json:"role" enums:"master,worker" swaggertype:"string"`
+	Role *NodeRole `json:"role" enums:"master,worker,etcd" swaggertype:"string"`
+	// DesiredSize hands the pool's membership to the scaling reconciler; -1
+	// hands it back to manual attach/detach.
+	DesiredSize *int `json:"desired_size,omitempty" example:"3"`
+	// Template replaces the whole server template when present.
+	Template *NodePoolTemplateRequest `json:"template,omitempty"`
+}
+
+// NodePoolTemplateRequest is what the reconciler creates new pool members
+// from. Size, image and region are the provider's own identifiers; ssh_user
+// defaults to the provider's login user.
+type NodePoolTemplateRequest struct {
+	CredentialID string `json:"credential_id"`
+	Size         string `json:"size" example:"cx22"`
+	Image        string `json:"image" example:"ubuntu-24.04"`
+	Region       string `json:"region,omitempty" example:"fsn1"`
+	Network      string `json:"network,omitempty"`
+	SshKeyID     string `json:"ssh_key_id"`
+	SSHUser      string `json:"ssh_user,omitempty"`
+}
+
+type NodePoolTemplate struct {
+	CredentialID *uuid.UUID `json:"credential_id,omitempty"`
+	Size         string     `json:"size,omitempty" example:"cx22"`
+	Image        string     `json:"image,omitempty" example:"ubuntu-24.04"`
+	Region       string     `json:"region,omitempty" example:"fsn1"`
+	Network      string     `json:"network,omitempty"`
+	SshKeyID     *uuid.UUID `json:"ssh_key_id,omitempty"`
+	SSHUser      string     `gorm:"column:ssh_user" json:"ssh_user,omitempty"`
 }
 
 type NodePoolResponse struct {
 	common.AuditFields
 	Name        string               `json:"name"`
-	Role        NodeRole             `json:"role" enums:"master,worker" swaggertype:"string"`
+	Role        NodeRole             `json:"role" enums:"master,worker,etcd" swaggertype:"string"`
 	Servers     []ServerResponse     `json:"servers"`
 	Annotations []AnnotationResponse `json:"annotations"`
 	Labels      []LabelResponse      `json:"labels"`
 	Taints      []TaintResponse      `json:"taints"`
+	DesiredSize *int                 `json:"desired_size,omitempty"`
+	Template    NodePoolTemplate     `gorm:"embedded;embeddedPrefix:template_" json:"template"`
+	JoinPending bool                 `json:"join_pending"`
+	ScaleError  string               `json:"scale_error,omitempty"`
+	ScaledAt    *time.Time           `json:"scaled_at,omitempty" format:"date-time"`
+}
+
+// NodePoolPlanResponse is what the scaling reconciler would do to a pool on
+// its next run.
+type NodePoolPlanResponse struct {
+	NodePoolID  uuid.UUID `json:"node_pool_id"`
+	DesiredSize *int      `json:"desired_size,omitempty"`
+	// Current counts members that are, or are becoming, part of the pool.
+	Current  int                  `json:"current"`
+	Creating int                  `json:"creating"`
+	Deleting int                  `json:"deleting"`
+	Create   int                  `json:"create"`
+	Remove   []NodePoolPlanServer `json:"remove"`
+	// Blockers are the parts of the plan the reconciler will not carry out.
+	Blockers    []string `json:"blockers"`
+	JoinPending bool     `json:"join_pending"`
+	ScaleError  string   `json:"scale_error,omitempty"`
+}
+
+type NodePoolPlanServer struct {
+	ID       uuid.UUID `json:"id"`
+	Hostname string    `json:"hostname"`
+	Status   string    `json:"status"`
 }
 
 type AttachServersRequest struct {
@@ -0,0 +1,35 @@
+package dto
+
+import "github.com/google/uuid"
+
+// ServerImportRow is one host in an inventory. SSHKey and NodePool are names,
+// resolved within the organization; NodePool is optional.
+type ServerImportRow struct {
+	Hostname         string `json:"hostname" yaml:"hostname"`
+	PublicIPAddress  string `json:"public_ip_address,omitempty" yaml:"public_ip_address"`
+	PrivateIPAddress string `json:"private_ip_address" yaml:"private_ip_address"`
+	SSHUser          string `json:"ssh_user" yaml:"ssh_user"`
+	SSHKey           string `json:"ssh_key" yaml:"ssh_key"`
+	Role             string `json:"role" yaml:"role" enums:"master,worker,etcd,bastion"`
+	NodePool         string `json:"node_pool,omitempty" yaml:"node_pool"`
+}
+
+type ServerImportRowResult struct {
+	// Row is 1-based and counts data rows only, so a CSV header is not row 1.
+	Row              int        `json:"row"`
+	Hostname         string     `json:"hostname"`
+	PrivateIPAddress string     `json:"private_ip_address"`
+	Role             string     `json:"role"`
+	NodePool         string     `json:"node_pool,omitempty"`
+	Errors           []string   `json:"errors,omitempty"`
+	ServerID         *uuid.UUID `json:"server_id,omitempty"`
+}
+
+type ServerImportResponse struct {
+	// Applied is false for a preview, which creates nothing.
+	Applied bool `json:"applied"`
+	// Valid is false when any row has errors; nothing is created then.
+	Valid   bool                    `json:"valid"`
+	Created int                     `json:"created"`
+	Rows    []ServerImportRowResult `json:"rows"`
+}
@@ -1,6 +1,11 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type CreateServerRequest struct {
 	Hostname         string `json:"hostname,omitempty"`
@@ -8,8 +13,8 @@ type CreateServerRequest struct {
 	PrivateIPAddress string `json:"private_ip_address"`
 	SSHUser          string `json:"ssh_user"`
 	SshKeyID         string `json:"ssh_key_id"`
-	Role             string `json:"role" example:"master|worker|bastion" enums:"master,worker,bastion"`
-	Status           string `json:"status,omitempty" example:"pending|provisioning|ready|failed" enums:"pending,provisioning,ready,failed"`
+	Role             string `json:"role" example:"master|worker|etcd|bastion" enums:"master,worker,etcd,bastion"`
+	Status           string `json:"status,omitempty" example:"pending|provisioning|ready|failed|unreachable" enums:"creating,pending,provisioning,ready,failed,unreachable,deleting"`
 }
 
 type UpdateServerRequest struct {
@@ -18,8 +23,8 @@ type UpdateServerRequest struct {
 	PrivateIPAddress *string `json:"private_ip_address,omitempty"`
 	SSHUser          *string `json:"ssh_user,omitempty"`
 	SshKeyID         *string `json:"ssh_key_id,omitempty"`
-	Role             *string `json:"role" example:"master|worker|bastion" enums:"master,worker,bastion"`
-	Status           *string `json:"status,omitempty" example:"pending|provisioning|ready|failed" enums:"pending,provisioning,ready,failed"`
+	Role             *string `json:"role" example:"master|worker|etcd|bastion" enums:"master,worker,etcd,bastion"`
+	Status           *string `json:"status,omitempty" example:"pending|provisioning|ready|failed|unreachable" enums:"creating,pending,provisioning,ready,failed,unreachable,deleting"`
 }
 
 type ServerResponse struct {
@@ -30,8 +35,75 @@ type ServerResponse struct {
 	PrivateIPAddress string    `json:"private_ip_address"`
 	SSHUser          string    `json:"ssh_user"`
 	SshKeyID         uuid.UUID `json:"ssh_key_id"`
-	Role             string    `json:"role" example:"master|worker|bastion" enums:"master,worker,bastion"`
-	Status           string    `json:"status,omitempty" example:"pending|provisioning|ready|failed" enums:"pending,provisioning,ready,failed"`
+	Role             string    `json:"role" example:"master|worker|etcd|bastion" enums:"master,worker,etcd,bastion"`
+	Status           string    `json:"status,omitempty" example:"pending|provisioning|ready|failed|unreachable" enums:"creating,pending,provisioning,ready,failed,unreachable,deleting"`
 	CreatedAt        string    `json:"created_at,omitempty"`
 	UpdatedAt        string    `json:"updated_at,omitempty"`
+	// LastSeenAt is the last successful reachability probe; ProbeError is
+	// the most recent failure, cleared once a probe succeeds.
+	LastSeenAt     *time.Time `json:"last_seen_at,omitempty" format:"date-time"`
+	LastProbeAt    *time.Time `json:"last_probe_at,omitempty" format:"date-time"`
+	ProbeLatencyMs *int64     `json:"probe_latency_ms,omitempty"`
+	ProbeError     string     `json:"probe_error,omitempty"`
+	// Facts is the last snapshot gathered from the host, absent until the
+	// first collection has been attempted.
+	Facts *ServerFactsResponse `json:"facts,omitempty"`
+	// Instance is set for servers autoglue created at a cloud provider.
+	Instance *ServerInstanceResponse `json:"instance,omitempty"`
+}
+
+type ServerFactsResponse struct {
+	OSID                    string          `json:"os_id" example:"ubuntu"`
+	OSVersion               string          `json:"os_version" example:"24.04"`
+	OSPrettyName            string          `json:"os_pretty_name"`
+	Kernel                  string          `json:"kernel"`
+	Arch                    string          `json:"arch"`
+	CPUCount                int             `json:"cpu_count"`
+	MemoryBytes             int64           `json:"memory_bytes"`
+	Disks                   json.RawMessage `json:"disks" swaggertype:"array,object"`
+	ContainerRuntime        string          `json:"container_runtime"`
+	ContainerRuntimeVersion string          `json:"container_runtime_version"`
+	TimeSynced              *bool           `json:"time_synced,omitempty"`
+	CollectedAt             *time.Time      `json:"collected_at,omitempty" format:"date-time"`
+	LastAttemptAt           time.Time       `json:"last_attempt_at" format:"date-time"`
+	LastError               string          `json:"last_error,omitempty"`
+}
+
+// ServerFactsRefreshResponse is returned when a facts collection is queued.
+type ServerFactsRefreshResponse struct {
+	JobID int64 `json:"job_id"`
+	// Duplicate is true when a collection for this server was already queued
+	// or running; JobID is then that job.
+	Duplicate bool `json:"duplicate"`
+}
+
+// ProvisionServerRequest creates a server at a cloud provider instead of
+// registering an existing one.
+type ProvisionServerRequest struct {
+	Hostname string `json:"hostname"`
+	Role     string `json:"role" example:"master|worker|etcd|bastion" enums:"master,worker,etcd,bastion"`
+	// SshKeyID is the org key installed on the machine and used to log in.
+	SshKeyID string `json:"ssh_key_id"`
+	// SSHUser defaults to the provider's login user (root on Hetzner).
+	SSHUser string `json:"ssh_user,omitempty"`
+	// CredentialID is an api_token credential of a supported provider.
+	CredentialID string `json:"credential_id"`
+	Size         string `json:"size" example:"cx22"`
+	Image        string `json:"image" example:"ubuntu-24.04"`
+	Location     string `json:"location,omitempty" example:"fsn1"`
+	// Network is the provider's private network id to attach, if any.
+	Network string `json:"network,omitempty"`
+}
+
+type ServerInstanceResponse struct {
+	Provider     string     `json:"provider" example:"hetzner"`
+	CredentialID uuid.UUID  `json:"credential_id"`
+	Size         string     `json:"size"`
+	Image        string     `json:"image"`
+	Location     string     `json:"location"`
+	Network      string     `json:"network,omitempty"`
+	InstanceID   string     `json:"instance_id,omitempty"`
+	Status       string     `json:"status" enums:"creating,running,deleting,failed"`
+	LastError    string     `json:"last_error,omitempty"`
+	ReadyAt      *time.Time `json:"ready_at,omitempty" format:"date-time"`
 }
@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SshCAResponse is the org's SSH user CA. Only the public half is ever
+// returned.
+type SshCAResponse struct {
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// InstallSSHCARequest picks the servers to install the CA on. Empty means
+// every ready server in the org.
+type InstallSSHCARequest struct {
+	ServerIDs []uuid.UUID `json:"server_ids,omitempty"`
+}
+
+type InstallSSHCAResponse struct {
+	JobID int64 `json:"job_id"`
+}
+
+type IssueSSHCertificateRequest struct {
+	// PublicKey is the caller's own OpenSSH public key. The private half never
+	// leaves the caller.
+	PublicKey string `json:"public_key" example:"ssh-ed25519 AAAA... me@laptop"`
+	// TTLSeconds defaults to 3600 and must be between 300 and 86400.
+	TTLSeconds *int `json:"ttl_seconds,omitempty" example:"3600"`
+}
+
+type SshCertificateResponse struct {
+	ID                   uuid.UUID `json:"id"`
+	UserID               uuid.UUID `json:"user_id"`
+	Serial               int64     `json:"serial"`
+	KeyID                string    `json:"key_id"`
+	Principals           []string  `json:"principals"`
+	PublicKeyFingerprint string    `json:"public_key_fingerprint"`
+	CAFingerprint        string    `json:"ca_fingerprint"`
+	ValidAfter           time.Time `json:"valid_after" format:"date-time"`
+	ValidBefore          time.Time `json:"valid_before" format:"date-time"`
+	CreatedAt            time.Time `json:"created_at" format:"date-time"`
+}
+
+// IssuedSshCertificateResponse carries the signed certificate itself, in the
+// form ssh expects next to the private key as <key>-cert.pub.
+type IssuedSshCertificateResponse struct {
+	SshCertificateResponse
+	Certificate string `json:"certificate"`
+}
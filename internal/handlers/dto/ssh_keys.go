@@ -47,3 +47,22 @@ type SshMaterialJSON struct {
 	// Suggested filenames (SDKs can save to disk without inferring names)
 	Filenames []string `json:"filenames"`
 }
+
+// RotateSSHKeyRequest controls the replacement key. Every field is optional:
+// by default the new key has the same name, type and size as the one it
+// replaces.
+type RotateSSHKeyRequest struct {
+	Name    *string `json:"name,omitempty"`
+	Comment string  `json:"comment,omitempty" example:"deploy@autoglue"`
+	Bits    *int    `json:"bits,omitempty"` // Only for RSA
+	Type    *string `json:"type,omitempty"` // "rsa" or "ed25519"; defaults to the old key's type
+}
+
+// SshKeyRotationResponse describes a rotation that has been queued. Per-server
+// progress is written to each server's logs.
+type SshKeyRotationResponse struct {
+	OldKeyID    string      `json:"old_key_id"`
+	NewKey      SshResponse `json:"new_key"`
+	ServerCount int         `json:"server_count"`
+	JobID       int64       `json:"job_id"`
+}
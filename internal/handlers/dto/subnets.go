@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SubnetResponse struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	CIDR           string    `json:"cidr" example:"10.0.0.0/24"`
+	Gateway        string    `json:"gateway,omitempty" example:"10.0.0.1"`
+	VLAN           string    `json:"vlan,omitempty" example:"prod-100"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CreateSubnetRequest struct {
+	Name    string `json:"name" example:"prod-private"`
+	CIDR    string `json:"cidr" example:"10.0.0.0/24"`
+	Gateway string `json:"gateway,omitempty" example:"10.0.0.1"`
+	VLAN    string `json:"vlan,omitempty" example:"prod-100"`
+}
+
+type UpdateSubnetRequest struct {
+	Name    *string `json:"name,omitempty" example:"prod-private"`
+	CIDR    *string `json:"cidr,omitempty" example:"10.0.0.0/24"`
+	Gateway *string `json:"gateway,omitempty" example:"10.0.0.1"`
+	VLAN    *string `json:"vlan,omitempty" example:"prod-100"`
+}
+
+// SubnetAllocationResponse is an address reserved in a subnet. Other
+// allocations skip it until ExpiresAt; it is taken for good when a server or
+// load balancer is saved with it.
+type SubnetAllocationResponse struct {
+	SubnetID  uuid.UUID `json:"subnet_id"`
+	Address   string    `json:"address" example:"10.0.0.2"`
+	CIDR      string    `json:"cidr" example:"10.0.0.0/24"`
+	Gateway   string    `json:"gateway,omitempty" example:"10.0.0.1"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+// TerminalMessage is a client-to-server frame on the terminal WebSocket, sent
+// as a text frame. Output travels the other way as binary frames of raw
+// terminal bytes.
+type TerminalMessage struct {
+	// Type is "input" or "resize".
+	Type string `json:"type" enums:"input,resize"`
+	// Data is keystrokes, for input.
+	Data string `json:"data,omitempty"`
+	// Cols and Rows are the new window size, for resize.
+	Cols int `json:"cols,omitempty"`
+	Rows int `json:"rows,omitempty"`
+}
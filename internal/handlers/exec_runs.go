@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/riverqueue/river"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	execDefaultConcurrency = 10
+	execMaxConcurrency     = 100
+	execDefaultTimeout     = 60
+	execMaxTimeout         = 3600
+	execMaxCommandBytes    = 8 << 10
+)
+
+// ExecOnCluster godoc
+//
+//	@ID				ExecOnCluster
+//	@Summary		Run a command on every server in a cluster (admin)
+//	@Description	Runs a shell command as each server's ssh user on every server in the cluster's node pools, reaching private hosts through the cluster bastion. Hosts run in parallel up to `concurrency`, each bounded by `timeout_seconds`. Returns the recorded run; poll GET /exec-runs/{id} for per-host exit codes and GET /exec-runs/{id}/hosts/{hostID}/logs for output.
+//	@Tags			Exec
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string			false	"Organization UUID"
+//	@Param			clusterID	path		string			true	"Cluster ID"
+//	@Param			body		body		dto.ExecRequest	true	"Command"
+//	@Success		202			{object}	models.ExecRun
+//	@Failure		400			{string}	string	"invalid request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required / admin required"
+//	@Failure		404			{string}	string	"cluster not found"
+//	@Failure		409			{string}	string	"no servers"
+//	@Failure		500			{string}	string	"db / enqueue error"
+//	@Router			/clusters/{clusterID}/exec [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ExecOnCluster(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		clusterID, err := uuid.Parse(chi.URLParam(r, "clusterID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_cluster_id", "invalid cluster id")
+			return
+		}
+		if err := db.Select("id").Where("id = ? AND organization_id = ?", clusterID, orgID).
+			First(&models.Cluster{}).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "cluster not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		var servers []models.Server
+		if err := db.Raw(`
+			SELECT DISTINCT s.* FROM servers s
+			JOIN node_servers ns ON ns.server_id = s.id
+			JOIN cluster_node_pools cnp ON cnp.node_pool_id = ns.node_pool_id
+			WHERE cnp.cluster_id = ? AND s.organization_id = ?
+			ORDER BY s.hostname`, clusterID, orgID).
+			Scan(&servers).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		startExecRun(w, r, db, jobs, orgID, models.ExecRun{ClusterID: &clusterID}, servers)
+	}
+}
+
+// ExecOnNodePool godoc
+//
+//	@ID				ExecOnNodePool
+//	@Summary		Run a command on every server in a node pool (admin)
+//	@Description	Runs a shell command as each server's ssh user on every server in the node pool, reaching private hosts through their cluster bastion. Hosts run in parallel up to `concurrency`, each bounded by `timeout_seconds`. Returns the recorded run; poll GET /exec-runs/{id} for per-host exit codes and GET /exec-runs/{id}/hosts/{hostID}/logs for output.
+//	@Tags			Exec
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string			false	"Organization UUID"
+//	@Param			id			path		string			true	"Node Pool ID"
+//	@Param			body		body		dto.ExecRequest	true	"Command"
+//	@Success		202			{object}	models.ExecRun
+//	@Failure		400			{string}	string	"invalid request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required / admin required"
+//	@Failure		404			{string}	string	"node pool not found"
+//	@Failure		409			{string}	string	"no servers"
+//	@Failure		500			{string}	string	"db / enqueue error"
+//	@Router			/node-pools/{id}/exec [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ExecOnNodePool(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		npID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid node pool id")
+			return
+		}
+
+		var np models.NodePool
+		if err := db.Preload("Servers").Where("id = ? AND organization_id = ?", npID, orgID).
+			First(&np).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "node pool not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		startExecRun(w, r, db, jobs, orgID, models.ExecRun{NodePoolID: &npID}, np.Servers)
+	}
+}
+
+// startExecRun validates the request, records the run with one host row per
+// server, and enqueues a job per host.
+func startExecRun(
+	w http.ResponseWriter,
+	r *http.Request,
+	db *gorm.DB,
+	jobs *bg.Client,
+	orgID uuid.UUID,
+	run models.ExecRun,
+	servers []models.Server,
+) {
+	var req dto.ExecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid_payload", "invalid JSON payload")
+		return
+	}
+	cmd := strings.TrimSpace(req.Command)
+	if cmd == "" {
+		utils.WriteError(w, http.StatusBadRequest, "invalid_command", "command is required")
+		return
+	}
+	if len(cmd) > execMaxCommandBytes {
+		utils.WriteError(w, http.StatusBadRequest, "invalid_command", "command is too long")
+		return
+	}
+	concurrency := execDefaultConcurrency
+	if req.Concurrency != nil {
+		if *req.Concurrency < 1 || *req.Concurrency > execMaxConcurrency {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_concurrency", "concurrency must be between 1 and 100")
+			return
+		}
+		concurrency = *req.Concurrency
+	}
+	timeout := execDefaultTimeout
+	if req.TimeoutSeconds != nil {
+		if *req.TimeoutSeconds < 1 || *req.TimeoutSeconds > execMaxTimeout {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_timeout", "timeout_seconds must be between 1 and 3600")
+			return
+		}
+		timeout = *req.TimeoutSeconds
+	}
+	if len(servers) == 0 {
+		utils.WriteError(w, http.StatusConflict, "no_servers", "there are no servers to run on")
+		return
+	}
+
+	run.OrganizationID = orgID
+	run.Command = cmd
+	run.Concurrency = concurrency
+	run.TimeoutSeconds = timeout
+	run.Status = models.ExecRunStatusRunning
+	if u, ok := mustUser(r); ok {
+		run.UserID = &u.ID
+	}
+	run.Hosts = make([]models.ExecRunHost, 0, len(servers))
+	for _, s := range servers {
+		run.Hosts = append(run.Hosts, models.ExecRunHost{
+			ServerID: s.ID,
+			Hostname: s.Hostname,
+			Status:   models.ExecHostStatusQueued,
+		})
+	}
+	if err := db.Create(&run).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to record exec run")
+		return
+	}
+
+	who := "org key"
+	if run.UserID != nil {
+		who = run.UserID.String()
+	}
+	log.Info().
+		Str("org_id", orgID.String()).
+		Str("exec_run_id", run.ID.String()).
+		Str("by", who).
+		Int("hosts", len(run.Hosts)).
+		Str("command", cmd).
+		Msg("[exec] run started")
+
+	params := make([]river.InsertManyParams, 0, len(run.Hosts))
+	for _, h := range run.Hosts {
+		params = append(params, river.InsertManyParams{Args: bg.ExecHostArgs{
+			OrgID:          orgID,
+			RunID:          run.ID,
+			HostID:         h.ID,
+			TimeoutSeconds: timeout,
+		}})
+	}
+	if _, err := jobs.InsertMany(r.Context(), params); err != nil {
+		now := time.Now()
+		_ = db.Model(&models.ExecRunHost{}).Where("exec_run_id = ?", run.ID).
+			Updates(map[string]any{"status": models.ExecHostStatusFailed, "error": "failed to enqueue", "finished_at": now}).Error
+		_ = db.Model(&models.ExecRun{}).Where("id = ?", run.ID).
+			Updates(map[string]any{"status": models.ExecRunStatusFailed, "finished_at": now}).Error
+		utils.WriteError(w, http.StatusInternalServerError, "job_error", "failed to enqueue exec run")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, run)
+}
+
+// ListExecRuns godoc
+//
+//	@ID				ListExecRuns
+//	@Summary		List ad-hoc exec runs (org scoped)
+//	@Description	Returns the record of ad-hoc command runs, newest first, without per-host detail.
+//	@Tags			Exec
+//	@Produce		json
+//	@Param			X-Org-ID		header		string	false	"Organization UUID"
+//	@Param			cluster_id		query		string	false	"Only runs against this cluster"
+//	@Param			node_pool_id	query		string	false	"Only runs against this node pool"
+//	@Success		200				{array}		models.ExecRun
+//	@Failure		400				{string}	string	"invalid filter"
+//	@Failure		401				{string}	string	"Unauthorized"
+//	@Failure		403				{string}	string	"organization required"
+//	@Failure		500				{string}	string	"db error"
+//	@Router			/exec-runs [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ListExecRuns(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		q := db.Where("organization_id = ?", orgID)
+		for param, col := range map[string]string{"cluster_id": "cluster_id", "node_pool_id": "node_pool_id"} {
+			if v := r.URL.Query().Get(param); v != "" {
+				id, err := uuid.Parse(v)
+				if err != nil {
+					utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid "+param)
+					return
+				}
+				q = q.Where(col+" = ?", id)
+			}
+		}
+
+		var out []models.ExecRun
+		if err := q.Order("created_at DESC").Limit(200).Find(&out).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// GetExecRun godoc
+//
+//	@ID				GetExecRun
+//	@Summary		Get an ad-hoc exec run with per-host results (org scoped)
+//	@Description	Returns the run and each host's status and exit code. exit_code is absent for a host whose command did not run to completion; error says why.
+//	@Tags			Exec
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Exec Run ID"
+//	@Success		200			{object}	models.ExecRun
+//	@Failure		400			{string}	string	"invalid id"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		500			{string}	string	"db error"
+//	@Router			/exec-runs/{id} [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func GetExecRun(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "id_invalid", "invalid id")
+			return
+		}
+
+		var run models.ExecRun
+		if err := db.Preload("Hosts", func(tx *gorm.DB) *gorm.DB { return tx.Order("hostname") }).
+			Where("id = ? AND organization_id = ?", id, orgID).
+			First(&run).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "exec run not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, run)
+	}
+}
+
+// GetExecRunHostLogs godoc
+//
+//	@ID				GetExecRunHostLogs
+//	@Summary		Tail one host's output from an ad-hoc exec run
+//	@Description	Returns the command output from one host, in order. Poll by passing the previous `next_cursor` as `after`; stop when `done` is true.
+//	@Tags			Exec
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Exec Run ID"
+//	@Param			hostID		path		string	true	"Exec Run Host ID"
+//	@Param			after		query		int		false	"Return chunks with an id greater than this"	default(0)
+//	@Param			limit		query		int		false	"Maximum chunks to return"						minimum(1)	maximum(1000)	default(200)
+//	@Success		200			{object}	dto.JobLogPage
+//	@Failure		400			{string}	string	"bad request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		500			{string}	string	"db error"
+//	@Router			/exec-runs/{id}/hosts/{hostID}/logs [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func GetExecRunHostLogs(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		runID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "id_invalid", "invalid id")
+			return
+		}
+		hostID, err := uuid.Parse(chi.URLParam(r, "hostID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_host_id", "invalid host id")
+			return
+		}
+
+		var host models.ExecRunHost
+		if err := db.Joins("JOIN exec_runs er ON er.id = exec_run_hosts.exec_run_id").
+			Where("exec_run_hosts.id = ? AND er.id = ? AND er.organization_id = ?", hostID, runID, orgID).
+			First(&host).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "host not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		after, limit := jobLogQuery(r)
+		items, cursor, err := readJobLogs(db, orgID, models.JobLogSubjectExecHost, hostID, after, limit)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+
+		done := host.Status == models.ExecHostStatusSucceeded || host.Status == models.ExecHostStatusFailed
+		utils.WriteJSON(w, http.StatusOK, dto.JobLogPage{
+			Items:      items,
+			NextCursor: cursor,
+			Done:       done && len(items) < limit,
+		})
+	}
+}
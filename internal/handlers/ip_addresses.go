@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/glueops/autoglue/internal/models"
+)
+
+// normalizeIPField validates an optional address field, returning "" for an
+// empty one.
+func normalizeIPField(field, raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+	ip, err := models.NormalizeIP(raw)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", field, err)
+	}
+	return ip, nil
+}
+
+// changedIPs is the non-empty addresses in next that are not already in prev.
+// Only those are checked for conflicts, so data that predates validation
+// does not block unrelated edits.
+func changedIPs(prev, next []string) []string {
+	var out []string
+	for _, ip := range next {
+		if ip != "" && !slices.Contains(prev, ip) && !slices.Contains(out, ip) {
+			out = append(out, ip)
+		}
+	}
+	return out
+}
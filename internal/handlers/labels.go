@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/common"
 	"github.com/glueops/autoglue/internal/handlers/dto"
 	"github.com/glueops/autoglue/internal/models"
@@ -123,7 +124,7 @@ func GetLabel(db *gorm.DB) http.HandlerFunc {
 //	@Param			X-Org-ID	header		string					false	"Organization UUID"
 //	@Param			body		body		dto.CreateLabelRequest	true	"Label payload"
 //	@Success		201			{object}	dto.LabelResponse
-//	@Failure		400			{string}	string	"invalid json / missing fields / invalid node_pool_ids"
+//	@Failure		400			{string}	string	"invalid json / missing fields / invalid node_pool_ids / key or value not valid for Kubernetes"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		500			{string}	string	"create failed"
@@ -158,6 +159,10 @@ func CreateLabel(db *gorm.DB) http.HandlerFunc {
 			Key:         req.Key,
 			Value:       req.Value,
 		}
+		if err := l.Validate(); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
 		if err := db.Create(&l).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
 			return
@@ -185,16 +190,17 @@ func CreateLabel(db *gorm.DB) http.HandlerFunc {
 //	@Param			id			path		string					true	"Label ID (UUID)"
 //	@Param			body		body		dto.UpdateLabelRequest	true	"Fields to update"
 //	@Success		200			{object}	dto.LabelResponse
-//	@Failure		400			{string}	string	"invalid id / invalid json"
+//	@Failure		400			{string}	string	"invalid id / invalid json / key or value not valid for Kubernetes"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"would give a node conflicting labels or taints"
 //	@Failure		500			{string}	string	"update failed"
 //	@Router			/labels/{id} [patch]
 //	@Security		BearerAuth
 //	@Security		OrgKeyAuth
 //	@Security		OrgSecretAuth
-func UpdateLabel(db *gorm.DB) http.HandlerFunc {
+func UpdateLabel(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -231,8 +237,17 @@ func UpdateLabel(db *gorm.DB) http.HandlerFunc {
 			l.Value = strings.TrimSpace(*req.Value)
 		}
 
-		if err := db.Save(&l).Error; err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+		if err := l.Validate(); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+
+		pools := poolsUsing(db, orgID, "node_labels", "label_id", id)
+		err = saveWithoutConflicts(db, pools, func(tx *gorm.DB) error {
+			return tx.Save(&l).Error
+		})
+		if err != nil {
+			writeConflictOrDBError(w, err)
 			return
 		}
 
@@ -241,6 +256,7 @@ func UpdateLabel(db *gorm.DB) http.HandlerFunc {
 			Key:         l.Key,
 			Value:       l.Value,
 		}
+		syncNodeMetadata(r.Context(), db, jobs, pools...)
 		utils.WriteJSON(w, http.StatusOK, out)
 	}
 }
@@ -263,7 +279,7 @@ func UpdateLabel(db *gorm.DB) http.HandlerFunc {
 //	@Security		BearerAuth
 //	@Security		OrgKeyAuth
 //	@Security		OrgSecretAuth
-func DeleteLabel(db *gorm.DB) http.HandlerFunc {
+func DeleteLabel(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -277,10 +293,13 @@ func DeleteLabel(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		// The join rows go with it, so find the pools first.
+		pools := poolsUsing(db, orgID, "node_labels", "label_id", id)
 		if err := db.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.Label{}).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, pools...)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -111,6 +111,7 @@ func GetLoadBalancer(db *gorm.DB) http.HandlerFunc {
 //	@Failure	400			{string}	string	"validation error"
 //	@Failure	403			{string}	string	"organization required"
 //	@Failure	404			{string}	string	"domain not found"
+//	@Failure	409			{string}	string	"address already in use"
 //	@Router		/load-balancers [post]
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
@@ -134,16 +135,32 @@ func CreateLoadBalancer(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "bad_kind", "invalid kind only 'glueops' or 'public'")
 			return
 		}
+		pub, err := normalizeIPField("public_ip_address", in.PublicIPAddress)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "ip_invalid", err.Error())
+			return
+		}
+		priv, err := normalizeIPField("private_ip_address", in.PrivateIPAddress)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "ip_invalid", err.Error())
+			return
+		}
 
 		row := &models.LoadBalancer{
 			OrganizationID:   orgID,
 			Name:             in.Name,
 			Kind:             strings.ToLower(in.Kind),
-			PublicIPAddress:  in.PublicIPAddress,
-			PrivateIPAddress: in.PrivateIPAddress,
+			PublicIPAddress:  pub,
+			PrivateIPAddress: priv,
 		}
-		if err := db.Create(row).Error; err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := models.CheckIPConflicts(tx, orgID, uuid.Nil, changedIPs(nil, []string{pub, priv})...); err != nil {
+				return err
+			}
+			return tx.Create(row).Error
+		})
+		if err != nil {
+			writeLoadBalancerSaveError(w, err)
 			return
 		}
 		utils.WriteJSON(w, http.StatusCreated, loadBalancerOut(row))
@@ -164,6 +181,7 @@ func CreateLoadBalancer(db *gorm.DB) http.HandlerFunc {
 //	@Failure	400			{string}	string	"validation error"
 //	@Failure	403			{string}	string	"organization required"
 //	@Failure	404			{string}	string	"not found"
+//	@Failure	409			{string}	string	"address already in use"
 //	@Router		/load-balancers/{id} [patch]
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
@@ -208,14 +226,32 @@ func UpdateLoadBalancer(db *gorm.DB) http.HandlerFunc {
 			}
 			row.Kind = strings.ToLower(*in.Kind)
 		}
+		prev := []string{row.PublicIPAddress, row.PrivateIPAddress}
 		if in.PublicIPAddress != nil {
-			row.PublicIPAddress = *in.PublicIPAddress
+			ip, err := normalizeIPField("public_ip_address", *in.PublicIPAddress)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "ip_invalid", err.Error())
+				return
+			}
+			row.PublicIPAddress = ip
 		}
 		if in.PrivateIPAddress != nil {
-			row.PrivateIPAddress = *in.PrivateIPAddress
+			ip, err := normalizeIPField("private_ip_address", *in.PrivateIPAddress)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "ip_invalid", err.Error())
+				return
+			}
+			row.PrivateIPAddress = ip
 		}
-		if err := db.Save(row).Error; err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+		ips := changedIPs(prev, []string{row.PublicIPAddress, row.PrivateIPAddress})
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := models.CheckIPConflicts(tx, orgID, row.ID, ips...); err != nil {
+				return err
+			}
+			return tx.Save(row).Error
+		})
+		if err != nil {
+			writeLoadBalancerSaveError(w, err)
 			return
 		}
 		utils.WriteJSON(w, http.StatusOK, loadBalancerOut(row))
@@ -270,6 +306,15 @@ func DeleteLoadBalancer(db *gorm.DB) http.HandlerFunc {
 	}
 }
 
+func writeLoadBalancerSaveError(w http.ResponseWriter, err error) {
+	var ic *models.IPConflictError
+	if errors.As(err, &ic) {
+		utils.WriteError(w, http.StatusConflict, "ip_conflict", ic.Error())
+		return
+	}
+	utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+}
+
 // ---------- Out mappers ----------
 
 func loadBalancerOut(m *models.LoadBalancer) dto.LoadBalancerResponse {
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListClusterNodeMetadata godoc
+//
+//	@ID				ListClusterNodeMetadata
+//	@Summary		Per-node drift of node pool labels, annotations and taints (org scoped)
+//	@Description	Returns, for each server in the cluster's node pools, what the last node metadata run found: whether the node's labels, annotations and taints matched its node pools, and what differed before the run corrected it. Runs are queued whenever pool metadata changes and periodically otherwise.
+//	@Tags			Clusters
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			clusterID	path		string	true	"Cluster ID"
+//	@Success		200			{array}		dto.NodeMetadataStatusResponse
+//	@Failure		400			{string}	string	"invalid cluster id"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"cluster not found"
+//	@Failure		500			{string}	string	"db error"
+//	@Router			/clusters/{clusterID}/node-metadata [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ListClusterNodeMetadata(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		clusterID, err := uuid.Parse(chi.URLParam(r, "clusterID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid cluster id")
+			return
+		}
+		if !clusterInOrg(w, db, orgID, clusterID) {
+			return
+		}
+
+		type row struct {
+			models.NodeMetadataStatus
+			Hostname string
+		}
+		var rows []row
+		if err := db.Table("node_metadata_statuses nms").
+			Select("nms.*, s.hostname").
+			Joins("JOIN servers s ON s.id = nms.server_id").
+			Where("nms.cluster_id = ?", clusterID).
+			Order("s.hostname").
+			Scan(&rows).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		out := make([]dto.NodeMetadataStatusResponse, 0, len(rows))
+		for _, r := range rows {
+			drift := []string{}
+			_ = json.Unmarshal(r.Drift, &drift)
+			out = append(out, dto.NodeMetadataStatusResponse{
+				ServerID:  r.ServerID,
+				Hostname:  r.Hostname,
+				NodeName:  r.NodeName,
+				InSync:    r.InSync,
+				Drift:     drift,
+				LastError: r.LastError,
+				CheckedAt: r.CheckedAt,
+				AppliedAt: r.AppliedAt,
+			})
+		}
+		utils.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// SyncClusterNodeMetadata godoc
+//
+//	@ID				SyncClusterNodeMetadata
+//	@Summary		Push node pool labels, annotations and taints to the cluster now (org scoped)
+//	@Description	Queues a node metadata run for the cluster instead of waiting for the next periodic one. The outcome shows up in GET /clusters/{clusterID}/node-metadata.
+//	@Tags			Clusters
+//	@Produce		json
+//	@Param			X-Org-ID	header	string	false	"Organization UUID"
+//	@Param			clusterID	path	string	true	"Cluster ID"
+//	@Success		202			"Accepted"
+//	@Failure		400			{string}	string	"invalid cluster id"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"cluster not found"
+//	@Failure		500			{string}	string	"failed to queue"
+//	@Router			/clusters/{clusterID}/node-metadata/sync [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func SyncClusterNodeMetadata(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		clusterID, err := uuid.Parse(chi.URLParam(r, "clusterID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid cluster id")
+			return
+		}
+		if !clusterInOrg(w, db, orgID, clusterID) {
+			return
+		}
+
+		if _, err := jobs.Insert(r.Context(), bg.NodeMetadataArgs{ClusterID: clusterID}, nil); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "job_error", "failed to queue node metadata sync")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// clusterInOrg writes the error response itself when the cluster is not the
+// org's.
+func clusterInOrg(w http.ResponseWriter, db *gorm.DB, orgID, clusterID uuid.UUID) bool {
+	var n int64
+	if err := db.Model(&models.Cluster{}).Where("id = ? AND organization_id = ?", clusterID, orgID).Count(&n).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+		return false
+	}
+	if n == 0 {
+		utils.WriteError(w, http.StatusNotFound, "not_found", "cluster not found")
+		return false
+	}
+	return true
+}
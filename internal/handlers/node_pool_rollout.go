@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	rolloutMaxBatch            = 100
+	rolloutDefaultReadyTimeout = 600
+	rolloutMinReadyTimeout     = 60
+	rolloutMaxReadyTimeout     = 3600
+)
+
+// StartNodePoolRollout godoc
+//
+//	@ID				StartNodePoolRollout
+//	@Summary		Reboot or run an action across a node pool, a batch at a time (org scoped)
+//	@Description	Takes the pool's servers, in hostname order, through the operation in batches of `batch_size`: cordon and drain, reboot or run the action, wait for the node to be Ready, uncordon. No more than `max_unavailable` of the pool's nodes are out at once, counting nodes that were already NotReady or cordoned. The run halts at the first failure, leaving that node cordoned and the servers after it untouched. Poll GET /clusters/{clusterID}/runs/{runID}/servers for per-server progress.
+//	@Tags			ClusterRuns
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string						false	"Organization UUID"
+//	@Param			clusterID	path		string						true	"Cluster ID"
+//	@Param			nodePoolID	path		string						true	"Node Pool ID"
+//	@Param			body		body		dto.NodePoolRolloutRequest	true	"Operation"
+//	@Success		201			{object}	dto.ClusterRunResponse
+//	@Failure		400			{string}	string	"invalid request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"cluster, node pool or action not found"
+//	@Failure		409			{string}	string	"cluster not ready / no servers / rollout in progress"
+//	@Failure		500			{string}	string	"db / enqueue error"
+//	@Router			/clusters/{clusterID}/node-pools/{nodePoolID}/rollouts [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func StartNodePoolRollout(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		clusterID, err := uuid.Parse(chi.URLParam(r, "clusterID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_cluster_id", "invalid cluster id")
+			return
+		}
+		npID, err := uuid.Parse(chi.URLParam(r, "nodePoolID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_node_pool_id", "invalid node pool id")
+			return
+		}
+
+		var req dto.NodePoolRolloutRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_payload", "invalid JSON payload")
+			return
+		}
+		args := bg.NodePoolRolloutArgs{
+			OrgID:               orgID,
+			ClusterID:           clusterID,
+			NodePoolID:          npID,
+			Operation:           strings.TrimSpace(req.Operation),
+			MaxUnavailable:      1,
+			ReadyTimeoutSeconds: rolloutDefaultReadyTimeout,
+		}
+		batchSize := 1
+		if req.BatchSize != nil {
+			if *req.BatchSize < 1 || *req.BatchSize > rolloutMaxBatch {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_batch_size", "batch_size must be between 1 and 100")
+				return
+			}
+			batchSize = *req.BatchSize
+		}
+		if req.MaxUnavailable != nil {
+			if *req.MaxUnavailable < 1 || *req.MaxUnavailable > rolloutMaxBatch {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_max_unavailable", "max_unavailable must be between 1 and 100")
+				return
+			}
+			args.MaxUnavailable = *req.MaxUnavailable
+		}
+		if req.ReadyTimeoutSeconds != nil {
+			if *req.ReadyTimeoutSeconds < rolloutMinReadyTimeout || *req.ReadyTimeoutSeconds > rolloutMaxReadyTimeout {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_timeout", "ready_timeout_seconds must be between 60 and 3600")
+				return
+			}
+			args.ReadyTimeoutSeconds = *req.ReadyTimeoutSeconds
+		}
+
+		switch args.Operation {
+		case bg.RolloutOperationReboot:
+			if req.ActionID != nil {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_operation", "action_id is only valid with operation \"action\"")
+				return
+			}
+		case bg.RolloutOperationAction:
+			if req.ActionID == nil {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_operation", "action_id is required with operation \"action\"")
+				return
+			}
+			var action models.Action
+			if err := db.Where("id = ?", *req.ActionID).First(&action).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					utils.WriteError(w, http.StatusNotFound, "action_not_found", "action not found")
+					return
+				}
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+				return
+			}
+			args.MakeTarget = action.MakeTarget
+		default:
+			utils.WriteError(w, http.StatusBadRequest, "invalid_operation", "operation must be \"reboot\" or \"action\"")
+			return
+		}
+
+		var cluster models.Cluster
+		if err := db.Where("id = ? AND organization_id = ?", clusterID, orgID).
+			First(&cluster).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "cluster not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		var np models.NodePool
+		if err := db.Preload("Servers").
+			Joins("JOIN cluster_node_pools cnp ON cnp.node_pool_id = node_pools.id").
+			Where("node_pools.id = ? AND node_pools.organization_id = ? AND cnp.cluster_id = ?", npID, orgID, clusterID).
+			First(&np).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "node pool not found in cluster")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		if cluster.Status != models.ClusterStatusReady || cluster.EncryptedKubeconfig == "" || cluster.BastionServerID == nil {
+			utils.WriteError(w, http.StatusConflict, "cluster_not_ready", "cluster must be ready, with a bastion and a kubeconfig")
+			return
+		}
+		if len(np.Servers) == 0 {
+			utils.WriteError(w, http.StatusConflict, "no_servers", "the node pool has no servers")
+			return
+		}
+
+		action := "rolling-reboot"
+		if args.Operation == bg.RolloutOperationAction {
+			action = "rolling:" + args.MakeTarget
+		}
+		run := models.ClusterRun{
+			OrganizationID: orgID,
+			ClusterID:      clusterID,
+			Action:         action,
+			Status:         models.ClusterRunStatusQueued,
+		}
+		servers := np.Servers
+		sort.Slice(servers, func(i, j int) bool { return servers[i].Hostname < servers[j].Hostname })
+
+		var busy bool
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// Two rollouts on one cluster would each count the other's nodes
+			// as already unavailable, and race to drain them. The cluster row
+			// lock makes the check and the insert one step.
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+				Where("id = ?", clusterID).First(&models.Cluster{}).Error; err != nil {
+				return err
+			}
+			var n int64
+			if err := tx.Model(&models.ClusterRun{}).
+				Where("cluster_id = ? AND status IN ?", clusterID,
+					[]string{models.ClusterRunStatusQueued, models.ClusterRunStatusRunning}).
+				Where("EXISTS (SELECT 1 FROM cluster_run_servers crs WHERE crs.cluster_run_id = cluster_runs.id)").
+				Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				busy = true
+				return nil
+			}
+			if err := tx.Create(&run).Error; err != nil {
+				return err
+			}
+			rows := make([]models.ClusterRunServer, 0, len(servers))
+			for i, s := range servers {
+				rows = append(rows, models.ClusterRunServer{
+					ClusterRunID: run.ID,
+					ServerID:     s.ID,
+					Hostname:     s.Hostname,
+					Batch:        i / batchSize,
+					Status:       models.ClusterRunServerStatusQueued,
+				})
+			}
+			return tx.Create(&rows).Error
+		})
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to record rollout")
+			return
+		}
+		if busy {
+			utils.WriteError(w, http.StatusConflict, "rollout_in_progress", "a rollout is already queued or running on this cluster")
+			return
+		}
+
+		args.RunID = run.ID
+		insertRes, enqueueErr := jobs.Insert(r.Context(), args, nil)
+		if enqueueErr != nil {
+			now := time.Now().UTC()
+			_ = db.Model(&models.ClusterRunServer{}).Where("cluster_run_id = ?", run.ID).
+				Updates(map[string]any{"status": models.ClusterRunServerStatusSkipped, "finished_at": now}).Error
+			_ = db.Model(&models.ClusterRun{}).Where("id = ?", run.ID).
+				Updates(map[string]any{
+					"status":      models.ClusterRunStatusFailed,
+					"error":       "failed to enqueue job: " + enqueueErr.Error(),
+					"finished_at": now,
+				}).Error
+			utils.WriteError(w, http.StatusInternalServerError, "job_error", "failed to enqueue rollout")
+			return
+		}
+		if insertRes != nil && insertRes.Job != nil {
+			_ = db.Model(&models.ClusterRun{}).Where("id = ?", run.ID).
+				Update("job_id", insertRes.Job.ID).Error
+		}
+		utils.WriteJSON(w, http.StatusCreated, clusterRunToDTO(run))
+	}
+}
+
+// ListClusterRunServers godoc
+//
+//	@ID				ListClusterRunServers
+//	@Summary		Per-server progress of a rolling node pool run (org scoped)
+//	@Description	Returns each server's batch, step and outcome, in the order the run takes them. Runs that are not rollouts have no servers.
+//	@Tags			ClusterRuns
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			clusterID	path		string	true	"Cluster ID"
+//	@Param			runID		path		string	true	"Run ID"
+//	@Success		200			{array}		models.ClusterRunServer
+//	@Failure		400			{string}	string	"bad request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"run not found"
+//	@Failure		500			{string}	string	"db error"
+//	@Router			/clusters/{clusterID}/runs/{runID}/servers [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ListClusterRunServers(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		clusterID, err := uuid.Parse(chi.URLParam(r, "clusterID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_cluster_id", "invalid cluster id")
+			return
+		}
+		runID, err := uuid.Parse(chi.URLParam(r, "runID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_run_id", "invalid run id")
+			return
+		}
+
+		if err := db.Select("id").
+			Where("id = ? AND organization_id = ? AND cluster_id = ?", runID, orgID, clusterID).
+			First(&models.ClusterRun{}).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "run not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		rows := []models.ClusterRunServer{}
+		if err := db.Where("cluster_run_id = ?", runID).
+			Order("batch, hostname").Find(&rows).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, rows)
+	}
+}
+
+// GetClusterRunServerLogs godoc
+//
+//	@ID				GetClusterRunServerLogs
+//	@Summary		Tail one server's output from a rolling node pool run
+//	@Description	Returns the drain, operation and readiness output for one server, in order. Poll by passing the previous `next_cursor` as `after`; stop when `done` is true.
+//	@Tags			ClusterRuns
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			clusterID	path		string	true	"Cluster ID"
+//	@Param			runID		path		string	true	"Run ID"
+//	@Param			serverID	path		string	true	"Cluster Run Server ID"
+//	@Param			after		query		int		false	"Return chunks with an id greater than this"	default(0)
+//	@Param			limit		query		int		false	"Maximum chunks to return"						minimum(1)	maximum(1000)	default(200)
+//	@Success		200			{object}	dto.JobLogPage
+//	@Failure		400			{string}	string	"bad request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		500			{string}	string	"db error"
+//	@Router			/clusters/{clusterID}/runs/{runID}/servers/{serverID}/logs [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func GetClusterRunServerLogs(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		clusterID, err := uuid.Parse(chi.URLParam(r, "clusterID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_cluster_id", "invalid cluster id")
+			return
+		}
+		runID, err := uuid.Parse(chi.URLParam(r, "runID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_run_id", "invalid run id")
+			return
+		}
+		rowID, err := uuid.Parse(chi.URLParam(r, "serverID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_server_id", "invalid server id")
+			return
+		}
+
+		var row models.ClusterRunServer
+		if err := db.Joins("JOIN cluster_runs cr ON cr.id = cluster_run_servers.cluster_run_id").
+			Where("cluster_run_servers.id = ? AND cr.id = ? AND cr.organization_id = ? AND cr.cluster_id = ?",
+				rowID, runID, orgID, clusterID).
+			First(&row).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "server not found in run")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		after, limit := jobLogQuery(r)
+		items, cursor, err := readJobLogs(db, orgID, models.JobLogSubjectClusterRunServer, rowID, after, limit)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+
+		done := row.Status == models.ClusterRunServerStatusSucceeded ||
+			row.Status == models.ClusterRunServerStatusFailed ||
+			row.Status == models.ClusterRunServerStatusSkipped
+		utils.WriteJSON(w, http.StatusOK, dto.JobLogPage{
+			Items:      items,
+			NextCursor: cursor,
+			Done:       done && len(items) < limit,
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/compute"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxNodePoolSize caps desired_size, so a typo cannot order a thousand
+// machines.
+const maxNodePoolSize = 100
+
+// GetNodePoolPlan godoc
+//
+//	@ID				GetNodePoolPlan
+//	@Summary		Show what the scaling reconciler would do to a node pool (org scoped)
+//	@Description	Compares the pool's desired_size with its members: how many servers would be created from the template, which would be drained and deleted, and anything that stops part of that from happening. Only servers autoglue created are ever removed. A pool without a desired_size has nothing to plan beyond cleaning up failed cloud servers.
+//	@Tags			NodePools
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Node Pool ID (UUID)"
+//	@Success		200			{object}	dto.NodePoolPlanResponse
+//	@Failure		400			{string}	string	"invalid id"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		500			{string}	string	"db error"
+//	@Router			/node-pools/{id}/plan [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func GetNodePoolPlan(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "id_required", "id required")
+			return
+		}
+
+		np, plan, err := bg.LoadNodePoolPlan(db, orgID, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "node_pool_not_found", "node pool not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		out := dto.NodePoolPlanResponse{
+			NodePoolID:  np.ID,
+			DesiredSize: plan.Desired,
+			Current:     plan.Current,
+			Creating:    plan.Creating,
+			Deleting:    plan.Deleting,
+			Create:      plan.Create,
+			Remove:      make([]dto.NodePoolPlanServer, 0, len(plan.Remove)),
+			Blockers:    plan.Blockers,
+			JoinPending: np.JoinPending,
+			ScaleError:  np.ScaleError,
+		}
+		if out.Blockers == nil {
+			out.Blockers = []string{}
+		}
+		for _, s := range plan.Remove {
+			out.Remove = append(out.Remove, dto.NodePoolPlanServer{ID: s.ID, Hostname: s.Hostname, Status: s.Status})
+		}
+		utils.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// nodePoolTemplate validates a template request against the org: the
+// credential must be an API token of a provider autoglue can create servers
+// at, and the key must be the org's.
+func nodePoolTemplate(db *gorm.DB, orgID uuid.UUID, req dto.NodePoolTemplateRequest) (models.ServerTemplate, string, string) {
+	t := models.ServerTemplate{
+		Size:    strings.TrimSpace(req.Size),
+		Image:   strings.TrimSpace(req.Image),
+		Region:  strings.TrimSpace(req.Region),
+		Network: strings.TrimSpace(req.Network),
+		SSHUser: strings.TrimSpace(req.SSHUser),
+	}
+	if t.Size == "" || t.Image == "" || req.CredentialID == "" || req.SshKeyID == "" {
+		return t, "bad_request", "template needs credential_id, ssh_key_id, size and image"
+	}
+
+	keyID, err := uuid.Parse(req.SshKeyID)
+	if err != nil {
+		return t, "bad_request", "invalid template ssh_key_id"
+	}
+	if err := ensureKeyBelongsToOrg(orgID, keyID, db); err != nil {
+		return t, "bad_request", "invalid or unauthorized template ssh_key_id"
+	}
+	t.SshKeyID = &keyID
+
+	credID, err := uuid.Parse(req.CredentialID)
+	if err != nil {
+		return t, "bad_request", "invalid template credential_id"
+	}
+	var cred models.Credential
+	if err := db.Where("id = ? AND organization_id = ?", credID, orgID).First(&cred).Error; err != nil {
+		return t, "bad_request", "invalid or unauthorized template credential_id"
+	}
+	if !compute.Supported(cred.Provider) || cred.Kind != "api_token" {
+		return t, "provider_unsupported", "credential must be an api_token of a supported compute provider (hetzner)"
+	}
+	t.CredentialID = &credID
+	return t, "", ""
+}
+
+func nodePoolTemplateToDTO(t models.ServerTemplate) dto.NodePoolTemplate {
+	return dto.NodePoolTemplate{
+		CredentialID: t.CredentialID,
+		Size:         t.Size,
+		Image:        t.Image,
+		Region:       t.Region,
+		Network:      t.Network,
+		SshKeyID:     t.SshKeyID,
+		SSHUser:      t.SSHUser,
+	}
+}
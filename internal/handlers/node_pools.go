@@ -1,19 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/common"
 	"github.com/glueops/autoglue/internal/handlers/dto"
 	"github.com/glueops/autoglue/internal/models"
 	"github.com/glueops/autoglue/internal/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
@@ -71,6 +75,11 @@ func ListNodePools(db *gorm.DB) http.HandlerFunc {
 				Labels:      make([]dto.LabelResponse, 0, len(p.Labels)),
 				Taints:      make([]dto.TaintResponse, 0, len(p.Taints)),
 				Annotations: make([]dto.AnnotationResponse, 0, len(p.Annotations)),
+				DesiredSize: p.DesiredSize,
+				Template:    nodePoolTemplateToDTO(p.Template),
+				JoinPending: p.JoinPending,
+				ScaleError:  p.ScaleError,
+				ScaledAt:    p.ScaledAt,
 			}
 			//Servers
 			for _, s := range p.Servers {
@@ -195,7 +204,7 @@ func GetNodePool(db *gorm.DB) http.HandlerFunc {
 //	@Param			X-Org-ID	header		string						false	"Organization UUID"
 //	@Param			body		body		dto.CreateNodePoolRequest	true	"NodePool payload"
 //	@Success		201			{object}	dto.NodePoolResponse
-//	@Failure		400			{string}	string	"invalid json / missing fields / invalid server_ids"
+//	@Failure		400			{string}	string	"invalid json / missing fields / invalid role / invalid server_ids"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		500			{string}	string	"create failed"
@@ -224,6 +233,10 @@ func CreateNodePool(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "bad_request", "missing name/role")
 			return
 		}
+		if err := models.ValidateNodePoolRole(string(req.Role)); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
 
 		n := models.NodePool{
 			AuditFields: common.AuditFields{
@@ -251,7 +264,7 @@ func CreateNodePool(db *gorm.DB) http.HandlerFunc {
 //
 //	@ID				UpdateNodePool
 //	@Summary		Update node pool (org scoped)
-//	@Description	Partially update node pool fields.
+//	@Description	Partially update node pool fields. Setting desired_size hands the pool's membership to a reconciler that creates servers from the template (and starts the join action on the pool's clusters) or drains and deletes servers it created, until the pool has that many; -1 turns it off again. See GET /node-pools/{id}/plan for what it would do.
 //	@Tags			NodePools
 //	@Accept			json
 //	@Produce		json
@@ -259,10 +272,11 @@ func CreateNodePool(db *gorm.DB) http.HandlerFunc {
 //	@Param			id			path		string						true	"Node Pool ID (UUID)"
 //	@Param			body		body		dto.UpdateNodePoolRequest	true	"Fields to update"
 //	@Success		200			{object}	dto.NodePoolResponse
-//	@Failure		400			{string}	string	"invalid id / invalid json"
+//	@Failure		400			{string}	string	"invalid id / invalid json / invalid role / invalid desired_size / invalid template"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"etcd role on a pool attached to a stacked cluster / role no longer matches the pool's servers"
 //	@Failure		500			{string}	string	"update failed"
 //	@Router			/node-pools/{id} [patch]
 //	@Security		BearerAuth
@@ -301,21 +315,67 @@ func UpdateNodePool(db *gorm.DB) http.HandlerFunc {
 		if req.Name != nil {
 			n.Name = strings.TrimSpace(*req.Name)
 		}
+		roleChanged := false
 		if req.Role != nil {
 			v := dto.NodeRole(strings.TrimSpace(string(*req.Role)))
+			if err := models.ValidateNodePoolRole(string(v)); err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+				return
+			}
+			if v == dto.NodeRoleEtcd && n.Role != string(v) {
+				var stacked []string
+				if err := db.Model(&models.Cluster{}).
+					Joins("JOIN cluster_node_pools cnp ON cnp.cluster_id = clusters.id").
+					Where("cnp.node_pool_id = ? AND clusters.topology <> ?", n.ID, models.ClusterTopologyExternal).
+					Pluck("clusters.name", &stacked).Error; err != nil {
+					utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+					return
+				}
+				if len(stacked) > 0 {
+					utils.WriteError(w, http.StatusConflict, "topology_conflict",
+						fmt.Sprintf("the etcd role needs the external topology, but the pool is attached to %s", strings.Join(stacked, ", ")))
+					return
+				}
+			}
+			roleChanged = n.Role != string(v)
 			n.Role = string(v)
 		}
+		if req.DesiredSize != nil {
+			switch d := *req.DesiredSize; {
+			case d == -1:
+				n.DesiredSize = nil
+			case d < 0 || d > maxNodePoolSize:
+				utils.WriteError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("desired_size must be between 0 and %d, or -1 for manual membership", maxNodePoolSize))
+				return
+			default:
+				n.DesiredSize = &d
+			}
+		}
+		if req.Template != nil {
+			t, code, msg := nodePoolTemplate(db, orgID, *req.Template)
+			if code != "" {
+				utils.WriteError(w, http.StatusBadRequest, code, msg)
+				return
+			}
+			n.Template = t
+		}
 
-		if err := db.Save(&n).Error; err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
-			return
+		// A new role can break the rules for every server in the pool. Other
+		// edits leave placement alone.
+		var placed []uuid.UUID
+		if roleChanged {
+			if placed, err = poolServerIDs(db, n.ID); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+				return
+			}
 		}
-		out := dto.NodePoolResponse{
-			AuditFields: n.AuditFields,
-			Name:        n.Name,
-			Role:        dto.NodeRole(n.Role),
+		if err := savePlacement(db, orgID, placed, func(tx *gorm.DB) error {
+			return tx.Save(&n).Error
+		}); err != nil {
+			writeConflictOrDBError(w, err)
+			return
 		}
-		utils.WriteJSON(w, http.StatusOK, out)
+		utils.WriteJSON(w, http.StatusOK, nodePoolToDTO(n))
 	}
 }
 
@@ -438,12 +498,13 @@ func ListNodePoolServers(db *gorm.DB) http.HandlerFunc {
 //	@Failure	401			{string}	string						"Unauthorized"
 //	@Failure	403			{string}	string						"organization required"
 //	@Failure	404			{string}	string						"not found"
+//	@Failure	409			{string}	string						"would give a node conflicting labels or taints, or break a placement rule"
 //	@Failure	500			{string}	string						"attach failed"
 //	@Router		/node-pools/{id}/servers [post]
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
 //	@Security	OrgSecretAuth
-func AttachNodePoolServers(db *gorm.DB) http.HandlerFunc {
+func AttachNodePoolServers(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -503,11 +564,15 @@ func AttachNodePoolServers(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if err := db.Model(&np).Association("Servers").Append(&servers); err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", "attach failed")
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return models.AttachPoolServers(tx, &np, servers)
+		})
+		if err != nil {
+			writeConflictOrDBError(w, err)
 			return
 		}
 
+		syncNodeMetadata(r.Context(), db, jobs, np.ID)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -531,7 +596,7 @@ func AttachNodePoolServers(db *gorm.DB) http.HandlerFunc {
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
 //	@Security	OrgSecretAuth
-func DetachNodePoolServer(db *gorm.DB) http.HandlerFunc {
+func DetachNodePoolServer(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -572,6 +637,7 @@ func DetachNodePoolServer(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "detach error")
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, np.ID)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -648,12 +714,13 @@ func ListNodePoolTaints(db *gorm.DB) http.HandlerFunc {
 //	@Failure	401			{string}	string					"Unauthorized"
 //	@Failure	403			{string}	string					"organization required"
 //	@Failure	404			{string}	string					"not found"
+//	@Failure	409			{string}	string					"would give a node conflicting labels or taints"
 //	@Failure	500			{string}	string					"attach failed"
 //	@Router		/node-pools/{id}/taints [post]
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
 //	@Security	OrgSecretAuth
-func AttachNodePoolTaints(db *gorm.DB) http.HandlerFunc {
+func AttachNodePoolTaints(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -711,10 +778,14 @@ func AttachNodePoolTaints(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if err := db.Model(&np).Association("Taints").Append(&taints); err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", "attach db error")
+		err = saveWithoutConflicts(db, []uuid.UUID{np.ID}, func(tx *gorm.DB) error {
+			return tx.Model(&np).Association("Taints").Append(&taints)
+		})
+		if err != nil {
+			writeConflictOrDBError(w, err)
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, np.ID)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -738,7 +809,7 @@ func AttachNodePoolTaints(db *gorm.DB) http.HandlerFunc {
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
 //	@Security	OrgSecretAuth
-func DetachNodePoolTaint(db *gorm.DB) http.HandlerFunc {
+func DetachNodePoolTaint(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -780,6 +851,7 @@ func DetachNodePoolTaint(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, np.ID)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -859,12 +931,13 @@ func ListNodePoolLabels(db *gorm.DB) http.HandlerFunc {
 //	@Failure	401			{string}	string					"Unauthorized"
 //	@Failure	403			{string}	string					"organization required"
 //	@Failure	404			{string}	string					"not found"
+//	@Failure	409			{string}	string					"would give a node conflicting labels or taints"
 //	@Failure	500			{string}	string					"attach failed"
 //	@Router		/node-pools/{id}/labels [post]
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
 //	@Security	OrgSecretAuth
-func AttachNodePoolLabels(db *gorm.DB) http.HandlerFunc {
+func AttachNodePoolLabels(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -919,10 +992,14 @@ func AttachNodePoolLabels(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if err := db.Model(&np).Association("Labels").Append(&labels); err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", "attach failed")
+		err = saveWithoutConflicts(db, []uuid.UUID{np.ID}, func(tx *gorm.DB) error {
+			return tx.Model(&np).Association("Labels").Append(&labels)
+		})
+		if err != nil {
+			writeConflictOrDBError(w, err)
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, np.ID)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -946,7 +1023,7 @@ func AttachNodePoolLabels(db *gorm.DB) http.HandlerFunc {
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
 //	@Security	OrgSecretAuth
-func DetachNodePoolLabel(db *gorm.DB) http.HandlerFunc {
+func DetachNodePoolLabel(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -988,6 +1065,7 @@ func DetachNodePoolLabel(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "detach error")
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, np.ID)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -1072,7 +1150,7 @@ func ListNodePoolAnnotations(db *gorm.DB) http.HandlerFunc {
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
 //	@Security	OrgSecretAuth
-func AttachNodePoolAnnotations(db *gorm.DB) http.HandlerFunc {
+func AttachNodePoolAnnotations(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -1132,6 +1210,7 @@ func AttachNodePoolAnnotations(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "attach failed")
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, np.ID)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -1155,7 +1234,7 @@ func AttachNodePoolAnnotations(db *gorm.DB) http.HandlerFunc {
 //	@Security	BearerAuth
 //	@Security	OrgKeyAuth
 //	@Security	OrgSecretAuth
-func DetachNodePoolAnnotation(db *gorm.DB) http.HandlerFunc {
+func DetachNodePoolAnnotation(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -1197,6 +1276,7 @@ func DetachNodePoolAnnotation(db *gorm.DB) http.HandlerFunc {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, np.ID)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -1257,3 +1337,69 @@ func ensureAnnotaionsBelongToOrg(orgID uuid.UUID, ids []uuid.UUID, db *gorm.DB)
 	}
 	return nil
 }
+
+// syncNodeMetadata queues the push of the pools' labels, annotations and
+// taints to the clusters they belong to. The change itself is saved already
+// and the periodic sweep would get there eventually, so a failure to queue is
+// only logged.
+func syncNodeMetadata(ctx context.Context, db *gorm.DB, jobs *bg.Client, poolIDs ...uuid.UUID) {
+	if err := bg.QueueNodeMetadataSync(ctx, db, jobs, poolIDs...); err != nil {
+		log.Warn().Err(err).Msg("could not queue node metadata sync")
+	}
+}
+
+// poolsUsing lists the org's node pools that one label, annotation or taint
+// is attached to, going by its join table.
+func poolsUsing(db *gorm.DB, orgID uuid.UUID, joinTable, column string, id uuid.UUID) []uuid.UUID {
+	var ids []uuid.UUID
+	_ = db.Table(joinTable+" j").
+		Joins("JOIN node_pools np ON np.id = j.node_pool_id").
+		Where("j."+column+" = ? AND np.organization_id = ?", id, orgID).
+		Pluck("j.node_pool_id", &ids).Error
+	return ids
+}
+
+// saveWithoutConflicts applies change in a transaction and keeps it only if
+// none of the pools, and no server in them, ends up with conflicting labels
+// or taints.
+func saveWithoutConflicts(db *gorm.DB, poolIDs []uuid.UUID, change func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+		return models.CheckNodeMetadata(tx, poolIDs)
+	})
+}
+
+// savePlacement applies change in a transaction and keeps it only if the
+// servers still follow the org's placement rules.
+func savePlacement(db *gorm.DB, orgID uuid.UUID, serverIDs []uuid.UUID, change func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+		return models.CheckPlacement(tx, orgID, serverIDs)
+	})
+}
+
+// poolServerIDs lists the servers in a pool, for changes such as a new role
+// or a new cluster that move every member at once.
+func poolServerIDs(db *gorm.DB, poolID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := db.Table("node_servers").Where("node_pool_id = ?", poolID).Pluck("server_id", &ids).Error
+	return ids, err
+}
+
+func writeConflictOrDBError(w http.ResponseWriter, err error) {
+	var mc *models.MetadataConflictError
+	if errors.As(err, &mc) {
+		utils.WriteError(w, http.StatusConflict, "metadata_conflict", mc.Error())
+		return
+	}
+	var pc *models.PlacementConflictError
+	if errors.As(err, &pc) {
+		utils.WriteError(w, http.StatusConflict, "placement_conflict", pc.Error())
+		return
+	}
+	utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/glueops/autoglue/internal/common"
@@ -358,6 +360,152 @@ func TestEnsureAnnotationsBelongToOrg_ForeignOrgFails(t *testing.T) {
 	}
 }
 
+func TestSaveWithoutConflicts_RejectsServerInTwoDisagreeingPools(t *testing.T) {
+	db := pgtest.DB(t)
+	org := createTestOrg(t, db, "org-conflicts")
+	key := createTestSshKey(t, db, org.ID, "conflicts")
+	srv := createTestServer(t, db, org.ID, key.ID, "node-1")
+
+	web := models.Label{AuditFields: common.AuditFields{OrganizationID: org.ID}, Key: "tier", Value: "web"}
+	dbTier := models.Label{AuditFields: common.AuditFields{OrganizationID: org.ID}, Key: "tier", Value: "db"}
+	if err := db.Create(&web).Error; err != nil {
+		t.Fatalf("create label: %v", err)
+	}
+	if err := db.Create(&dbTier).Error; err != nil {
+		t.Fatalf("create label: %v", err)
+	}
+
+	a := models.NodePool{AuditFields: common.AuditFields{OrganizationID: org.ID}, Name: "a", Role: "worker",
+		Labels: []models.Label{web}, Servers: []models.Server{srv}}
+	b := models.NodePool{AuditFields: common.AuditFields{OrganizationID: org.ID}, Name: "b", Role: "worker",
+		Labels: []models.Label{dbTier}}
+	if err := db.Create(&a).Error; err != nil {
+		t.Fatalf("create pool a: %v", err)
+	}
+	if err := db.Create(&b).Error; err != nil {
+		t.Fatalf("create pool b: %v", err)
+	}
+
+	err := saveWithoutConflicts(db, []uuid.UUID{b.ID}, func(tx *gorm.DB) error {
+		return tx.Model(&b).Association("Servers").Append(&srv)
+	})
+	var mc *models.MetadataConflictError
+	if !errors.As(err, &mc) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+
+	var n int64
+	db.Table("node_servers").Where("node_pool_id = ?", b.ID).Count(&n)
+	if n != 0 {
+		t.Fatalf("conflicting attach was kept")
+	}
+}
+
+func TestSavePlacement_KeepsServerInOneClusterUnlessTheOrgAllowsIt(t *testing.T) {
+	db := pgtest.DB(t)
+	org := createTestOrg(t, db, "org-placement")
+	key := createTestSshKey(t, db, org.ID, "placement")
+	srv := createTestServer(t, db, org.ID, key.ID, "node-1")
+
+	prod := models.Cluster{OrganizationID: org.ID, Name: "prod"}
+	staging := models.Cluster{OrganizationID: org.ID, Name: "staging"}
+	for _, c := range []*models.Cluster{&prod, &staging} {
+		if err := db.Create(c).Error; err != nil {
+			t.Fatalf("create cluster: %v", err)
+		}
+	}
+	a := models.NodePool{AuditFields: common.AuditFields{OrganizationID: org.ID}, Name: "a", Role: "worker",
+		Servers: []models.Server{srv}, Clusters: []models.Cluster{prod}}
+	b := models.NodePool{AuditFields: common.AuditFields{OrganizationID: org.ID}, Name: "b", Role: "worker",
+		Servers: []models.Server{srv}}
+	for _, np := range []*models.NodePool{&a, &b} {
+		if err := db.Create(np).Error; err != nil {
+			t.Fatalf("create pool: %v", err)
+		}
+	}
+
+	attach := func() error {
+		return savePlacement(db, org.ID, []uuid.UUID{srv.ID}, func(tx *gorm.DB) error {
+			return tx.Model(&staging).Association("NodePools").Append(&b)
+		})
+	}
+
+	var pc *models.PlacementConflictError
+	if err := attach(); !errors.As(err, &pc) {
+		t.Fatalf("expected a placement conflict, got %v", err)
+	}
+	if msg := pc.Error(); !strings.Contains(msg, "prod (node pool a)") || !strings.Contains(msg, "staging (node pool b)") {
+		t.Fatalf("conflict should name both clusters and pools: %s", msg)
+	}
+
+	if err := db.Model(&models.Organization{}).Where("id = ?", org.ID).
+		Update("exclusive_servers", false).Error; err != nil {
+		t.Fatalf("relax rule: %v", err)
+	}
+	if err := attach(); err != nil {
+		t.Fatalf("attach with the rule off: %v", err)
+	}
+}
+
+func TestCheckPlacement_IgnoresServersTheChangeDoesNotTouch(t *testing.T) {
+	db := pgtest.DB(t)
+	org := createTestOrg(t, db, "org-placement-scope")
+	key := createTestSshKey(t, db, org.ID, "placement-scope")
+	bastion := createTestServer(t, db, org.ID, key.ID, "bastion-1")
+	if err := db.Model(&bastion).Update("role", "bastion").Error; err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+	worker := createTestServer(t, db, org.ID, key.ID, "worker-1")
+
+	// A bastion placed before the rules existed.
+	pool := models.NodePool{AuditFields: common.AuditFields{OrganizationID: org.ID}, Name: "workers", Role: "worker",
+		Servers: []models.Server{bastion}}
+	if err := db.Create(&pool).Error; err != nil {
+		t.Fatalf("create pool: %v", err)
+	}
+
+	err := savePlacement(db, org.ID, []uuid.UUID{worker.ID}, func(tx *gorm.DB) error {
+		return tx.Model(&pool).Association("Servers").Append(&worker)
+	})
+	if err != nil {
+		t.Fatalf("adding a worker should not trip over the existing bastion: %v", err)
+	}
+
+	var pc *models.PlacementConflictError
+	if err := models.CheckPlacement(db, org.ID, []uuid.UUID{bastion.ID}); !errors.As(err, &pc) {
+		t.Fatalf("the bastion itself should still be reported, got %v", err)
+	}
+}
+
+func TestAttachPoolServers_RefusesABastion(t *testing.T) {
+	db := pgtest.DB(t)
+	org := createTestOrg(t, db, "org-attach-bastion")
+	key := createTestSshKey(t, db, org.ID, "attach-bastion")
+	bastion := createTestServer(t, db, org.ID, key.ID, "bastion-1")
+	if err := db.Model(&bastion).Update("role", "bastion").Error; err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+	pool := models.NodePool{AuditFields: common.AuditFields{OrganizationID: org.ID}, Name: "workers", Role: "worker"}
+	if err := db.Create(&pool).Error; err != nil {
+		t.Fatalf("create pool: %v", err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return models.AttachPoolServers(tx, &pool, []models.Server{{ID: bastion.ID}})
+	})
+	var pc *models.PlacementConflictError
+	if !errors.As(err, &pc) {
+		t.Fatalf("expected a placement conflict, got %v", err)
+	}
+	ids, err := poolServerIDs(db, pool.ID)
+	if err != nil {
+		t.Fatalf("list members: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("refused attach left members behind: %v", ids)
+	}
+}
+
 func createTestSshKey(t *testing.T, db *gorm.DB, orgID uuid.UUID, name string) models.SshKey {
 	t.Helper()
 
@@ -184,6 +184,13 @@ func GetOrg(db *gorm.DB) http.HandlerFunc {
 type orgUpdateReq struct {
 	Name   *string `json:"name,omitempty"`
 	Domain *string `json:"domain,omitempty"`
+	// Placement rules, checked whenever servers join node pools and node
+	// pools join clusters. Existing placements are not re-checked.
+	ExclusiveServers  *bool `json:"exclusive_servers,omitempty"`
+	MatchServerRoles  *bool `json:"match_server_roles,omitempty"`
+	NoBastionsInPools *bool `json:"no_bastions_in_pools,omitempty"`
+	// DNSAutoReapply re-applies record sets changed outside autoglue.
+	DNSAutoReapply *bool `json:"dns_auto_reapply,omitempty"`
 }
 
 // UpdateOrg godoc
@@ -233,6 +240,18 @@ func UpdateOrg(db *gorm.DB) http.HandlerFunc {
 				changes["domain"] = d
 			}
 		}
+		if req.ExclusiveServers != nil {
+			changes["exclusive_servers"] = *req.ExclusiveServers
+		}
+		if req.MatchServerRoles != nil {
+			changes["match_server_roles"] = *req.MatchServerRoles
+		}
+		if req.NoBastionsInPools != nil {
+			changes["no_bastions_in_pools"] = *req.NoBastionsInPools
+		}
+		if req.DNSAutoReapply != nil {
+			changes["dns_auto_reapply"] = *req.DNSAutoReapply
+		}
 		if len(changes) > 0 {
 			if err := db.Model(&models.Organization{}).Where("id = ?", oid).Updates(changes).Error; err != nil {
 				utils.WriteError(w, 500, "db_error", err.Error())
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// applyServerFactsFilters narrows a server query by gathered facts. The join
+// is only added when a facts filter is present, so an unfiltered list still
+// includes servers that have never been probed.
+func applyServerFactsFilters(q *gorm.DB, r *http.Request) (*gorm.DB, error) {
+	qs := r.URL.Query()
+	joined := false
+	join := func() {
+		if !joined {
+			q = q.Joins("JOIN server_facts sf ON sf.server_id = servers.id AND sf.collected_at IS NOT NULL")
+			joined = true
+		}
+	}
+
+	for param, col := range map[string]string{
+		"os_id":      "sf.os_id",
+		"os_version": "sf.os_version",
+		"arch":       "sf.arch",
+	} {
+		if v := strings.TrimSpace(qs.Get(param)); v != "" {
+			join()
+			q = q.Where(col+" = ?", v)
+		}
+	}
+	if v := strings.TrimSpace(qs.Get("kernel")); v != "" {
+		join()
+		q = q.Where("sf.kernel LIKE ?", escapeLike(v)+"%")
+	}
+	if v := strings.TrimSpace(qs.Get("container_runtime")); v != "" {
+		join()
+		if v == "none" {
+			v = ""
+		}
+		q = q.Where("sf.container_runtime = ?", v)
+	}
+	if v := strings.TrimSpace(qs.Get("min_cpus")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("min_cpus must be a non-negative integer")
+		}
+		join()
+		q = q.Where("sf.cpu_count >= ?", n)
+	}
+	if v := strings.TrimSpace(qs.Get("min_memory_mb")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("min_memory_mb must be a non-negative integer")
+		}
+		join()
+		q = q.Where("sf.memory_bytes >= ?", n*1024*1024)
+	}
+	if v := strings.TrimSpace(qs.Get("time_synced")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("time_synced must be true or false")
+		}
+		join()
+		q = q.Where("sf.time_synced = ?", b)
+	}
+	return q, nil
+}
+
+// escapeLike makes user input literal inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func serverFactsToDTO(f *models.ServerFacts) *dto.ServerFactsResponse {
+	if f == nil {
+		return nil
+	}
+	disks := json.RawMessage(f.Disks)
+	if len(disks) == 0 {
+		disks = json.RawMessage("[]")
+	}
+	return &dto.ServerFactsResponse{
+		OSID:                    f.OSID,
+		OSVersion:               f.OSVersion,
+		OSPrettyName:            f.OSPrettyName,
+		Kernel:                  f.Kernel,
+		Arch:                    f.Arch,
+		CPUCount:                f.CPUCount,
+		MemoryBytes:             f.MemoryBytes,
+		Disks:                   disks,
+		ContainerRuntime:        f.ContainerRuntime,
+		ContainerRuntimeVersion: f.ContainerRuntimeVersion,
+		TimeSynced:              f.TimeSynced,
+		CollectedAt:             f.CollectedAt,
+		LastAttemptAt:           f.LastAttemptAt,
+		LastError:               f.LastError,
+	}
+}
+
+// RefreshServerFacts godoc
+//
+//	@ID				RefreshServerFacts
+//	@Summary		Gather facts from a server now (org scoped)
+//	@Description	Queues a server_facts job that connects to the server, directly or through its cluster bastion, and records OS, kernel, CPU, memory, disks, container runtime and time sync. Facts are otherwise refreshed periodically. The result appears on GET /servers/{id}.
+//	@Tags			Servers
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Server ID"
+//	@Success		202			{object}	dto.ServerFactsRefreshResponse
+//	@Failure		400			{string}	string	"invalid id"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		500			{string}	string	"enqueue failed"
+//	@Router			/servers/{id}/facts [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func RefreshServerFacts(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "id_invalid", "invalid id")
+			return
+		}
+		if err := db.Select("id").Where("id = ? AND organization_id = ?", id, orgID).
+			First(&models.Server{}).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "server_not_found", "server not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		res, err := jobs.Insert(r.Context(), bg.ServerFactsArgs{ServerID: id}, nil)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "job_error", "failed to enqueue facts collection")
+			return
+		}
+		utils.WriteJSON(w, http.StatusAccepted, dto.ServerFactsRefreshResponse{
+			JobID:     res.Job.ID,
+			Duplicate: res.UniqueSkippedAsDuplicate,
+		})
+	}
+}
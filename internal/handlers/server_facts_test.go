@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/testutil/pgtest"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+func listServersReq(orgID uuid.UUID, query string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/servers"+query, nil)
+	return r.WithContext(httpmiddleware.WithOrg(r.Context(), &models.Organization{ID: orgID}))
+}
+
+// The facts filters join server_facts, which also has organization_id: every
+// servers column in the list query has to be qualified or Postgres rejects it
+// as ambiguous. Only a real database notices.
+func TestListServersFiltersByFacts(t *testing.T) {
+	db := pgtest.DB(t)
+
+	orgID := uuid.New()
+	big := seedServer(t, db, orgID, "ready")
+	var keyID uuid.UUID
+	db.Model(&models.Server{}).Where("id = ?", big).Pluck("ssh_key_id", &keyID)
+	small := createTestServer(t, db, orgID, keyID, "small").ID
+	unprobed := createTestServer(t, db, orgID, keyID, "unprobed").ID
+
+	now := time.Now()
+	for id, cpus := range map[uuid.UUID]int{big: 16, small: 2} {
+		f := models.ServerFacts{
+			ServerID: id, OrganizationID: orgID, OSID: "ubuntu", CPUCount: cpus,
+			Disks: datatypes.JSON("[]"), CollectedAt: &now, LastAttemptAt: now,
+		}
+		if err := db.Create(&f).Error; err != nil {
+			t.Fatalf("seed facts: %v", err)
+		}
+	}
+
+	list := func(query string) []dto.ServerResponse {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		ListServers(db).ServeHTTP(rr, listServersReq(orgID, query))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, rr.Code, rr.Body.String())
+		}
+		var out []dto.ServerResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return out
+	}
+
+	if got := list(""); len(got) != 3 {
+		t.Errorf("unfiltered list has %d servers, want 3 (unprobed included)", len(got))
+	}
+
+	got := list("?min_cpus=8&os_id=ubuntu&status=ready")
+	if len(got) != 1 || got[0].ID != big {
+		t.Fatalf("min_cpus=8 returned %+v, want only the 16-cpu server", got)
+	}
+	if got[0].Facts == nil || got[0].Facts.CPUCount != 16 {
+		t.Errorf("facts not included in list response: %+v", got[0].Facts)
+	}
+
+	for _, s := range list("?os_id=ubuntu") {
+		if s.ID == unprobed {
+			t.Error("facts filter matched a server with no facts")
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	ListServers(db).ServeHTTP(rr, listServersReq(orgID, "?min_cpus=lots"))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("bad min_cpus: status %d, want 400", rr.Code)
+	}
+}
@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+const (
+	maxImportRows  = 1000
+	maxImportBytes = 4 << 20
+)
+
+// ImportServers godoc
+//
+//	@ID				ImportServers
+//	@Summary		Import servers from an inventory (org scoped)
+//	@Description	Creates many servers at once from a CSV, JSON or YAML inventory and attaches each to a node pool by name. The format comes from ?format, else from Content-Type (text/csv, application/json, application/yaml). CSV needs a header row naming the columns: hostname, public_ip_address, private_ip_address, ssh_user, ssh_key, role, node_pool. JSON and YAML take a list of objects with the same keys. ssh_key and node_pool are names, and must each match exactly one in the organization. Every row is checked with the same rules as POST /servers before anything is written; if any row fails, nothing is created and the response is 422 with the errors per row. By default only the checks run and nothing is created; send the same inventory again with apply=true to create all servers and attach them in one transaction.
+//	@Tags			Servers
+//	@Accept			json
+//	@Accept			plain
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			format		query		string	false	"csv|json|yaml; overrides Content-Type"
+//	@Param			apply		query		bool	false	"Create the servers instead of only validating them"
+//	@Param			body		body		[]dto.ServerImportRow	true	"Inventory"
+//	@Success		200			{object}	dto.ServerImportResponse	"preview"
+//	@Success		201			{object}	dto.ServerImportResponse	"applied"
+//	@Failure		400			{string}	string	"unreadable inventory / unsupported format / too many rows"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		409			{string}	string						"a node pool assignment breaks a placement rule or clashes on a label or taint / an address was taken meanwhile"
+//	@Failure		422			{object}	dto.ServerImportResponse	"rows failed validation"
+//	@Failure		500			{string}	string	"import failed"
+//	@Router			/servers/import [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ImportServers(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		apply := false
+		if v := strings.TrimSpace(r.URL.Query().Get("apply")); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "bad_request", "apply must be a boolean")
+				return
+			}
+			apply = b
+		}
+
+		format := importFormat(r)
+		if format == "" {
+			utils.WriteError(w, http.StatusBadRequest, "unsupported_format", "format must be csv, json or yaml")
+			return
+		}
+
+		rows, err := parseServerInventory(format, http.MaxBytesReader(w, r.Body, maxImportBytes))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_inventory", err.Error())
+			return
+		}
+		if len(rows) == 0 {
+			utils.WriteError(w, http.StatusBadRequest, "bad_inventory", "inventory has no rows")
+			return
+		}
+		if len(rows) > maxImportRows {
+			utils.WriteError(w, http.StatusBadRequest, "too_many_rows", fmt.Sprintf("at most %d rows per import", maxImportRows))
+			return
+		}
+
+		keys, err := namesToIDs(db.Model(&models.SshKey{}), orgID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to load ssh keys")
+			return
+		}
+		pools, err := namesToIDs(db.Model(&models.NodePool{}), orgID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to load node pools")
+			return
+		}
+		taken, err := models.OrgIPHolders(db, orgID, uuid.Nil)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to load addresses in use")
+			return
+		}
+
+		servers, poolIDs, results := planServerImport(orgID, rows, keys, pools, taken)
+		resp := dto.ServerImportResponse{Valid: true, Rows: results}
+		for _, res := range results {
+			if len(res.Errors) > 0 {
+				resp.Valid = false
+				break
+			}
+		}
+		if !resp.Valid {
+			utils.WriteJSON(w, http.StatusUnprocessableEntity, resp)
+			return
+		}
+		if !apply {
+			utils.WriteJSON(w, http.StatusOK, resp)
+			return
+		}
+		resp.Applied = true
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var ips []string
+			for _, s := range servers {
+				ips = append(ips, changedIPs(nil, serverIPs(s))...)
+			}
+			if err := models.CheckIPConflicts(tx, orgID, uuid.Nil, ips...); err != nil {
+				return err
+			}
+			if err := tx.Create(&servers).Error; err != nil {
+				return err
+			}
+			byPool := map[uuid.UUID][]models.Server{}
+			for i, pid := range poolIDs {
+				if pid != uuid.Nil {
+					byPool[pid] = append(byPool[pid], models.Server{ID: servers[i].ID})
+				}
+			}
+			for pid, members := range byPool {
+				np := models.NodePool{}
+				np.ID = pid
+				np.OrganizationID = orgID
+				if err := models.AttachPoolServers(tx, &np, members); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			var pc *models.PlacementConflictError
+			if errors.As(err, &pc) {
+				utils.WriteError(w, http.StatusConflict, "placement_conflict", pc.Error())
+				return
+			}
+			var mc *models.MetadataConflictError
+			if errors.As(err, &mc) {
+				utils.WriteError(w, http.StatusConflict, "metadata_conflict", mc.Error())
+				return
+			}
+			var ic *models.IPConflictError
+			if errors.As(err, &ic) {
+				utils.WriteError(w, http.StatusConflict, "ip_conflict", ic.Error())
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to import servers")
+			return
+		}
+
+		for i := range servers {
+			id := servers[i].ID
+			resp.Rows[i].ServerID = &id
+		}
+		resp.Created = len(servers)
+		utils.WriteJSON(w, http.StatusCreated, resp)
+	}
+}
+
+// importFormat picks the inventory format from ?format, falling back to the
+// request Content-Type. It returns "" when neither names a supported one.
+func importFormat(r *http.Request) string {
+	if f := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); f != "" {
+		switch f {
+		case "csv", "json":
+			return f
+		case "yaml", "yml":
+			return "yaml"
+		}
+		return ""
+	}
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case "text/csv":
+		return "csv"
+	case "application/json":
+		return "json"
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return "yaml"
+	}
+	return ""
+}
+
+var importColumns = []string{
+	"hostname", "public_ip_address", "private_ip_address", "ssh_user", "ssh_key", "role", "node_pool",
+}
+
+// parseServerInventory decodes an inventory. Unknown columns or keys are
+// rejected rather than ignored: a misspelt node_pool would otherwise import
+// every host unattached without a word.
+func parseServerInventory(format string, body io.Reader) ([]dto.ServerImportRow, error) {
+	var rows []dto.ServerImportRow
+	switch format {
+	case "json":
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rows); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+	case "yaml":
+		dec := yaml.NewDecoder(body)
+		dec.KnownFields(true)
+		if err := dec.Decode(&rows); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid yaml: %w", err)
+		}
+	case "csv":
+		return parseServerCSV(body)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return rows, nil
+}
+
+func parseServerCSV(body io.Reader) ([]dto.ServerImportRow, error) {
+	cr := csv.NewReader(body)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if !slices.Contains(importColumns, h) {
+			return nil, fmt.Errorf("unknown csv column %q", h)
+		}
+		if _, dup := col[h]; dup {
+			return nil, fmt.Errorf("csv column %q appears twice", h)
+		}
+		col[h] = i
+	}
+
+	var rows []dto.ServerImportRow
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok {
+				return rec[i]
+			}
+			return ""
+		}
+		rows = append(rows, dto.ServerImportRow{
+			Hostname:         get("hostname"),
+			PublicIPAddress:  get("public_ip_address"),
+			PrivateIPAddress: get("private_ip_address"),
+			SSHUser:          get("ssh_user"),
+			SSHKey:           get("ssh_key"),
+			Role:             get("role"),
+			NodePool:         get("node_pool"),
+		})
+		if len(rows) > maxImportRows {
+			break
+		}
+	}
+	return rows, nil
+}
+
+// namesToIDs maps each name in the org to the ids carrying it, so the import
+// can tell a missing name from an ambiguous one. q is a Model on a table with
+// name and organization_id columns.
+func namesToIDs(q *gorm.DB, orgID uuid.UUID) (map[string][]uuid.UUID, error) {
+	var rows []struct {
+		ID   uuid.UUID
+		Name string
+	}
+	if err := q.Select("id, name").Where("organization_id = ?", orgID).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string][]uuid.UUID, len(rows))
+	for _, r := range rows {
+		out[r.Name] = append(out[r.Name], r.ID)
+	}
+	return out, nil
+}
+
+// planServerImport validates every row and builds the servers to create. The
+// returned slices are parallel to rows; poolIDs holds uuid.Nil for a row with
+// no node pool. taken maps addresses already in use in the org to their
+// holders. The servers are only meaningful when no result has errors.
+func planServerImport(orgID uuid.UUID, rows []dto.ServerImportRow, keys, pools map[string][]uuid.UUID, taken map[string]string) ([]models.Server, []uuid.UUID, []dto.ServerImportRowResult) {
+	servers := make([]models.Server, len(rows))
+	poolIDs := make([]uuid.UUID, len(rows))
+	results := make([]dto.ServerImportRowResult, len(rows))
+	seenIP := map[string]int{}
+
+	for i, row := range rows {
+		hostname := strings.TrimSpace(row.Hostname)
+		pub := strings.TrimSpace(row.PublicIPAddress)
+		priv := strings.TrimSpace(row.PrivateIPAddress)
+		user := strings.TrimSpace(row.SSHUser)
+		keyName := strings.TrimSpace(row.SSHKey)
+		role := strings.ToLower(strings.TrimSpace(row.Role))
+		poolName := strings.TrimSpace(row.NodePool)
+
+		res := dto.ServerImportRowResult{
+			Row: i + 1, Hostname: hostname, PrivateIPAddress: priv, Role: role, NodePool: poolName,
+		}
+		fail := func(format string, a ...any) { res.Errors = append(res.Errors, fmt.Sprintf(format, a...)) }
+
+		for _, f := range [][2]string{{"private_ip_address", priv}, {"ssh_user", user}, {"ssh_key", keyName}, {"role", role}} {
+			if f[1] == "" {
+				fail("%s is required", f[0])
+			}
+		}
+		if _, msg := checkServerFields(role, "", pub); msg != "" {
+			fail("%s", msg)
+		}
+		for _, f := range [][2]string{{"private_ip_address", priv}, {"public_ip_address", pub}} {
+			ip, err := normalizeIPField(f[0], f[1])
+			switch {
+			case err != nil:
+				fail("%s", err)
+				continue
+			case ip == "":
+				continue
+			}
+			if f[0] == "private_ip_address" {
+				priv = ip
+			} else {
+				pub = ip
+			}
+			if holder, ok := taken[ip]; ok {
+				fail("%s %s is already used by %s", f[0], ip, holder)
+			} else if prev, dup := seenIP[ip]; dup && prev != i+1 {
+				fail("%s %s is also on row %d", f[0], ip, prev)
+			} else {
+				seenIP[ip] = i + 1
+			}
+		}
+
+		var keyID uuid.UUID
+		if keyName != "" {
+			switch ids := keys[keyName]; len(ids) {
+			case 0:
+				fail("ssh key %q not found", keyName)
+			case 1:
+				keyID = ids[0]
+			default:
+				fail("ssh key name %q is ambiguous: %d keys share it", keyName, len(ids))
+			}
+		}
+		if poolName != "" {
+			switch ids := pools[poolName]; len(ids) {
+			case 0:
+				fail("node pool %q not found", poolName)
+			case 1:
+				poolIDs[i] = ids[0]
+			default:
+				fail("node pool name %q is ambiguous: %d node pools share it", poolName, len(ids))
+			}
+		}
+
+		var publicPtr *string
+		if pub != "" {
+			publicPtr = &pub
+		}
+		servers[i] = models.Server{
+			OrganizationID:   orgID,
+			Hostname:         hostname,
+			PublicIPAddress:  publicPtr,
+			PrivateIPAddress: priv,
+			SSHUser:          user,
+			SshKeyID:         keyID,
+			Role:             role,
+			Status:           "pending",
+		}
+		results[i] = res
+	}
+	return servers, poolIDs, results
+}
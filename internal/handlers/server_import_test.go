@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/common"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/testutil/pgtest"
+	"github.com/google/uuid"
+)
+
+func TestParseServerInventoryFormatsAgree(t *testing.T) {
+	want := dto.ServerImportRow{
+		Hostname: "w1", PrivateIPAddress: "10.0.0.5", SSHUser: "ubuntu",
+		SSHKey: "deploy", Role: "worker", NodePool: "workers",
+	}
+	inputs := map[string]string{
+		"csv": "Hostname, private_ip_address,ssh_user,ssh_key,role,node_pool\n" +
+			"w1,10.0.0.5,ubuntu,deploy,worker,workers\n",
+		"json": `[{"hostname":"w1","private_ip_address":"10.0.0.5","ssh_user":"ubuntu","ssh_key":"deploy","role":"worker","node_pool":"workers"}]`,
+		"yaml": "- hostname: w1\n  private_ip_address: 10.0.0.5\n  ssh_user: ubuntu\n  ssh_key: deploy\n  role: worker\n  node_pool: workers\n",
+	}
+	for format, in := range inputs {
+		rows, err := parseServerInventory(format, strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(rows) != 1 || rows[0] != want {
+			t.Errorf("%s: rows = %+v", format, rows)
+		}
+	}
+}
+
+func TestParseServerInventoryRejectsUnknownFields(t *testing.T) {
+	inputs := map[string]string{
+		"csv":  "hostname,private_ip,role\nw1,10.0.0.5,worker\n",
+		"json": `[{"hostname":"w1","nodepool":"workers"}]`,
+		"yaml": "- hostname: w1\n  nodepool: workers\n",
+	}
+	for format, in := range inputs {
+		if _, err := parseServerInventory(format, strings.NewReader(in)); err == nil {
+			t.Errorf("%s: expected an error for an unknown field", format)
+		}
+	}
+}
+
+func TestPlanServerImportReportsEveryRow(t *testing.T) {
+	orgID := uuid.New()
+	keys := map[string][]uuid.UUID{"deploy": {uuid.New()}, "dup": {uuid.New(), uuid.New()}}
+	pools := map[string][]uuid.UUID{"workers": {uuid.New()}}
+
+	rows := []dto.ServerImportRow{
+		{PrivateIPAddress: "10.0.0.1", SSHUser: "u", SSHKey: "deploy", Role: "Worker", NodePool: "workers"},
+		{PrivateIPAddress: "10.0.0.2", SSHUser: "u", SSHKey: "deploy", Role: "bastion"},
+		{PrivateIPAddress: "10.0.0.1", SSHUser: "u", SSHKey: "dup", Role: "worker", NodePool: "nope"},
+		{SSHKey: "missing"},
+	}
+	servers, poolIDs, res := planServerImport(orgID, rows, keys, pools, nil)
+
+	if len(res[0].Errors) != 0 {
+		t.Errorf("row 1: unexpected errors %v", res[0].Errors)
+	}
+	if servers[0].Role != "worker" || servers[0].SshKeyID != keys["deploy"][0] || poolIDs[0] != pools["workers"][0] {
+		t.Errorf("row 1: server = %+v pool = %s", servers[0], poolIDs[0])
+	}
+	if got := strings.Join(res[1].Errors, "; "); !strings.Contains(got, "public_ip_address is required") {
+		t.Errorf("row 2: errors = %q", got)
+	}
+	got := strings.Join(res[2].Errors, "; ")
+	for _, want := range []string{"also on row 1", "ambiguous", `node pool "nope" not found`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("row 3: errors %q missing %q", got, want)
+		}
+	}
+	if len(res[3].Errors) != 4 {
+		t.Errorf("row 4: errors = %v", res[3].Errors)
+	}
+}
+
+func TestImportServersCreatesAndAttachesInOneGo(t *testing.T) {
+	db := pgtest.DB(t)
+	org := createTestOrg(t, db, "import")
+	createTestSshKey(t, db, org.ID, "deploy")
+	pool := models.NodePool{AuditFields: common.AuditFields{OrganizationID: org.ID}, Name: "workers", Role: "worker"}
+	if err := db.Create(&pool).Error; err != nil {
+		t.Fatalf("create pool: %v", err)
+	}
+
+	body := "hostname,private_ip_address,ssh_user,ssh_key,role,node_pool\n" +
+		"w1,10.1.0.1,ubuntu,deploy,worker,workers\n" +
+		"w2,10.1.0.2,ubuntu,deploy,worker,workers\n"
+	run := func(query string) (int, dto.ServerImportResponse) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/servers/import"+query, strings.NewReader(body))
+		r.Header.Set("Content-Type", "text/csv")
+		r = r.WithContext(httpmiddleware.WithOrg(r.Context(), &org))
+		rr := httptest.NewRecorder()
+		ImportServers(db).ServeHTTP(rr, r)
+		var out dto.ServerImportResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode %s: %v", rr.Body.String(), err)
+		}
+		return rr.Code, out
+	}
+
+	code, out := run("")
+	if code != http.StatusOK || !out.Valid || out.Applied || out.Created != 0 {
+		t.Fatalf("preview: %d %+v", code, out)
+	}
+	var n int64
+	db.Model(&models.Server{}).Where("organization_id = ?", org.ID).Count(&n)
+	if n != 0 {
+		t.Fatalf("preview created %d servers", n)
+	}
+
+	code, out = run("?apply=true")
+	if code != http.StatusCreated || !out.Applied || out.Created != 2 {
+		t.Fatalf("import: %d %+v", code, out)
+	}
+	var attached int64
+	db.Table("node_servers").Where("node_pool_id = ?", pool.ID).Count(&attached)
+	if attached != 2 {
+		t.Errorf("attached = %d, want 2", attached)
+	}
+}
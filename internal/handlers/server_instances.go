@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/compute"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProvisionServer godoc
+//
+//	@ID				ProvisionServer
+//	@Summary		Create a server at a cloud provider (org scoped)
+//	@Description	Creates a machine at the credential's provider (Hetzner Cloud for now) from a size, image and location, with the org SSH key installed. The server is returned at once in status creating; a background job fills in its IPs when the machine is running and moves it to pending, from where it follows the usual lifecycle. Deleting the server later deletes the machine too.
+//	@Tags			Servers
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string						false	"Organization UUID"
+//	@Param			body		body		dto.ProvisionServerRequest	true	"Machine spec"
+//	@Success		202			{object}	dto.ServerResponse
+//	@Failure		400			{string}	string	"invalid json / missing fields / invalid ssh_key_id / invalid or unsupported credential_id"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		500			{string}	string	"create failed"
+//	@Router			/servers/provision [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ProvisionServer(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		var req dto.ProvisionServerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "bad request")
+			return
+		}
+		req.Hostname = strings.TrimSpace(req.Hostname)
+		req.Role = strings.ToLower(strings.TrimSpace(req.Role))
+		req.Size = strings.TrimSpace(req.Size)
+		req.Image = strings.TrimSpace(req.Image)
+
+		if req.Hostname == "" || req.Role == "" || req.SshKeyID == "" || req.CredentialID == "" || req.Size == "" || req.Image == "" {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "hostname, role, ssh_key_id, credential_id, size and image are required")
+			return
+		}
+
+		keyID, err := uuid.Parse(req.SshKeyID)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid ssh_key_id")
+			return
+		}
+		if err := ensureKeyBelongsToOrg(orgID, keyID, db); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid or unauthorized ssh_key_id")
+			return
+		}
+
+		credID, err := uuid.Parse(req.CredentialID)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid credential_id")
+			return
+		}
+		var cred models.Credential
+		if err := db.Where("id = ? AND organization_id = ?", credID, orgID).First(&cred).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid or unauthorized credential_id")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+		if !compute.Supported(cred.Provider) || cred.Kind != "api_token" {
+			utils.WriteError(w, http.StatusBadRequest, "provider_unsupported", "credential must be an api_token of a supported compute provider (hetzner)")
+			return
+		}
+
+		user := strings.TrimSpace(req.SSHUser)
+		if user == "" {
+			p, err := compute.New(cred.Provider, "")
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "provider_unsupported", err.Error())
+				return
+			}
+			user = p.DefaultUser()
+		}
+
+		s := models.Server{
+			OrganizationID: orgID,
+			Hostname:       req.Hostname,
+			SSHUser:        user,
+			SshKeyID:       keyID,
+			Role:           req.Role,
+			Status:         "creating",
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&s).Error; err != nil {
+				return err
+			}
+			s.Instance = &models.ServerInstance{
+				ServerID:       s.ID,
+				OrganizationID: orgID,
+				Provider:       cred.Provider,
+				CredentialID:   cred.ID,
+				Size:           req.Size,
+				Image:          req.Image,
+				Location:       strings.TrimSpace(req.Location),
+				Network:        strings.TrimSpace(req.Network),
+				Status:         models.ServerInstanceCreating,
+			}
+			return tx.Create(s.Instance).Error
+		})
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to create server")
+			return
+		}
+
+		if _, err := jobs.Insert(r.Context(), bg.ComputeCreateArgs{ServerID: s.ID}, nil); err != nil {
+			_ = db.Model(&models.ServerInstance{}).Where("server_id = ?", s.ID).
+				Updates(map[string]any{"status": models.ServerInstanceFailed, "last_error": "could not queue creation"}).Error
+			_ = db.Model(&models.Server{}).Where("id = ?", s.ID).Update("status", "failed").Error
+			utils.WriteError(w, http.StatusInternalServerError, "job_error", "failed to queue machine creation")
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusAccepted, serverResponse(s))
+	}
+}
+
+func serverResponse(s models.Server) dto.ServerResponse {
+	out := serverToDTO(s)
+	out.OrganizationID = s.OrganizationID
+	out.Facts = serverFactsToDTO(s.Facts)
+	out.Instance = serverInstanceToDTO(s.Instance)
+	return out
+}
+
+func serverInstanceToDTO(in *models.ServerInstance) *dto.ServerInstanceResponse {
+	if in == nil {
+		return nil
+	}
+	return &dto.ServerInstanceResponse{
+		Provider:     in.Provider,
+		CredentialID: in.CredentialID,
+		Size:         in.Size,
+		Image:        in.Image,
+		Location:     in.Location,
+		Network:      in.Network,
+		InstanceID:   in.InstanceID,
+		Status:       in.Status,
+		LastError:    in.LastError,
+		ReadyAt:      in.ReadyAt,
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/handlers/dto"
 	"github.com/glueops/autoglue/internal/models"
 	"github.com/glueops/autoglue/internal/utils"
@@ -20,16 +21,25 @@ import (
 //
 //	@ID				ListServers
 //	@Summary		List servers (org scoped)
-//	@Description	Returns servers for the organization in X-Org-ID. Optional filters: status, role.
+//	@Description	Returns servers for the organization in X-Org-ID. Optional filters: status, role, and facts gathered from the hosts. Any facts filter excludes servers whose facts have not been collected yet.
 //	@Tags			Servers
 //	@Produce		json
-//	@Param			X-Org-ID	header		string	false	"Organization UUID"
-//	@Param			status		query		string	false	"Filter by status (pending|provisioning|ready|failed)"
-//	@Param			role		query		string	false	"Filter by role"
-//	@Success		200			{array}		dto.ServerResponse
-//	@Failure		401			{string}	string	"Unauthorized"
-//	@Failure		403			{string}	string	"organization required"
-//	@Failure		500			{string}	string	"failed to list servers"
+//	@Param			X-Org-ID			header		string	false	"Organization UUID"
+//	@Param			status				query		string	false	"Filter by status (creating|pending|provisioning|ready|failed|unreachable|deleting)"
+//	@Param			role				query		string	false	"Filter by role"
+//	@Param			os_id				query		string	false	"Filter by os-release ID, e.g. ubuntu"
+//	@Param			os_version			query		string	false	"Filter by os-release VERSION_ID, e.g. 24.04"
+//	@Param			arch				query		string	false	"Filter by machine architecture, e.g. x86_64"
+//	@Param			kernel				query		string	false	"Filter by kernel release prefix, e.g. 6.8"
+//	@Param			container_runtime	query		string	false	"Filter by container runtime (containerd|docker|cri-o|none)"
+//	@Param			min_cpus			query		int		false	"Only servers with at least this many CPUs"
+//	@Param			min_memory_mb		query		int		false	"Only servers with at least this much memory"
+//	@Param			time_synced			query		bool	false	"Filter by NTP synchronization"
+//	@Success		200					{array}		dto.ServerResponse
+//	@Failure		400					{string}	string	"invalid filter"
+//	@Failure		401					{string}	string	"Unauthorized"
+//	@Failure		403					{string}	string	"organization required"
+//	@Failure		500					{string}	string	"failed to list servers"
 //	@Router			/servers [get]
 //	@Security		BearerAuth
 //	@Security		OrgKeyAuth
@@ -42,22 +52,28 @@ func ListServers(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		q := db.Where("organization_id = ?", orgID)
+		q := db.Preload("Facts").Preload("Instance").Where("servers.organization_id = ?", orgID)
 
 		if s := strings.TrimSpace(r.URL.Query().Get("status")); s != "" {
 			if !validStatus(s) {
 				utils.WriteError(w, http.StatusBadRequest, "status_invalid", "invalid status")
 				return
 			}
-			q = q.Where("status = ?", strings.ToLower(s))
+			q = q.Where("servers.status = ?", strings.ToLower(s))
 		}
 
 		if role := strings.TrimSpace(r.URL.Query().Get("role")); role != "" {
-			q = q.Where("role = ?", role)
+			q = q.Where("servers.role = ?", role)
+		}
+
+		q, err := applyServerFactsFilters(q, r)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+			return
 		}
 
 		var rows []models.Server
-		if err := q.Order("created_at DESC").Find(&rows).Error; err != nil {
+		if err := q.Order("servers.created_at DESC").Find(&rows).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to list servers")
 			return
 		}
@@ -74,8 +90,14 @@ func ListServers(db *gorm.DB) http.HandlerFunc {
 				SshKeyID:         row.SshKeyID,
 				Role:             row.Role,
 				Status:           row.Status,
+				LastSeenAt:       row.LastSeenAt,
+				LastProbeAt:      row.LastProbeAt,
+				ProbeLatencyMs:   row.ProbeLatencyMs,
+				ProbeError:       row.ProbeError,
 				CreatedAt:        row.CreatedAt.UTC().Format(time.RFC3339),
 				UpdatedAt:        row.UpdatedAt.UTC().Format(time.RFC3339),
+				Facts:            serverFactsToDTO(row.Facts),
+				Instance:         serverInstanceToDTO(row.Instance),
 			})
 		}
 		utils.WriteJSON(w, http.StatusOK, out)
@@ -86,7 +108,7 @@ func ListServers(db *gorm.DB) http.HandlerFunc {
 //
 //	@ID				GetServer
 //	@Summary		Get server by ID (org scoped)
-//	@Description	Returns one server in the given organization.
+//	@Description	Returns one server in the given organization, including the last facts snapshot gathered from the host.
 //	@Tags			Servers
 //	@Produce		json
 //	@Param			X-Org-ID	header		string	false	"Organization UUID"
@@ -116,7 +138,7 @@ func GetServer(db *gorm.DB) http.HandlerFunc {
 		}
 
 		var row models.Server
-		if err := db.Where("id = ? AND organization_id = ?", id, orgID).First(&row).Error; err != nil {
+		if err := db.Preload("Facts").Preload("Instance").Where("id = ? AND organization_id = ?", id, orgID).First(&row).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				utils.WriteError(w, http.StatusNotFound, "server_not_found", "server not found")
 				return
@@ -140,9 +162,10 @@ func GetServer(db *gorm.DB) http.HandlerFunc {
 //	@Param			X-Org-ID	header		string					false	"Organization UUID"
 //	@Param			body		body		dto.CreateServerRequest	true	"Server payload"
 //	@Success		201			{object}	dto.ServerResponse
-//	@Failure		400			{string}	string	"invalid json / missing fields / invalid status / invalid ssh_key_id"
+//	@Failure		400			{string}	string	"invalid json / missing fields / invalid status / invalid ssh_key_id / invalid ip address"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
+//	@Failure		409			{string}	string	"address already in use"
 //	@Failure		500			{string}	string	"create failed"
 //	@Router			/servers [post]
 //	@Security		BearerAuth
@@ -171,13 +194,16 @@ func CreateServer(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if req.Status != "" && !validStatus(req.Status) {
-			utils.WriteError(w, http.StatusBadRequest, "status_invalid", "invalid status")
+		if code, msg := checkServerFields(req.Role, req.Status, pub); code != "" {
+			utils.WriteError(w, http.StatusBadRequest, code, msg)
 			return
 		}
-
-		if req.Role == "bastion" && pub == "" {
-			utils.WriteError(w, http.StatusBadRequest, "public_ip_required", "public_ip_address is required for role=bastion")
+		priv, err := normalizeIPField("private_ip_address", req.PrivateIPAddress)
+		if err == nil {
+			pub, err = normalizeIPField("public_ip_address", pub)
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "ip_invalid", err.Error())
 			return
 		}
 
@@ -200,7 +226,7 @@ func CreateServer(db *gorm.DB) http.HandlerFunc {
 			OrganizationID:   orgID,
 			Hostname:         req.Hostname,
 			PublicIPAddress:  publicPtr,
-			PrivateIPAddress: req.PrivateIPAddress,
+			PrivateIPAddress: priv,
 			SSHUser:          req.SSHUser,
 			SshKeyID:         keyID,
 			Role:             req.Role,
@@ -210,7 +236,18 @@ func CreateServer(db *gorm.DB) http.HandlerFunc {
 			s.Status = strings.ToLower(req.Status)
 		}
 
-		if err := db.Create(&s).Error; err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := models.CheckIPConflicts(tx, orgID, uuid.Nil, changedIPs(nil, []string{priv, pub})...); err != nil {
+				return err
+			}
+			return tx.Create(&s).Error
+		})
+		if err != nil {
+			var ic *models.IPConflictError
+			if errors.As(err, &ic) {
+				utils.WriteError(w, http.StatusConflict, "ip_conflict", ic.Error())
+				return
+			}
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to create server")
 			return
 		}
@@ -230,10 +267,11 @@ func CreateServer(db *gorm.DB) http.HandlerFunc {
 //	@Param			id			path		string					true	"Server ID (UUID)"
 //	@Param			body		body		dto.UpdateServerRequest	true	"Fields to update"
 //	@Success		200			{object}	dto.ServerResponse
-//	@Failure		400			{string}	string	"invalid id / invalid json / invalid status / invalid ssh_key_id"
+//	@Failure		400			{string}	string	"invalid id / invalid json / invalid status / invalid ssh_key_id / invalid ip address"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"new role breaks a placement rule / address already in use"
 //	@Failure		500			{string}	string	"update failed"
 //	@Router			/servers/{id} [patch]
 //	@Security		BearerAuth
@@ -275,10 +313,23 @@ func UpdateServer(db *gorm.DB) http.HandlerFunc {
 			next.Hostname = *req.Hostname
 		}
 		if req.PrivateIPAddress != nil {
-			next.PrivateIPAddress = *req.PrivateIPAddress
+			ip, err := models.NormalizeIP(*req.PrivateIPAddress)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "ip_invalid", "private_ip_address: "+err.Error())
+				return
+			}
+			next.PrivateIPAddress = ip
 		}
 		if req.PublicIPAddress != nil {
-			next.PublicIPAddress = req.PublicIPAddress
+			ip, err := normalizeIPField("public_ip_address", *req.PublicIPAddress)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "ip_invalid", err.Error())
+				return
+			}
+			next.PublicIPAddress = nil
+			if ip != "" {
+				next.PublicIPAddress = &ip
+			}
 		}
 		if req.SSHUser != nil {
 			next.SSHUser = *req.SSHUser
@@ -313,7 +364,29 @@ func UpdateServer(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if err := db.Save(&next).Error; err != nil {
+		// A role change can break the org's placement rules in any pool the
+		// server is already in. Other edits leave placement alone.
+		var placed []uuid.UUID
+		if !strings.EqualFold(next.Role, server.Role) {
+			placed = []uuid.UUID{id}
+		}
+		ips := changedIPs(serverIPs(server), serverIPs(next))
+		if err := savePlacement(db, orgID, placed, func(tx *gorm.DB) error {
+			if err := models.CheckIPConflicts(tx, orgID, id, ips...); err != nil {
+				return err
+			}
+			return tx.Save(&next).Error
+		}); err != nil {
+			var pc *models.PlacementConflictError
+			if errors.As(err, &pc) {
+				utils.WriteError(w, http.StatusConflict, "placement_conflict", pc.Error())
+				return
+			}
+			var ic *models.IPConflictError
+			if errors.As(err, &ic) {
+				utils.WriteError(w, http.StatusConflict, "ip_conflict", ic.Error())
+				return
+			}
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to update server")
 			return
 		}
@@ -325,11 +398,12 @@ func UpdateServer(db *gorm.DB) http.HandlerFunc {
 //
 //	@ID				DeleteServer
 //	@Summary		Delete server (org scoped)
-//	@Description	Permanently deletes the server.
+//	@Description	Permanently deletes the server. A server autoglue created at a cloud provider is first moved to status deleting and answered with 202; its machine is deleted at the provider in the background, and the server row goes once that succeeds.
 //	@Tags			Servers
 //	@Produce		json
 //	@Param			X-Org-ID	header	string	false	"Organization UUID"
 //	@Param			id			path	string	true	"Server ID (UUID)"
+//	@Success		202			"Accepted"
 //	@Success		204			"No Content"
 //	@Failure		400			{string}	string	"invalid id"
 //	@Failure		401			{string}	string	"Unauthorized"
@@ -339,7 +413,7 @@ func UpdateServer(db *gorm.DB) http.HandlerFunc {
 //	@Security		BearerAuth
 //	@Security		OrgKeyAuth
 //	@Security		OrgSecretAuth
-func DeleteServer(db *gorm.DB) http.HandlerFunc {
+func DeleteServer(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -353,11 +427,21 @@ func DeleteServer(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if err := db.Where("id = ? AND organization_id = ?", id, orgID).First(&models.Server{}).Error; err != nil {
+		var s models.Server
+		if err := db.Preload("Instance").Where("id = ? AND organization_id = ?", id, orgID).First(&s).Error; err != nil {
 			utils.WriteError(w, http.StatusNotFound, "server_not_found", "server not found")
 			return
 		}
 
+		if s.Instance != nil {
+			if err := bg.QueueServerDeletion(r.Context(), db, jobs, s.ID); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "job_error", "failed to queue machine deletion")
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
 		if err := db.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.Server{}).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to delete server")
 			return
@@ -428,13 +512,35 @@ func ResetServerHostKey(db *gorm.DB) http.HandlerFunc {
 
 func validStatus(status string) bool {
 	switch strings.ToLower(status) {
-	case "pending", "provisioning", "ready", "failed", "":
+	case "creating", "pending", "provisioning", "ready", "failed", "unreachable", "deleting", "":
 		return true
 	default:
 		return false
 	}
 }
 
+// checkServerFields holds the field rules a new server must pass beyond the
+// required fields, shared by CreateServer and ImportServers. It returns an
+// error code and message, or two empty strings.
+func checkServerFields(role, status, publicIP string) (code, msg string) {
+	if status != "" && !validStatus(status) {
+		return "status_invalid", "invalid status"
+	}
+	if role == "bastion" && publicIP == "" {
+		return "public_ip_required", "public_ip_address is required for role=bastion"
+	}
+	return "", ""
+}
+
+// serverIPs is the server's addresses, private first.
+func serverIPs(s models.Server) []string {
+	out := []string{s.PrivateIPAddress}
+	if s.PublicIPAddress != nil {
+		out = append(out, *s.PublicIPAddress)
+	}
+	return out
+}
+
 func ensureKeyBelongsToOrg(orgID, keyID uuid.UUID, db *gorm.DB) error {
 	var k models.SshKey
 	if err := db.Where("id = ? AND organization_id = ?", keyID, orgID).First(&k).Error; err != nil {
@@ -10,7 +10,7 @@ import (
 
 func TestValidStatus(t *testing.T) {
 	// known-good statuses from servers.go
-	valid := []string{"pending", "provisioning", "ready", "failed"}
+	valid := []string{"pending", "provisioning", "ready", "failed", "unreachable"}
 	for _, s := range valid {
 		if !validStatus(s) {
 			t.Errorf("expected validStatus(%q) = true, got false", s)
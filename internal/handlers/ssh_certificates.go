@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/sshca"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// GetSSHCA godoc
+//
+//	@ID				GetSSHCA
+//	@Summary		Get the org SSH user CA (org scoped)
+//	@Description	Returns the public key of the organization's SSH user certificate authority, creating the CA on first use. Servers trust this key through TrustedUserCAKeys.
+//	@Tags			Ssh
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Success		200			{object}	dto.SshCAResponse
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		500			{string}	string	"failed to load ca"
+//	@Router			/ssh/ca [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func GetSSHCA(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		ca, err := sshca.EnsureOrgCA(db, orgID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "ca_error", "failed to load ssh ca")
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, dto.SshCAResponse{PublicKey: ca.PublicKey, Fingerprint: ca.Fingerprint})
+	}
+}
+
+// InstallSSHCA godoc
+//
+//	@ID				InstallSSHCA
+//	@Summary		Install the org SSH user CA on servers (org scoped, admin)
+//	@Description	Queues a job that writes the CA public key, a principals file admitting the autoglue-admin principal as each server's ssh user, and an sshd TrustedUserCAKeys drop-in. New bastions get this during bootstrap; use this for servers that existed before the CA. Progress is written to each server's logs.
+//	@Tags			Ssh
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string						false	"Organization UUID"
+//	@Param			body		body		dto.InstallSSHCARequest		false	"Servers to install on (default: all ready servers)"
+//	@Success		202			{object}	dto.InstallSSHCAResponse
+//	@Failure		400			{string}	string	"invalid json / unknown server"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required / admin required"
+//	@Failure		500			{string}	string	"enqueue failed"
+//	@Router			/ssh/ca/install [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func InstallSSHCA(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		var req dto.InstallSSHCARequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_payload", "invalid JSON payload")
+			return
+		}
+
+		if len(req.ServerIDs) > 0 {
+			var n int64
+			if err := db.Model(&models.Server{}).
+				Where("organization_id = ? AND id IN ?", orgID, req.ServerIDs).
+				Count(&n).Error; err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to look up servers")
+				return
+			}
+			if int(n) != len(req.ServerIDs) {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_server_ids", "one or more servers do not belong to this organization")
+				return
+			}
+		}
+
+		// Create the CA here rather than in the job so a failure surfaces to
+		// the caller instead of in a job log.
+		if _, err := sshca.EnsureOrgCA(db, orgID); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "ca_error", "failed to load ssh ca")
+			return
+		}
+
+		res, err := jobs.Insert(r.Context(), bg.SSHCAInstallArgs{OrgID: orgID, ServerIDs: req.ServerIDs}, nil)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "job_error", "failed to enqueue ca install")
+			return
+		}
+		utils.WriteJSON(w, http.StatusAccepted, dto.InstallSSHCAResponse{JobID: res.Job.ID})
+	}
+}
+
+// IssueSSHCertificate godoc
+//
+//	@ID				IssueSSHCertificate
+//	@Summary		Sign a short-lived ssh certificate (org scoped)
+//	@Description	Signs the caller's own public key with the org CA. Principals are derived from the caller: autoglue-user-<user id> plus autoglue-<role> for each org role they hold (an owner gets owner, admin and member). Servers admit autoglue-admin by default. Every issued certificate is recorded. Requires a user session; org API keys cannot be issued certificates.
+//	@Tags			Ssh
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string							true	"Organization UUID"
+//	@Param			body		body		dto.IssueSSHCertificateRequest	true	"Public key and TTL"
+//	@Success		201			{object}	dto.IssuedSshCertificateResponse
+//	@Failure		400			{string}	string	"invalid json / invalid public key / invalid ttl"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required / user required"
+//	@Failure		500			{string}	string	"signing failed"
+//	@Router			/ssh/certificates [post]
+//	@Security		BearerAuth
+func IssueSSHCertificate(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		// A certificate names a person. An org key has no identity to put in
+		// it, and a machine that needs a server should hold a server key.
+		u, ok := mustUser(r)
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "user_required", "ssh certificates are issued to users, not API keys")
+			return
+		}
+		roles, _ := httpmiddleware.RolesFrom(r.Context())
+
+		var req dto.IssueSSHCertificateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_payload", "invalid JSON payload")
+			return
+		}
+
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(req.PublicKey)))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_public_key", "public_key must be an OpenSSH public key")
+			return
+		}
+
+		ttl := sshca.DefaultTTL
+		if req.TTLSeconds != nil {
+			ttl = time.Duration(*req.TTLSeconds) * time.Second
+		}
+		if ttl < sshca.MinTTL || ttl > sshca.MaxTTL {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_ttl", sshca.ErrInvalidTTL.Error())
+			return
+		}
+
+		ca, err := sshca.EnsureOrgCA(db, orgID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "ca_error", "failed to load ssh ca")
+			return
+		}
+
+		cert, rec, err := sshca.Issue(db, ca, u.ID, pub, sshca.Principals(u.ID, roles), ttl)
+		if err != nil {
+			if _, isCert := pub.(*ssh.Certificate); isCert {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_public_key", err.Error())
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "sign_error", "failed to sign certificate")
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusCreated, dto.IssuedSshCertificateResponse{
+			SshCertificateResponse: sshCertificateToDTO(*rec),
+			Certificate:            strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		})
+	}
+}
+
+// ListSSHCertificates godoc
+//
+//	@ID				ListSSHCertificates
+//	@Summary		List issued ssh certificates (org scoped)
+//	@Description	Returns the audit log of certificates signed by the org CA, newest first.
+//	@Tags			Ssh
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			user_id		query		string	false	"Only certificates issued to this user"
+//	@Param			active		query		bool	false	"Only certificates that have not expired"
+//	@Success		200			{array}		dto.SshCertificateResponse
+//	@Failure		400			{string}	string	"invalid user_id"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		500			{string}	string	"failed to list certificates"
+//	@Router			/ssh/certificates [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ListSSHCertificates(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		q := db.Where("organization_id = ?", orgID)
+		if v := r.URL.Query().Get("user_id"); v != "" {
+			uid, err := uuid.Parse(v)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_user_id", "invalid user_id")
+				return
+			}
+			q = q.Where("user_id = ?", uid)
+		}
+		if r.URL.Query().Get("active") == "true" {
+			q = q.Where("valid_before > ?", time.Now())
+		}
+
+		var rows []models.SshCertificate
+		if err := q.Order("created_at DESC").Limit(500).Find(&rows).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to list certificates")
+			return
+		}
+
+		out := make([]dto.SshCertificateResponse, 0, len(rows))
+		for _, c := range rows {
+			out = append(out, sshCertificateToDTO(c))
+		}
+		utils.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+func sshCertificateToDTO(c models.SshCertificate) dto.SshCertificateResponse {
+	var principals []string
+	_ = json.Unmarshal(c.Principals, &principals)
+	if principals == nil {
+		principals = []string{}
+	}
+	return dto.SshCertificateResponse{
+		ID:                   c.ID,
+		UserID:               c.UserID,
+		Serial:               c.Serial,
+		KeyID:                c.KeyID,
+		Principals:           principals,
+		PublicKeyFingerprint: c.PublicKeyFingerprint,
+		CAFingerprint:        c.CAFingerprint,
+		ValidAfter:           c.ValidAfter,
+		ValidBefore:          c.ValidBefore,
+		CreatedAt:            c.CreatedAt,
+	}
+}
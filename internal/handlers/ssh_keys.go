@@ -11,10 +11,12 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/common"
 	"github.com/glueops/autoglue/internal/handlers/dto"
 	"github.com/glueops/autoglue/internal/models"
@@ -503,6 +505,174 @@ func DownloadSSHKey(db *gorm.DB) http.HandlerFunc {
 	}
 }
 
+// RotateSSHKey godoc
+//
+//	@ID				RotateSSHKey
+//	@Summary		Rotate an ssh key across every server using it (org scoped)
+//	@Description	Generates a replacement keypair and queues a background job that, for each server referencing this key, appends the new public key to authorized_keys, verifies login with it, repoints the server at the new key, and then removes the old public key. Per-server progress is written to each server's logs. The old key row is kept; delete it once nothing references it.
+//	@Tags			Ssh
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string						false	"Organization UUID"
+//	@Param			id			path		string						true	"SSH Key ID (UUID)"
+//	@Param			body		body		dto.RotateSSHKeyRequest		false	"Replacement key options"
+//	@Success		202			{object}	dto.SshKeyRotationResponse
+//	@Failure		400			{string}	string	"invalid id / invalid json / invalid bits"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"key is unattached / rotation already in progress"
+//	@Failure		500			{string}	string	"generation/enqueue failed"
+//	@Router			/ssh/{id}/rotate [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func RotateSSHKey(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_ssh_key_id", "invalid SSH Key ID")
+			return
+		}
+
+		var old models.SshKey
+		if err := db.Where("id = ? AND organization_id = ?", id, orgID).First(&old).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "ssh_key_not_found", "ssh key not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to get ssh key")
+			return
+		}
+
+		// The body is optional: an empty POST rotates to a key shaped like
+		// the old one.
+		var req dto.RotateSSHKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.WriteError(w, http.StatusBadRequest, "invalid_payload", "invalid JSON payload")
+			return
+		}
+
+		var serverCount int64
+		if err := db.Model(&models.Server{}).
+			Where("organization_id = ? AND ssh_key_id = ?", orgID, old.ID).
+			Count(&serverCount).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to count servers")
+			return
+		}
+		if serverCount == 0 {
+			utils.WriteError(w, http.StatusConflict, "ssh_key_unattached", "no servers use this key; create a new key instead")
+			return
+		}
+
+		keyType, bits := sshKeyShape(old.PublicKey)
+		if req.Type != nil && strings.TrimSpace(*req.Type) != "" {
+			keyType = strings.ToLower(strings.TrimSpace(*req.Type))
+			bits = 4096
+		}
+		switch keyType {
+		case "rsa":
+			if req.Bits != nil {
+				if !allowedBits(*req.Bits) {
+					utils.WriteError(w, http.StatusBadRequest, "invalid_bits", "invalid bits (allowed: 2048, 3072, 4096)")
+					return
+				}
+				bits = *req.Bits
+			}
+		case "ed25519":
+			if req.Bits != nil {
+				utils.WriteError(w, http.StatusBadRequest, "invalid_bits_for_type", "bits is only valid for RSA")
+				return
+			}
+		default:
+			utils.WriteError(w, http.StatusBadRequest, "invalid_type", "invalid type (rsa|ed25519)")
+			return
+		}
+
+		var privPEM, pubAuth string
+		if keyType == "rsa" {
+			privPEM, pubAuth, err = GenerateRSAPEMAndAuthorized(bits, strings.TrimSpace(req.Comment))
+		} else {
+			privPEM, pubAuth, err = GenerateEd25519PEMAndAuthorized(strings.TrimSpace(req.Comment))
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "keygen_failure", "key generation failed")
+			return
+		}
+
+		cipher, iv, tag, err := utils.EncryptForOrg(orgID, []byte(privPEM), db)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "encryption_failed", "encryption failed")
+			return
+		}
+
+		parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubAuth))
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "ssh_failure", "ssh public key parsing failed")
+			return
+		}
+
+		name := old.Name
+		if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
+			name = strings.TrimSpace(*req.Name)
+		}
+
+		key := models.SshKey{
+			AuditFields: common.AuditFields{
+				OrganizationID: orgID,
+			},
+			Name:                name,
+			PublicKey:           pubAuth,
+			EncryptedPrivateKey: cipher,
+			PrivateIV:           iv,
+			PrivateTag:          tag,
+			Fingerprint:         ssh.FingerprintSHA256(parsed),
+		}
+		if err := db.Create(&key).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to create ssh key")
+			return
+		}
+
+		// The replacement is only useful to the job that installs it, so it is
+		// removed again if that job cannot be queued.
+		discard := func() { _ = db.Where("id = ?", key.ID).Delete(&models.SshKey{}).Error }
+
+		res, err := jobs.Insert(r.Context(), bg.SSHKeyRotateArgs{
+			OrgID:    orgID,
+			OldKeyID: old.ID,
+			NewKeyID: key.ID,
+		}, nil)
+		if err != nil {
+			discard()
+			utils.WriteError(w, http.StatusInternalServerError, "job_error", "failed to enqueue key rotation")
+			return
+		}
+		if res.UniqueSkippedAsDuplicate {
+			discard()
+			utils.WriteError(w, http.StatusConflict, "rotation_in_progress", "a rotation of this key is already in progress")
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusAccepted, dto.SshKeyRotationResponse{
+			OldKeyID: old.ID.String(),
+			NewKey: dto.SshResponse{
+				AuditFields: key.AuditFields,
+				Name:        key.Name,
+				PublicKey:   key.PublicKey,
+				Fingerprint: key.Fingerprint,
+			},
+			ServerCount: int(serverCount),
+			JobID:       res.Job.ID,
+		})
+	}
+}
+
 // --- Helpers ---
 
 func allowedBits(b int) bool {
@@ -550,6 +720,27 @@ func toZipFile(filename string, content []byte, zw *zip.Writer) error {
 	return err
 }
 
+// sshKeyShape reports the type and, for RSA, the size of an authorized_keys
+// line, so a rotation can default to a key like the one it replaces. Anything
+// unrecognised falls back to the CreateSSHKey default.
+func sshKeyShape(pubAuth string) (keyType string, bits int) {
+	pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubAuth))
+	if err != nil {
+		return "rsa", 4096
+	}
+	switch pk.Type() {
+	case ssh.KeyAlgoED25519:
+		return "ed25519", 0
+	case ssh.KeyAlgoRSA:
+		if cpk, ok := pk.(ssh.CryptoPublicKey); ok {
+			if rk, ok := cpk.CryptoPublicKey().(*rsa.PublicKey); ok && allowedBits(rk.N.BitLen()) {
+				return "rsa", rk.N.BitLen()
+			}
+		}
+	}
+	return "rsa", 4096
+}
+
 func keyFilenamePrefix(pubAuth string) string {
 	pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubAuth))
 	if err != nil {
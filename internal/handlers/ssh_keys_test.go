@@ -138,3 +138,25 @@ func intOrNil(p *int) any {
 	}
 	return *p
 }
+
+func TestSshKeyShapeFollowsTheOldKey(t *testing.T) {
+	_, rsaPub, err := GenerateRSAPEMAndAuthorized(2048, "old")
+	if err != nil {
+		t.Fatalf("generate rsa: %v", err)
+	}
+	if typ, bits := sshKeyShape(rsaPub); typ != "rsa" || bits != 2048 {
+		t.Errorf("rsa shape = %s/%d, want rsa/2048", typ, bits)
+	}
+
+	_, edPub, err := GenerateEd25519PEMAndAuthorized("")
+	if err != nil {
+		t.Fatalf("generate ed25519: %v", err)
+	}
+	if typ, _ := sshKeyShape(edPub); typ != "ed25519" {
+		t.Errorf("ed25519 shape = %s, want ed25519", typ)
+	}
+
+	if typ, bits := sshKeyShape("not a key"); typ != "rsa" || bits != 4096 {
+		t.Errorf("fallback shape = %s/%d, want rsa/4096", typ, bits)
+	}
+}
@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListSubnets godoc
+//
+//	@ID				ListSubnets
+//	@Summary		List subnets (org scoped)
+//	@Description	Returns the address ranges defined for the organization in X-Org-ID.
+//	@Tags			Subnets
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Success		200			{array}		dto.SubnetResponse
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		500			{string}	string	"failed to list subnets"
+//	@Router			/subnets [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ListSubnets(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		var rows []models.Subnet
+		if err := db.Where("organization_id = ?", orgID).Order("name").Find(&rows).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+		out := make([]dto.SubnetResponse, 0, len(rows))
+		for i := range rows {
+			out = append(out, subnetOut(&rows[i]))
+		}
+		utils.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// GetSubnet godoc
+//
+//	@ID				GetSubnet
+//	@Summary		Get a subnet (org scoped)
+//	@Tags			Subnets
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Subnet ID (UUID)"
+//	@Success		200			{object}	dto.SubnetResponse
+//	@Failure		400			{string}	string	"invalid id"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Router			/subnets/{id} [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func GetSubnet(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		row, ok := loadSubnet(w, r, db, orgID)
+		if !ok {
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, subnetOut(row))
+	}
+}
+
+// CreateSubnet godoc
+//
+//	@ID				CreateSubnet
+//	@Summary		Create a subnet (org scoped)
+//	@Description	Defines an address range. The CIDR is stored with its host bits cleared, and may not overlap another subnet in the organization. The gateway, if given, has to be a host address inside it.
+//	@Tags			Subnets
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string					false	"Organization UUID"
+//	@Param			body		body		dto.CreateSubnetRequest	true	"Subnet payload"
+//	@Success		201			{object}	dto.SubnetResponse
+//	@Failure		400			{string}	string	"invalid json / missing name / invalid cidr or gateway"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		409			{string}	string	"overlaps another subnet"
+//	@Router			/subnets [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func CreateSubnet(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+
+		var in dto.CreateSubnetRequest
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_json", err.Error())
+			return
+		}
+		row := &models.Subnet{
+			OrganizationID: orgID,
+			Name:           strings.TrimSpace(in.Name),
+			CIDR:           in.CIDR,
+			Gateway:        in.Gateway,
+			VLAN:           strings.TrimSpace(in.VLAN),
+		}
+		if row.Name == "" {
+			utils.WriteError(w, http.StatusBadRequest, "bad_request", "name is required")
+			return
+		}
+		if !normalizeSubnet(w, row) {
+			return
+		}
+		if err := saveSubnet(db, row, true); err != nil {
+			writeSubnetSaveError(w, err)
+			return
+		}
+		utils.WriteJSON(w, http.StatusCreated, subnetOut(row))
+	}
+}
+
+// UpdateSubnet godoc
+//
+//	@ID				UpdateSubnet
+//	@Summary		Update a subnet (org scoped)
+//	@Description	Changing the CIDR leaves servers and load balancers with addresses outside it alone.
+//	@Tags			Subnets
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Org-ID	header		string					false	"Organization UUID"
+//	@Param			id			path		string					true	"Subnet ID (UUID)"
+//	@Param			body		body		dto.UpdateSubnetRequest	true	"Fields to update"
+//	@Success		200			{object}	dto.SubnetResponse
+//	@Failure		400			{string}	string	"invalid id / invalid json / invalid cidr or gateway"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"overlaps another subnet"
+//	@Router			/subnets/{id} [patch]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func UpdateSubnet(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		row, ok := loadSubnet(w, r, db, orgID)
+		if !ok {
+			return
+		}
+
+		var in dto.UpdateSubnetRequest
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_json", err.Error())
+			return
+		}
+		prevCIDR := row.CIDR
+		if in.Name != nil {
+			row.Name = strings.TrimSpace(*in.Name)
+			if row.Name == "" {
+				utils.WriteError(w, http.StatusBadRequest, "bad_request", "name cannot be empty")
+				return
+			}
+		}
+		if in.CIDR != nil {
+			row.CIDR = *in.CIDR
+		}
+		if in.Gateway != nil {
+			row.Gateway = *in.Gateway
+		}
+		if in.VLAN != nil {
+			row.VLAN = strings.TrimSpace(*in.VLAN)
+		}
+		if !normalizeSubnet(w, row) {
+			return
+		}
+		if err := saveSubnet(db, row, row.CIDR != prevCIDR); err != nil {
+			writeSubnetSaveError(w, err)
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, subnetOut(row))
+	}
+}
+
+// DeleteSubnet godoc
+//
+//	@ID				DeleteSubnet
+//	@Summary		Delete a subnet (org scoped)
+//	@Description	Servers and load balancers keep their addresses.
+//	@Tags			Subnets
+//	@Produce		json
+//	@Param			X-Org-ID	header	string	false	"Organization UUID"
+//	@Param			id			path	string	true	"Subnet ID (UUID)"
+//	@Success		204
+//	@Failure		400	{string}	string	"invalid id"
+//	@Failure		403	{string}	string	"organization required"
+//	@Failure		404	{string}	string	"not found"
+//	@Router			/subnets/{id} [delete]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func DeleteSubnet(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		row, ok := loadSubnet(w, r, db, orgID)
+		if !ok {
+			return
+		}
+		if err := db.Delete(row).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// addressReservationTTL is how long an allocated address is held for the
+// caller to save it on a server or load balancer.
+const addressReservationTTL = 15 * time.Minute
+
+// errSubnetFull is returned out of the allocation transaction when every host
+// address is used or reserved.
+var errSubnetFull = errors.New("subnet full")
+
+// AllocateSubnetAddress godoc
+//
+//	@ID				AllocateSubnetAddress
+//	@Summary		Reserve the next free address in a subnet (org scoped)
+//	@Description	Reserves and returns the lowest host address in the subnet that is not the gateway, not used by any server or load balancer in the organization, and not reserved by an earlier call. The reservation lasts until expires_at, so concurrent callers get different addresses; give the address to a server or load balancer before then to take it.
+//	@Tags			Subnets
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Subnet ID (UUID)"
+//	@Success		200			{object}	dto.SubnetAllocationResponse
+//	@Failure		400			{string}	string	"invalid id"
+//	@Failure		403			{string}	string	"organization required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"subnet is full"
+//	@Router			/subnets/{id}/allocate [post]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func AllocateSubnetAddress(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		row, ok := loadSubnet(w, r, db, orgID)
+		if !ok {
+			return
+		}
+		prefix, err := netip.ParsePrefix(row.CIDR)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "bad_subnet", "stored cidr is invalid")
+			return
+		}
+
+		var res models.SubnetReservation
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := models.LockOrg(tx, orgID); err != nil {
+				return err
+			}
+			now := time.Now()
+			if err := tx.Where("organization_id = ? AND expires_at <= ?", orgID, now).
+				Delete(&models.SubnetReservation{}).Error; err != nil {
+				return err
+			}
+			holders, err := models.OrgIPHolders(tx, orgID, uuid.Nil)
+			if err != nil {
+				return err
+			}
+			var reserved []string
+			if err := tx.Model(&models.SubnetReservation{}).
+				Where("organization_id = ?", orgID).
+				Pluck("address", &reserved).Error; err != nil {
+				return err
+			}
+			used := make(map[string]bool, len(holders)+len(reserved))
+			for ip := range holders {
+				used[ip] = true
+			}
+			for _, ip := range reserved {
+				used[ip] = true
+			}
+			addr, ok := models.NextFreeAddress(prefix, row.Gateway, used)
+			if !ok {
+				return errSubnetFull
+			}
+			res = models.SubnetReservation{
+				OrganizationID: orgID,
+				SubnetID:       row.ID,
+				Address:        addr,
+				ExpiresAt:      now.Add(addressReservationTTL),
+			}
+			return tx.Create(&res).Error
+		})
+		if errors.Is(err, errSubnetFull) {
+			utils.WriteError(w, http.StatusConflict, "subnet_full", fmt.Sprintf("no free address left in %s", row.CIDR))
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "failed to reserve an address")
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, dto.SubnetAllocationResponse{
+			SubnetID:  row.ID,
+			Address:   res.Address,
+			CIDR:      row.CIDR,
+			Gateway:   row.Gateway,
+			ExpiresAt: res.ExpiresAt,
+		})
+	}
+}
+
+// ---------- Helpers ----------
+
+// subnetOverlapError names the subnet a new or changed CIDR collides with.
+type subnetOverlapError struct {
+	msg string
+}
+
+func (e *subnetOverlapError) Error() string { return e.msg }
+
+func loadSubnet(w http.ResponseWriter, r *http.Request, db *gorm.DB, orgID uuid.UUID) (*models.Subnet, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "bad_id", "invalid UUID")
+		return nil, false
+	}
+	var row models.Subnet
+	if err := db.Where("id = ? AND organization_id = ?", id, orgID).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "not_found", "subnet not found")
+			return nil, false
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+		return nil, false
+	}
+	return &row, true
+}
+
+// normalizeSubnet validates the subnet's CIDR and gateway and rewrites them in
+// canonical form, writing a 400 if they are invalid.
+func normalizeSubnet(w http.ResponseWriter, row *models.Subnet) bool {
+	prefix, gw, err := models.ParseSubnet(row.CIDR, row.Gateway)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "bad_subnet", err.Error())
+		return false
+	}
+	row.CIDR = prefix.String()
+	row.Gateway = gw
+	return true
+}
+
+// saveSubnet writes the subnet. When checkOverlap is set it first locks the
+// org, so two subnets cannot be added over each other at once, and refuses a
+// CIDR that overlaps another subnet in the org.
+func saveSubnet(db *gorm.DB, row *models.Subnet, checkOverlap bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if checkOverlap {
+			if err := models.LockOrg(tx, row.OrganizationID); err != nil {
+				return err
+			}
+			var others []models.Subnet
+			if err := tx.Where("organization_id = ? AND id <> ?", row.OrganizationID, row.ID).
+				Find(&others).Error; err != nil {
+				return err
+			}
+			prefix := netip.MustParsePrefix(row.CIDR)
+			for _, o := range others {
+				if op, err := netip.ParsePrefix(o.CIDR); err == nil && op.Overlaps(prefix) {
+					return &subnetOverlapError{msg: fmt.Sprintf("%s overlaps subnet %s (%s)", row.CIDR, o.Name, o.CIDR)}
+				}
+			}
+		}
+		if row.ID == uuid.Nil {
+			return tx.Create(row).Error
+		}
+		return tx.Save(row).Error
+	})
+}
+
+func writeSubnetSaveError(w http.ResponseWriter, err error) {
+	var oe *subnetOverlapError
+	if errors.As(err, &oe) {
+		utils.WriteError(w, http.StatusConflict, "subnet_overlap", oe.Error())
+		return
+	}
+	utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+}
+
+// ---------- Out mappers ----------
+
+func subnetOut(m *models.Subnet) dto.SubnetResponse {
+	return dto.SubnetResponse{
+		ID:             m.ID,
+		OrganizationID: m.OrganizationID,
+		Name:           m.Name,
+		CIDR:           m.CIDR,
+		Gateway:        m.Gateway,
+		VLAN:           m.VLAN,
+		CreatedAt:      m.CreatedAt.UTC(),
+		UpdatedAt:      m.UpdatedAt.UTC(),
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/testutil/pgtest"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func orgRequest(method, target, body string, orgID uuid.UUID, id string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	ctx := httpmiddleware.WithOrg(req.Context(), &models.Organization{ID: orgID})
+	routeCtx := chi.NewRouteContext()
+	if id != "" {
+		routeCtx.URLParams.Add("id", id)
+	}
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, routeCtx))
+}
+
+func TestSubnets_AllocateSkipsAddressesInUseAndRejectsConflicts(t *testing.T) {
+	db := pgtest.DB(t)
+	org := createTestOrg(t, db, "org-ipam")
+	key := createTestSshKey(t, db, org.ID, "ipam")
+	createTestServer(t, db, org.ID, key.ID, "node-1") // 10.0.0.1
+
+	rr := httptest.NewRecorder()
+	CreateSubnet(db).ServeHTTP(rr, orgRequest(http.MethodPost, "/subnets",
+		`{"name":"prod","cidr":"10.0.0.7/29","gateway":"10.0.0.6","vlan":"100"}`, org.ID, ""))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create subnet: %d %s", rr.Code, rr.Body.String())
+	}
+	var subnet dto.SubnetResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &subnet)
+	if subnet.CIDR != "10.0.0.0/29" {
+		t.Fatalf("cidr should be stored masked, got %s", subnet.CIDR)
+	}
+
+	rr = httptest.NewRecorder()
+	CreateSubnet(db).ServeHTTP(rr, orgRequest(http.MethodPost, "/subnets",
+		`{"name":"wide","cidr":"10.0.0.0/24"}`, org.ID, ""))
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "prod") {
+		t.Fatalf("overlapping subnet: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	CreateLoadBalancer(db).ServeHTTP(rr, orgRequest(http.MethodPost, "/load-balancers",
+		`{"name":"lb","kind":"public","public_ip_address":"203.0.113.10","private_ip_address":"10.0.0.1"}`, org.ID, ""))
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "server node-1") {
+		t.Fatalf("load balancer on a server's address: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	CreateLoadBalancer(db).ServeHTTP(rr, orgRequest(http.MethodPost, "/load-balancers",
+		`{"name":"lb","kind":"public","public_ip_address":"203.0.113.10","private_ip_address":"10.0.0.2"}`, org.ID, ""))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create load balancer: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	AllocateSubnetAddress(db).ServeHTTP(rr, orgRequest(http.MethodPost, "/subnets/x/allocate", "", org.ID, subnet.ID.String()))
+	var alloc dto.SubnetAllocationResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &alloc)
+	if rr.Code != http.StatusOK || alloc.Address != "10.0.0.3" {
+		t.Fatalf("allocate: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	AllocateSubnetAddress(db).ServeHTTP(rr, orgRequest(http.MethodPost, "/subnets/x/allocate", "", org.ID, subnet.ID.String()))
+	_ = json.Unmarshal(rr.Body.Bytes(), &alloc)
+	if rr.Code != http.StatusOK || alloc.Address != "10.0.0.4" {
+		t.Fatalf("a second allocation should skip the reserved address: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	CreateServer(db).ServeHTTP(rr, orgRequest(http.MethodPost, "/servers",
+		`{"private_ip_address":"10.0.0.256","ssh_user":"u","ssh_key_id":"`+key.ID.String()+`","role":"worker"}`, org.ID, ""))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid address: %d %s", rr.Code, rr.Body.String())
+	}
+}
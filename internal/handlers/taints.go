@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
 	"github.com/glueops/autoglue/internal/handlers/dto"
 	"github.com/glueops/autoglue/internal/models"
 	"github.com/glueops/autoglue/internal/utils"
@@ -121,7 +122,7 @@ func GetTaint(db *gorm.DB) http.HandlerFunc {
 //	@Param			X-Org-ID	header		string					false	"Organization UUID"
 //	@Param			body		body		dto.CreateTaintRequest	true	"Taint payload"
 //	@Success		201			{object}	dto.TaintResponse
-//	@Failure		400			{string}	string	"invalid json / missing fields / invalid node_pool_ids"
+//	@Failure		400			{string}	string	"invalid json / missing fields / invalid node_pool_ids / key, value or effect not valid for Kubernetes"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		500			{string}	string	"create failed"
@@ -152,17 +153,16 @@ func CreateTaint(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if _, ok := allowedEffects[req.Effect]; !ok {
-			utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid effect")
-			return
-		}
-
 		t := models.Taint{
 			OrganizationID: orgID,
 			Key:            req.Key,
 			Value:          req.Value,
 			Effect:         req.Effect,
 		}
+		if err := t.Validate(); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
 		if err := db.Create(&t).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
 			return
@@ -193,16 +193,17 @@ func CreateTaint(db *gorm.DB) http.HandlerFunc {
 //	@Param			id			path		string					true	"Node Taint ID (UUID)"
 //	@Param			body		body		dto.UpdateTaintRequest	true	"Fields to update"
 //	@Success		200			{object}	dto.TaintResponse
-//	@Failure		400			{string}	string	"invalid id / invalid json"
+//	@Failure		400			{string}	string	"invalid id / invalid json / key, value or effect not valid for Kubernetes"
 //	@Failure		401			{string}	string	"Unauthorized"
 //	@Failure		403			{string}	string	"organization required"
 //	@Failure		404			{string}	string	"not found"
+//	@Failure		409			{string}	string	"would give a node conflicting labels or taints"
 //	@Failure		500			{string}	string	"update failed"
 //	@Router			/taints/{id} [patch]
 //	@Security		BearerAuth
 //	@Security		OrgKeyAuth
 //	@Security		OrgSecretAuth
-func UpdateTaint(db *gorm.DB) http.HandlerFunc {
+func UpdateTaint(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -246,15 +247,20 @@ func UpdateTaint(db *gorm.DB) http.HandlerFunc {
 				utils.WriteError(w, http.StatusBadRequest, "bad_request", "missing effect")
 				return
 			}
-			if _, ok := allowedEffects[e]; !ok {
-				utils.WriteError(w, http.StatusBadRequest, "bad_request", "invalid effect")
-				return
-			}
 			next.Effect = e
 		}
 
-		if err := db.Save(&next).Error; err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+		if err := next.Validate(); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+
+		pools := poolsUsing(db, orgID, "node_taints", "taint_id", id)
+		err = saveWithoutConflicts(db, pools, func(tx *gorm.DB) error {
+			return tx.Save(&next).Error
+		})
+		if err != nil {
+			writeConflictOrDBError(w, err)
 			return
 		}
 
@@ -267,6 +273,7 @@ func UpdateTaint(db *gorm.DB) http.HandlerFunc {
 			CreatedAt:      next.CreatedAt.UTC().Format(time.RFC3339),
 			UpdatedAt:      next.UpdatedAt.UTC().Format(time.RFC3339),
 		}
+		syncNodeMetadata(r.Context(), db, jobs, pools...)
 		utils.WriteJSON(w, http.StatusOK, out)
 	}
 }
@@ -289,7 +296,7 @@ func UpdateTaint(db *gorm.DB) http.HandlerFunc {
 //	@Security		BearerAuth
 //	@Security		OrgKeyAuth
 //	@Security		OrgSecretAuth
-func DeleteTaint(db *gorm.DB) http.HandlerFunc {
+func DeleteTaint(db *gorm.DB, jobs *bg.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
 		if !ok {
@@ -313,17 +320,13 @@ func DeleteTaint(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		// The join rows go with it, so find the pools first.
+		pools := poolsUsing(db, orgID, "node_taints", "taint_id", id)
 		if err := db.Delete(&row).Error; err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
 			return
 		}
+		syncNodeMetadata(r.Context(), db, jobs, pools...)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
-
-// --- Helpers ---
-var allowedEffects = map[string]struct{}{
-	"NoSchedule":       {},
-	"PreferNoSchedule": {},
-	"NoExecute":        {},
-}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/bg"
+	"github.com/glueops/autoglue/internal/config"
+	"github.com/glueops/autoglue/internal/handlers/dto"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	// terminalIdleTimeout closes a session nobody has typed into for this
+	// long. Output alone does not keep it open: a forgotten `tail -f` in a
+	// background tab should not hold a root shell indefinitely.
+	terminalIdleTimeout = 30 * time.Minute
+
+	terminalDefaultCols = 120
+	terminalDefaultRows = 32
+	terminalMaxDim      = 1000
+)
+
+// ServerTerminal godoc
+//
+//	@ID				ServerTerminal
+//	@Summary		Open an interactive terminal to a server (WebSocket)
+//	@Description	Upgrades to a WebSocket carrying a PTY login shell on the server, using the server's stored key and reaching private-only hosts through their cluster bastion. Requires the owner or admin role in the server's organization. Send text frames of dto.TerminalMessage ({"type":"input","data":"ls\r"} or {"type":"resize","cols":120,"rows":32}); output arrives as binary frames. The session output is recorded and can be read back from /servers/{id}/terminal-sessions/{sessionID}/logs. Browsers cannot set headers on a WebSocket, so the org comes from the server and the user from the session cookie; `cols` and `rows` set the initial size.
+//	@Tags			Servers
+//	@Param			id		path		string	true	"Server ID"
+//	@Param			cols	query		int		false	"Initial terminal width"	default(120)
+//	@Param			rows	query		int		false	"Initial terminal height"	default(32)
+//	@Success		101		{string}	string	"Switching Protocols"
+//	@Failure		400		{string}	string	"invalid id / not a websocket request"
+//	@Failure		401		{string}	string	"Unauthorized"
+//	@Failure		403		{string}	string	"admin required / origin not allowed"
+//	@Failure		404		{string}	string	"not found"
+//	@Failure		502		{string}	string	"could not reach server"
+//	@Router			/servers/{id}/terminal [get]
+//	@Security		BearerAuth
+func ServerTerminal(db *gorm.DB) http.HandlerFunc {
+	allowedOrigins := config.AllowedOrigins()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// A shell is a person at a keyboard; an org key has no one to record.
+		u, ok := mustUser(r)
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "user_required", "terminals are opened by users, not API keys")
+			return
+		}
+
+		serverID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "id_invalid", "invalid id")
+			return
+		}
+
+		// Cookie-authenticated, so a page on another origin could open this
+		// socket with the user's session unless the origin is checked.
+		if !terminalOriginAllowed(r.Header.Get("Origin"), r.Host, allowedOrigins) {
+			utils.WriteError(w, http.StatusForbidden, "origin_forbidden", "origin not allowed")
+			return
+		}
+
+		var srv models.Server
+		if err := db.Preload("SshKey").Where("id = ?", serverID).First(&srv).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "server_not_found", "server not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+		// If the caller did name an org, the server has to be in it.
+		if orgID, ok := httpmiddleware.OrgIDFrom(r.Context()); ok && orgID != srv.OrganizationID {
+			utils.WriteError(w, http.StatusNotFound, "server_not_found", "server not found")
+			return
+		}
+		isAdmin, role := isOrgRole(db, u.ID, srv.OrganizationID, "owner", "admin")
+		if role == "" {
+			// Not a member: indistinguishable from a server that does not exist.
+			utils.WriteError(w, http.StatusNotFound, "server_not_found", "server not found")
+			return
+		}
+		if !isAdmin {
+			utils.WriteError(w, http.StatusForbidden, "forbidden", "terminal access requires the admin role")
+			return
+		}
+
+		cols := clamp(atoiDefault(r.URL.Query().Get("cols"), terminalDefaultCols), 1, terminalMaxDim)
+		rows := clamp(atoiDefault(r.URL.Query().Get("rows"), terminalDefaultRows), 1, terminalMaxDim)
+
+		ws := websocket.Server{
+			// Origin was checked above against the configured list; the
+			// library's default check would only confirm it parses.
+			Handshake: func(*websocket.Config, *http.Request) error { return nil },
+			Handler: func(conn *websocket.Conn) {
+				// The session row is only written once the upgrade has
+				// succeeded, so a failed handshake leaves no open session
+				// behind.
+				rec := models.TerminalSession{
+					OrganizationID: srv.OrganizationID,
+					ServerID:       srv.ID,
+					UserID:         u.ID,
+					Status:         models.TerminalSessionStatusOpen,
+					RemoteAddr:     r.RemoteAddr,
+				}
+				if err := db.Create(&rec).Error; err != nil {
+					log.Error().Err(err).Str("server_id", srv.ID.String()).Msg("[terminal] could not record session")
+					_, _ = conn.Write([]byte("\r\nautoglue: could not record the session\r\n"))
+					_ = conn.Close()
+					return
+				}
+				runTerminal(r.Context(), db, conn, &srv, &rec, u, cols, rows)
+			},
+		}
+		ws.ServeHTTP(w, r)
+	}
+}
+
+// runTerminal pumps one session until either end goes away.
+func runTerminal(
+	ctx context.Context,
+	db *gorm.DB,
+	conn *websocket.Conn,
+	srv *models.Server,
+	rec *models.TerminalSession,
+	u *models.User,
+	cols, rows int,
+) {
+	defer func() { _ = conn.Close() }()
+
+	// The http.Server write timeout is still armed on the hijacked
+	// connection and would cut the session off after a minute.
+	_ = conn.SetWriteDeadline(time.Time{})
+	conn.PayloadType = websocket.BinaryFrame
+
+	sink := bg.NewLogSink(db, 0, rec.OrganizationID, models.JobLogSubjectTerminalSession, rec.ID)
+	defer func() { _ = sink.Close() }()
+
+	finish := func(status, errMsg string) {
+		now := time.Now()
+		if err := db.Model(&models.TerminalSession{}).Where("id = ?", rec.ID).
+			Updates(map[string]any{"status": status, "error": errMsg, "ended_at": now}).Error; err != nil {
+			log.Error().Err(err).Str("session_id", rec.ID.String()).Msg("[terminal] could not close session row")
+		}
+	}
+
+	who := u.ID.String()
+	if u.PrimaryEmail != nil {
+		who = *u.PrimaryEmail
+	}
+	sink.System(fmt.Sprintf("terminal opened by %s on %s (%s)", who, srv.Hostname, srv.ID))
+
+	dialCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	term, err := bg.OpenTerminal(dialCtx, db, srv, cols, rows)
+	cancel()
+	if err != nil {
+		sink.System("connect failed: " + err.Error())
+		_, _ = conn.Write([]byte("\r\nautoglue: could not open a shell: " + err.Error() + "\r\n"))
+		finish(models.TerminalSessionStatusFailed, err.Error())
+		return
+	}
+	defer func() { _ = term.Close() }()
+
+	_ = db.Model(&models.TerminalSession{}).Where("id = ?", rec.ID).Update("via", term.Via()).Error
+	sink.System("connected via " + term.Via())
+
+	// Remote output goes to the browser and into the transcript. What the
+	// user types is captured as the shell echoes it, which is also why a
+	// password typed at a no-echo prompt is not recorded.
+	outDone := make(chan struct{})
+	go func() {
+		defer close(outDone)
+		_, _ = io.Copy(io.MultiWriter(conn, sink), term.Stdout)
+		// Shell exited: unblock the reader below.
+		_ = conn.Close()
+	}()
+
+	var reason string
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(terminalIdleTimeout))
+		var msg dto.TerminalMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			var ne interface{ Timeout() bool }
+			if errors.As(err, &ne) && ne.Timeout() {
+				reason = "idle timeout"
+				_, _ = conn.Write([]byte("\r\nautoglue: session closed after " + terminalIdleTimeout.String() + " idle\r\n"))
+			}
+			break
+		}
+		switch msg.Type {
+		case "input":
+			if _, err := io.WriteString(term.Stdin, msg.Data); err != nil {
+				reason = "write failed: " + err.Error()
+			}
+		case "resize":
+			if msg.Cols > 0 && msg.Rows > 0 {
+				_ = term.Resize(min(msg.Cols, terminalMaxDim), min(msg.Rows, terminalMaxDim))
+			}
+		}
+		if reason != "" {
+			break
+		}
+	}
+
+	_ = term.Close()
+	<-outDone
+
+	if reason == "" {
+		reason = "closed"
+	}
+	sink.System("terminal " + reason)
+	finish(models.TerminalSessionStatusClosed, "")
+}
+
+// terminalOriginAllowed accepts a missing Origin (not a browser, so no ambient
+// cookie to abuse), the API's own host, or one of the configured CORS origins.
+func terminalOriginAllowed(origin, host string, allowed []string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+	canon := canonicalOrigin(origin)
+	for _, a := range allowed {
+		if canonicalOrigin(a) == canon && canon != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ListServerTerminalSessions godoc
+//
+//	@ID				ListServerTerminalSessions
+//	@Summary		List terminal sessions opened to a server (admin)
+//	@Description	Returns browser terminal sessions for the server, newest first. Each session's recorded output is at /servers/{id}/terminal-sessions/{sessionID}/logs.
+//	@Tags			Servers
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Server ID"
+//	@Success		200			{array}		models.TerminalSession
+//	@Failure		400			{string}	string	"invalid id"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required / admin required"
+//	@Failure		500			{string}	string	"db error"
+//	@Router			/servers/{id}/terminal-sessions [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func ListServerTerminalSessions(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		serverID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "id_invalid", "invalid id")
+			return
+		}
+
+		var out []models.TerminalSession
+		if err := db.Where("organization_id = ? AND server_id = ?", orgID, serverID).
+			Order("created_at DESC").
+			Limit(200).
+			Find(&out).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, out)
+	}
+}
+
+// GetTerminalSessionLogs godoc
+//
+//	@ID				GetTerminalSessionLogs
+//	@Summary		Read the recording of a terminal session (admin)
+//	@Description	Returns the recorded output of a browser terminal session, in order. Poll by passing the previous `next_cursor` as `after`; `done` is true once the session has ended and the reader has caught up.
+//	@Tags			Servers
+//	@Produce		json
+//	@Param			X-Org-ID	header		string	false	"Organization UUID"
+//	@Param			id			path		string	true	"Server ID"
+//	@Param			sessionID	path		string	true	"Terminal session ID"
+//	@Param			after		query		int		false	"Return chunks with an id greater than this"	default(0)
+//	@Param			limit		query		int		false	"Maximum chunks to return"						minimum(1)	maximum(1000)	default(200)
+//	@Success		200			{object}	dto.JobLogPage
+//	@Failure		400			{string}	string	"bad request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"organization required / admin required"
+//	@Failure		404			{string}	string	"not found"
+//	@Failure		500			{string}	string	"db error"
+//	@Router			/servers/{id}/terminal-sessions/{sessionID}/logs [get]
+//	@Security		BearerAuth
+//	@Security		OrgKeyAuth
+//	@Security		OrgSecretAuth
+func GetTerminalSessionLogs(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := httpmiddleware.OrgIDFrom(r.Context())
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, "org_required", "specify X-Org-ID")
+			return
+		}
+		serverID, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "id_invalid", "invalid id")
+			return
+		}
+		sessionID, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "bad_session_id", "invalid session id")
+			return
+		}
+
+		var sess models.TerminalSession
+		if err := db.Where("id = ? AND organization_id = ? AND server_id = ?", sessionID, orgID, serverID).
+			First(&sess).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "not_found", "session not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", "db error")
+			return
+		}
+
+		after, limit := jobLogQuery(r)
+		items, cursor, err := readJobLogs(db, orgID, models.JobLogSubjectTerminalSession, sessionID, after, limit)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "db_error", err.Error())
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, dto.JobLogPage{
+			Items:      items,
+			NextCursor: cursor,
+			Done:       sess.EndedAt != nil && len(items) < limit,
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glueops/autoglue/internal/api/httpmiddleware"
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/testutil/pgtest"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestTerminalOriginAllowed(t *testing.T) {
+	allowed := []string{"https://app.example.com", "http://localhost:5173"}
+
+	cases := []struct {
+		origin, host string
+		want         bool
+	}{
+		{"", "api.example.com", true},
+		{"https://api.example.com", "api.example.com", true},
+		{"https://app.example.com", "api.example.com", true},
+		{"https://app.example.com/some/path", "api.example.com", true},
+		{"http://localhost:5173", "127.0.0.1:8080", true},
+		{"https://evil.example.net", "api.example.com", false},
+		{"http://app.example.com", "api.example.com", false},
+		{"null", "api.example.com", false},
+	}
+	for _, c := range cases {
+		if got := terminalOriginAllowed(c.origin, c.host, allowed); got != c.want {
+			t.Errorf("origin %q host %q: got %v, want %v", c.origin, c.host, got, c.want)
+		}
+	}
+}
+
+func terminalReq(u *models.User, serverID uuid.UUID) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/servers/"+serverID.String()+"/terminal", nil)
+	ctx := r.Context()
+	if u != nil {
+		ctx = httpmiddleware.WithUser(ctx, u)
+	}
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", serverID.String())
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, routeCtx)
+	return r.WithContext(ctx)
+}
+
+// The role checks run before the upgrade, so they can be exercised with a
+// plain request: nothing here ever reaches a server, and a request that
+// passes them still fails the handshake without recording a session.
+func TestServerTerminalRoleGate(t *testing.T) {
+	db := pgtest.DB(t)
+
+	orgID := uuid.New()
+	serverID := seedServer(t, db, orgID, "ready")
+
+	member := models.User{}
+	admin := models.User{}
+	outsider := models.User{}
+	for _, u := range []*models.User{&member, &admin, &outsider} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	for u, role := range map[*models.User]string{&member: "member", &admin: "admin"} {
+		if err := db.Create(&models.Membership{UserID: u.ID, OrganizationID: orgID, Role: role}).Error; err != nil {
+			t.Fatalf("create membership: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name string
+		user *models.User
+		want int
+	}{
+		{"org key", nil, http.StatusForbidden},
+		{"member", &member, http.StatusForbidden},
+		{"outsider", &outsider, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		rr := httptest.NewRecorder()
+		ServerTerminal(db).ServeHTTP(rr, terminalReq(c.user, serverID))
+		if rr.Code != c.want {
+			t.Errorf("%s: status %d, want %d (body=%s)", c.name, rr.Code, c.want, rr.Body.String())
+		}
+	}
+
+	// Allowed, but a plain request is no websocket upgrade. The handshake
+	// needs a real connection to hijack.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServerTerminal(db).ServeHTTP(w, r.WithContext(terminalReq(&admin, serverID).Context()))
+	}))
+	defer ts.Close()
+	res, err := http.Get(ts.URL + "/servers/" + serverID.String() + "/terminal")
+	if err != nil {
+		t.Fatalf("admin without upgrade: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("admin without upgrade: status %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+
+	var n int64
+	db.Model(&models.TerminalSession{}).Where("server_id = ?", serverID).Count(&n)
+	if n != 0 {
+		t.Errorf("%d sessions recorded for refused requests and failed handshakes, want 0", n)
+	}
+}
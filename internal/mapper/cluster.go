@@ -57,6 +57,13 @@ func ClusterToDTO(c models.Cluster) dto.ClusterResponse {
 		metadata[m.Key] = m.Value
 	}
 
+	// The payload names the etcd members outright, so the playbooks need not
+	// work out the topology from pool roles themselves.
+	var etcd []dto.ServerResponse
+	for _, s := range c.EtcdMembers() {
+		etcd = append(etcd, ServerToDTO(s))
+	}
+
 	return dto.ClusterResponse{
 		ID:                    c.ID,
 		Name:                  c.Name,
@@ -68,6 +75,8 @@ func ClusterToDTO(c models.Cluster) dto.ClusterResponse {
 		BastionServer:         bastion,
 		Provider:              c.Provider,
 		Region:                c.Region,
+		Topology:              c.Topology,
+		EtcdMembers:           etcd,
 		Status:                c.Status,
 		LastError:             c.LastError,
 		RandomToken:           c.RandomToken,
@@ -16,6 +16,14 @@ const (
 	ClusterStatusBootstrapping = "bootstrapping"
 )
 
+const (
+	// ClusterTopologyStacked runs etcd on the control plane nodes.
+	ClusterTopologyStacked = "stacked"
+	// ClusterTopologyExternal runs etcd on its own nodes, from the cluster's
+	// etcd node pools.
+	ClusterTopologyExternal = "external"
+)
+
 type Cluster struct {
 	ID                      uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	OrganizationID          uuid.UUID         `gorm:"type:uuid;not null" json:"organization_id"`
@@ -23,8 +31,11 @@ type Cluster struct {
 	Name                    string            `gorm:"not null" json:"name"`
 	Provider                string            `json:"provider"`
 	Region                  string            `json:"region"`
+	Topology                string            `gorm:"type:varchar(20);not null;default:'stacked'" json:"topology"`
 	Status                  string            `gorm:"type:varchar(20);not null;default:'pre_pending'" json:"status"`
 	LastError               string            `gorm:"type:text;not null;default:''" json:"last_error"`
+	Degraded                bool              `gorm:"not null;default:false" json:"degraded"` // a node or the bastion is unreachable; independent of Status
+	DegradedReason          string            `gorm:"type:text;not null;default:''" json:"degraded_reason"`
 	CaptainDomainID         *uuid.UUID        `gorm:"type:uuid" json:"captain_domain_id"`
 	CaptainDomain           Domain            `gorm:"foreignKey:CaptainDomainID" json:"captain_domain"`
 	ControlPlaneRecordSetID *uuid.UUID        `gorm:"type:uuid" json:"control_plane_record_set_id,omitempty"`
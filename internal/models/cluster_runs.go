@@ -27,3 +27,31 @@ type ClusterRun struct {
 	UpdatedAt  time.Time `json:"updated_at,omitempty" gorm:"type:timestamptz;autoUpdateTime;column:updated_at;not null;default:now()" format:"date-time"`
 	FinishedAt time.Time `json:"finished_at,omitempty" gorm:"type:timestamptz" format:"date-time"`
 }
+
+const (
+	ClusterRunServerStatusQueued       = "queued"
+	ClusterRunServerStatusDraining     = "draining"
+	ClusterRunServerStatusRunning      = "running"
+	ClusterRunServerStatusWaitingReady = "waiting_ready"
+	ClusterRunServerStatusUncordoning  = "uncordoning"
+	ClusterRunServerStatusSucceeded    = "succeeded"
+	ClusterRunServerStatusFailed       = "failed"
+	// ClusterRunServerStatusSkipped is a server a rolling run never reached
+	// because an earlier one failed.
+	ClusterRunServerStatusSkipped = "skipped"
+)
+
+// ClusterRunServer is one server's progress through a rolling node pool run.
+// Servers sharing a Batch are taken out together. Its output is in job_logs
+// under JobLogSubjectClusterRunServer with this row's ID.
+type ClusterRunServer struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id" format:"uuid"`
+	ClusterRunID uuid.UUID  `gorm:"type:uuid;not null;index" json:"cluster_run_id" format:"uuid"`
+	ServerID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"server_id" format:"uuid"`
+	Hostname     string     `gorm:"type:text;not null;default:''" json:"hostname"`
+	Batch        int        `gorm:"not null" json:"batch"`
+	Status       string     `gorm:"type:text;not null" json:"status"`
+	Error        string     `gorm:"type:text;not null;default:''" json:"error"`
+	StartedAt    *time.Time `gorm:"type:timestamptz" json:"started_at,omitempty" format:"date-time"`
+	FinishedAt   *time.Time `gorm:"type:timestamptz" json:"finished_at,omitempty" format:"date-time"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	NodePoolRoleMaster = "master"
+	NodePoolRoleWorker = "worker"
+	// NodePoolRoleEtcd pools hold the etcd members of a cluster with the
+	// external topology.
+	NodePoolRoleEtcd = "etcd"
+)
+
+// NodePoolRoles are the roles a node pool can have.
+var NodePoolRoles = []string{NodePoolRoleMaster, NodePoolRoleWorker, NodePoolRoleEtcd}
+
+// ClusterTopologies are the etcd layouts a cluster can have.
+var ClusterTopologies = []string{ClusterTopologyStacked, ClusterTopologyExternal}
+
+func ValidateNodePoolRole(role string) error {
+	for _, r := range NodePoolRoles {
+		if role == r {
+			return nil
+		}
+	}
+	return fmt.Errorf("role %q must be one of %s", role, strings.Join(NodePoolRoles, ", "))
+}
+
+func ValidateClusterTopology(topology string) error {
+	for _, t := range ClusterTopologies {
+		if topology == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("topology %q must be one of %s", topology, strings.Join(ClusterTopologies, ", "))
+}
+
+// EtcdMembers is every server in the cluster's etcd pools, once each, by
+// hostname. Only an external topology has any.
+func (c *Cluster) EtcdMembers() []Server {
+	if c.Topology != ClusterTopologyExternal {
+		return nil
+	}
+	seen := map[uuid.UUID]bool{}
+	var out []Server
+	for _, np := range c.NodePools {
+		if np.Role != NodePoolRoleEtcd {
+			continue
+		}
+		for _, s := range np.Servers {
+			if !seen[s.ID] {
+				seen[s.ID] = true
+				out = append(out, s)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hostname < out[j].Hostname })
+	return out
+}
+
+// ValidateTopology checks the cluster's node pools against its topology. It
+// needs NodePools and their Servers loaded. An external cluster needs an odd
+// number of etcd members, so a quorum survives losing a minority, and none of
+// them may also be a control plane node.
+func (c *Cluster) ValidateTopology() error {
+	topology := c.Topology
+	if topology == "" {
+		topology = ClusterTopologyStacked
+	}
+	if err := ValidateClusterTopology(topology); err != nil {
+		return err
+	}
+
+	var etcdPools []string
+	masters := map[uuid.UUID]string{}
+	for _, np := range c.NodePools {
+		switch np.Role {
+		case NodePoolRoleEtcd:
+			etcdPools = append(etcdPools, np.Name)
+		case NodePoolRoleMaster:
+			for _, s := range np.Servers {
+				masters[s.ID] = np.Name
+			}
+		}
+	}
+
+	if topology == ClusterTopologyStacked {
+		if len(etcdPools) > 0 {
+			return fmt.Errorf("etcd node pools (%s) need the external topology", strings.Join(etcdPools, ", "))
+		}
+		return nil
+	}
+
+	members := c.EtcdMembers()
+	if len(members) == 0 {
+		return errors.New("external topology needs an etcd node pool with servers")
+	}
+	if len(members)%2 == 0 {
+		return fmt.Errorf("external topology needs an odd number of etcd members, has %d", len(members))
+	}
+	for _, s := range members {
+		if pool, ok := masters[s.ID]; ok {
+			return fmt.Errorf("server %s is an etcd member and also in master pool %s", s.Hostname, pool)
+		}
+	}
+	return nil
+}
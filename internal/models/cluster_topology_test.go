@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func topologyServers(names ...string) []Server {
+	out := make([]Server, 0, len(names))
+	for _, n := range names {
+		out = append(out, Server{ID: uuid.New(), Hostname: n})
+	}
+	return out
+}
+
+func TestValidateTopologyStacked(t *testing.T) {
+	c := Cluster{NodePools: []NodePool{{Name: "cp", Role: NodePoolRoleMaster, Servers: topologyServers("cp1")}}}
+	if err := c.ValidateTopology(); err != nil {
+		t.Fatalf("stacked cluster without etcd pools: %v", err)
+	}
+	if len(c.EtcdMembers()) != 0 {
+		t.Fatal("a stacked cluster has no external etcd members")
+	}
+
+	c.NodePools = append(c.NodePools, NodePool{Name: "etcd", Role: NodePoolRoleEtcd, Servers: topologyServers("e1")})
+	if err := c.ValidateTopology(); err == nil || !strings.Contains(err.Error(), "external topology") {
+		t.Fatalf("etcd pool on a stacked cluster should be rejected, got %v", err)
+	}
+}
+
+func TestValidateTopologyExternal(t *testing.T) {
+	etcd := topologyServers("e3", "e1", "e2")
+	c := Cluster{
+		Topology: ClusterTopologyExternal,
+		NodePools: []NodePool{
+			{Name: "cp", Role: NodePoolRoleMaster, Servers: topologyServers("cp1")},
+			{Name: "etcd-a", Role: NodePoolRoleEtcd, Servers: etcd[:2]},
+			// A server in two etcd pools is still one member.
+			{Name: "etcd-b", Role: NodePoolRoleEtcd, Servers: etcd[1:]},
+		},
+	}
+	if err := c.ValidateTopology(); err != nil {
+		t.Fatalf("three members: %v", err)
+	}
+	var names []string
+	for _, s := range c.EtcdMembers() {
+		names = append(names, s.Hostname)
+	}
+	if strings.Join(names, ",") != "e1,e2,e3" {
+		t.Fatalf("members = %v", names)
+	}
+
+	c.NodePools[2].Servers = etcd[1:2]
+	if err := c.ValidateTopology(); err == nil || !strings.Contains(err.Error(), "odd number") {
+		t.Fatalf("two members should be rejected, got %v", err)
+	}
+
+	c.NodePools[2].Servers = etcd[1:]
+	c.NodePools[0].Servers = append(c.NodePools[0].Servers, etcd[0])
+	if err := c.ValidateTopology(); err == nil || !strings.Contains(err.Error(), "master pool cp") {
+		t.Fatalf("an etcd member in a master pool should be rejected, got %v", err)
+	}
+
+	c.NodePools = c.NodePools[:1]
+	if err := c.ValidateTopology(); err == nil {
+		t.Fatal("external topology without etcd members should be rejected")
+	}
+}
+
+func TestValidateRoleAndTopologyNames(t *testing.T) {
+	for _, r := range []string{"master", "worker", "etcd"} {
+		if err := ValidateNodePoolRole(r); err != nil {
+			t.Errorf("%s: %v", r, err)
+		}
+	}
+	if ValidateNodePoolRole("bastion") == nil || ValidateClusterTopology("hybrid") == nil {
+		t.Fatal("unknown role and topology should be rejected")
+	}
+}
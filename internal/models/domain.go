@@ -12,13 +12,24 @@ type Domain struct {
 	OrganizationID uuid.UUID    `gorm:"type:uuid;not null;index;uniqueIndex:uniq_org_domain,priority:1"`
 	Organization   Organization `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE" json:"organization"`
 	DomainName     string       `gorm:"type:varchar(253);not null;uniqueIndex:uniq_org_domain,priority:2"`
-	ZoneID         string       `gorm:"type:varchar(128);not null;default:''"`       // backfilled for R53 (e.g. "/hostedzone/Z123...")
-	Status         string       `gorm:"type:varchar(20);not null;default:'pending'"` // pending, provisioning, ready, failed
+	ZoneID         string       `gorm:"type:varchar(128);not null;default:''"`       // provider zone id, backfilled by the reconciler (R53 "Z123...", Cloudflare zone tag)
+	Status         string       `gorm:"type:varchar(20);not null;default:'pending'"` // pending, provisioning, ready, failed, deleting
 	LastError      string       `gorm:"type:text;not null;default:''"`
 	CredentialID   uuid.UUID    `gorm:"type:uuid;not null" json:"credential_id"`
 	Credential     Credential   `gorm:"foreignKey:CredentialID" json:"credential,omitempty"`
-	CreatedAt      time.Time    `json:"created_at,omitempty" gorm:"type:timestamptz;column:created_at;not null;default:now()"`
-	UpdatedAt      time.Time    `json:"updated_at,omitempty" gorm:"type:timestamptz;autoUpdateTime;column:updated_at;not null;default:now()"`
+	// CreateZone has the reconciler create the zone when the provider has
+	// none by this name.
+	CreateZone bool `gorm:"not null;default:false"`
+	// NameServers are the ones the provider assigned the zone ([]string).
+	NameServers datatypes.JSON `gorm:"type:jsonb"`
+	// What the parent zone delegates the domain to, read from a parent
+	// domain in autoglue or else from DNS, and how that compares.
+	DelegationStatus     string         `gorm:"type:varchar(20);not null;default:''"` // delegated, partial, not_delegated, unknown
+	DelegationSource     string         `gorm:"type:varchar(16);not null;default:''"` // autoglue, dns
+	DelegatedNameServers datatypes.JSON `gorm:"type:jsonb"`
+	DelegationCheckedAt  *time.Time     `gorm:"type:timestamptz"`
+	CreatedAt            time.Time      `json:"created_at,omitempty" gorm:"type:timestamptz;column:created_at;not null;default:now()"`
+	UpdatedAt            time.Time      `json:"updated_at,omitempty" gorm:"type:timestamptz;autoUpdateTime;column:updated_at;not null;default:now()"`
 }
 
 type RecordSet struct {
@@ -29,13 +40,22 @@ type RecordSet struct {
 	Type        string         `gorm:"type:varchar(10);not null;index"` // A, AAAA, CNAME, TXT, MX, SRV, NS, CAA...
 	TTL         *int           `gorm:""`                                // nil for alias targets (Route 53 ignores TTL for alias)
 	Values      datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"`
-	Fingerprint string         `gorm:"type:char(64);not null;index"` // sha256 of canonical(name,type,ttl,values|alias)
-	Status      string         `gorm:"type:varchar(20);not null;default:'pending'"`
+	Alias       datatypes.JSON `gorm:"type:jsonb"`                                  // Route 53 alias target for A/AAAA, in place of Values (dto.AliasTarget)
+	Fingerprint string         `gorm:"type:char(64);not null;index"`                // sha256 of canonical(name,type,ttl,values|alias)
+	Status      string         `gorm:"type:varchar(20);not null;default:'pending'"` // pending, provisioning, ready, drifted, failed, deleting
 	Owner       string         `gorm:"type:varchar(16);not null;default:'unknown'"` // 'autoglue' | 'external' | 'unknown'
 	LastError   string         `gorm:"type:text;not null;default:''"`
-	_           struct{}       `gorm:"uniqueIndex:uniq_domain_name_type,priority:1"` // tag holder
-	_           struct{}       `gorm:"uniqueIndex:uniq_domain_name_type,priority:2"`
-	_           struct{}       `gorm:"uniqueIndex:uniq_domain_name_type,priority:3"`
-	CreatedAt   time.Time      `json:"created_at,omitempty" gorm:"type:timestamptz;column:created_at;not null;default:now()"`
-	UpdatedAt   time.Time      `json:"updated_at,omitempty" gorm:"type:timestamptz;autoUpdateTime;column:updated_at;not null;default:now()"`
+	ChangeID    string         `gorm:"type:varchar(64);not null;default:''"` // provider change a provisioning record waits on
+	// What the drift check last read from the provider when it differed from
+	// the row; ObservedValues is [] when the record was gone.
+	ObservedTTL    *int           `gorm:""`
+	ObservedValues datatypes.JSON `gorm:"type:jsonb"`
+	ObservedAlias  datatypes.JSON `gorm:"type:jsonb"`
+	DriftedAt      *time.Time     `gorm:"type:timestamptz"`
+	DriftCheckedAt *time.Time     `gorm:"type:timestamptz"`
+	_              struct{}       `gorm:"uniqueIndex:uniq_domain_name_type,priority:1"` // tag holder
+	_              struct{}       `gorm:"uniqueIndex:uniq_domain_name_type,priority:2"`
+	_              struct{}       `gorm:"uniqueIndex:uniq_domain_name_type,priority:3"`
+	CreatedAt      time.Time      `json:"created_at,omitempty" gorm:"type:timestamptz;column:created_at;not null;default:now()"`
+	UpdatedAt      time.Time      `json:"updated_at,omitempty" gorm:"type:timestamptz;autoUpdateTime;column:updated_at;not null;default:now()"`
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ExecRunStatusRunning   = "running"
+	ExecRunStatusSucceeded = "succeeded"
+	ExecRunStatusFailed    = "failed"
+
+	ExecHostStatusQueued    = "queued"
+	ExecHostStatusRunning   = "running"
+	ExecHostStatusSucceeded = "succeeded"
+	ExecHostStatusFailed    = "failed"
+)
+
+// ExecRun records one ad-hoc command invocation against a cluster or node
+// pool: who ran what, where, and how it went. Exactly one of ClusterID and
+// NodePoolID is set.
+type ExecRun struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id" format:"uuid"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id" format:"uuid"`
+	ClusterID      *uuid.UUID `gorm:"type:uuid;index" json:"cluster_id,omitempty" format:"uuid"`
+	NodePoolID     *uuid.UUID `gorm:"type:uuid;index" json:"node_pool_id,omitempty" format:"uuid"`
+	// UserID is nil when the caller was an org API key.
+	UserID         *uuid.UUID    `gorm:"type:uuid;index" json:"user_id,omitempty" format:"uuid"`
+	Command        string        `gorm:"type:text;not null" json:"command"`
+	Concurrency    int           `gorm:"not null" json:"concurrency"`
+	TimeoutSeconds int           `gorm:"not null" json:"timeout_seconds"`
+	Status         string        `gorm:"type:text;not null" json:"status"`
+	Hosts          []ExecRunHost `gorm:"foreignKey:ExecRunID;constraint:OnDelete:CASCADE" json:"hosts,omitempty"`
+	CreatedAt      time.Time     `gorm:"type:timestamptz;not null;default:now();index" json:"created_at" format:"date-time"`
+	UpdatedAt      time.Time     `gorm:"type:timestamptz;autoUpdateTime;not null;default:now()" json:"updated_at" format:"date-time"`
+	FinishedAt     *time.Time    `gorm:"type:timestamptz" json:"finished_at,omitempty" format:"date-time"`
+}
+
+// ExecRunHost is one server's share of an ExecRun. Its output is in job_logs
+// under JobLogSubjectExecHost with this row's ID.
+type ExecRunHost struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id" format:"uuid"`
+	ExecRunID uuid.UUID `gorm:"type:uuid;not null;index" json:"exec_run_id" format:"uuid"`
+	ServerID  uuid.UUID `gorm:"type:uuid;not null;index" json:"server_id" format:"uuid"`
+	Hostname  string    `gorm:"type:text;not null;default:''" json:"hostname"`
+	Status    string    `gorm:"type:text;not null" json:"status"`
+	// ExitCode is nil when the command never ran to completion: the host
+	// could not be reached, or it hit the timeout.
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	Error      string     `gorm:"type:text;not null;default:''" json:"error"`
+	JobID      *int64     `gorm:"index" json:"job_id,omitempty"`
+	StartedAt  *time.Time `gorm:"type:timestamptz" json:"started_at,omitempty" format:"date-time"`
+	FinishedAt *time.Time `gorm:"type:timestamptz" json:"finished_at,omitempty" format:"date-time"`
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NormalizeIP parses a single IPv4 or IPv6 address and returns it in
+// canonical form, so "10.0.0.01" is refused and "2001:DB8::1" and
+// "2001:db8:0::1" compare equal. IPv4-mapped IPv6 addresses are unmapped.
+func NormalizeIP(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", fmt.Errorf("%q is not an IP address", s)
+	}
+	if addr.Zone() != "" {
+		return "", fmt.Errorf("%q has a zone, which an address here cannot carry", s)
+	}
+	return addr.Unmap().String(), nil
+}
+
+// ParseSubnet validates a subnet's CIDR and optional gateway. It returns the
+// prefix with its host bits cleared and the gateway in canonical form. The
+// gateway has to be a usable host address inside the prefix.
+func ParseSubnet(cidr, gateway string) (netip.Prefix, string, error) {
+	cidr = strings.TrimSpace(cidr)
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, "", fmt.Errorf("%q is not a CIDR", cidr)
+	}
+	p = p.Masked()
+	if p.Addr().Is4In6() {
+		return netip.Prefix{}, "", fmt.Errorf("%q is an IPv4-mapped prefix; write it as IPv4", cidr)
+	}
+
+	if strings.TrimSpace(gateway) == "" {
+		return p, "", nil
+	}
+	gw, err := NormalizeIP(gateway)
+	if err != nil {
+		return netip.Prefix{}, "", err
+	}
+	addr := netip.MustParseAddr(gw)
+	if !p.Contains(addr) {
+		return netip.Prefix{}, "", fmt.Errorf("gateway %s is outside %s", gw, p)
+	}
+	if first, last := hostRange(p); addr.Less(first) || last.Less(addr) {
+		return netip.Prefix{}, "", fmt.Errorf("gateway %s is not a host address in %s", gw, p)
+	}
+	return p, gw, nil
+}
+
+// hostRange is the first and last address in p that can be given to a host.
+// For IPv4 prefixes shorter than /31 that excludes the network and broadcast
+// addresses; for IPv6 it excludes the subnet-router anycast address.
+func hostRange(p netip.Prefix) (netip.Addr, netip.Addr) {
+	first := p.Addr()
+	last := lastAddr(p)
+	bits := p.Addr().BitLen() - p.Bits()
+	if p.Addr().Is4() {
+		if bits >= 2 {
+			first, last = first.Next(), last.Prev()
+		}
+	} else if bits >= 1 {
+		first = first.Next()
+	}
+	return first, last
+}
+
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - uint(i%8))
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+// NextFreeAddress is the lowest host address in p that is neither the
+// gateway nor in used. Keys in used are canonical addresses as returned by
+// NormalizeIP. ok is false when the subnet is full.
+func NextFreeAddress(p netip.Prefix, gateway string, used map[string]bool) (addr string, ok bool) {
+	first, last := hostRange(p)
+	for a := first; a.IsValid() && !last.Less(a); a = a.Next() {
+		s := a.String()
+		if s != gateway && !used[s] {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// IPConflictError is returned out of a rolled-back change that would have
+// given an address to two servers or load balancers in the same org.
+type IPConflictError struct {
+	conflicts []string
+}
+
+func (e *IPConflictError) Error() string {
+	return strings.Join(e.conflicts, "; ")
+}
+
+// OrgIPHolders maps every address held by a server or load balancer in the
+// org, in canonical form, to a description of its holder. The resource except
+// is left out, so an update does not conflict with itself. Stored values that
+// do not parse predate validation and are skipped.
+func OrgIPHolders(tx *gorm.DB, orgID, except uuid.UUID) (map[string]string, error) {
+	var servers []Server
+	if err := tx.Select("id", "hostname", "public_ip_address", "private_ip_address").
+		Where("organization_id = ? AND id <> ?", orgID, except).
+		Find(&servers).Error; err != nil {
+		return nil, err
+	}
+	var lbs []LoadBalancer
+	if err := tx.Select("id", "name", "public_ip_address", "private_ip_address").
+		Where("organization_id = ? AND id <> ?", orgID, except).
+		Find(&lbs).Error; err != nil {
+		return nil, err
+	}
+
+	out := map[string]string{}
+	hold := func(raw, holder string) {
+		if ip, err := NormalizeIP(raw); err == nil {
+			if _, taken := out[ip]; !taken {
+				out[ip] = holder
+			}
+		}
+	}
+	for _, s := range servers {
+		name := s.Hostname
+		if name == "" {
+			name = s.ID.String()
+		}
+		hold(s.PrivateIPAddress, "server "+name)
+		if s.PublicIPAddress != nil {
+			hold(*s.PublicIPAddress, "server "+name)
+		}
+	}
+	for _, lb := range lbs {
+		hold(lb.PrivateIPAddress, "load balancer "+lb.Name)
+		hold(lb.PublicIPAddress, "load balancer "+lb.Name)
+	}
+	return out, nil
+}
+
+// CheckIPConflicts locks the org and fails with an IPConflictError if any of
+// addrs is already held by another server or load balancer in it. Run it
+// inside the transaction that writes the addresses, before the write; the
+// lock keeps two concurrent writes from both taking the same address.
+func CheckIPConflicts(tx *gorm.DB, orgID, except uuid.UUID, addrs ...string) error {
+	if len(addrs) == 0 {
+		return nil
+	}
+	if err := LockOrg(tx, orgID); err != nil {
+		return err
+	}
+	holders, err := OrgIPHolders(tx, orgID, except)
+	if err != nil {
+		return err
+	}
+	var conflicts []string
+	for _, ip := range addrs {
+		if holder, ok := holders[ip]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s is already used by %s", ip, holder))
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return &IPConflictError{conflicts: conflicts}
+	}
+	return nil
+}
+
+// LockOrg takes a row lock on the org for the rest of the transaction. Writes
+// that have to be checked against every other row in the org, such as a new
+// address or subnet, take it first so they cannot race each other.
+func LockOrg(tx *gorm.DB, orgID uuid.UUID) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("id = ?", orgID).First(&Organization{}).Error
+}
+
+// CheckIPReservations fails with an IPConflictError if any of addrs is held
+// by an unexpired reservation from the allocate endpoint. An address that
+// did not come out of a reservation, such as one a cloud provider assigned,
+// must not take one that was handed to somebody else. Run it after LockOrg.
+func CheckIPReservations(tx *gorm.DB, orgID uuid.UUID, addrs ...string) error {
+	if len(addrs) == 0 {
+		return nil
+	}
+	var reserved []string
+	if err := tx.Model(&SubnetReservation{}).
+		Where("organization_id = ? AND address IN ? AND expires_at > ?", orgID, addrs, time.Now()).
+		Pluck("address", &reserved).Error; err != nil {
+		return err
+	}
+	if len(reserved) == 0 {
+		return nil
+	}
+	sort.Strings(reserved)
+	conflicts := make([]string, len(reserved))
+	for i, ip := range reserved {
+		conflicts[i] = fmt.Sprintf("%s is reserved by an address allocation", ip)
+	}
+	return &IPConflictError{conflicts: conflicts}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestNormalizeIP(t *testing.T) {
+	cases := map[string]string{
+		" 10.0.0.5 ":       "10.0.0.5",
+		"2001:DB8:0::1":    "2001:db8::1",
+		"::ffff:192.0.2.1": "192.0.2.1",
+	}
+	for in, want := range cases {
+		if got, err := NormalizeIP(in); err != nil || got != want {
+			t.Errorf("NormalizeIP(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "10.0.0", "10.0.0.01", "10.0.0.0/24", "fe80::1%eth0", "host.example"} {
+		if _, err := NormalizeIP(bad); err == nil {
+			t.Errorf("NormalizeIP(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseSubnet(t *testing.T) {
+	p, gw, err := ParseSubnet("10.1.2.3/24", "10.1.2.1")
+	if err != nil || p.String() != "10.1.2.0/24" || gw != "10.1.2.1" {
+		t.Fatalf("ParseSubnet = %s, %q, %v", p, gw, err)
+	}
+	if _, gw, err := ParseSubnet("2001:db8::/64", ""); err != nil || gw != "" {
+		t.Fatalf("no gateway: %q, %v", gw, err)
+	}
+	for _, c := range [][2]string{
+		{"10.1.2.0", ""},
+		{"10.1.2.0/33", ""},
+		{"10.1.2.0/24", "10.1.3.1"},
+		{"10.1.2.0/24", "10.1.2.0"},
+		{"10.1.2.0/24", "10.1.2.255"},
+	} {
+		if _, _, err := ParseSubnet(c[0], c[1]); err == nil {
+			t.Errorf("ParseSubnet(%q, %q) should fail", c[0], c[1])
+		}
+	}
+}
+
+func TestNextFreeAddress(t *testing.T) {
+	p := netip.MustParsePrefix("10.0.0.0/29")
+	used := map[string]bool{"10.0.0.2": true, "10.0.0.4": true}
+	if got, ok := NextFreeAddress(p, "10.0.0.1", used); !ok || got != "10.0.0.3" {
+		t.Fatalf("next = %q, %v; want 10.0.0.3", got, ok)
+	}
+	for _, ip := range []string{"10.0.0.3", "10.0.0.5", "10.0.0.6"} {
+		used[ip] = true
+	}
+	if got, ok := NextFreeAddress(p, "10.0.0.1", used); ok {
+		t.Fatalf("a full subnet handed out %s (the broadcast address must not be used)", got)
+	}
+
+	if got, _ := NextFreeAddress(netip.MustParsePrefix("192.0.2.8/31"), "", nil); got != "192.0.2.8" {
+		t.Fatalf("a /31 has no network address to skip, got %s", got)
+	}
+	if got, _ := NextFreeAddress(netip.MustParsePrefix("2001:db8::/64"), "2001:db8::1", nil); got != "2001:db8::2" {
+		t.Fatalf("ipv6 next = %s", got)
+	}
+}
@@ -12,6 +12,18 @@ import (
 const (
 	JobLogSubjectServer     = "server"
 	JobLogSubjectClusterRun = "cluster_run"
+
+	// JobLogSubjectTerminalSession is a browser terminal transcript, keyed by
+	// TerminalSession.ID. Written by the API process, not a job, so JobID is 0.
+	JobLogSubjectTerminalSession = "terminal_session"
+
+	// JobLogSubjectExecHost is one host's output from an ad-hoc exec, keyed
+	// by ExecRunHost.ID.
+	JobLogSubjectExecHost = "exec_host"
+
+	// JobLogSubjectClusterRunServer is one server's output from a rolling
+	// node pool run, keyed by ClusterRunServer.ID.
+	JobLogSubjectClusterRunServer = "cluster_run_server"
 )
 
 // Log streams.
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// NodeMetadataStatus is what the node_metadata job last found on one node of
+// a cluster: whether its labels, annotations and taints matched what its node
+// pools ask for, and what was different. One row per cluster and server,
+// overwritten on each run.
+type NodeMetadataStatus struct {
+	ClusterID      uuid.UUID `gorm:"type:uuid;primaryKey" json:"cluster_id" format:"uuid"`
+	ServerID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"server_id" format:"uuid"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index" json:"organization_id" format:"uuid"`
+	NodeName       string    `gorm:"type:text;not null;default:''" json:"node_name"`
+	InSync         bool      `gorm:"not null;default:false" json:"in_sync"`
+	// Drift is []string, one entry per difference found before applying.
+	Drift     datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"drift" swaggertype:"array,string"`
+	LastError string         `gorm:"type:text;not null;default:''" json:"last_error"`
+	CheckedAt time.Time      `gorm:"type:timestamptz;not null;default:now()" json:"checked_at" format:"date-time"`
+	// AppliedAt is the last time drift was found and corrected.
+	AppliedAt *time.Time `gorm:"type:timestamptz" json:"applied_at,omitempty" format:"date-time"`
+}
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The rules below are Kubernetes' own (apimachinery's validation package),
+// restated so a bad label, annotation or taint is refused when it is created
+// rather than halfway through a bootstrap.
+
+const (
+	qualifiedNameMaxLength = 63
+	labelValueMaxLength    = 63
+	dns1123SubdomainMax    = 253
+	// annotationValueMax stands in for the API server's 256 KiB limit on all
+	// of a node's annotations together.
+	annotationValueMax = 256 << 10
+)
+
+var (
+	qualifiedNamePattern = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+	dns1123SubdomainPart = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// TaintEffects are the effects Kubernetes accepts on a node taint.
+var TaintEffects = []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+
+// ValidateQualifiedName checks a label, annotation or taint key: an optional
+// DNS subdomain prefix and a slash, then a name of at most 63 alphanumerics,
+// '-', '_' or '.', starting and ending with an alphanumeric.
+func ValidateQualifiedName(key string) error {
+	if key == "" {
+		return errors.New("key must not be empty")
+	}
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if err := validateDNSSubdomain(prefix); err != nil {
+			return fmt.Errorf("key %q: prefix %w", key, err)
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("key %q: name part must not be empty", key)
+	}
+	if len(name) > qualifiedNameMaxLength {
+		return fmt.Errorf("key %q: name part must be at most %d characters", key, qualifiedNameMaxLength)
+	}
+	if !qualifiedNamePattern.MatchString(name) {
+		return fmt.Errorf("key %q: name part must consist of alphanumerics, '-', '_' or '.', and start and end with an alphanumeric", key)
+	}
+	return nil
+}
+
+func validateDNSSubdomain(s string) error {
+	if s == "" {
+		return errors.New("must not be empty")
+	}
+	if len(s) > dns1123SubdomainMax {
+		return fmt.Errorf("must be at most %d characters", dns1123SubdomainMax)
+	}
+	for _, part := range strings.Split(s, ".") {
+		if !dns1123SubdomainPart.MatchString(part) {
+			return errors.New("must be a lowercase DNS subdomain")
+		}
+	}
+	return nil
+}
+
+// ValidateLabelValue checks a label or taint value: empty, or at most 63
+// alphanumerics, '-', '_' or '.', starting and ending with an alphanumeric.
+func ValidateLabelValue(v string) error {
+	if v == "" {
+		return nil
+	}
+	if len(v) > labelValueMaxLength {
+		return fmt.Errorf("value %q must be at most %d characters", v, labelValueMaxLength)
+	}
+	if !qualifiedNamePattern.MatchString(v) {
+		return fmt.Errorf("value %q must consist of alphanumerics, '-', '_' or '.', and start and end with an alphanumeric", v)
+	}
+	return nil
+}
+
+func ValidateTaintEffect(effect string) error {
+	for _, e := range TaintEffects {
+		if effect == e {
+			return nil
+		}
+	}
+	return fmt.Errorf("effect %q must be one of %s", effect, strings.Join(TaintEffects, ", "))
+}
+
+func (l Label) Validate() error {
+	if err := ValidateQualifiedName(l.Key); err != nil {
+		return err
+	}
+	return ValidateLabelValue(l.Value)
+}
+
+// Validate checks the key only: annotation values are free-form, bar size.
+func (a Annotation) Validate() error {
+	if err := ValidateQualifiedName(a.Key); err != nil {
+		return err
+	}
+	if len(a.Value) > annotationValueMax {
+		return fmt.Errorf("annotation %q: value must be at most %d bytes", a.Key, annotationValueMax)
+	}
+	return nil
+}
+
+func (t Taint) Validate() error {
+	if err := ValidateQualifiedName(t.Key); err != nil {
+		return err
+	}
+	if err := ValidateLabelValue(t.Value); err != nil {
+		return err
+	}
+	return ValidateTaintEffect(t.Effect)
+}
+
+// NodeMetadataConflicts lists the labels and taints that the given node pools
+// set to different values, as they would all land on one node. Taints are
+// told apart by key and effect, as kubectl does.
+func NodeMetadataConflicts(pools []NodePool) []string {
+	type source struct{ value, pool string }
+	labels := map[string][]source{}
+	taints := map[string][]source{}
+	for _, p := range pools {
+		for _, l := range p.Labels {
+			labels[l.Key] = append(labels[l.Key], source{l.Value, p.Name})
+		}
+		for _, t := range p.Taints {
+			id := t.Key + ":" + t.Effect
+			taints[id] = append(taints[id], source{t.Value, p.Name})
+		}
+	}
+
+	var out []string
+	report := func(kind string, by map[string][]source) {
+		keys := make([]string, 0, len(by))
+		for k := range by {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			seen := map[string]string{}
+			var parts []string
+			for _, s := range by[k] {
+				if _, dup := seen[s.value]; dup {
+					continue
+				}
+				seen[s.value] = s.pool
+				parts = append(parts, fmt.Sprintf("%q (pool %s)", s.value, s.pool))
+			}
+			if len(seen) > 1 {
+				out = append(out, fmt.Sprintf("%s %s is set to %s", kind, k, strings.Join(parts, " and ")))
+			}
+		}
+	}
+	report("label", labels)
+	report("taint", taints)
+	return out
+}
+
+// MetadataConflictError is returned out of a rolled-back change that would
+// have left a node with two values for one label or taint.
+type MetadataConflictError struct {
+	conflicts []string
+}
+
+func (e *MetadataConflictError) Error() string {
+	return strings.Join(e.conflicts, "; ")
+}
+
+// CheckNodeMetadata fails with a MetadataConflictError if any of the pools,
+// or any server in them, has two values for one label or taint. Run it
+// inside the transaction that made the change, after the change.
+func CheckNodeMetadata(tx *gorm.DB, poolIDs []uuid.UUID) error {
+	conflicts, err := poolMetadataConflicts(tx, poolIDs)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &MetadataConflictError{conflicts: conflicts}
+	}
+	return nil
+}
+
+// poolMetadataConflicts checks each pool on its own, and each of their
+// servers against all the pools it is in.
+func poolMetadataConflicts(tx *gorm.DB, poolIDs []uuid.UUID) ([]string, error) {
+	if len(poolIDs) == 0 {
+		return nil, nil
+	}
+	var pools []NodePool
+	if err := tx.Preload("Labels").
+		Preload("Taints").
+		Preload("Servers.NodePools.Labels").
+		Preload("Servers.NodePools.Taints").
+		Where("id IN ?", poolIDs).
+		Find(&pools).Error; err != nil {
+		return nil, err
+	}
+
+	var out []string
+	checked := map[uuid.UUID]bool{}
+	for _, p := range pools {
+		for _, c := range NodeMetadataConflicts([]NodePool{p}) {
+			out = append(out, "node pool "+p.Name+": "+c)
+		}
+		for _, s := range p.Servers {
+			if checked[s.ID] || len(s.NodePools) < 2 {
+				continue
+			}
+			checked[s.ID] = true
+			for _, c := range NodeMetadataConflicts(s.NodePools) {
+				out = append(out, "server "+s.Hostname+": "+c)
+			}
+		}
+	}
+	return out, nil
+}
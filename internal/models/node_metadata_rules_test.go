@@ -0,0 +1,56 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateQualifiedName(t *testing.T) {
+	valid := []string{"tier", "app.kubernetes.io/name", "node-role.kubernetes.io/worker", "a", "A_b.c-9"}
+	for _, k := range valid {
+		if err := ValidateQualifiedName(k); err != nil {
+			t.Errorf("%q: unexpected error %v", k, err)
+		}
+	}
+	invalid := []string{"", "has space", "-lead", "trail-", "/name", "Example.com/name", "prefix/", strings.Repeat("a", 64)}
+	for _, k := range invalid {
+		if err := ValidateQualifiedName(k); err == nil {
+			t.Errorf("%q: expected an error", k)
+		}
+	}
+}
+
+func TestValidateLabelValue(t *testing.T) {
+	for _, v := range []string{"", "web", "v1.2_3-x"} {
+		if err := ValidateLabelValue(v); err != nil {
+			t.Errorf("%q: unexpected error %v", v, err)
+		}
+	}
+	for _, v := range []string{"two words", "a/b", ".dot", strings.Repeat("v", 64)} {
+		if err := ValidateLabelValue(v); err == nil {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
+
+func TestTaintValidate(t *testing.T) {
+	if err := (Taint{Key: "gpu", Value: "true", Effect: "NoSchedule"}).Validate(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := (Taint{Key: "gpu", Value: "true", Effect: "Foo"}).Validate(); err == nil {
+		t.Fatalf("expected an error for effect Foo")
+	}
+}
+
+func TestNodeMetadataConflicts(t *testing.T) {
+	pools := []NodePool{
+		{Name: "a", Labels: []Label{{Key: "tier", Value: "web"}, {Key: "zone", Value: "1"}},
+			Taints: []Taint{{Key: "gpu", Value: "x", Effect: "NoSchedule"}}},
+		{Name: "b", Labels: []Label{{Key: "tier", Value: "db"}, {Key: "zone", Value: "1"}},
+			Taints: []Taint{{Key: "gpu", Value: "y", Effect: "NoExecute"}}},
+	}
+	got := NodeMetadataConflicts(pools)
+	if len(got) != 1 || !strings.Contains(got[0], "label tier") || !strings.Contains(got[0], `"db" (pool b)`) {
+		t.Fatalf("conflicts = %v", got)
+	}
+}
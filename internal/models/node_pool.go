@@ -1,7 +1,11 @@
 package models
 
 import (
+	"time"
+
 	"github.com/glueops/autoglue/internal/common"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type NodePool struct {
@@ -13,6 +17,51 @@ type NodePool struct {
 	Labels       []Label      `gorm:"many2many:node_labels;constraint:OnDelete:CASCADE" json:"labels,omitempty"`
 	Taints       []Taint      `gorm:"many2many:node_taints;constraint:OnDelete:CASCADE" json:"taints,omitempty"`
 	Clusters     []Cluster    `gorm:"many2many:cluster_node_pools;constraint:OnDelete:CASCADE" json:"clusters,omitempty"`
-	//Topology  string    `gorm:"not null,default:'stacked'" json:"topology,omitempty"` // stacked or external
-	Role string `gorm:"not null,default:'worker'" json:"role,omitempty"` // master, worker, or etcd (etcd only if topology = external
+	Role         string       `gorm:"not null,default:'worker'" json:"role,omitempty"` // master, worker, or etcd (etcd only if the cluster's topology is external)
+
+	// DesiredSize turns on the node_pool_scale reconciler for this pool; nil
+	// leaves membership entirely manual.
+	DesiredSize *int           `json:"desired_size,omitempty"`
+	Template    ServerTemplate `gorm:"embedded;embeddedPrefix:template_" json:"template"`
+	// JoinPending is set when the reconciler adds servers and cleared once it
+	// has started the join action on the pool's clusters.
+	JoinPending bool       `gorm:"not null;default:false" json:"join_pending"`
+	ScaleError  string     `gorm:"type:text;not null;default:''" json:"scale_error,omitempty"`
+	ScaledAt    *time.Time `gorm:"type:timestamptz" json:"scaled_at,omitempty" format:"date-time"`
+}
+
+// ServerTemplate is what the reconciler creates new pool members from.
+type ServerTemplate struct {
+	CredentialID *uuid.UUID `gorm:"type:uuid" json:"credential_id,omitempty"`
+	Size         string     `gorm:"type:text;not null;default:''" json:"size,omitempty" example:"cx22"`
+	Image        string     `gorm:"type:text;not null;default:''" json:"image,omitempty" example:"ubuntu-24.04"`
+	Region       string     `gorm:"type:text;not null;default:''" json:"region,omitempty" example:"fsn1"`
+	Network      string     `gorm:"type:text;not null;default:''" json:"network,omitempty"`
+	SshKeyID     *uuid.UUID `gorm:"type:uuid" json:"ssh_key_id,omitempty"`
+	SSHUser      string     `gorm:"column:ssh_user;type:text;not null;default:''" json:"ssh_user,omitempty"`
+}
+
+// AttachPoolServers adds servers to the pool under the org lock, then fails
+// with a PlacementConflictError or MetadataConflictError if the servers no
+// longer follow the org's placement rules or end up with clashing labels or
+// taints. Every path that puts servers into a pool goes through it; run it
+// inside a transaction so a refused attach is rolled back.
+func AttachPoolServers(tx *gorm.DB, np *NodePool, servers []Server) error {
+	if len(servers) == 0 {
+		return nil
+	}
+	if err := LockOrg(tx, np.OrganizationID); err != nil {
+		return err
+	}
+	if err := tx.Model(np).Association("Servers").Append(&servers); err != nil {
+		return err
+	}
+	ids := make([]uuid.UUID, len(servers))
+	for i, s := range servers {
+		ids[i] = s.ID
+	}
+	if err := CheckPlacement(tx, np.OrganizationID, ids); err != nil {
+		return err
+	}
+	return CheckNodeMetadata(tx, []uuid.UUID{np.ID})
 }
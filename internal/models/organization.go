@@ -8,9 +8,13 @@ import (
 
 type Organization struct {
 	// example: 3fa85f64-5717-4562-b3fc-2c963f66afa6
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id" format:"uuid"`
-	Name      string    `gorm:"not null" json:"name"`
-	Domain    *string   `gorm:"index" json:"domain"`
-	CreatedAt time.Time `gorm:"column:created_at;not null;default:now()" json:"created_at" format:"date-time"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime;column:updated_at;not null;default:now()" json:"updated_at" format:"date-time"`
+	ID                uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id" format:"uuid"`
+	Name              string    `gorm:"not null" json:"name"`
+	Domain            *string   `gorm:"index" json:"domain"`
+	ExclusiveServers  bool      `gorm:"not null;default:true" json:"exclusive_servers"`
+	MatchServerRoles  bool      `gorm:"not null;default:true" json:"match_server_roles"`
+	NoBastionsInPools bool      `gorm:"not null;default:true" json:"no_bastions_in_pools"`
+	DNSAutoReapply    bool      `gorm:"not null;default:false" json:"dns_auto_reapply"` // re-apply record sets that drifted instead of only flagging them
+	CreatedAt         time.Time `gorm:"column:created_at;not null;default:now()" json:"created_at" format:"date-time"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime;column:updated_at;not null;default:now()" json:"updated_at" format:"date-time"`
 }
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PlacementRules are an organization's rules for putting servers into node
+// pools, and through them into clusters. Each can be turned off for an estate
+// that breaks it on purpose.
+type PlacementRules struct {
+	// ExclusiveServers keeps a server in at most one cluster.
+	ExclusiveServers bool
+	// MatchServerRoles keeps a server out of pools whose role differs from
+	// its own.
+	MatchServerRoles bool
+	// NoBastionsInPools keeps bastion-role servers out of node pools.
+	NoBastionsInPools bool
+}
+
+func (o Organization) PlacementRules() PlacementRules {
+	return PlacementRules{
+		ExclusiveServers:  o.ExclusiveServers,
+		MatchServerRoles:  o.MatchServerRoles,
+		NoBastionsInPools: o.NoBastionsInPools,
+	}
+}
+
+// PlacementViolations lists how servers break rules, naming the pool or
+// cluster each is in conflict with. Each server needs its NodePools, and
+// their Clusters, loaded.
+func PlacementViolations(rules PlacementRules, servers []Server) []string {
+	var out []string
+	for _, s := range servers {
+		role := strings.ToLower(strings.TrimSpace(s.Role))
+		pools := append([]NodePool(nil), s.NodePools...)
+		sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+
+		for _, np := range pools {
+			switch {
+			case rules.NoBastionsInPools && role == "bastion":
+				out = append(out, fmt.Sprintf("server %s has the bastion role and cannot be in node pool %s", s.Hostname, np.Name))
+			case rules.MatchServerRoles && role != "bastion" && role != strings.ToLower(np.Role):
+				out = append(out, fmt.Sprintf("server %s has role %s but node pool %s has role %s", s.Hostname, role, np.Name, np.Role))
+			}
+		}
+
+		if !rules.ExclusiveServers {
+			continue
+		}
+		via := map[uuid.UUID]string{}
+		var clusters []Cluster
+		for _, np := range pools {
+			for _, c := range np.Clusters {
+				if _, ok := via[c.ID]; !ok {
+					via[c.ID] = np.Name
+					clusters = append(clusters, c)
+				}
+			}
+		}
+		if len(clusters) > 1 {
+			sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+			parts := make([]string, 0, len(clusters))
+			for _, c := range clusters {
+				parts = append(parts, fmt.Sprintf("%s (node pool %s)", c.Name, via[c.ID]))
+			}
+			out = append(out, fmt.Sprintf("server %s would be in more than one cluster: %s", s.Hostname, strings.Join(parts, " and ")))
+		}
+	}
+	return out
+}
+
+// PlacementConflictError is returned out of a rolled-back change that would
+// have broken one of the org's placement rules.
+type PlacementConflictError struct {
+	violations []string
+}
+
+func (e *PlacementConflictError) Error() string {
+	return strings.Join(e.violations, "; ")
+}
+
+// CheckPlacement fails with a PlacementConflictError if any of the servers
+// breaks the org's placement rules. Only the servers a request places or
+// changes are passed in, so placements made before a rule was turned on do
+// not block unrelated edits. Run it inside the transaction that made the
+// change, after the change.
+func CheckPlacement(tx *gorm.DB, orgID uuid.UUID, serverIDs []uuid.UUID) error {
+	if len(serverIDs) == 0 {
+		return nil
+	}
+	var org Organization
+	if err := tx.Where("id = ?", orgID).First(&org).Error; err != nil {
+		return err
+	}
+	rules := org.PlacementRules()
+	if rules == (PlacementRules{}) {
+		return nil
+	}
+
+	var servers []Server
+	if err := tx.Preload("NodePools.Clusters").
+		Where("id IN ? AND organization_id = ?", serverIDs, orgID).
+		Order("hostname").
+		Find(&servers).Error; err != nil {
+		return err
+	}
+	if v := PlacementViolations(rules, servers); len(v) > 0 {
+		return &PlacementConflictError{violations: v}
+	}
+	return nil
+}
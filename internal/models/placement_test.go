@@ -0,0 +1,44 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func placed(host, role string, pools ...NodePool) Server {
+	return Server{ID: uuid.New(), Hostname: host, Role: role, NodePools: pools}
+}
+
+func TestPlacementViolations(t *testing.T) {
+	prod := Cluster{ID: uuid.New(), Name: "prod"}
+	staging := Cluster{ID: uuid.New(), Name: "staging"}
+	workersA := NodePool{Name: "workers-a", Role: "worker", Clusters: []Cluster{prod}}
+	workersB := NodePool{Name: "workers-b", Role: "worker", Clusters: []Cluster{staging}}
+	masters := NodePool{Name: "masters", Role: "master", Clusters: []Cluster{prod}}
+	all := PlacementRules{ExclusiveServers: true, MatchServerRoles: true, NoBastionsInPools: true}
+
+	cases := []struct {
+		name   string
+		rules  PlacementRules
+		server Server
+		want   string
+	}{
+		{"fine", all, placed("w1", "worker", workersA), ""},
+		{"two clusters", all, placed("w1", "worker", workersA, workersB),
+			"server w1 would be in more than one cluster: prod (node pool workers-a) and staging (node pool workers-b)"},
+		{"two clusters allowed", PlacementRules{MatchServerRoles: true}, placed("w1", "worker", workersA, workersB), ""},
+		{"role mismatch", all, placed("w1", "Worker", masters),
+			"server w1 has role worker but node pool masters has role master"},
+		{"bastion", all, placed("b1", "bastion", workersA),
+			"server b1 has the bastion role and cannot be in node pool workers-a"},
+		{"bastion allowed", PlacementRules{MatchServerRoles: true}, placed("b1", "bastion", workersA), ""},
+	}
+	for _, c := range cases {
+		got := strings.Join(PlacementViolations(c.rules, []Server{c.server}), "; ")
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -10,27 +10,36 @@ import (
 )
 
 type Server struct {
-	ID               uuid.UUID    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	OrganizationID   uuid.UUID    `gorm:"type:uuid;not null" json:"organization_id"`
-	Organization     Organization `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE" json:"organization"`
-	Hostname         string       `json:"hostname"`
-	PublicIPAddress  *string      `json:"public_ip_address,omitempty"`
-	PrivateIPAddress string       `gorm:"not null" json:"private_ip_address"`
-	SSHUser          string       `gorm:"not null" json:"ssh_user"`
-	SshKeyID         uuid.UUID    `gorm:"type:uuid;not null" json:"ssh_key_id"`
-	SshKey           SshKey       `gorm:"foreignKey:SshKeyID" json:"ssh_key"`
-	Role             string       `gorm:"not null" json:"role" enums:"master,worker,bastion"`                           // e.g., "master", "worker", "bastion"
-	Status           string       `gorm:"default:'pending'" json:"status" enums:"pending, provisioning, ready, failed"` // pending, provisioning, ready, failed
-	NodePools        []NodePool   `gorm:"many2many:node_servers;constraint:OnDelete:CASCADE" json:"node_pools,omitempty"`
-	SSHHostKey       string       `gorm:"column:ssh_host_key"`
-	SSHHostKeyAlgo   string       `gorm:"column:ssh_host_key_algo"`
-	CreatedAt        time.Time    `gorm:"not null;default:now()" json:"created_at" format:"date-time"`
-	UpdatedAt        time.Time    `gorm:"not null;default:now()" json:"updated_at" format:"date-time"`
+	ID               uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OrganizationID   uuid.UUID       `gorm:"type:uuid;not null" json:"organization_id"`
+	Organization     Organization    `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE" json:"organization"`
+	Hostname         string          `json:"hostname"`
+	PublicIPAddress  *string         `json:"public_ip_address,omitempty"`
+	PrivateIPAddress string          `gorm:"not null" json:"private_ip_address"`
+	SSHUser          string          `gorm:"not null" json:"ssh_user"`
+	SshKeyID         uuid.UUID       `gorm:"type:uuid;not null" json:"ssh_key_id"`
+	SshKey           SshKey          `gorm:"foreignKey:SshKeyID" json:"ssh_key"`
+	Role             string          `gorm:"not null" json:"role" enums:"master,worker,etcd,bastion"`                                                       // e.g., "master", "worker", "etcd", "bastion"
+	Status           string          `gorm:"default:'pending'" json:"status" enums:"creating, pending, provisioning, ready, failed, unreachable, deleting"` // creating, pending, provisioning, ready, failed, unreachable, deleting
+	NodePools        []NodePool      `gorm:"many2many:node_servers;constraint:OnDelete:CASCADE" json:"node_pools,omitempty"`
+	SSHHostKey       string          `gorm:"column:ssh_host_key"`
+	SSHHostKeyAlgo   string          `gorm:"column:ssh_host_key_algo"`
+	Facts            *ServerFacts    `gorm:"foreignKey:ServerID;constraint:OnDelete:CASCADE" json:"facts,omitempty"`
+	Instance         *ServerInstance `gorm:"foreignKey:ServerID;constraint:OnDelete:CASCADE" json:"instance,omitempty"`
+	LastSeenAt       *time.Time      `gorm:"type:timestamptz" json:"last_seen_at,omitempty" format:"date-time"`
+	LastProbeAt      *time.Time      `gorm:"type:timestamptz" json:"last_probe_at,omitempty" format:"date-time"`
+	ProbeLatencyMs   *int64          `json:"probe_latency_ms,omitempty"`
+	ProbeError       string          `gorm:"type:text;not null;default:''" json:"probe_error,omitempty"`
+	ProbeFailures    int             `gorm:"not null;default:0" json:"-"` // consecutive; drives ready -> unreachable
+	ProbeSuccesses   int             `gorm:"not null;default:0" json:"-"` // consecutive; drives unreachable -> ready
+	CreatedAt        time.Time       `gorm:"not null;default:now()" json:"created_at" format:"date-time"`
+	UpdatedAt        time.Time       `gorm:"not null;default:now()" json:"updated_at" format:"date-time"`
 }
 
 func (s *Server) BeforeSave(tx *gorm.DB) error {
 	role := strings.ToLower(strings.TrimSpace(s.Role))
-	if role == "bastion" {
+	// A cloud server has no address until the provider hands one out.
+	if role == "bastion" && s.Status != "creating" {
 		if s.PublicIPAddress == nil || strings.TrimSpace(*s.PublicIPAddress) == "" {
 			return errors.New("public_ip_address is required for role=bastion")
 		}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// ServerFacts is the latest snapshot of what a server actually is, gathered
+// over SSH by the server_facts job. One row per server, overwritten on each
+// successful collection; a failed collection only touches the Last* fields so
+// the last good snapshot survives a host being briefly unreachable.
+type ServerFacts struct {
+	ServerID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"server_id" format:"uuid"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index" json:"organization_id" format:"uuid"`
+
+	// From /etc/os-release.
+	OSID         string `gorm:"column:os_id;type:text;not null;default:'';index" json:"os_id" example:"ubuntu"`
+	OSVersion    string `gorm:"column:os_version;type:text;not null;default:''" json:"os_version" example:"24.04"`
+	OSPrettyName string `gorm:"column:os_pretty_name;type:text;not null;default:''" json:"os_pretty_name"`
+
+	Kernel      string `gorm:"type:text;not null;default:''" json:"kernel" example:"6.8.0-45-generic"`
+	Arch        string `gorm:"type:text;not null;default:''" json:"arch" example:"x86_64"`
+	CPUCount    int    `gorm:"column:cpu_count;not null;default:0" json:"cpu_count"`
+	MemoryBytes int64  `gorm:"not null;default:0" json:"memory_bytes"`
+	// Disks is []ServerDisk.
+	Disks datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"disks" swaggertype:"array,object"`
+
+	// ContainerRuntime is the first of containerd, docker and cri-o found,
+	// empty when none is installed.
+	ContainerRuntime        string `gorm:"type:text;not null;default:''" json:"container_runtime" example:"containerd"`
+	ContainerRuntimeVersion string `gorm:"type:text;not null;default:''" json:"container_runtime_version" example:"1.7.22"`
+	// TimeSynced is nil when the host could not say (no timedatectl).
+	TimeSynced *bool `json:"time_synced,omitempty"`
+
+	CollectedAt   *time.Time `gorm:"type:timestamptz" json:"collected_at,omitempty" format:"date-time"`
+	LastAttemptAt time.Time  `gorm:"type:timestamptz;not null;default:now()" json:"last_attempt_at" format:"date-time"`
+	LastError     string     `gorm:"type:text;not null;default:''" json:"last_error"`
+}
+
+func (ServerFacts) TableName() string { return "server_facts" }
+
+// ServerDisk is one mounted filesystem.
+type ServerDisk struct {
+	Mount      string `json:"mount"`
+	Device     string `json:"device"`
+	SizeBytes  int64  `json:"size_bytes"`
+	AvailBytes int64  `json:"avail_bytes"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ServerInstanceCreating = "creating"
+	ServerInstanceRunning  = "running"
+	ServerInstanceDeleting = "deleting"
+	ServerInstanceFailed   = "failed"
+)
+
+// ServerInstance ties a server to the cloud machine autoglue created for it.
+// Servers typed in by hand have none. The row outlives a failed create so the
+// machine can still be found and deleted if the provider did make it.
+type ServerInstance struct {
+	ServerID       uuid.UUID  `gorm:"type:uuid;primaryKey" json:"server_id" format:"uuid"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id" format:"uuid"`
+	Provider       string     `gorm:"type:varchar(50);not null" json:"provider" example:"hetzner"`
+	CredentialID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"credential_id" format:"uuid"`
+	Size           string     `gorm:"type:text;not null" json:"size" example:"cx22"`
+	Image          string     `gorm:"type:text;not null" json:"image" example:"ubuntu-24.04"`
+	Location       string     `gorm:"type:text;not null;default:''" json:"location" example:"fsn1"`
+	Network        string     `gorm:"type:text;not null;default:''" json:"network,omitempty"`
+	InstanceID     string     `gorm:"type:text;not null;default:''" json:"instance_id,omitempty"`
+	Status         string     `gorm:"type:varchar(20);not null;default:'creating'" json:"status" enums:"creating,running,deleting,failed"`
+	LastError      string     `gorm:"type:text;not null;default:''" json:"last_error,omitempty"`
+	ReadyAt        *time.Time `gorm:"type:timestamptz" json:"ready_at,omitempty" format:"date-time"`
+	CreatedAt      time.Time  `gorm:"type:timestamptz;not null;default:now()" json:"created_at" format:"date-time"`
+	UpdatedAt      time.Time  `gorm:"type:timestamptz;autoUpdateTime;not null;default:now()" json:"updated_at" format:"date-time"`
+}
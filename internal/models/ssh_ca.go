@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// SshCertificateAuthority is an organization's SSH user CA. There is at most
+// one per org; the private half is encrypted with the org key exactly like an
+// SshKey's.
+type SshCertificateAuthority struct {
+	ID                  uuid.UUID    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	OrganizationID      uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex" json:"organization_id"`
+	Organization        Organization `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE" json:"-"`
+	PublicKey           string       `gorm:"not null" json:"public_key"`
+	EncryptedPrivateKey string       `gorm:"not null" json:"-"`
+	PrivateIV           string       `gorm:"not null" json:"-"`
+	PrivateTag          string       `gorm:"not null" json:"-"`
+	Fingerprint         string       `gorm:"not null" json:"fingerprint"`
+	CreatedAt           time.Time    `gorm:"type:timestamptz;not null;default:now()" json:"created_at" format:"date-time"`
+	UpdatedAt           time.Time    `gorm:"type:timestamptz;autoUpdateTime;not null;default:now()" json:"updated_at" format:"date-time"`
+}
+
+func (SshCertificateAuthority) TableName() string { return "ssh_certificate_authorities" }
+
+// SshCertificate records one certificate signed by an org CA. The certificate
+// itself is not kept: it is short-lived and the holder has it. This row is the
+// audit trail of who was issued what, for how long.
+type SshCertificate struct {
+	ID                   uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	OrganizationID       uuid.UUID      `gorm:"type:uuid;not null;index" json:"organization_id"`
+	Organization         Organization   `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE" json:"-"`
+	UserID               uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	Serial               int64          `gorm:"not null;index" json:"serial"`
+	KeyID                string         `gorm:"not null" json:"key_id"`
+	Principals           datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"principals" swaggertype:"array,string"`
+	PublicKeyFingerprint string         `gorm:"not null" json:"public_key_fingerprint"`
+	CAFingerprint        string         `gorm:"not null" json:"ca_fingerprint"`
+	ValidAfter           time.Time      `gorm:"type:timestamptz;not null" json:"valid_after" format:"date-time"`
+	ValidBefore          time.Time      `gorm:"type:timestamptz;not null" json:"valid_before" format:"date-time"`
+	CreatedAt            time.Time      `gorm:"type:timestamptz;not null;default:now();index" json:"created_at" format:"date-time"`
+}
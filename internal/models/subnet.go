@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Subnet is an address range an organization hands out server and load
+// balancer addresses from. CIDR is stored in its canonical form, with the
+// host bits cleared.
+type Subnet struct {
+	ID             uuid.UUID    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID    `json:"organization_id" gorm:"type:uuid;not null;index"`
+	Organization   Organization `json:"organization" gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE"`
+	Name           string       `json:"name" gorm:"not null"`
+	CIDR           string       `json:"cidr" gorm:"column:cidr;not null"`
+	Gateway        string       `json:"gateway" gorm:"not null;default:''"`
+	VLAN           string       `json:"vlan" gorm:"column:vlan;not null;default:''"`
+	CreatedAt      time.Time    `json:"created_at,omitempty" gorm:"type:timestamptz;column:created_at;not null;default:now()"`
+	UpdatedAt      time.Time    `json:"updated_at,omitempty" gorm:"type:timestamptz;autoUpdateTime;column:updated_at;not null;default:now()"`
+}
+
+// SubnetReservation holds an address the allocate endpoint handed out until
+// ExpiresAt, so concurrent callers are given different addresses. Saving a
+// server or load balancer with the address is what takes it for good.
+type SubnetReservation struct {
+	ID             uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;not null;uniqueIndex:idx_subnet_reservation_org_address"`
+	SubnetID       uuid.UUID `json:"subnet_id" gorm:"type:uuid;not null;index"`
+	Subnet         Subnet    `json:"-" gorm:"foreignKey:SubnetID;constraint:OnDelete:CASCADE"`
+	Address        string    `json:"address" gorm:"not null;uniqueIndex:idx_subnet_reservation_org_address"`
+	ExpiresAt      time.Time `json:"expires_at" gorm:"type:timestamptz;not null;index"`
+	CreatedAt      time.Time `json:"created_at,omitempty" gorm:"type:timestamptz;column:created_at;not null;default:now()"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TerminalSessionStatusOpen   = "open"
+	TerminalSessionStatusClosed = "closed"
+	TerminalSessionStatusFailed = "failed"
+)
+
+// TerminalSession is one interactive shell opened from the browser. The
+// transcript lives in job_logs under JobLogSubjectTerminalSession with this
+// row's ID; the row is what makes a session findable from its server.
+type TerminalSession struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id" format:"uuid"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id" format:"uuid"`
+	ServerID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"server_id" format:"uuid"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id" format:"uuid"`
+	Status         string     `gorm:"type:text;not null;default:'open'" json:"status"`
+	Via            string     `gorm:"type:text;not null;default:''" json:"via"`
+	Error          string     `gorm:"type:text;not null;default:''" json:"error"`
+	RemoteAddr     string     `gorm:"type:text;not null;default:''" json:"remote_addr"`
+	CreatedAt      time.Time  `gorm:"type:timestamptz;not null;default:now();index" json:"created_at" format:"date-time"`
+	EndedAt        *time.Time `gorm:"type:timestamptz" json:"ended_at,omitempty" format:"date-time"`
+}
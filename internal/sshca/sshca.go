@@ -0,0 +1,212 @@
+// Package sshca is the per-organization SSH user certificate authority.
+//
+// Instead of handing out long-lived private keys, autoglue signs a user's own
+// public key for a short TTL. Servers trust the org CA through
+// TrustedUserCAKeys and map certificate principals to local accounts through
+// an AuthorizedPrincipalsFile, so revocation is simply expiry.
+package sshca
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/glueops/autoglue/internal/utils"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Principal naming. Every certificate carries the holder's user principal and
+// one role principal per org role they hold, so an owner's certificate also
+// satisfies anything that admits admins.
+const (
+	PrincipalPrefix = "autoglue-"
+
+	// AdminPrincipal is what servers admit by default: the principals file
+	// written on install lists it for the server's ssh user.
+	AdminPrincipal = PrincipalPrefix + "admin"
+)
+
+// TTL bounds for issued certificates.
+const (
+	DefaultTTL = time.Hour
+	MinTTL     = 5 * time.Minute
+	MaxTTL     = 24 * time.Hour
+
+	// clockSkew backdates ValidAfter so a server whose clock runs slightly
+	// behind does not reject a certificate that was issued a moment ago.
+	clockSkew = 5 * time.Minute
+)
+
+var ErrInvalidTTL = fmt.Errorf("ttl must be between %s and %s", MinTTL, MaxTTL)
+
+// EnsureOrgCA returns the org's CA, generating it on first use.
+//
+// Creation is an INSERT ... ON CONFLICT DO NOTHING followed by a read, so two
+// requests racing to create the first CA both end up with the same one rather
+// than one of them failing on the unique index.
+func EnsureOrgCA(db *gorm.DB, orgID uuid.UUID) (*models.SshCertificateAuthority, error) {
+	var ca models.SshCertificateAuthority
+	err := db.Where("organization_id = ?", orgID).First(&ca).Error
+	if err == nil {
+		return &ca, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate ca key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("marshal ca key: %w", err)
+	}
+	var privPEM bytes.Buffer
+	if err := pem.Encode(&privPEM, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return nil, fmt.Errorf("encode ca key: %w", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("ca public key: %w", err)
+	}
+
+	cipher, iv, tag, err := utils.EncryptForOrg(orgID, privPEM.Bytes(), db)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt ca key: %w", err)
+	}
+
+	ca = models.SshCertificateAuthority{
+		OrganizationID:      orgID,
+		PublicKey:           strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " autoglue-user-ca",
+		EncryptedPrivateKey: cipher,
+		PrivateIV:           iv,
+		PrivateTag:          tag,
+		Fingerprint:         ssh.FingerprintSHA256(sshPub),
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ca).Error; err != nil {
+		return nil, fmt.Errorf("store ca: %w", err)
+	}
+
+	var out models.SshCertificateAuthority
+	if err := db.Where("organization_id = ?", orgID).First(&out).Error; err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Principals derives the certificate principals for a user from their id and
+// org roles. roles are the request-context role strings ("role:admin", ...);
+// anything else is ignored, which keeps machine principals out.
+func Principals(userID uuid.UUID, roles []string) []string {
+	out := []string{PrincipalPrefix + "user-" + userID.String()}
+	for _, r := range roles {
+		if name, ok := strings.CutPrefix(r, "role:"); ok && name != "" {
+			out = append(out, PrincipalPrefix+name)
+		}
+	}
+	return out
+}
+
+// Issue signs pub as a user certificate valid for ttl and records it.
+//
+// The audit row is written before the certificate is returned: a certificate
+// that cannot be logged is not handed out.
+func Issue(
+	db *gorm.DB,
+	ca *models.SshCertificateAuthority,
+	userID uuid.UUID,
+	pub ssh.PublicKey,
+	principals []string,
+	ttl time.Duration,
+) (*ssh.Certificate, *models.SshCertificate, error) {
+	if ttl < MinTTL || ttl > MaxTTL {
+		return nil, nil, ErrInvalidTTL
+	}
+	if _, isCert := pub.(*ssh.Certificate); isCert {
+		return nil, nil, errors.New("public key must be a plain key, not a certificate")
+	}
+
+	priv, err := utils.DecryptForOrg(ca.OrganizationID, ca.EncryptedPrivateKey, ca.PrivateIV, ca.PrivateTag, db)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decrypt ca key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(priv))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse ca key: %w", err)
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	certID := uuid.New()
+	cert := &ssh.Certificate{
+		Key:             pub,
+		Serial:          uint64(serial),
+		CertType:        ssh.UserCert,
+		KeyId:           fmt.Sprintf("autoglue:%s:%s", userID, certID),
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(now.Add(-clockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(ttl).Unix()),
+		Permissions: ssh.Permissions{
+			// Port forwarding is what ProxyJump through a bastion rides on.
+			// Agent and X11 forwarding stay off, matching the bastion's own
+			// sshd hardening.
+			Extensions: map[string]string{
+				"permit-pty":             "",
+				"permit-port-forwarding": "",
+			},
+		},
+	}
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		return nil, nil, fmt.Errorf("sign: %w", err)
+	}
+
+	principalsJSON, err := json.Marshal(principals)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rec := models.SshCertificate{
+		ID:                   certID,
+		OrganizationID:       ca.OrganizationID,
+		UserID:               userID,
+		Serial:               serial,
+		KeyID:                cert.KeyId,
+		Principals:           datatypes.JSON(principalsJSON),
+		PublicKeyFingerprint: ssh.FingerprintSHA256(pub),
+		CAFingerprint:        ca.Fingerprint,
+		ValidAfter:           time.Unix(int64(cert.ValidAfter), 0).UTC(),
+		ValidBefore:          time.Unix(int64(cert.ValidBefore), 0).UTC(),
+	}
+	if err := db.Create(&rec).Error; err != nil {
+		return nil, nil, fmt.Errorf("record certificate: %w", err)
+	}
+	return cert, &rec, nil
+}
+
+// randomSerial returns a positive 63-bit serial, so it survives the round
+// trip through a Postgres bigint unchanged.
+func randomSerial() (int64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, fmt.Errorf("serial: %w", err)
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) >> 1), nil
+}
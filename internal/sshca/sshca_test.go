@@ -0,0 +1,67 @@
+package sshca
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glueops/autoglue/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPrincipalsFollowRoles(t *testing.T) {
+	uid := uuid.New()
+
+	got := Principals(uid, []string{"role:owner", "role:admin", "role:member"})
+	want := []string{"autoglue-user-" + uid.String(), "autoglue-owner", "autoglue-admin", "autoglue-member"}
+	if !slices.Equal(got, want) {
+		t.Errorf("owner principals = %v, want %v", got, want)
+	}
+
+	// Machine roles carry no person and must not turn into principals.
+	got = Principals(uid, []string{"org:machine", "role:"})
+	if !slices.Equal(got, []string{"autoglue-user-" + uid.String()}) {
+		t.Errorf("non-role entries leaked into principals: %v", got)
+	}
+}
+
+// TTL and key shape are checked before the CA key is touched, so these need
+// no database.
+func TestIssueRejectsBadInput(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &models.SshCertificateAuthority{OrganizationID: uuid.New()}
+
+	for _, ttl := range []time.Duration{0, MinTTL - time.Second, MaxTTL + time.Second} {
+		if _, _, err := Issue(nil, ca, uuid.New(), sshPub, nil, ttl); !errors.Is(err, ErrInvalidTTL) {
+			t.Errorf("ttl %s: err = %v, want ErrInvalidTTL", ttl, err)
+		}
+	}
+
+	cert := &ssh.Certificate{Key: sshPub}
+	if _, _, err := Issue(nil, ca, uuid.New(), cert, nil, DefaultTTL); err == nil {
+		t.Error("signing a certificate as if it were a key succeeded")
+	}
+}
+
+func TestRandomSerialIsPositive(t *testing.T) {
+	for range 64 {
+		s, err := randomSerial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s <= 0 {
+			t.Fatalf("serial %d is not positive", s)
+		}
+	}
+}
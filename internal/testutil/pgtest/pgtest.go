@@ -126,7 +126,12 @@ func initDB() {
 		&models.RefreshToken{},
 		&models.OrganizationKey{},
 		&models.SshKey{},
+		&models.SshCertificateAuthority{},
+		&models.SshCertificate{},
 		&models.Server{},
+		&models.ServerFacts{},
+		&models.ServerInstance{},
+		&models.NodeMetadataStatus{},
 		&models.Taint{},
 		&models.Label{},
 		&models.Annotation{},
@@ -135,11 +140,17 @@ func initDB() {
 		&models.Domain{},
 		&models.RecordSet{},
 		&models.LoadBalancer{},
+		&models.Subnet{},
+		&models.SubnetReservation{},
 		&models.Cluster{},
 		&models.Action{},
 		&models.ClusterRun{},
+		&models.ClusterRunServer{},
+		&models.ExecRun{},
+		&models.ExecRunHost{},
 		&models.ClusterMetadata{},
 		&models.JobLog{},
+		&models.TerminalSession{},
 	); err != nil {
 		initErr = fmt.Errorf("migrate: %w", err)
 		return
@@ -80,9 +80,12 @@ const statusIcon = (s?: string) => {
     case "ready":
       return <CheckCircle2 className="h-4 w-4 text-emerald-600" />
     case "provisioning":
+    case "deleting":
       return <Loader2 className="h-4 w-4 animate-spin text-blue-600" />
     case "failed":
       return <AlertTriangle className="h-4 w-4 text-red-600" />
+    case "drifted":
+      return <AlertTriangle className="h-4 w-4 text-amber-600" />
     default:
       return <Circle className="text-muted-foreground h-4 w-4" />
   }
@@ -361,7 +364,7 @@ export const DnsPage = () => {
   const deleteDomainMut = useMutation({
     mutationFn: (id: string) => dnsApi.deleteDomain(id),
     onSuccess: async () => {
-      toast.success("Domain deleted")
+      toast.success("Domain deletion started")
       await qc.invalidateQueries({ queryKey: ["dns", "domains"] })
       setSelectedOverride(null)
     },
@@ -455,7 +458,7 @@ export const DnsPage = () => {
   const deleteRecordMut = useMutation({
     mutationFn: (id: string) => dnsApi.deleteRecordSetsByDomain(id),
     onSuccess: async () => {
-      toast.success("Record set deleted")
+      toast.success("Record set deletion started")
       await qc.invalidateQueries({ queryKey: ["dns", "records", selected?.id] })
     },
     onError: (e: any) =>
@@ -637,7 +640,8 @@ export const DnsPage = () => {
                             <AlertDialogHeader>
                               <AlertDialogTitle>Delete “{d.domain_name}”?</AlertDialogTitle>
                               <AlertDialogDescription>
-                                This deletes the domain metadata. External DNS records are not
+                                Record sets autoglue created are deleted from the DNS provider
+                                first, then the domain is removed. Records it does not own are not
                                 touched.
                               </AlertDialogDescription>
                             </AlertDialogHeader>
@@ -851,8 +855,8 @@ export const DnsPage = () => {
                                     Delete “{r.name || "@"} {r.type}”?
                                   </AlertDialogTitle>
                                   <AlertDialogDescription>
-                                    This removes the record set from your project. Your worker does
-                                    not delete it from the DNS provider right now.
+                                    The record set is deleted from the DNS provider, along with its
+                                    ownership marker, and then removed from your project.
                                   </AlertDialogDescription>
                                 </AlertDialogHeader>
                                 <AlertDialogFooter>